@@ -18,6 +18,7 @@ pragma solidity ^0.8.19;
 
 import "@openzeppelin/contracts/token/ERC20/IERC20.sol";
 import "@openzeppelin/contracts/token/ERC20/utils/SafeERC20.sol";
+import {MerkleProof} from "@openzeppelin/contracts/utils/cryptography/MerkleProof.sol";
 import "./interfaces/IYellowCustody.sol";
 import {IZKVerifier} from "./interfaces/IZKVerifier.sol";
 import {PoseidonT6} from "poseidon-solidity/PoseidonT6.sol";
@@ -43,6 +44,10 @@ contract DarkPoolRouter {
     /// against block.timestamp. This bounds how stale a proof can be.
     uint256 public constant MAX_PROOF_AGE = 300;
 
+    /// @notice Number of OrderDetails fields a field root commits to, indexed in declaration
+    /// order: 0 orderId, 1 user, 2 sellToken, 3 buyToken, 4 sellAmount, 5 minBuyAmount, 6 expiresAt
+    uint8 public constant ORDER_FIELDS = 7;
+
     // ============ STATE ============
 
     IYellowCustody public immutable custody;
@@ -51,6 +56,16 @@ contract DarkPoolRouter {
 
     mapping(bytes32 => Commitment) public commitments;
 
+    /// @notice Optional field-level commitment per order for staged disclosure: a Merkle
+    /// root with one leaf per OrderDetails field, so fields can be revealed one at a time
+    mapping(bytes32 => bytes32) public fieldRoots;
+
+    /// @notice Fields revealed so far per order, bit i set for field i
+    mapping(bytes32 => uint256) public revealedFields;
+
+    /// @notice Value of each revealed field, widened to uint256
+    mapping(bytes32 => mapping(uint8 => uint256)) public revealedValues;
+
     // Removed "Settling" status. With partial fills, orders stay Active
     // until explicitly marked Settled via markFullySettled. Each partial fill
     // increments settledAmount; the off-chain matches table tracks per-match state.
@@ -82,6 +97,14 @@ contract DarkPoolRouter {
         uint256 expiresAt;
     }
 
+    /// @notice One field disclosed by revealFields, with its salt and its leaf's proof
+    struct FieldReveal {
+        uint8 field;
+        uint256 value;
+        bytes32 salt;
+        bytes32[] proof;
+    }
+
     // ============ EVENTS ============
 
     event OrderCommitted(bytes32 indexed orderId, address indexed user, bytes32 orderHash);
@@ -91,6 +114,8 @@ contract DarkPoolRouter {
     // may reach full settlement at different times with partial fills.
     event OrderSettled(bytes32 indexed orderId);
     event OrderCancelled(bytes32 indexed orderId);
+    event FieldRootCommitted(bytes32 indexed orderId, bytes32 fieldRoot);
+    event FieldRevealed(bytes32 indexed orderId, uint8 indexed field, uint256 value);
 
     // ============ CONSTRUCTOR ============
 
@@ -121,6 +146,38 @@ contract DarkPoolRouter {
         return bytes32(PoseidonT4.hash([h1, o.minBuyAmount, o.expiresAt]));
     }
 
+    /// @notice Order fields in field-root order, widened to uint256
+    function _fieldValues(OrderDetails calldata o) internal pure returns (uint256[7] memory) {
+        return [
+            uint256(o.orderId),
+            uint256(uint160(o.user)),
+            uint256(uint160(o.sellToken)),
+            uint256(uint160(o.buyToken)),
+            o.sellAmount,
+            o.minBuyAmount,
+            o.expiresAt
+        ];
+    }
+
+    /// @notice Leaf of one field in a field root. Double hashed so a leaf can't pass as an
+    /// inner node; the salt keeps low-entropy values like amounts from being brute-forced.
+    function _fieldLeaf(bytes32 orderId, uint8 field, uint256 value, bytes32 salt) internal pure returns (bytes32) {
+        return keccak256(bytes.concat(keccak256(abi.encode(orderId, field, value, salt))));
+    }
+
+    /// @notice Require order details to agree with every field revealed for the order
+    function _checkRevealedFields(bytes32 orderId, OrderDetails calldata o) internal view {
+        uint256 revealed = revealedFields[orderId];
+        if (revealed == 0) return;
+
+        uint256[7] memory values = _fieldValues(o);
+        for (uint8 i = 0; i < ORDER_FIELDS; i++) {
+            if ((revealed & (uint256(1) << i)) != 0) {
+                require(revealedValues[orderId][i] == values[i], "Revealed field mismatch");
+            }
+        }
+    }
+
     // ============ USER FUNCTIONS ============
 
     /// @notice Deposit tokens to Yellow and commit order hash (one transaction)
@@ -188,6 +245,50 @@ contract DarkPoolRouter {
         emit OrderCancelled(orderId);
     }
 
+    /// @notice Commit a field root so the order's fields can be revealed in stages.
+    /// @dev Leaf i is _fieldLeaf(orderId, i, value, salt) for field i (see ORDER_FIELDS), each
+    ///      with its own random salt; the 7 leaves are padded to 8 with bytes32(0) and pairs are
+    ///      hashed sorted, as OpenZeppelin MerkleProof expects. Nothing ties the root to orderHash
+    ///      on-chain, so revealAndSettle rejects details contradicting any revealed field.
+    function commitFieldRoot(bytes32 orderId, bytes32 fieldRoot) external {
+        Commitment storage c = commitments[orderId];
+        require(c.user == msg.sender, "Not your order");
+        require(c.status == Status.Active, "Not active");
+        require(fieldRoots[orderId] == bytes32(0), "Field root exists");
+        require(fieldRoot != bytes32(0), "Empty field root");
+
+        fieldRoots[orderId] = fieldRoot;
+
+        emit FieldRootCommitted(orderId, fieldRoot);
+    }
+
+    /// @notice Reveal some of an order's fields, each verified against the order's field root.
+    /// Fields may be revealed over several calls, in any order, but each only once.
+    function revealFields(bytes32 orderId, FieldReveal[] calldata reveals) external {
+        Commitment storage c = commitments[orderId];
+        require(c.user == msg.sender, "Not your order");
+        require(c.status == Status.Active, "Not active");
+        bytes32 root = fieldRoots[orderId];
+        require(root != bytes32(0), "No field root");
+
+        uint256 revealed = revealedFields[orderId];
+        for (uint256 i = 0; i < reveals.length; i++) {
+            FieldReveal calldata r = reveals[i];
+            require(r.field < ORDER_FIELDS, "Unknown field");
+            require((revealed & (uint256(1) << r.field)) == 0, "Field already revealed");
+            require(
+                MerkleProof.verifyCalldata(r.proof, root, _fieldLeaf(orderId, r.field, r.value, r.salt)),
+                "Invalid field proof"
+            );
+
+            revealed |= uint256(1) << r.field;
+            revealedValues[orderId][r.field] = r.value;
+
+            emit FieldRevealed(orderId, r.field, r.value);
+        }
+        revealedFields[orderId] = revealed;
+    }
+
     // ============ ENGINE FUNCTIONS ============
 
     /// @notice Verify order details and settle a partial or full fill (fallback path — reveals order details).
@@ -220,6 +321,10 @@ contract DarkPoolRouter {
         require(_computeOrderHash(seller) == sellerC.orderHash, "Seller hash mismatch");
         require(_computeOrderHash(buyer) == buyerC.orderHash, "Buyer hash mismatch");
 
+        // Fields disclosed in stages must be the ones settled
+        _checkRevealedFields(sellerOrderId, seller);
+        _checkRevealedFields(buyerOrderId, buyer);
+
         // Verify not expired
         require(block.timestamp < seller.expiresAt, "Seller expired");
         require(block.timestamp < buyer.expiresAt, "Buyer expired");
@@ -124,6 +124,83 @@ contract DarkPoolRouterTest is Test {
         router.depositAndCommit(token, depositAmount, order.orderId, hash);
     }
 
+    /// @notice Salt of one field, as the frontend would draw at random
+    function _fieldSalt(bytes32 orderId, uint256 field) internal pure returns (bytes32) {
+        return keccak256(abi.encode("field-salt", orderId, field));
+    }
+
+    function _fieldValues(DarkPoolRouter.OrderDetails memory o) internal pure returns (uint256[7] memory) {
+        return [
+            uint256(o.orderId),
+            uint256(uint160(o.user)),
+            uint256(uint160(o.sellToken)),
+            uint256(uint160(o.buyToken)),
+            o.sellAmount,
+            o.minBuyAmount,
+            o.expiresAt
+        ];
+    }
+
+    /// @notice Sorted-pair hash, as OpenZeppelin MerkleProof computes it
+    function _hashPair(bytes32 a, bytes32 b) internal pure returns (bytes32) {
+        return a < b ? keccak256(abi.encodePacked(a, b)) : keccak256(abi.encodePacked(b, a));
+    }
+
+    /// @notice Field leaves matching the contract's _fieldLeaf, padded to 8
+    function _fieldLeaves(DarkPoolRouter.OrderDetails memory o) internal pure returns (bytes32[8] memory leaves) {
+        uint256[7] memory values = _fieldValues(o);
+        for (uint256 i = 0; i < 7; i++) {
+            leaves[i] =
+                keccak256(bytes.concat(keccak256(abi.encode(o.orderId, uint8(i), values[i], _fieldSalt(o.orderId, i)))));
+        }
+    }
+
+    function _fieldRoot(DarkPoolRouter.OrderDetails memory o) internal pure returns (bytes32) {
+        bytes32[8] memory leaves = _fieldLeaves(o);
+        return _hashPair(
+            _hashPair(_hashPair(leaves[0], leaves[1]), _hashPair(leaves[2], leaves[3])),
+            _hashPair(_hashPair(leaves[4], leaves[5]), _hashPair(leaves[6], leaves[7]))
+        );
+    }
+
+    /// @notice Reveal of one field of o, with its proof against _fieldRoot(o)
+    function _fieldReveal(DarkPoolRouter.OrderDetails memory o, uint8 field)
+        internal
+        pure
+        returns (DarkPoolRouter.FieldReveal memory r)
+    {
+        bytes32[8] memory leaves = _fieldLeaves(o);
+        uint256 i = field;
+        uint256 pair = (i / 2) ^ 1; // Sibling of the leaf's pair
+        uint256 quad = (i / 4) ^ 1; // Sibling of the leaf's half
+
+        r.field = field;
+        r.value = _fieldValues(o)[i];
+        r.salt = _fieldSalt(o.orderId, i);
+        r.proof = new bytes32[](3);
+        r.proof[0] = leaves[i ^ 1];
+        r.proof[1] = _hashPair(leaves[pair * 2], leaves[pair * 2 + 1]);
+        r.proof[2] = _hashPair(
+            _hashPair(leaves[quad * 4], leaves[quad * 4 + 1]), _hashPair(leaves[quad * 4 + 2], leaves[quad * 4 + 3])
+        );
+    }
+
+    function _reveal(address user, bytes32 orderId, DarkPoolRouter.FieldReveal memory r) internal {
+        DarkPoolRouter.FieldReveal[] memory reveals = new DarkPoolRouter.FieldReveal[](1);
+        reveals[0] = r;
+        vm.prank(user);
+        router.revealFields(orderId, reveals);
+    }
+
+    /// @notice Commit the standard seller order along with its field root
+    function _commitSellerWithFieldRoot() internal returns (DarkPoolRouter.OrderDetails memory seller) {
+        seller = _makeSellerOrder(100 ether, 90 ether, block.timestamp + 1 hours);
+        _commitOrder(alice, address(tokenA), 100 ether, seller);
+        bytes32 root = _fieldRoot(seller);
+        vm.prank(alice);
+        router.commitFieldRoot(SELLER_ORDER_ID, root);
+    }
+
     // ============ HAPPY PATH: FULL FILL ============
 
     function test_FullLifecycle() public {
@@ -511,6 +588,124 @@ contract DarkPoolRouterTest is Test {
         router.commitOnly(goodId, badHash);
     }
 
+    // ============ PARTIAL REVEAL ============
+
+    function test_PartialReveal_InStages() public {
+        DarkPoolRouter.OrderDetails memory seller = _commitSellerWithFieldRoot();
+        DarkPoolRouter.OrderDetails memory buyer =
+            _makeBuyerOrder(BUYER_ORDER_ID, bob, 95 ether, 90 ether, seller.expiresAt);
+        _commitOrder(bob, address(tokenB), 95 ether, buyer);
+        assertEq(router.fieldRoots(SELLER_ORDER_ID), _fieldRoot(seller));
+
+        // Stage 1: size only
+        _reveal(alice, SELLER_ORDER_ID, _fieldReveal(seller, 4));
+        assertEq(router.revealedFields(SELLER_ORDER_ID), 1 << 4);
+        assertEq(router.revealedValues(SELLER_ORDER_ID, 4), 100 ether);
+        assertEq(router.revealedValues(SELLER_ORDER_ID, 5), 0);
+
+        // Stage 2: price limit and expiry in one call
+        DarkPoolRouter.FieldReveal[] memory reveals = new DarkPoolRouter.FieldReveal[](2);
+        reveals[0] = _fieldReveal(seller, 5);
+        reveals[1] = _fieldReveal(seller, 6);
+        vm.prank(alice);
+        router.revealFields(SELLER_ORDER_ID, reveals);
+        assertEq(router.revealedFields(SELLER_ORDER_ID), (1 << 4) | (1 << 5) | (1 << 6));
+        assertEq(router.revealedValues(SELLER_ORDER_ID, 5), 90 ether);
+        assertEq(router.revealedValues(SELLER_ORDER_ID, 6), seller.expiresAt);
+
+        // The full reveal agrees with the staged one
+        vm.prank(engine);
+        router.revealAndSettle(SELLER_ORDER_ID, BUYER_ORDER_ID, seller, buyer, 100 ether, 95 ether);
+        (,,, uint256 settledAmount,) = router.commitments(SELLER_ORDER_ID);
+        assertEq(settledAmount, 100 ether);
+    }
+
+    function test_RevertPartialReveal_WrongValue() public {
+        DarkPoolRouter.OrderDetails memory seller = _commitSellerWithFieldRoot();
+        DarkPoolRouter.FieldReveal memory r = _fieldReveal(seller, 4);
+        r.value = 99 ether;
+
+        vm.expectRevert("Invalid field proof");
+        _reveal(alice, SELLER_ORDER_ID, r);
+    }
+
+    function test_RevertPartialReveal_ProofOfAnotherField() public {
+        DarkPoolRouter.OrderDetails memory seller = _commitSellerWithFieldRoot();
+        // minBuyAmount's leaf and proof, claimed as sellAmount
+        DarkPoolRouter.FieldReveal memory r = _fieldReveal(seller, 5);
+        r.field = 4;
+
+        vm.expectRevert("Invalid field proof");
+        _reveal(alice, SELLER_ORDER_ID, r);
+    }
+
+    function test_RevertPartialReveal_Twice() public {
+        DarkPoolRouter.OrderDetails memory seller = _commitSellerWithFieldRoot();
+        DarkPoolRouter.FieldReveal memory r = _fieldReveal(seller, 4);
+        _reveal(alice, SELLER_ORDER_ID, r);
+
+        vm.expectRevert("Field already revealed");
+        _reveal(alice, SELLER_ORDER_ID, r);
+    }
+
+    function test_RevertPartialReveal_UnknownField() public {
+        DarkPoolRouter.OrderDetails memory seller = _commitSellerWithFieldRoot();
+        DarkPoolRouter.FieldReveal memory r = _fieldReveal(seller, 6);
+        r.field = 7;
+
+        vm.expectRevert("Unknown field");
+        _reveal(alice, SELLER_ORDER_ID, r);
+    }
+
+    function test_RevertPartialReveal_NotOwner() public {
+        DarkPoolRouter.OrderDetails memory seller = _commitSellerWithFieldRoot();
+        DarkPoolRouter.FieldReveal memory r = _fieldReveal(seller, 4);
+
+        vm.expectRevert("Not your order");
+        _reveal(bob, SELLER_ORDER_ID, r);
+    }
+
+    function test_RevertPartialReveal_NoFieldRoot() public {
+        DarkPoolRouter.OrderDetails memory seller = _makeSellerOrder(100 ether, 90 ether, block.timestamp + 1 hours);
+        _commitOrder(alice, address(tokenA), 100 ether, seller);
+        DarkPoolRouter.FieldReveal memory r = _fieldReveal(seller, 4);
+
+        vm.expectRevert("No field root");
+        _reveal(alice, SELLER_ORDER_ID, r);
+    }
+
+    function test_RevertCommitFieldRoot_NotOwnerOrTwice() public {
+        DarkPoolRouter.OrderDetails memory seller = _commitSellerWithFieldRoot();
+        bytes32 root = _fieldRoot(seller);
+
+        vm.prank(bob);
+        vm.expectRevert("Not your order");
+        router.commitFieldRoot(SELLER_ORDER_ID, root);
+
+        vm.prank(alice);
+        vm.expectRevert("Field root exists");
+        router.commitFieldRoot(SELLER_ORDER_ID, root);
+    }
+
+    function test_RevertRevealAndSettle_ContradictsRevealedField() public {
+        uint256 expiresAt = block.timestamp + 1 hours;
+        DarkPoolRouter.OrderDetails memory seller = _makeSellerOrder(100 ether, 90 ether, expiresAt);
+        DarkPoolRouter.OrderDetails memory buyer = _makeBuyerOrder(BUYER_ORDER_ID, bob, 95 ether, 90 ether, expiresAt);
+        _commitOrder(alice, address(tokenA), 100 ether, seller);
+        _commitOrder(bob, address(tokenB), 95 ether, buyer);
+
+        // The field root discloses a smaller size than the order hash commits to
+        DarkPoolRouter.OrderDetails memory claimed = _makeSellerOrder(50 ether, 90 ether, expiresAt);
+        bytes32 root = _fieldRoot(claimed);
+        vm.prank(alice);
+        router.commitFieldRoot(SELLER_ORDER_ID, root);
+        _reveal(alice, SELLER_ORDER_ID, _fieldReveal(claimed, 4));
+
+        vm.prank(engine);
+        vm.expectRevert("Revealed field mismatch");
+        router.revealAndSettle(SELLER_ORDER_ID, BUYER_ORDER_ID, seller, buyer, 100 ether, 95 ether);
+    }
+
     // ============ PROVE AND SETTLE (ZK) ============
 
     // Dummy proof data for MockZKVerifier (always returns true)
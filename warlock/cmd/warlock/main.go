@@ -9,6 +9,7 @@ import (
 
 	"github.com/darkpool/warlock/internal/config"
 	"github.com/darkpool/warlock/internal/db"
+	"github.com/darkpool/warlock/internal/events"
 	grpcserver "github.com/darkpool/warlock/internal/grpc"
 	"github.com/darkpool/warlock/internal/matcher"
 	"github.com/rs/zerolog"
@@ -48,6 +49,15 @@ func main() {
 	}
 	defer db.Close(pool)
 
+	// Apply pending migrations before anything reads the schema
+	if cfg.AutoMigrate {
+		applied, err := db.RunMigrations(ctx, pool, cfg.MigrationsDir, cfg.MigrationsBaseline)
+		if err != nil {
+			log.Fatal().Err(err).Strs("applied", applied).Msg("Failed to apply migrations")
+		}
+		log.Info().Strs("applied", applied).Msg("Database migrations up to date")
+	}
+
 	// Run database migrations (simple check)
 	if err := checkDatabaseSchema(ctx, pool); err != nil {
 		log.Fatal().Err(err).Msg("Database schema check failed")
@@ -55,6 +65,25 @@ func main() {
 
 	// Create matching engine
 	engine := matcher.NewEngine(pool, cfg)
+	var hooks matcher.MatchHooks
+	if cfg.MatchWebhookURL != "" {
+		hooks = append(hooks, matcher.NewWebhookHook(cfg.MatchWebhookURL))
+	}
+
+	// Relay the event outbox to the broker
+	if cfg.EventBroker != "" {
+		publisher, err := events.NewPublisher(cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create event publisher")
+		}
+		relay := events.NewRelay(pool, cfg, publisher)
+		hooks = append(hooks, relay)
+		go relay.Run(ctx)
+		log.Info().Str("broker", cfg.EventBroker).Str("topic", cfg.EventTopic).Msg("Event publishing enabled")
+	}
+	if len(hooks) > 0 {
+		engine.SetMatchHook(hooks)
+	}
 
 	// Start matching engine
 	if err := engine.Start(ctx); err != nil {
@@ -84,11 +113,14 @@ func main() {
 		log.Info().Str("signal", sig.String()).Msg("Shutdown signal received")
 	}
 
-	// Graceful shutdown
-	log.Info().Msg("Shutting down gracefully...")
+	// Graceful shutdown. Every server draining connections shares one
+	// deadline, so shutdown is bounded however many clients are connected.
+	log.Info().Dur("timeout", cfg.ShutdownTimeout).Msg("Shutting down gracefully...")
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
 
 	// Stop gRPC server
-	grpcSrv.Stop()
+	grpcSrv.Shutdown(shutdownCtx)
 
 	// Stop matching engine
 	engine.Stop()
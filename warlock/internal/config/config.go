@@ -1,52 +1,503 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// Execution price modes
+const (
+	ExecutionPriceMidpoint = "MIDPOINT"
+	ExecutionPriceVWAP     = "VWAP"
+)
+
+// Execution price tie-breaks within the overlap of two orders' ranges
+const (
+	PriceTieBreakSplit = "SPLIT"
+	PriceTieBreakMaker = "MAKER_FAVORABLE"
+	PriceTieBreakTaker = "TAKER_FAVORABLE"
+)
+
+// Engine roles
+const (
+	EngineRolePrimary = "PRIMARY"
+	EngineRoleStandby = "STANDBY"
+)
+
+// Matching modes
+const (
+	MatchingModeContinuous   = "CONTINUOUS"
+	MatchingModeBatchAuction = "BATCH_AUCTION"
+)
+
+// Maker/taker policies: which of two crossing orders took liquidity
+const (
+	MakerTakerIncoming     = "INCOMING"
+	MakerTakerEarlierOrder = "EARLIER_ORDER"
+)
+
+// Candidate rankings: the order compatible makers are tried in
+const (
+	CandidateRankingLimit       = "LIMIT"
+	CandidateRankingImprovement = "PRICE_IMPROVEMENT"
+	CandidateRankingTime        = "TIME_WITHIN_TOLERANCE"
+)
+
+// What the engine does when a match hook fails
+const (
+	MatchHookFailureLog   = "LOG"   // Log the failure and carry on
+	MatchHookFailureBlock = "BLOCK" // Retry until the hook succeeds, holding the worker
+)
+
+// Book display policies: how much of a book GetOrderBook and GetDepthChart
+// reveal
+const (
+	BookDisplayFull       = "FULL"        // Every level with its quantity and order count
+	BookDisplayBucketed   = "BUCKETED"    // Quantities rounded up to BookDisplayBucket, no order counts
+	BookDisplayIndicative = "INDICATIVE"  // Prices only
+	BookDisplayTop        = "TOP_OF_BOOK" // Best bid and ask prices only
+	BookDisplayNone       = "NONE"        // No levels at all
+)
+
+// Event brokers
+const (
+	EventBrokerNATS  = "NATS"  // Publishes to a NATS server
+	EventBrokerKafka = "KAFKA" // Publishes to a Kafka cluster
+)
+
+// maxPriceSignificantDigits bounds PriceSignificantDigits; NUMERIC prices
+// carry no more meaningful precision than this
+const maxPriceSignificantDigits = 38
+
+// Precision of the NUMERIC(36, 18) columns prices and quantities are stored in
+const (
+	storedDecimalPlaces     = 18
+	storedSignificantDigits = 36
 )
 
-// Config holds all configuration for the warlock service
+// MatchingRules overrides how one pair is matched. Empty fields keep the
+// global setting.
+type MatchingRules struct {
+	ExecutionPriceMode  string `yaml:"execution_price_mode"`
+	PriceTieBreak       string `yaml:"price_tie_break"`
+	CandidateRanking    string `yaml:"candidate_ranking"`
+	MaxMatchesPerSecond int    `yaml:"max_matches_per_second"`
+
+	PriceSignificantDigits int `yaml:"price_significant_digits"`
+}
+
+// OrderBounds caps a single order's price and notional (price * quantity),
+// limits the size of each fill and sets the lot size order and fill
+// quantities must be multiples of. A zero value leaves that bound unset.
+type OrderBounds struct {
+	MaxPrice     decimal.Decimal `yaml:"max_price"`
+	MaxNotional  decimal.Decimal `yaml:"max_notional"`
+	MinMatchSize decimal.Decimal `yaml:"min_match_size"`
+	MaxMatchSize decimal.Decimal `yaml:"max_match_size"`
+	LotSize      decimal.Decimal `yaml:"lot_size"`
+
+	// Smallest fill notional (quantity * price, in quote) worth settling on-chain
+	MinSettlementNotional decimal.Decimal `yaml:"min_settlement_notional"`
+
+	// Smallest quantity one taker may fill against a maker in total, unless
+	// that empties the maker
+	MinMakerFill decimal.Decimal `yaml:"min_maker_fill"`
+}
+
+// APIKey authorizes order submissions. With Addresses set, the key may only
+// act for those user addresses; with Admin set, it may also call the admin
+// RPCs.
+type APIKey struct {
+	SHA256    string   `yaml:"sha256"` // Hex SHA-256 of the key
+	Addresses []string `yaml:"addresses"`
+	Admin     bool     `yaml:"admin"`
+}
+
+// Config holds all configuration for the warlock service.
+// The yaml tags name the keys accepted in the optional config file.
 type Config struct {
 	// Server configuration
-	GRPCPort int
-	Workers  int
+	GRPCPort int `yaml:"grpc_port"`
+	Workers  int `yaml:"workers"`
+
+	// PRIMARY matches orders; STANDBY keeps its books in step with the
+	// database every StandbySyncInterval, ready to be promoted. With
+	// PrimaryAddr set a standby compares its book checksums with the
+	// primary's every StandbyCheckInterval.
+	EngineRole           string        `yaml:"engine_role"`
+	StandbySyncInterval  time.Duration `yaml:"standby_sync_interval"`
+	PrimaryAddr          string        `yaml:"primary_addr"`
+	StandbyCheckInterval time.Duration `yaml:"standby_check_interval"`
+
+	// Worker autoscaling based on order queue depth
+	WorkerAutoscale    bool          `yaml:"worker_autoscale"`
+	WorkersMin         int           `yaml:"workers_min"`
+	WorkersMax         int           `yaml:"workers_max"`
+	QueueHighWatermark int           `yaml:"queue_high_watermark"`
+	QueueLowWatermark  int           `yaml:"queue_low_watermark"`
+	AutoscaleInterval  time.Duration `yaml:"autoscale_interval"`
+
+	// Speed bump: each incoming order is held for a random delay between
+	// SpeedBumpMin and SpeedBumpMax, neither matching nor matchable, so
+	// arriving microseconds sooner buys nothing (0 and 0 disable)
+	SpeedBumpMin time.Duration `yaml:"speed_bump_min"`
+	SpeedBumpMax time.Duration `yaml:"speed_bump_max"`
 
 	// Database configuration
-	DatabaseURL         string
-	DatabaseMaxConns    int
-	DatabaseMinConns    int
-	DatabaseMaxConnLife time.Duration
+	DatabaseURL         string        `yaml:"database_url"`
+	DatabaseMaxConns    int           `yaml:"database_max_conns"`
+	DatabaseMinConns    int           `yaml:"database_min_conns"`
+	DatabaseMaxConnLife time.Duration `yaml:"database_max_conn_life"`
+
+	// Schema migrations applied by the engine: at startup with AutoMigrate, or
+	// on demand through ApplyMigrations. MigrationsBaseline is the last
+	// migration applied by hand before schema_migrations tracked them.
+	AutoMigrate        bool   `yaml:"auto_migrate"`
+	MigrationsDir      string `yaml:"migrations_dir"`
+	MigrationsBaseline string `yaml:"migrations_baseline"`
+
+	// Per-class query timeouts so slow reporting queries can't hold pooled
+	// connections the matching path needs
+	MatchQueryTimeout time.Duration `yaml:"match_query_timeout"` // Candidate lookup in MatchOrder
+	ReadQueryTimeout  time.Duration `yaml:"read_query_timeout"`  // Lookup and reporting RPCs
+
+	// Consecutive failed match attempts against the database after which the
+	// engine stops matching until a probe finds it reachable again (0 disables)
+	DBFailureThreshold int           `yaml:"db_failure_threshold"`
+	DBProbeInterval    time.Duration `yaml:"db_probe_interval"`
 
 	// Matching engine configuration
-	OrderChannelSize  int
-	MatchChannelSize  int
-	CancelChannelSize int
+	OrderChannelSize  int `yaml:"order_channel_size"`
+	MatchChannelSize  int `yaml:"match_channel_size"`
+	CancelChannelSize int `yaml:"cancel_channel_size"`
+
+	// How long a synchronous SubmitOrder waits for the engine to match the order
+	SyncSubmitTimeout time.Duration `yaml:"sync_submit_timeout"`
+
+	// Minimum time an order's expiry must lie beyond the server clock when
+	// it is submitted, so a client clock running behind can't place an order
+	// that is already expired or about to be (0 only rejects past expiries)
+	ExpiryClockSkewTolerance time.Duration `yaml:"expiry_clock_skew_tolerance"`
+
+	// How long shutdown waits for in-flight RPCs and open streams to finish
+	// before closing them
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+
+	// How long CancelOrder waits for room in a full cancel channel before failing
+	CancelSubmitTimeout time.Duration `yaml:"cancel_submit_timeout"`
+
+	// Match hooks run after each match is recorded: each call's deadline,
+	// what a failure does (LOG or BLOCK), and a URL every match is POSTed to
+	// (empty disables the webhook)
+	MatchHookTimeout     time.Duration `yaml:"match_hook_timeout"`
+	MatchHookFailureMode string        `yaml:"match_hook_failure_mode"`
+	MatchWebhookURL      string        `yaml:"match_webhook_url"`
+
+	// Event publishing through the event_outbox table: the broker (NATS or
+	// KAFKA, or empty to disable), its URL, the subject prefix or topic events
+	// go to, and how often and in what batches the outbox is relayed
+	EventBroker       string        `yaml:"event_broker"`
+	EventBrokerURL    string        `yaml:"event_broker_url"`
+	EventTopic        string        `yaml:"event_topic"`
+	EventPollInterval time.Duration `yaml:"event_poll_interval"`
+	EventBatchSize    int           `yaml:"event_batch_size"`
+
+	// Pairs ("BASE/QUOTE") loaded before the engine starts serving; all other
+	// books warm up in the background. Empty loads everything up front.
+	HotPairs []string `yaml:"hot_pairs"`
+
+	// Pairs ops have opened or retired ("BASE/QUOTE"). Orders on a pair that
+	// isn't tradable are rejected, and resting ones are cancelled at startup.
+	TradablePairs []string `yaml:"tradable_pairs"` // Empty allows every pair
+	DisabledPairs []string `yaml:"disabled_pairs"`
+
+	// Cap on distinct pairs with an in-memory book; orders for a new pair past
+	// it are rejected unless the pair is in HotPairs or TradablePairs (0 = no cap)
+	MaxPairs int `yaml:"max_pairs"`
+
+	// Empty books untouched this long are dropped from memory (0 disables)
+	BookIdleTTL time.Duration `yaml:"book_idle_ttl"`
+
+	// Books untouched this long have their heaps and indexes reallocated at
+	// their current size, returning memory left over from busier times (0 disables)
+	BookCompactAfter time.Duration `yaml:"book_compact_after"`
+
+	// How often every book's best bid and ask is recorded to book_snapshots (0 disables)
+	BookSnapshotInterval time.Duration `yaml:"book_snapshot_interval"`
+
+	// How often resting orders are checked against the database and drift
+	// corrected (0 disables), and how long the books may go without a
+	// successful check before new orders are rejected (0 never rejects)
+	BookReconcileInterval time.Duration `yaml:"book_reconcile_interval"`
+	MaxBookStaleness      time.Duration `yaml:"max_book_staleness"`
+
+	// Directory of the book write-ahead log and checkpoint, from which a
+	// restarted engine recovers its books (empty disables), and how often the
+	// books are checkpointed there and the log truncated
+	BookWALDir        string        `yaml:"book_wal_dir"`
+	BookWALCheckpoint time.Duration `yaml:"book_wal_checkpoint"`
+
+	// Named liquidity pools orders may route to, besides the shared pool
+	Pools []string `yaml:"pools"`
+
+	// Chain ids orders may be submitted for (empty accepts any chain)
+	SupportedChains []int32 `yaml:"supported_chains"`
+
+	// Chain ids paused at startup, e.g. for settlement maintenance; see
+	// Engine.PauseChain. SetChainStatus changes this at runtime.
+	PausedChains []int32 `yaml:"paused_chains"`
+
+	// Pairs ("BASE/QUOTE") whose orders always record a match trace
+	TracePairs []string `yaml:"trace_pairs"`
+
+	// Pairs ("BASE/QUOTE") that reject taker orders nothing on the other side
+	// of the book can match, rather than let them rest one-sided
+	OneSidedRejectPairs []string `yaml:"one_sided_reject_pairs"`
+
+	// Fat-finger guards checked at submission. OrderBounds applies to every
+	// pair without its own entry in PairOrderBounds (keyed "BASE/QUOTE").
+	OrderBounds     OrderBounds            `yaml:"order_bounds"`
+	PairOrderBounds map[string]OrderBounds `yaml:"pair_order_bounds"`
+
+	// Most decimal places and significant digits a submitted price or
+	// quantity may be written with
+	MaxDecimalPlaces     int `yaml:"max_decimal_places"`
+	MaxSignificantDigits int `yaml:"max_significant_digits"`
+
+	// Self-trade prevention: orders whose owners belong to the same entity
+	// never match. Entities maps an entity id to its addresses; an address in
+	// no entity is an entity of its own.
+	SelfTradePrevention bool                `yaml:"self_trade_prevention"`
+	Entities            map[string][]string `yaml:"entities"`
+
+	// Lowercased address -> entity id, built from Entities by Load
+	entityByAddress map[string]string
+
+	// API keys order submissions must carry, by key name; none disables the
+	// check for submissions. Admin RPCs always need a key marked admin. Only
+	// the keys' SHA-256 hashes are configured.
+	APIKeys map[string]APIKey `yaml:"api_keys"`
+
+	// Lowercased hex hash -> key name, built from APIKeys by Load
+	apiKeyByHash map[string]string
+
+	// Claim candidates with FOR UPDATE SKIP LOCKED in one transaction per
+	// incoming order, so concurrent workers never contend for the same rows
+	MatchSkipLocked bool `yaml:"match_skip_locked"`
+
+	// At equal price, orders resting longer than this lose time priority to
+	// younger ones, to favour fresh liquidity (0 keeps plain FIFO)
+	PriorityDecayAfter time.Duration `yaml:"priority_decay_after"`
+
+	// Make matching reproducible for replays and golden-file tests: time
+	// priority ties break on the order sequence instead of the clock, and a
+	// single worker processes orders in submission order
+	DeterministicMatching bool `yaml:"deterministic_matching"`
+
+	// CONTINUOUS matches each order as it arrives; BATCH_AUCTION lets orders
+	// rest and crosses each book every AuctionInterval at one clearing price
+	MatchingMode    string        `yaml:"matching_mode"`
+	AuctionInterval time.Duration `yaml:"auction_interval"`
+
+	// How a taker crossing several makers is priced: MIDPOINT prices each fill
+	// on its own, VWAP executes every fill at the quantity-weighted blend
+	ExecutionPriceMode string `yaml:"execution_price_mode"`
+
+	// Where in the overlap of two orders' price ranges a fill is priced
+	PriceTieBreak string `yaml:"price_tie_break"`
+
+	// Which order of a match is the taker: INCOMING treats the order being
+	// matched as the taker, EARLIER_ORDER makes whichever arrived first the
+	// maker
+	MakerTakerPolicy string `yaml:"maker_taker_policy"`
+
+	// Order compatible candidates are tried in: LIMIT follows their limit
+	// prices, PRICE_IMPROVEMENT the taker's improvement at the execution price,
+	// TIME_WITHIN_TOLERANCE age among those within CandidateToleranceBps of the best
+	CandidateRanking string `yaml:"candidate_ranking"`
+
+	// Width, in basis points of the best candidate's limit price, of the band
+	// TIME_WITHIN_TOLERANCE ranking treats as equally priced
+	CandidateToleranceBps int `yaml:"candidate_tolerance_bps"`
+
+	// Spread each taker across as many makers as possible, capping every
+	// maker's fill at an even share before filling the rest in priority order
+	CounterpartyDiversity bool `yaml:"counterparty_diversity"`
+
+	// Per-pair overrides of the three settings above, MaxMatchesPerSecond and
+	// PriceSignificantDigits, keyed "BASE/QUOTE"
+	PairMatching map[string]MatchingRules `yaml:"pair_matching"`
+
+	// Matches a single pair may produce per second; orders past the rate rest
+	// and are matched once it allows (0 disables)
+	MaxMatchesPerSecond int `yaml:"max_matches_per_second"`
+
+	// Significant digits limit prices are rounded to before deciding whether
+	// two orders cross, so quotes differing only past that precision still
+	// match (0 compares full precision)
+	PriceSignificantDigits int `yaml:"price_significant_digits"`
+
+	// Minimum width, in basis points of its midpoint, by which a buy and a
+	// sell band must overlap to match; a band inside the other always does
+	// (0 matches on any overlap)
+	MinBandOverlapBps int `yaml:"min_band_overlap_bps"`
+
+	// Trading sessions for pairs that only match during set hours, keyed
+	// "BASE/QUOTE"; pairs without one trade around the clock
+	MarketHours map[string]*MarketSession `yaml:"market_hours"`
+
+	// Minimum spread, in basis points of the mid price, that an order's unfilled
+	// remainder must leave against the opposite best to rest (0 disables)
+	MinRestingSpreadBps int `yaml:"min_resting_spread_bps"`
+
+	// Resting orders priced more than this many basis points of the opposite
+	// best away from it are cancelled by the reaper (0 disables)
+	MaxMarketDistanceBps int `yaml:"max_market_distance_bps"`
+
+	// Fees in basis points of each fill's notional, in the quote token. The
+	// taker pays TakerFeeBps; the maker is rebated MakerRebateBps out of it,
+	// so the rebate can never exceed the fee.
+	TakerFeeBps    int `yaml:"taker_fee_bps"`
+	MakerRebateBps int `yaml:"maker_rebate_bps"`
+
+	// Decimal places for prices in display-facing responses (order book,
+	// match stream); -1 keeps full precision. Stored values are never rounded.
+	DisplayPriceDecimals int `yaml:"display_price_decimals"`
+
+	// Net out crossing bid and ask levels in GetOrderBook and GetDepthChart,
+	// so the displayed book is never locked or crossed
+	UncrossBookDisplay bool `yaml:"uncross_book_display"`
+
+	// How much depth public book views reveal (see the BookDisplay policies),
+	// and the quantity BUCKETED rounds to; the book itself stays exact
+	BookDisplayPolicy string          `yaml:"book_display_policy"`
+	BookDisplayBucket decimal.Decimal `yaml:"book_display_bucket"`
+
+	// Most matches a StreamMatches resume may replay before the client is
+	// told to resync instead
+	StreamReplayLimit int `yaml:"stream_replay_limit"`
+
+	// Most StreamMatches/StreamStats subscriptions one client may hold open
+	// at once (0 disables the limit)
+	MaxStreamsPerClient int `yaml:"max_streams_per_client"`
+
+	// Matches a StreamMatches subscriber may fall behind before it is
+	// disconnected
+	MaxStreamBacklog int `yaml:"max_stream_backlog"`
+
+	// How long matches are held back from StreamMatches subscribers that
+	// aren't filtered to one of the parties (0 publishes at once)
+	TradePrintDelay time.Duration `yaml:"trade_print_delay"`
+
+	// Minimum gap between two StreamTicker updates; changes within it are
+	// coalesced into the next update
+	TickerMinInterval time.Duration `yaml:"ticker_min_interval"`
+
+	// Settlement deadline: matches still PENDING/SETTLING after this long are
+	// failed by the reaper and their quantity restored (0 disables)
+	SettlementTimeout time.Duration `yaml:"settlement_timeout"`
+	ReaperInterval    time.Duration `yaml:"reaper_interval"`
+
+	// Have the reaper cancel resting orders whose owner no longer has a token
+	// approval large enough to settle them
+	CheckApprovals bool `yaml:"check_approvals"`
+
+	// GetUserActivityStats flags a user as a likely spoofer with at least
+	// SpoofMinOrders orders in the window, of which at least SpoofCancelRateBps
+	// were cancelled, on average within SpoofCancelLifetime of submission
+	// (0 ignores how quickly)
+	SpoofMinOrders      int           `yaml:"spoof_min_orders"`
+	SpoofCancelRateBps  int           `yaml:"spoof_cancel_rate_bps"`
+	SpoofCancelLifetime time.Duration `yaml:"spoof_cancel_lifetime"`
 
 	// Logging
-	LogLevel string
+	LogLevel string `yaml:"log_level"`
 
 	// Service metadata
-	ServiceName    string
-	ServiceVersion string
+	ServiceName    string `yaml:"service_name"`
+	ServiceVersion string `yaml:"service_version"`
 }
 
-// Load reads configuration from environment variables
+// Load builds the configuration in layers: defaults, then the YAML file named
+// by CONFIG_FILE (if set), then environment variables. Later layers win.
 func Load() (*Config, error) {
 	cfg := &Config{
 		// Defaults
-		GRPCPort:            50051,
-		Workers:             4,
-		DatabaseMaxConns:    25,
-		DatabaseMinConns:    5,
-		DatabaseMaxConnLife: 30 * time.Minute,
-		OrderChannelSize:    1000,
-		MatchChannelSize:    1000,
-		CancelChannelSize:   100,
-		LogLevel:            "info",
-		ServiceName:         "warlock",
-		ServiceVersion:      "0.1.0",
+		GRPCPort:             50051,
+		EngineRole:           EngineRolePrimary,
+		StandbySyncInterval:  time.Second,
+		StandbyCheckInterval: 30 * time.Second,
+		MaxDecimalPlaces:     storedDecimalPlaces,
+		MaxSignificantDigits: storedSignificantDigits,
+		Workers:              4,
+		WorkerAutoscale:      false,
+		WorkersMin:           1,
+		WorkersMax:           16,
+		QueueHighWatermark:   500,
+		QueueLowWatermark:    50,
+		AutoscaleInterval:    time.Second,
+		DatabaseMaxConns:     25,
+		DatabaseMinConns:     5,
+		DatabaseMaxConnLife:  30 * time.Minute,
+		MigrationsDir:        "migrations",
+		MatchQueryTimeout:    2 * time.Second,
+		ReadQueryTimeout:     30 * time.Second,
+		DBFailureThreshold:   5,
+		DBProbeInterval:      5 * time.Second,
+		OrderChannelSize:     1000,
+		MatchChannelSize:     1000,
+		CancelChannelSize:    100,
+		SyncSubmitTimeout:    5 * time.Second,
+		ShutdownTimeout:      10 * time.Second,
+		MatchHookTimeout:     5 * time.Second,
+		MatchHookFailureMode: MatchHookFailureLog,
+		EventTopic:           "warlock",
+		EventPollInterval:    time.Second,
+		EventBatchSize:       100,
+		CancelSubmitTimeout:  2 * time.Second,
+		BookIdleTTL:          time.Hour,
+		BookCompactAfter:     10 * time.Minute,
+		BookWALCheckpoint:    time.Minute,
+		ExecutionPriceMode:   ExecutionPriceMidpoint,
+		PriceTieBreak:        PriceTieBreakSplit,
+		MakerTakerPolicy:     MakerTakerIncoming,
+		MatchingMode:         MatchingModeContinuous,
+		AuctionInterval:      time.Second,
+		CandidateRanking:     CandidateRankingLimit,
+		DisplayPriceDecimals: -1,
+		UncrossBookDisplay:   true,
+		BookDisplayPolicy:    BookDisplayFull,
+		StreamReplayLimit:    10000,
+		MaxStreamsPerClient:  100,
+		MaxStreamBacklog:     1000,
+		TickerMinInterval:    250 * time.Millisecond,
+		SettlementTimeout:    0,
+		ReaperInterval:       30 * time.Second,
+		SpoofMinOrders:       20,
+		SpoofCancelRateBps:   9000,
+		SpoofCancelLifetime:  5 * time.Second,
+		LogLevel:             "info",
+		ServiceName:          "warlock",
+		ServiceVersion:       "0.1.0",
+	}
+
+	// Override from config file
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
 	}
 
 	// Override from environment variables
@@ -58,6 +509,30 @@ func Load() (*Config, error) {
 		cfg.GRPCPort = p
 	}
 
+	if role := os.Getenv("ENGINE_ROLE"); role != "" {
+		cfg.EngineRole = role
+	}
+
+	if interval := os.Getenv("STANDBY_SYNC_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STANDBY_SYNC_INTERVAL: %w", err)
+		}
+		cfg.StandbySyncInterval = d
+	}
+
+	if addr := os.Getenv("PRIMARY_ADDR"); addr != "" {
+		cfg.PrimaryAddr = addr
+	}
+
+	if interval := os.Getenv("STANDBY_CHECK_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STANDBY_CHECK_INTERVAL: %w", err)
+		}
+		cfg.StandbyCheckInterval = d
+	}
+
 	if workers := os.Getenv("WORKERS"); workers != "" {
 		w, err := strconv.Atoi(workers)
 		if err != nil {
@@ -66,8 +541,74 @@ func Load() (*Config, error) {
 		cfg.Workers = w
 	}
 
-	// Database URL is required
-	cfg.DatabaseURL = os.Getenv("DATABASE_URL")
+	if autoscale := os.Getenv("WORKER_AUTOSCALE"); autoscale != "" {
+		a, err := strconv.ParseBool(autoscale)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WORKER_AUTOSCALE: %w", err)
+		}
+		cfg.WorkerAutoscale = a
+	}
+
+	if minWorkers := os.Getenv("WORKERS_MIN"); minWorkers != "" {
+		w, err := strconv.Atoi(minWorkers)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WORKERS_MIN: %w", err)
+		}
+		cfg.WorkersMin = w
+	}
+
+	if maxWorkers := os.Getenv("WORKERS_MAX"); maxWorkers != "" {
+		w, err := strconv.Atoi(maxWorkers)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WORKERS_MAX: %w", err)
+		}
+		cfg.WorkersMax = w
+	}
+
+	if high := os.Getenv("QUEUE_HIGH_WATERMARK"); high != "" {
+		h, err := strconv.Atoi(high)
+		if err != nil {
+			return nil, fmt.Errorf("invalid QUEUE_HIGH_WATERMARK: %w", err)
+		}
+		cfg.QueueHighWatermark = h
+	}
+
+	if low := os.Getenv("QUEUE_LOW_WATERMARK"); low != "" {
+		l, err := strconv.Atoi(low)
+		if err != nil {
+			return nil, fmt.Errorf("invalid QUEUE_LOW_WATERMARK: %w", err)
+		}
+		cfg.QueueLowWatermark = l
+	}
+
+	if interval := os.Getenv("AUTOSCALE_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUTOSCALE_INTERVAL: %w", err)
+		}
+		cfg.AutoscaleInterval = d
+	}
+
+	if bump := os.Getenv("SPEED_BUMP_MIN"); bump != "" {
+		d, err := time.ParseDuration(bump)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SPEED_BUMP_MIN: %w", err)
+		}
+		cfg.SpeedBumpMin = d
+	}
+
+	if bump := os.Getenv("SPEED_BUMP_MAX"); bump != "" {
+		d, err := time.ParseDuration(bump)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SPEED_BUMP_MAX: %w", err)
+		}
+		cfg.SpeedBumpMax = d
+	}
+
+	// Database URL is required, from either the environment or the config file
+	if dbURL := os.Getenv("DATABASE_URL"); dbURL != "" {
+		cfg.DatabaseURL = dbURL
+	}
 	if cfg.DatabaseURL == "" {
 		return nil, fmt.Errorf("DATABASE_URL environment variable is required")
 	}
@@ -80,30 +621,1266 @@ func Load() (*Config, error) {
 		cfg.DatabaseMaxConns = mc
 	}
 
-	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
-		cfg.LogLevel = logLevel
+	if autoMigrate := os.Getenv("AUTO_MIGRATE"); autoMigrate != "" {
+		a, err := strconv.ParseBool(autoMigrate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUTO_MIGRATE: %w", err)
+		}
+		cfg.AutoMigrate = a
 	}
 
-	return cfg, nil
-}
+	if dir := os.Getenv("MIGRATIONS_DIR"); dir != "" {
+		cfg.MigrationsDir = dir
+	}
 
-// Validate checks that the configuration is valid
-func (c *Config) Validate() error {
-	if c.GRPCPort < 1 || c.GRPCPort > 65535 {
-		return fmt.Errorf("invalid GRPC_PORT: must be between 1 and 65535")
+	if baseline := os.Getenv("MIGRATIONS_BASELINE"); baseline != "" {
+		cfg.MigrationsBaseline = baseline
 	}
 
-	if c.Workers < 1 {
-		return fmt.Errorf("invalid WORKERS: must be at least 1")
+	if timeout := os.Getenv("MATCH_QUERY_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MATCH_QUERY_TIMEOUT: %w", err)
+		}
+		cfg.MatchQueryTimeout = d
 	}
 
-	if c.DatabaseURL == "" {
-		return fmt.Errorf("DATABASE_URL is required")
+	if timeout := os.Getenv("READ_QUERY_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid READ_QUERY_TIMEOUT: %w", err)
+		}
+		cfg.ReadQueryTimeout = d
 	}
 
-	if c.DatabaseMaxConns < c.DatabaseMinConns {
-		return fmt.Errorf("DB_MAX_CONNS must be >= DB_MIN_CONNS")
+	if timeout := os.Getenv("SYNC_SUBMIT_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SYNC_SUBMIT_TIMEOUT: %w", err)
+		}
+		cfg.SyncSubmitTimeout = d
 	}
 
-	return nil
+	if tolerance := os.Getenv("EXPIRY_CLOCK_SKEW_TOLERANCE"); tolerance != "" {
+		d, err := time.ParseDuration(tolerance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EXPIRY_CLOCK_SKEW_TOLERANCE: %w", err)
+		}
+		cfg.ExpiryClockSkewTolerance = d
+	}
+
+	if timeout := os.Getenv("SHUTDOWN_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SHUTDOWN_TIMEOUT: %w", err)
+		}
+		cfg.ShutdownTimeout = d
+	}
+
+	if timeout := os.Getenv("MATCH_HOOK_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MATCH_HOOK_TIMEOUT: %w", err)
+		}
+		cfg.MatchHookTimeout = d
+	}
+
+	if mode := os.Getenv("MATCH_HOOK_FAILURE_MODE"); mode != "" {
+		cfg.MatchHookFailureMode = mode
+	}
+
+	if webhook := os.Getenv("MATCH_WEBHOOK_URL"); webhook != "" {
+		cfg.MatchWebhookURL = webhook
+	}
+
+	if broker := os.Getenv("EVENT_BROKER"); broker != "" {
+		cfg.EventBroker = broker
+	}
+
+	if brokerURL := os.Getenv("EVENT_BROKER_URL"); brokerURL != "" {
+		cfg.EventBrokerURL = brokerURL
+	}
+
+	if topic := os.Getenv("EVENT_TOPIC"); topic != "" {
+		cfg.EventTopic = topic
+	}
+
+	if interval := os.Getenv("EVENT_POLL_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EVENT_POLL_INTERVAL: %w", err)
+		}
+		cfg.EventPollInterval = d
+	}
+
+	if batch := os.Getenv("EVENT_BATCH_SIZE"); batch != "" {
+		n, err := strconv.Atoi(batch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EVENT_BATCH_SIZE: %w", err)
+		}
+		cfg.EventBatchSize = n
+	}
+
+	if timeout := os.Getenv("CANCEL_SUBMIT_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CANCEL_SUBMIT_TIMEOUT: %w", err)
+		}
+		cfg.CancelSubmitTimeout = d
+	}
+
+	if hotPairs := os.Getenv("HOT_PAIRS"); hotPairs != "" {
+		cfg.HotPairs = splitList(hotPairs)
+	}
+
+	if pairs := os.Getenv("TRADABLE_PAIRS"); pairs != "" {
+		cfg.TradablePairs = splitList(pairs)
+	}
+
+	if pairs := os.Getenv("DISABLED_PAIRS"); pairs != "" {
+		cfg.DisabledPairs = splitList(pairs)
+	}
+
+	if maxPrice := os.Getenv("MAX_ORDER_PRICE"); maxPrice != "" {
+		d, err := decimal.NewFromString(maxPrice)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_ORDER_PRICE: %w", err)
+		}
+		cfg.OrderBounds.MaxPrice = d
+	}
+
+	if maxNotional := os.Getenv("MAX_ORDER_NOTIONAL"); maxNotional != "" {
+		d, err := decimal.NewFromString(maxNotional)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_ORDER_NOTIONAL: %w", err)
+		}
+		cfg.OrderBounds.MaxNotional = d
+	}
+
+	if minMatch := os.Getenv("MIN_MATCH_SIZE"); minMatch != "" {
+		d, err := decimal.NewFromString(minMatch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MIN_MATCH_SIZE: %w", err)
+		}
+		cfg.OrderBounds.MinMatchSize = d
+	}
+
+	if maxMatch := os.Getenv("MAX_MATCH_SIZE"); maxMatch != "" {
+		d, err := decimal.NewFromString(maxMatch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_MATCH_SIZE: %w", err)
+		}
+		cfg.OrderBounds.MaxMatchSize = d
+	}
+
+	if places := os.Getenv("MAX_DECIMAL_PLACES"); places != "" {
+		n, err := strconv.Atoi(places)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_DECIMAL_PLACES: %w", err)
+		}
+		cfg.MaxDecimalPlaces = n
+	}
+
+	if digits := os.Getenv("MAX_SIGNIFICANT_DIGITS"); digits != "" {
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_SIGNIFICANT_DIGITS: %w", err)
+		}
+		cfg.MaxSignificantDigits = n
+	}
+
+	if makerFill := os.Getenv("MIN_MAKER_FILL"); makerFill != "" {
+		d, err := decimal.NewFromString(makerFill)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MIN_MAKER_FILL: %w", err)
+		}
+		cfg.OrderBounds.MinMakerFill = d
+	}
+
+	if lot := os.Getenv("LOT_SIZE"); lot != "" {
+		d, err := decimal.NewFromString(lot)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LOT_SIZE: %w", err)
+		}
+		cfg.OrderBounds.LotSize = d
+	}
+
+	if notional := os.Getenv("MIN_SETTLEMENT_NOTIONAL"); notional != "" {
+		d, err := decimal.NewFromString(notional)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MIN_SETTLEMENT_NOTIONAL: %w", err)
+		}
+		cfg.OrderBounds.MinSettlementNotional = d
+	}
+
+	if threshold := os.Getenv("DB_FAILURE_THRESHOLD"); threshold != "" {
+		t, err := strconv.Atoi(threshold)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_FAILURE_THRESHOLD: %w", err)
+		}
+		cfg.DBFailureThreshold = t
+	}
+
+	if interval := os.Getenv("DB_PROBE_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DB_PROBE_INTERVAL: %w", err)
+		}
+		cfg.DBProbeInterval = d
+	}
+
+	if maxPairs := os.Getenv("MAX_PAIRS"); maxPairs != "" {
+		m, err := strconv.Atoi(maxPairs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_PAIRS: %w", err)
+		}
+		cfg.MaxPairs = m
+	}
+
+	if ttl := os.Getenv("BOOK_IDLE_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BOOK_IDLE_TTL: %w", err)
+		}
+		cfg.BookIdleTTL = d
+	}
+
+	if after := os.Getenv("BOOK_COMPACT_AFTER"); after != "" {
+		d, err := time.ParseDuration(after)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BOOK_COMPACT_AFTER: %w", err)
+		}
+		cfg.BookCompactAfter = d
+	}
+
+	if interval := os.Getenv("BOOK_SNAPSHOT_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BOOK_SNAPSHOT_INTERVAL: %w", err)
+		}
+		cfg.BookSnapshotInterval = d
+	}
+
+	if interval := os.Getenv("BOOK_RECONCILE_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BOOK_RECONCILE_INTERVAL: %w", err)
+		}
+		cfg.BookReconcileInterval = d
+	}
+
+	if staleness := os.Getenv("MAX_BOOK_STALENESS"); staleness != "" {
+		d, err := time.ParseDuration(staleness)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_BOOK_STALENESS: %w", err)
+		}
+		cfg.MaxBookStaleness = d
+	}
+
+	if dir := os.Getenv("BOOK_WAL_DIR"); dir != "" {
+		cfg.BookWALDir = dir
+	}
+
+	if interval := os.Getenv("BOOK_WAL_CHECKPOINT"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BOOK_WAL_CHECKPOINT: %w", err)
+		}
+		cfg.BookWALCheckpoint = d
+	}
+
+	if pools := os.Getenv("POOLS"); pools != "" {
+		cfg.Pools = splitList(pools)
+	}
+
+	if chains := os.Getenv("SUPPORTED_CHAINS"); chains != "" {
+		ids, err := parseChainIDs(chains)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SUPPORTED_CHAINS: %w", err)
+		}
+		cfg.SupportedChains = ids
+	}
+
+	if chains := os.Getenv("PAUSED_CHAINS"); chains != "" {
+		ids, err := parseChainIDs(chains)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PAUSED_CHAINS: %w", err)
+		}
+		cfg.PausedChains = ids
+	}
+
+	if pairs := os.Getenv("TRACE_PAIRS"); pairs != "" {
+		cfg.TracePairs = splitList(pairs)
+	}
+
+	if pairs := os.Getenv("ONE_SIDED_REJECT_PAIRS"); pairs != "" {
+		cfg.OneSidedRejectPairs = splitList(pairs)
+	}
+
+	if decay := os.Getenv("PRIORITY_DECAY_AFTER"); decay != "" {
+		d, err := time.ParseDuration(decay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PRIORITY_DECAY_AFTER: %w", err)
+		}
+		cfg.PriorityDecayAfter = d
+	}
+
+	if skipLocked := os.Getenv("MATCH_SKIP_LOCKED"); skipLocked != "" {
+		b, err := strconv.ParseBool(skipLocked)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MATCH_SKIP_LOCKED: %w", err)
+		}
+		cfg.MatchSkipLocked = b
+	}
+
+	if deterministic := os.Getenv("DETERMINISTIC_MATCHING"); deterministic != "" {
+		b, err := strconv.ParseBool(deterministic)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DETERMINISTIC_MATCHING: %w", err)
+		}
+		cfg.DeterministicMatching = b
+	}
+
+	if uncross := os.Getenv("UNCROSS_BOOK_DISPLAY"); uncross != "" {
+		b, err := strconv.ParseBool(uncross)
+		if err != nil {
+			return nil, fmt.Errorf("invalid UNCROSS_BOOK_DISPLAY: %w", err)
+		}
+		cfg.UncrossBookDisplay = b
+	}
+
+	if policy := os.Getenv("BOOK_DISPLAY_POLICY"); policy != "" {
+		cfg.BookDisplayPolicy = policy
+	}
+
+	if bucket := os.Getenv("BOOK_DISPLAY_BUCKET"); bucket != "" {
+		d, err := decimal.NewFromString(bucket)
+		if err != nil {
+			return nil, fmt.Errorf("invalid BOOK_DISPLAY_BUCKET: %w", err)
+		}
+		cfg.BookDisplayBucket = d
+	}
+
+	if mode := os.Getenv("MATCHING_MODE"); mode != "" {
+		cfg.MatchingMode = mode
+	}
+
+	if interval := os.Getenv("AUCTION_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AUCTION_INTERVAL: %w", err)
+		}
+		cfg.AuctionInterval = d
+	}
+
+	if mode := os.Getenv("EXECUTION_PRICE_MODE"); mode != "" {
+		cfg.ExecutionPriceMode = mode
+	}
+
+	if tieBreak := os.Getenv("PRICE_TIE_BREAK"); tieBreak != "" {
+		cfg.PriceTieBreak = tieBreak
+	}
+
+	if policy := os.Getenv("MAKER_TAKER_POLICY"); policy != "" {
+		cfg.MakerTakerPolicy = policy
+	}
+
+	if ranking := os.Getenv("CANDIDATE_RANKING"); ranking != "" {
+		cfg.CandidateRanking = ranking
+	}
+
+	if tolerance := os.Getenv("CANDIDATE_TOLERANCE_BPS"); tolerance != "" {
+		bps, err := strconv.Atoi(tolerance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CANDIDATE_TOLERANCE_BPS: %w", err)
+		}
+		cfg.CandidateToleranceBps = bps
+	}
+
+	if diversity := os.Getenv("COUNTERPARTY_DIVERSITY"); diversity != "" {
+		b, err := strconv.ParseBool(diversity)
+		if err != nil {
+			return nil, fmt.Errorf("invalid COUNTERPARTY_DIVERSITY: %w", err)
+		}
+		cfg.CounterpartyDiversity = b
+	}
+
+	if rate := os.Getenv("MAX_MATCHES_PER_SECOND"); rate != "" {
+		r, err := strconv.Atoi(rate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_MATCHES_PER_SECOND: %w", err)
+		}
+		cfg.MaxMatchesPerSecond = r
+	}
+
+	if digits := os.Getenv("PRICE_SIGNIFICANT_DIGITS"); digits != "" {
+		d, err := strconv.Atoi(digits)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PRICE_SIGNIFICANT_DIGITS: %w", err)
+		}
+		cfg.PriceSignificantDigits = d
+	}
+
+	if overlap := os.Getenv("MIN_BAND_OVERLAP_BPS"); overlap != "" {
+		bps, err := strconv.Atoi(overlap)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MIN_BAND_OVERLAP_BPS: %w", err)
+		}
+		cfg.MinBandOverlapBps = bps
+	}
+
+	if spread := os.Getenv("MIN_RESTING_SPREAD_BPS"); spread != "" {
+		bps, err := strconv.Atoi(spread)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MIN_RESTING_SPREAD_BPS: %w", err)
+		}
+		cfg.MinRestingSpreadBps = bps
+	}
+
+	if distance := os.Getenv("MAX_MARKET_DISTANCE_BPS"); distance != "" {
+		bps, err := strconv.Atoi(distance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_MARKET_DISTANCE_BPS: %w", err)
+		}
+		cfg.MaxMarketDistanceBps = bps
+	}
+
+	if fee := os.Getenv("TAKER_FEE_BPS"); fee != "" {
+		bps, err := strconv.Atoi(fee)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TAKER_FEE_BPS: %w", err)
+		}
+		cfg.TakerFeeBps = bps
+	}
+
+	if rebate := os.Getenv("MAKER_REBATE_BPS"); rebate != "" {
+		bps, err := strconv.Atoi(rebate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAKER_REBATE_BPS: %w", err)
+		}
+		cfg.MakerRebateBps = bps
+	}
+
+	if places := os.Getenv("DISPLAY_PRICE_DECIMALS"); places != "" {
+		p, err := strconv.Atoi(places)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DISPLAY_PRICE_DECIMALS: %w", err)
+		}
+		cfg.DisplayPriceDecimals = p
+	}
+
+	if limit := os.Getenv("STREAM_REPLAY_LIMIT"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STREAM_REPLAY_LIMIT: %w", err)
+		}
+		cfg.StreamReplayLimit = l
+	}
+
+	if limit := os.Getenv("MAX_STREAMS_PER_CLIENT"); limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_STREAMS_PER_CLIENT: %w", err)
+		}
+		cfg.MaxStreamsPerClient = l
+	}
+
+	if backlog := os.Getenv("MAX_STREAM_BACKLOG"); backlog != "" {
+		b, err := strconv.Atoi(backlog)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MAX_STREAM_BACKLOG: %w", err)
+		}
+		cfg.MaxStreamBacklog = b
+	}
+
+	if delay := os.Getenv("TRADE_PRINT_DELAY"); delay != "" {
+		d, err := time.ParseDuration(delay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRADE_PRINT_DELAY: %w", err)
+		}
+		cfg.TradePrintDelay = d
+	}
+
+	if interval := os.Getenv("TICKER_MIN_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TICKER_MIN_INTERVAL: %w", err)
+		}
+		cfg.TickerMinInterval = d
+	}
+
+	if timeout := os.Getenv("SETTLEMENT_TIMEOUT"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SETTLEMENT_TIMEOUT: %w", err)
+		}
+		cfg.SettlementTimeout = d
+	}
+
+	if interval := os.Getenv("REAPER_INTERVAL"); interval != "" {
+		d, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid REAPER_INTERVAL: %w", err)
+		}
+		cfg.ReaperInterval = d
+	}
+
+	if check := os.Getenv("CHECK_APPROVALS"); check != "" {
+		b, err := strconv.ParseBool(check)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CHECK_APPROVALS: %w", err)
+		}
+		cfg.CheckApprovals = b
+	}
+
+	if orders := os.Getenv("SPOOF_MIN_ORDERS"); orders != "" {
+		n, err := strconv.Atoi(orders)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SPOOF_MIN_ORDERS: %w", err)
+		}
+		cfg.SpoofMinOrders = n
+	}
+
+	if rate := os.Getenv("SPOOF_CANCEL_RATE_BPS"); rate != "" {
+		bps, err := strconv.Atoi(rate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SPOOF_CANCEL_RATE_BPS: %w", err)
+		}
+		cfg.SpoofCancelRateBps = bps
+	}
+
+	if lifetime := os.Getenv("SPOOF_CANCEL_LIFETIME"); lifetime != "" {
+		d, err := time.ParseDuration(lifetime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SPOOF_CANCEL_LIFETIME: %w", err)
+		}
+		cfg.SpoofCancelLifetime = d
+	}
+
+	if stp := os.Getenv("SELF_TRADE_PREVENTION"); stp != "" {
+		b, err := strconv.ParseBool(stp)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SELF_TRADE_PREVENTION: %w", err)
+		}
+		cfg.SelfTradePrevention = b
+	}
+
+	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
+		cfg.LogLevel = logLevel
+	}
+
+	cfg.entityByAddress = make(map[string]string)
+	for entity, addresses := range cfg.Entities {
+		for _, addr := range addresses {
+			cfg.entityByAddress[strings.ToLower(strings.TrimSpace(addr))] = entity
+		}
+	}
+
+	cfg.apiKeyByHash = make(map[string]string, len(cfg.APIKeys))
+	for name, key := range cfg.APIKeys {
+		cfg.apiKeyByHash[strings.ToLower(strings.TrimSpace(key.SHA256))] = name
+	}
+
+	for pair, session := range cfg.MarketHours {
+		if session == nil {
+			return nil, fmt.Errorf("invalid market_hours for %s: session is empty", pair)
+		}
+		if err := session.compile(); err != nil {
+			return nil, fmt.Errorf("invalid market_hours for %s: %w", pair, err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// loadFile overlays values from a YAML config file onto c.
+// Keys missing from the file keep their current value; unknown keys are rejected.
+func (c *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open CONFIG_FILE: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid CONFIG_FILE %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate checks that the configuration is valid
+func (c *Config) Validate() error {
+	if c.GRPCPort < 1 || c.GRPCPort > 65535 {
+		return fmt.Errorf("invalid GRPC_PORT: must be between 1 and 65535")
+	}
+
+	if c.Workers < 1 {
+		return fmt.Errorf("invalid WORKERS: must be at least 1")
+	}
+
+	if c.WorkerAutoscale {
+		if c.WorkersMin < 1 || c.WorkersMin > c.WorkersMax {
+			return fmt.Errorf("invalid WORKERS_MIN/WORKERS_MAX: need 1 <= min <= max")
+		}
+
+		if c.Workers < c.WorkersMin || c.Workers > c.WorkersMax {
+			return fmt.Errorf("invalid WORKERS: must be between WORKERS_MIN and WORKERS_MAX when autoscaling")
+		}
+
+		if c.QueueLowWatermark >= c.QueueHighWatermark {
+			return fmt.Errorf("QUEUE_LOW_WATERMARK must be < QUEUE_HIGH_WATERMARK")
+		}
+
+		if c.AutoscaleInterval <= 0 {
+			return fmt.Errorf("invalid AUTOSCALE_INTERVAL: must be positive")
+		}
+	}
+
+	if c.SpeedBumpMin < 0 || c.SpeedBumpMax < c.SpeedBumpMin {
+		return fmt.Errorf("invalid SPEED_BUMP_MIN/SPEED_BUMP_MAX: need 0 <= min <= max")
+	}
+
+	if c.DatabaseURL == "" {
+		return fmt.Errorf("DATABASE_URL is required")
+	}
+
+	if c.DatabaseMaxConns < c.DatabaseMinConns {
+		return fmt.Errorf("DB_MAX_CONNS must be >= DB_MIN_CONNS")
+	}
+
+	if c.MaxPairs < 0 {
+		return fmt.Errorf("invalid MAX_PAIRS: must not be negative")
+	}
+
+	if c.BookIdleTTL < 0 {
+		return fmt.Errorf("invalid BOOK_IDLE_TTL: must not be negative")
+	}
+
+	if c.BookCompactAfter < 0 {
+		return fmt.Errorf("invalid BOOK_COMPACT_AFTER: must not be negative")
+	}
+
+	if c.BookSnapshotInterval < 0 {
+		return fmt.Errorf("invalid BOOK_SNAPSHOT_INTERVAL: must not be negative")
+	}
+
+	if c.BookReconcileInterval < 0 {
+		return fmt.Errorf("invalid BOOK_RECONCILE_INTERVAL: must not be negative")
+	}
+
+	if c.MaxBookStaleness < 0 {
+		return fmt.Errorf("invalid MAX_BOOK_STALENESS: must not be negative")
+	}
+
+	if c.BookWALDir != "" && c.BookWALCheckpoint <= 0 {
+		return fmt.Errorf("invalid BOOK_WAL_CHECKPOINT: must be positive")
+	}
+
+	// Without reconciles, or with them further apart than the limit, the
+	// books would go stale and every order be rejected
+	if c.MaxBookStaleness > 0 && (c.BookReconcileInterval == 0 || c.BookReconcileInterval >= c.MaxBookStaleness) {
+		return fmt.Errorf("invalid MAX_BOOK_STALENESS: must exceed BOOK_RECONCILE_INTERVAL, which must be set")
+	}
+
+	if c.MatchQueryTimeout <= 0 {
+		return fmt.Errorf("invalid MATCH_QUERY_TIMEOUT: must be positive")
+	}
+
+	if c.ReadQueryTimeout <= 0 {
+		return fmt.Errorf("invalid READ_QUERY_TIMEOUT: must be positive")
+	}
+
+	if c.DBFailureThreshold < 0 {
+		return fmt.Errorf("invalid DB_FAILURE_THRESHOLD: must not be negative")
+	}
+
+	if c.DBFailureThreshold > 0 && c.DBProbeInterval <= 0 {
+		return fmt.Errorf("invalid DB_PROBE_INTERVAL: must be positive")
+	}
+
+	if c.SyncSubmitTimeout <= 0 {
+		return fmt.Errorf("invalid SYNC_SUBMIT_TIMEOUT: must be positive")
+	}
+
+	if c.ExpiryClockSkewTolerance < 0 {
+		return fmt.Errorf("invalid EXPIRY_CLOCK_SKEW_TOLERANCE: must not be negative")
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("invalid SHUTDOWN_TIMEOUT: must be positive")
+	}
+
+	if c.MatchHookTimeout <= 0 {
+		return fmt.Errorf("invalid MATCH_HOOK_TIMEOUT: must be positive")
+	}
+
+	if c.MatchHookFailureMode != MatchHookFailureLog && c.MatchHookFailureMode != MatchHookFailureBlock {
+		return fmt.Errorf("invalid MATCH_HOOK_FAILURE_MODE: must be LOG or BLOCK")
+	}
+
+	if c.MatchWebhookURL != "" {
+		if u, err := url.Parse(c.MatchWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid MATCH_WEBHOOK_URL: must be an http or https URL")
+		}
+	}
+
+	switch c.EventBroker {
+	case "":
+	case EventBrokerNATS:
+		servers := splitList(c.EventBrokerURL)
+		if len(servers) == 0 {
+			return fmt.Errorf("invalid EVENT_BROKER_URL: must be a nats://host:port URL")
+		}
+		for _, server := range servers {
+			if u, err := url.Parse(server); err != nil || (u.Scheme != "nats" && u.Scheme != "tls") || u.Host == "" {
+				return fmt.Errorf("invalid EVENT_BROKER_URL: %q must be a nats:// or tls://host:port URL", server)
+			}
+		}
+	case EventBrokerKafka:
+		if _, err := ParseKafkaBrokers(c.EventBrokerURL); err != nil {
+			return fmt.Errorf("invalid EVENT_BROKER_URL: %w", err)
+		}
+	default:
+		return fmt.Errorf("invalid EVENT_BROKER: must be NATS or KAFKA")
+	}
+
+	if c.EventTopic == "" {
+		return fmt.Errorf("invalid EVENT_TOPIC: must not be empty")
+	}
+
+	if c.EventPollInterval <= 0 {
+		return fmt.Errorf("invalid EVENT_POLL_INTERVAL: must be positive")
+	}
+
+	if c.EventBatchSize <= 0 {
+		return fmt.Errorf("invalid EVENT_BATCH_SIZE: must be positive")
+	}
+
+	if c.CancelSubmitTimeout <= 0 {
+		return fmt.Errorf("invalid CANCEL_SUBMIT_TIMEOUT: must be positive")
+	}
+
+	for _, pair := range c.HotPairs {
+		if _, _, ok := ParsePair(pair); !ok {
+			return fmt.Errorf("invalid HOT_PAIRS entry %q: expected BASE/QUOTE", pair)
+		}
+	}
+
+	for _, pair := range c.TradablePairs {
+		if _, _, ok := ParsePair(pair); !ok {
+			return fmt.Errorf("invalid TRADABLE_PAIRS entry %q: expected BASE/QUOTE", pair)
+		}
+	}
+
+	for _, pair := range c.DisabledPairs {
+		if _, _, ok := ParsePair(pair); !ok {
+			return fmt.Errorf("invalid DISABLED_PAIRS entry %q: expected BASE/QUOTE", pair)
+		}
+	}
+
+	for _, pool := range c.Pools {
+		if len(pool) > 64 {
+			return fmt.Errorf("invalid POOLS entry %q: at most 64 characters", pool)
+		}
+	}
+
+	for _, pair := range c.TracePairs {
+		if _, _, ok := ParsePair(pair); !ok {
+			return fmt.Errorf("invalid TRACE_PAIRS entry %q: expected BASE/QUOTE", pair)
+		}
+	}
+
+	for _, pair := range c.OneSidedRejectPairs {
+		if _, _, ok := ParsePair(pair); !ok {
+			return fmt.Errorf("invalid ONE_SIDED_REJECT_PAIRS entry %q: expected BASE/QUOTE", pair)
+		}
+	}
+
+	if c.OrderBounds.MaxPrice.IsNegative() || c.OrderBounds.MaxNotional.IsNegative() {
+		return fmt.Errorf("invalid MAX_ORDER_PRICE/MAX_ORDER_NOTIONAL: must not be negative")
+	}
+
+	if c.MaxDecimalPlaces < 0 || c.MaxDecimalPlaces > storedDecimalPlaces {
+		return fmt.Errorf("invalid MAX_DECIMAL_PLACES: must be between 0 and %d", storedDecimalPlaces)
+	}
+
+	if c.MaxSignificantDigits < 1 || c.MaxSignificantDigits > storedSignificantDigits {
+		return fmt.Errorf("invalid MAX_SIGNIFICANT_DIGITS: must be between 1 and %d", storedSignificantDigits)
+	}
+
+	if err := c.OrderBounds.validateMatchSize(); err != nil {
+		return fmt.Errorf("invalid MIN_MATCH_SIZE/MAX_MATCH_SIZE/MIN_MAKER_FILL/LOT_SIZE: %w", err)
+	}
+
+	if c.OrderBounds.MinSettlementNotional.IsNegative() {
+		return fmt.Errorf("invalid MIN_SETTLEMENT_NOTIONAL: must not be negative")
+	}
+
+	for pair, bounds := range c.PairOrderBounds {
+		if _, _, ok := ParsePair(pair); !ok {
+			return fmt.Errorf("invalid pair_order_bounds key %q: expected BASE/QUOTE", pair)
+		}
+		if bounds.MaxPrice.IsNegative() || bounds.MaxNotional.IsNegative() || bounds.MinSettlementNotional.IsNegative() {
+			return fmt.Errorf("invalid pair_order_bounds for %s: bounds must not be negative", pair)
+		}
+		if err := bounds.validateMatchSize(); err != nil {
+			return fmt.Errorf("invalid pair_order_bounds for %s: %w", pair, err)
+		}
+	}
+
+	keyHashes := make(map[string]string)
+	for name, key := range c.APIKeys {
+		hash := strings.ToLower(strings.TrimSpace(key.SHA256))
+		if decoded, err := hex.DecodeString(hash); err != nil || len(decoded) != sha256.Size {
+			return fmt.Errorf("invalid api_keys for %s: sha256 must be 64 hex digits", name)
+		}
+		if other, dup := keyHashes[hash]; dup {
+			return fmt.Errorf("invalid api_keys: %s and %s have the same hash", other, name)
+		}
+		keyHashes[hash] = name
+		for _, addr := range key.Addresses {
+			if strings.TrimSpace(addr) == "" {
+				return fmt.Errorf("invalid api_keys for %s: addresses must not be empty", name)
+			}
+		}
+	}
+
+	seen := make(map[string]string)
+	for entity, addresses := range c.Entities {
+		if entity == "" {
+			return fmt.Errorf("invalid entities: entity id must not be empty")
+		}
+		for _, addr := range addresses {
+			key := strings.ToLower(strings.TrimSpace(addr))
+			if key == "" {
+				return fmt.Errorf("invalid entities for %s: addresses must not be empty", entity)
+			}
+			if other, dup := seen[key]; dup && other != entity {
+				return fmt.Errorf("invalid entities: %s belongs to both %s and %s", addr, other, entity)
+			}
+			seen[key] = entity
+		}
+	}
+
+	if c.PriorityDecayAfter < 0 {
+		return fmt.Errorf("invalid PRIORITY_DECAY_AFTER: must not be negative")
+	}
+
+	// Concurrent workers and age-based priority both make the outcome depend on timing
+	if c.DeterministicMatching {
+		if c.Workers != 1 || c.WorkerAutoscale {
+			return fmt.Errorf("invalid DETERMINISTIC_MATCHING: requires WORKERS=1 and WORKER_AUTOSCALE off")
+		}
+		if c.PriorityDecayAfter > 0 {
+			return fmt.Errorf("invalid DETERMINISTIC_MATCHING: can't be combined with PRIORITY_DECAY_AFTER")
+		}
+	}
+
+	if c.EngineRole != EngineRolePrimary && c.EngineRole != EngineRoleStandby {
+		return fmt.Errorf("invalid ENGINE_ROLE: must be PRIMARY or STANDBY")
+	}
+
+	if c.EngineRole == EngineRoleStandby && c.StandbySyncInterval <= 0 {
+		return fmt.Errorf("invalid STANDBY_SYNC_INTERVAL: must be positive for a STANDBY")
+	}
+
+	if c.PrimaryAddr != "" && c.StandbyCheckInterval <= 0 {
+		return fmt.Errorf("invalid STANDBY_CHECK_INTERVAL: must be positive when PRIMARY_ADDR is set")
+	}
+
+	if c.MatchingMode != MatchingModeContinuous && c.MatchingMode != MatchingModeBatchAuction {
+		return fmt.Errorf("invalid MATCHING_MODE: must be CONTINUOUS or BATCH_AUCTION")
+	}
+
+	if c.MatchingMode == MatchingModeBatchAuction && c.AuctionInterval <= 0 {
+		return fmt.Errorf("invalid AUCTION_INTERVAL: must be positive in BATCH_AUCTION mode")
+	}
+
+	if !validExecutionPriceMode(c.ExecutionPriceMode) {
+		return fmt.Errorf("invalid EXECUTION_PRICE_MODE: must be MIDPOINT or VWAP")
+	}
+
+	if !validPriceTieBreak(c.PriceTieBreak) {
+		return fmt.Errorf("invalid PRICE_TIE_BREAK: must be SPLIT, MAKER_FAVORABLE or TAKER_FAVORABLE")
+	}
+
+	if c.MakerTakerPolicy != MakerTakerIncoming && c.MakerTakerPolicy != MakerTakerEarlierOrder {
+		return fmt.Errorf("invalid MAKER_TAKER_POLICY: must be INCOMING or EARLIER_ORDER")
+	}
+
+	if !validCandidateRanking(c.CandidateRanking) {
+		return fmt.Errorf("invalid CANDIDATE_RANKING: must be LIMIT, PRICE_IMPROVEMENT or TIME_WITHIN_TOLERANCE")
+	}
+
+	if c.CandidateToleranceBps < 0 || c.CandidateToleranceBps > 10000 {
+		return fmt.Errorf("invalid CANDIDATE_TOLERANCE_BPS: must be between 0 and 10000")
+	}
+
+	if c.MaxMatchesPerSecond < 0 {
+		return fmt.Errorf("invalid MAX_MATCHES_PER_SECOND: must not be negative")
+	}
+
+	if c.PriceSignificantDigits < 0 || c.PriceSignificantDigits > maxPriceSignificantDigits {
+		return fmt.Errorf("invalid PRICE_SIGNIFICANT_DIGITS: must be between 0 and %d", maxPriceSignificantDigits)
+	}
+
+	if c.MinBandOverlapBps < 0 || c.MinBandOverlapBps > 10000 {
+		return fmt.Errorf("invalid MIN_BAND_OVERLAP_BPS: must be between 0 and 10000")
+	}
+
+	for pair := range c.MarketHours {
+		if _, _, ok := ParsePair(pair); !ok {
+			return fmt.Errorf("invalid market_hours key %q: expected BASE/QUOTE", pair)
+		}
+	}
+
+	for pair, rules := range c.PairMatching {
+		if _, _, ok := ParsePair(pair); !ok {
+			return fmt.Errorf("invalid pair_matching key %q: expected BASE/QUOTE", pair)
+		}
+		if rules.ExecutionPriceMode != "" && !validExecutionPriceMode(rules.ExecutionPriceMode) {
+			return fmt.Errorf("invalid pair_matching for %s: execution_price_mode must be MIDPOINT or VWAP", pair)
+		}
+		if rules.PriceTieBreak != "" && !validPriceTieBreak(rules.PriceTieBreak) {
+			return fmt.Errorf("invalid pair_matching for %s: price_tie_break must be SPLIT, MAKER_FAVORABLE or TAKER_FAVORABLE", pair)
+		}
+		if rules.CandidateRanking != "" && !validCandidateRanking(rules.CandidateRanking) {
+			return fmt.Errorf("invalid pair_matching for %s: candidate_ranking must be LIMIT, PRICE_IMPROVEMENT or TIME_WITHIN_TOLERANCE", pair)
+		}
+		if rules.MaxMatchesPerSecond < 0 {
+			return fmt.Errorf("invalid pair_matching for %s: max_matches_per_second must not be negative", pair)
+		}
+		if rules.PriceSignificantDigits < 0 || rules.PriceSignificantDigits > maxPriceSignificantDigits {
+			return fmt.Errorf("invalid pair_matching for %s: price_significant_digits must be between 0 and %d", pair, maxPriceSignificantDigits)
+		}
+	}
+
+	if c.MinRestingSpreadBps < 0 || c.MinRestingSpreadBps > 10000 {
+		return fmt.Errorf("invalid MIN_RESTING_SPREAD_BPS: must be between 0 and 10000")
+	}
+
+	if c.MaxMarketDistanceBps < 0 {
+		return fmt.Errorf("invalid MAX_MARKET_DISTANCE_BPS: must not be negative")
+	}
+
+	if c.SpoofMinOrders < 1 {
+		return fmt.Errorf("invalid SPOOF_MIN_ORDERS: must be at least 1")
+	}
+
+	if c.SpoofCancelRateBps < 1 || c.SpoofCancelRateBps > 10000 {
+		return fmt.Errorf("invalid SPOOF_CANCEL_RATE_BPS: must be between 1 and 10000")
+	}
+
+	if c.SpoofCancelLifetime < 0 {
+		return fmt.Errorf("invalid SPOOF_CANCEL_LIFETIME: must not be negative")
+	}
+
+	if c.TakerFeeBps < 0 || c.TakerFeeBps > 10000 {
+		return fmt.Errorf("invalid TAKER_FEE_BPS: must be between 0 and 10000")
+	}
+
+	if c.MakerRebateBps < 0 || c.MakerRebateBps > c.TakerFeeBps {
+		return fmt.Errorf("invalid MAKER_REBATE_BPS: must be between 0 and TAKER_FEE_BPS")
+	}
+
+	if c.DisplayPriceDecimals < -1 || c.DisplayPriceDecimals > 18 {
+		return fmt.Errorf("invalid DISPLAY_PRICE_DECIMALS: must be between -1 and 18")
+	}
+
+	switch c.BookDisplayPolicy {
+	case BookDisplayFull, BookDisplayIndicative, BookDisplayTop, BookDisplayNone:
+	case BookDisplayBucketed:
+		if !c.BookDisplayBucket.IsPositive() {
+			return fmt.Errorf("invalid BOOK_DISPLAY_BUCKET: must be positive with BOOK_DISPLAY_POLICY=BUCKETED")
+		}
+	default:
+		return fmt.Errorf("invalid BOOK_DISPLAY_POLICY: must be FULL, BUCKETED, INDICATIVE, TOP_OF_BOOK or NONE")
+	}
+
+	if c.StreamReplayLimit < 1 {
+		return fmt.Errorf("invalid STREAM_REPLAY_LIMIT: must be at least 1")
+	}
+
+	if c.MaxStreamsPerClient < 0 {
+		return fmt.Errorf("invalid MAX_STREAMS_PER_CLIENT: must not be negative")
+	}
+
+	if c.MaxStreamBacklog < 1 {
+		return fmt.Errorf("invalid MAX_STREAM_BACKLOG: must be at least 1")
+	}
+
+	if c.TradePrintDelay < 0 {
+		return fmt.Errorf("invalid TRADE_PRINT_DELAY: must not be negative")
+	}
+
+	if c.TickerMinInterval <= 0 {
+		return fmt.Errorf("invalid TICKER_MIN_INTERVAL: must be positive")
+	}
+
+	if c.SettlementTimeout < 0 {
+		return fmt.Errorf("invalid SETTLEMENT_TIMEOUT: must not be negative")
+	}
+
+	if c.ReaperInterval <= 0 {
+		return fmt.Errorf("invalid REAPER_INTERVAL: must be positive")
+	}
+
+	return nil
+}
+
+// BoundsFor returns the order bounds for a pair, falling back to OrderBounds
+func (c *Config) BoundsFor(baseToken, quoteToken string) OrderBounds {
+	for pair, bounds := range c.PairOrderBounds {
+		if pairMatches(pair, baseToken, quoteToken) {
+			return bounds
+		}
+	}
+	return c.OrderBounds
+}
+
+func validExecutionPriceMode(mode string) bool {
+	return mode == ExecutionPriceMidpoint || mode == ExecutionPriceVWAP
+}
+
+func validPriceTieBreak(tieBreak string) bool {
+	return tieBreak == PriceTieBreakSplit || tieBreak == PriceTieBreakMaker || tieBreak == PriceTieBreakTaker
+}
+
+func validCandidateRanking(ranking string) bool {
+	return ranking == CandidateRankingLimit || ranking == CandidateRankingImprovement || ranking == CandidateRankingTime
+}
+
+// ForPair returns the configuration to match a pair with: c itself, or a
+// copy with the pair's PairMatching overrides applied
+func (c *Config) ForPair(baseToken, quoteToken string) *Config {
+	for pair, rules := range c.PairMatching {
+		if !pairMatches(pair, baseToken, quoteToken) {
+			continue
+		}
+		pairCfg := *c
+		if rules.ExecutionPriceMode != "" {
+			pairCfg.ExecutionPriceMode = rules.ExecutionPriceMode
+		}
+		if rules.PriceTieBreak != "" {
+			pairCfg.PriceTieBreak = rules.PriceTieBreak
+		}
+		if rules.CandidateRanking != "" {
+			pairCfg.CandidateRanking = rules.CandidateRanking
+		}
+		if rules.MaxMatchesPerSecond != 0 {
+			pairCfg.MaxMatchesPerSecond = rules.MaxMatchesPerSecond
+		}
+		if rules.PriceSignificantDigits != 0 {
+			pairCfg.PriceSignificantDigits = rules.PriceSignificantDigits
+		}
+		return &pairCfg
+	}
+	return c
+}
+
+// validateMatchSize checks the fill size limits and lot size are usable together
+func (b OrderBounds) validateMatchSize() error {
+	if b.MinMatchSize.IsNegative() || b.MaxMatchSize.IsNegative() {
+		return fmt.Errorf("match sizes must not be negative")
+	}
+	if !b.MaxMatchSize.IsZero() && b.MinMatchSize.GreaterThan(b.MaxMatchSize) {
+		return fmt.Errorf("min match size exceeds max match size")
+	}
+	if b.MinMakerFill.IsNegative() {
+		return fmt.Errorf("min maker fill must not be negative")
+	}
+	if b.LotSize.IsNegative() {
+		return fmt.Errorf("lot size must not be negative")
+	}
+	if !b.MaxMatchSize.IsZero() && b.MaxMatchSize.LessThan(b.LotSize) {
+		return fmt.Errorf("max match size is smaller than one lot")
+	}
+	return nil
+}
+
+// RoundToLot rounds a quantity down to a whole number of lots; without a lot
+// size it is returned unchanged
+func (b OrderBounds) RoundToLot(qty decimal.Decimal) decimal.Decimal {
+	if !b.LotSize.IsPositive() {
+		return qty
+	}
+	return qty.Div(b.LotSize).Floor().Mul(b.LotSize)
+}
+
+// PairTradable reports whether ops allow trading a pair: it must not be in
+// DisabledPairs and, when TradablePairs is set, must be listed there
+func (c *Config) PairTradable(baseToken, quoteToken string) bool {
+	if pairListed(c.DisabledPairs, baseToken, quoteToken) {
+		return false
+	}
+	return len(c.TradablePairs) == 0 || pairListed(c.TradablePairs, baseToken, quoteToken)
+}
+
+// PairPinned reports whether a pair is named in HotPairs or TradablePairs,
+// which exempts it from MaxPairs
+func (c *Config) PairPinned(baseToken, quoteToken string) bool {
+	return pairListed(c.HotPairs, baseToken, quoteToken) || pairListed(c.TradablePairs, baseToken, quoteToken)
+}
+
+// PoolAllowed reports whether orders may route to a pool. The shared pool
+// (empty id) is always allowed.
+func (c *Config) PoolAllowed(poolID string) bool {
+	if poolID == "" {
+		return true
+	}
+	for _, pool := range c.Pools {
+		if pool == poolID {
+			return true
+		}
+	}
+	return false
+}
+
+// parseChainIDs parses a comma-separated list of chain ids
+func parseChainIDs(s string) ([]int32, error) {
+	var ids []int32
+	for _, chain := range splitList(s) {
+		id, err := strconv.ParseInt(chain, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, int32(id))
+	}
+	return ids, nil
+}
+
+// ChainSupported reports whether orders may be submitted for a chain
+func (c *Config) ChainSupported(chainID int32) bool {
+	if len(c.SupportedChains) == 0 {
+		return true
+	}
+	for _, id := range c.SupportedChains {
+		if id == chainID {
+			return true
+		}
+	}
+	return false
+}
+
+// EntityFor returns the entity an address belongs to, and whether it was
+// grouped in Entities. Ungrouped addresses are their own entity, keyed by
+// the lowercased address.
+func (c *Config) EntityFor(address string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(address))
+	if entity, ok := c.entityByAddress[key]; ok {
+		return entity, true
+	}
+	return key, false
+}
+
+// SameEntity reports whether two addresses belong to the same entity
+func (c *Config) SameEntity(address1, address2 string) bool {
+	entity1, _ := c.EntityFor(address1)
+	entity2, _ := c.EntityFor(address2)
+	return entity1 == entity2
+}
+
+// AuthenticateAPIKey returns the name of the configured key matching a raw
+// API key
+func (c *Config) AuthenticateAPIKey(key string) (string, bool) {
+	sum := sha256.Sum256([]byte(key))
+	name, ok := c.apiKeyByHash[hex.EncodeToString(sum[:])]
+	return name, ok
+}
+
+// APIKeyIsAdmin reports whether a key may call the admin RPCs
+func (c *Config) APIKeyIsAdmin(name string) bool {
+	return c.APIKeys[name].Admin
+}
+
+// APIKeyAllows reports whether a key may act for an address; keys without
+// addresses may act for any. Addresses compare case-insensitively.
+func (c *Config) APIKeyAllows(name, address string) bool {
+	key := c.APIKeys[name]
+	if len(key.Addresses) == 0 {
+		return true
+	}
+	for _, allowed := range key.Addresses {
+		if strings.EqualFold(strings.TrimSpace(allowed), strings.TrimSpace(address)) {
+			return true
+		}
+	}
+	return false
+}
+
+// PairTraced reports whether every order on a pair records a match trace
+func (c *Config) PairTraced(baseToken, quoteToken string) bool {
+	return pairListed(c.TracePairs, baseToken, quoteToken)
+}
+
+// RejectsOneSided reports whether the pair rejects taker orders that find
+// nothing to match on the other side of the book
+func (c *Config) RejectsOneSided(baseToken, quoteToken string) bool {
+	return pairListed(c.OneSidedRejectPairs, baseToken, quoteToken)
+}
+
+// ParsePair splits a "BASE/QUOTE" entry, normalizing 0x tokens the same way
+// orders are normalized at ingest
+func ParsePair(pair string) (baseToken, quoteToken string, ok bool) {
+	base, quote, found := strings.Cut(pair, "/")
+	base, quote = normalizePairToken(base), normalizePairToken(quote)
+	if !found || base == "" || quote == "" {
+		return "", "", false
+	}
+	return base, quote, true
+}
+
+// ParseKafkaBrokers splits a kafka://host:port[,host:port...] URL into the
+// addresses of its bootstrap brokers
+func ParseKafkaBrokers(rawURL string) ([]string, error) {
+	rest, ok := strings.CutPrefix(rawURL, "kafka://")
+	if !ok {
+		return nil, fmt.Errorf("must be a kafka://host:port URL")
+	}
+	brokers := splitList(rest)
+	if len(brokers) == 0 {
+		return nil, fmt.Errorf("must name at least one broker")
+	}
+	for _, broker := range brokers {
+		if host, port, err := net.SplitHostPort(broker); err != nil || host == "" || port == "" {
+			return nil, fmt.Errorf("broker %q must be host:port", broker)
+		}
+	}
+	return brokers, nil
+}
+
+func normalizePairToken(token string) string {
+	token = strings.TrimSpace(token)
+	if len(token) > 2 && (token[:2] == "0x" || token[:2] == "0X") {
+		return strings.ToLower(token)
+	}
+	return token
+}
+
+func pairMatches(pair, baseToken, quoteToken string) bool {
+	base, quote, ok := ParsePair(pair)
+	return ok && base == baseToken && quote == quoteToken
+}
+
+func pairListed(pairs []string, baseToken, quoteToken string) bool {
+	for _, pair := range pairs {
+		if pairMatches(pair, baseToken, quoteToken) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitList parses a comma-separated env value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
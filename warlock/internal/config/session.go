@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarketSession limits matching on a pair to trading windows, for tokenized
+// assets that only trade during set hours. Orders that arrive while the
+// session is closed rest and are matched at the next open, or are rejected
+// outright with RejectClosed.
+type MarketSession struct {
+	Timezone     string          `yaml:"timezone"`      // IANA name, e.g. America/New_York; empty is UTC
+	Days         []string        `yaml:"days"`          // MON..SUN; empty trades every day
+	Windows      []SessionWindow `yaml:"windows"`       // Open hours within each trading day
+	RejectClosed bool            `yaml:"reject_closed"` // Reject submissions while closed instead of queueing them
+
+	// Filled in by compile
+	loc     *time.Location
+	days    [7]bool
+	windows [][2]int // [open, close) in minutes after local midnight, by open
+}
+
+// SessionWindow is one open period in local time, "HH:MM". Close is
+// exclusive and may be "24:00"; overnight windows are split in two.
+type SessionWindow struct {
+	Open  string `yaml:"open"`
+	Close string `yaml:"close"`
+}
+
+var sessionDays = map[string]time.Weekday{
+	"SUN": time.Sunday, "MON": time.Monday, "TUE": time.Tuesday, "WED": time.Wednesday,
+	"THU": time.Thursday, "FRI": time.Friday, "SAT": time.Saturday,
+}
+
+// compile parses the timezone, days and windows
+func (s *MarketSession) compile() error {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return fmt.Errorf("unknown timezone %q", s.Timezone)
+	}
+	s.loc = loc
+
+	s.days = [7]bool{}
+	if len(s.Days) == 0 {
+		for i := range s.days {
+			s.days[i] = true
+		}
+	}
+	for _, day := range s.Days {
+		weekday, ok := sessionDays[strings.ToUpper(strings.TrimSpace(day))]
+		if !ok {
+			return fmt.Errorf("unknown day %q: expected MON..SUN", day)
+		}
+		s.days[weekday] = true
+	}
+
+	if len(s.Windows) == 0 {
+		return fmt.Errorf("at least one window is required")
+	}
+	s.windows = make([][2]int, 0, len(s.Windows))
+	for _, w := range s.Windows {
+		open, err := parseClock(w.Open)
+		if err != nil {
+			return fmt.Errorf("invalid open %q: %w", w.Open, err)
+		}
+		closing, err := parseClock(w.Close)
+		if err != nil {
+			return fmt.Errorf("invalid close %q: %w", w.Close, err)
+		}
+		if open >= closing {
+			return fmt.Errorf("window %s-%s must open before it closes", w.Open, w.Close)
+		}
+		s.windows = append(s.windows, [2]int{open, closing})
+	}
+	sort.Slice(s.windows, func(i, j int) bool { return s.windows[i][0] < s.windows[j][0] })
+	for i := 1; i < len(s.windows); i++ {
+		if s.windows[i][0] < s.windows[i-1][1] {
+			return fmt.Errorf("windows must not overlap")
+		}
+	}
+	return nil
+}
+
+// parseClock reads "HH:MM" as minutes after midnight; "24:00" is allowed
+func parseClock(clock string) (int, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(clock), ":")
+	if !ok {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM")
+	}
+	total := h*60 + m
+	if h < 0 || m < 0 || m > 59 || total > 24*60 {
+		return 0, fmt.Errorf("out of range")
+	}
+	return total, nil
+}
+
+// State reports whether the session is open at t and when that next changes:
+// the close of the current window, or the next open. next is zero when the
+// session never opens.
+func (s *MarketSession) State(t time.Time) (open bool, next time.Time) {
+	local := t.In(s.loc)
+	for offset := 0; offset <= 7; offset++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+offset, 0, 0, 0, 0, s.loc)
+		if !s.days[day.Weekday()] {
+			continue
+		}
+		for _, w := range s.windows {
+			// Built from the wall clock so DST changes don't shift the window
+			start := time.Date(day.Year(), day.Month(), day.Day(), 0, w[0], 0, 0, s.loc)
+			end := time.Date(day.Year(), day.Month(), day.Day(), 0, w[1], 0, 0, s.loc)
+			if !t.Before(start) && t.Before(end) {
+				return true, end
+			}
+			if start.After(t) {
+				return false, start
+			}
+		}
+	}
+	return false, time.Time{}
+}
+
+// IsOpen reports whether the session is open at t
+func (s *MarketSession) IsOpen(t time.Time) bool {
+	open, _ := s.State(t)
+	return open
+}
+
+// SessionFor returns the pair's market session, or nil if it trades around
+// the clock
+func (c *Config) SessionFor(baseToken, quoteToken string) *MarketSession {
+	for pair, session := range c.MarketHours {
+		if pairMatches(pair, baseToken, quoteToken) {
+			return session
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// migrationLockID is the advisory lock key held while migrating, so two
+// instances starting together don't apply the same file twice
+const migrationLockID = 0x7761726c6f636b // "warlock"
+
+// RunMigrations applies every *.up.sql file in dir that isn't yet recorded in
+// schema_migrations, in file name order, each in its own transaction, and
+// returns the versions applied. A database migrated by hand before
+// schema_migrations existed needs baseline, the number of the last migration
+// applied that way (e.g. "014"); those files are recorded without running.
+func RunMigrations(ctx context.Context, pool *pgxpool.Pool, dir, baseline string) ([]string, error) {
+	versions, err := migrationVersions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return nil, fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	var tracked, migratedByHand bool
+	err = conn.QueryRow(ctx, `
+		SELECT to_regclass('schema_migrations') IS NOT NULL,
+		       to_regclass('orders') IS NOT NULL
+	`).Scan(&tracked, &migratedByHand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect schema: %w", err)
+	}
+
+	if !tracked {
+		if migratedByHand && baseline == "" {
+			return nil, fmt.Errorf("schema predates schema_migrations: set MIGRATIONS_BASELINE to the last migration applied by hand")
+		}
+		if _, err := conn.Exec(ctx, `
+			CREATE TABLE schema_migrations (
+				version VARCHAR(255) PRIMARY KEY,
+				applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+			)
+		`); err != nil {
+			return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+		}
+		if migratedByHand {
+			if err := recordBaseline(ctx, conn, versions, baseline); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	applied := make(map[string]bool)
+	rows, err := conn.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	for rows.Next() {
+		var version string
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+
+	newlyApplied := make([]string, 0)
+	for _, version := range versions {
+		if applied[version] {
+			continue
+		}
+
+		sql, err := os.ReadFile(filepath.Join(dir, version+".up.sql"))
+		if err != nil {
+			return newlyApplied, fmt.Errorf("failed to read migration %s: %w", version, err)
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return newlyApplied, fmt.Errorf("failed to begin migration %s: %w", version, err)
+		}
+		// Without arguments Exec uses the simple protocol, which runs every statement in the file
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			tx.Rollback(ctx)
+			return newlyApplied, fmt.Errorf("migration %s failed: %w", version, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			tx.Rollback(ctx)
+			return newlyApplied, fmt.Errorf("failed to record migration %s: %w", version, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return newlyApplied, fmt.Errorf("failed to commit migration %s: %w", version, err)
+		}
+
+		log.Info().Str("version", version).Msg("Applied migration")
+		newlyApplied = append(newlyApplied, version)
+	}
+
+	return newlyApplied, nil
+}
+
+// migrationVersions lists the up migrations in dir, without the .up.sql
+// suffix, in the order they apply
+func migrationVersions(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.up.sql"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	versions := make([]string, 0, len(files))
+	for _, file := range files {
+		versions = append(versions, strings.TrimSuffix(filepath.Base(file), ".up.sql"))
+	}
+	sort.Strings(versions)
+	return versions, nil
+}
+
+// recordBaseline marks the migrations numbered up to baseline as applied
+func recordBaseline(ctx context.Context, conn *pgxpool.Conn, versions []string, baseline string) error {
+	last, err := strconv.Atoi(baseline)
+	if err != nil {
+		return fmt.Errorf("invalid MIGRATIONS_BASELINE %q: %w", baseline, err)
+	}
+	for _, version := range versions {
+		number, err := strconv.Atoi(strings.SplitN(version, "_", 2)[0])
+		if err != nil || number > last {
+			continue
+		}
+		if _, err := conn.Exec(ctx, `INSERT INTO schema_migrations (version) VALUES ($1)`, version); err != nil {
+			return fmt.Errorf("failed to record baseline migration %s: %w", version, err)
+		}
+	}
+	log.Info().Str("baseline", baseline).Msg("Recorded migrations applied before schema_migrations existed")
+	return nil
+}
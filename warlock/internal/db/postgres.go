@@ -42,6 +42,11 @@ func New(ctx context.Context, cfg *config.Config) (*pgxpool.Pool, error) {
 	// Connection timeout
 	poolConfig.ConnConfig.ConnectTimeout = 10 * time.Second
 
+	// Have the event_outbox triggers record events for the publisher
+	if cfg.EventBroker != "" {
+		poolConfig.ConnConfig.RuntimeParams["warlock.event_outbox"] = "on"
+	}
+
 	// Create pool
 	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
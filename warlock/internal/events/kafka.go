@@ -0,0 +1,76 @@
+//go:build kafka
+
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/segmentio/kafka-go"
+)
+
+// kafkaBatchTimeout bounds how long the writer waits to fill a batch; the
+// relay already hands it whole batches
+const kafkaBatchTimeout = 10 * time.Millisecond
+
+// KafkaPublisher publishes events to a Kafka topic, keyed by aggregate id so
+// the events of one match or order stay in order on one partition, with the
+// event type in a "type" header. Publish returns once every in-sync replica
+// has the batch.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher creates a publisher for a kafka://host:port[,host:port...]
+// URL naming bootstrap brokers. It connects on first use.
+func NewKafkaPublisher(rawURL string) (*KafkaPublisher, error) {
+	brokers, err := config.ParseKafkaBrokers(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Kafka URL: %w", err)
+	}
+	return &KafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		BatchTimeout: kafkaBatchTimeout,
+	}}, nil
+}
+
+// newKafkaPublisher is NewKafkaPublisher for NewPublisher
+func newKafkaPublisher(rawURL string) (Publisher, error) {
+	p, err := NewKafkaPublisher(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Publish implements Publisher
+func (p *KafkaPublisher) Publish(ctx context.Context, topic string, events []Event) error {
+	messages := make([]kafka.Message, len(events))
+	for i, e := range events {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %d: %w", e.ID, err)
+		}
+		messages[i] = kafka.Message{
+			Topic:   topic,
+			Key:     []byte(e.AggregateID),
+			Value:   payload,
+			Headers: []kafka.Header{{Key: "type", Value: []byte(e.Type)}},
+			Time:    e.CreatedAt,
+		}
+	}
+	if err := p.writer.WriteMessages(ctx, messages...); err != nil {
+		return fmt.Errorf("failed to write to Kafka: %w", err)
+	}
+	return nil
+}
+
+// Close implements Publisher
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
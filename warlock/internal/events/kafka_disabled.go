@@ -0,0 +1,11 @@
+//go:build !kafka
+
+package events
+
+import "errors"
+
+// newKafkaPublisher fails: the Kafka client is only compiled in with the
+// kafka build tag
+func newKafkaPublisher(rawURL string) (Publisher, error) {
+	return nil, errors.New("this binary was built without Kafka support; rebuild with -tags kafka")
+}
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+// NATSPublisher publishes events to a NATS server, each to
+// "<topic>.<event type>", e.g. warlock.match or warlock.order.filled. After a
+// batch it flushes with a PING, so Publish returns once the server has
+// processed every message.
+type NATSPublisher struct {
+	conn *nats.Conn
+}
+
+// NewNATSPublisher connects to the servers in a comma-separated list of
+// nats:// or tls:// URLs, which may carry credentials. A server that is down
+// doesn't fail it: the client keeps reconnecting in the background, and
+// publishing fails meanwhile.
+func NewNATSPublisher(url string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("warlock"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Warn().Err(err).Msg("Disconnected from NATS")
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			log.Info().Str("server", conn.ConnectedUrlRedacted()).Msg("Reconnected to NATS")
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return &NATSPublisher{conn: conn}, nil
+}
+
+// Publish implements Publisher
+func (p *NATSPublisher) Publish(ctx context.Context, topic string, events []Event) error {
+	for _, e := range events {
+		payload, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode event %d: %w", e.ID, err)
+		}
+		if err := p.conn.Publish(topic+"."+e.Type, payload); err != nil {
+			return fmt.Errorf("failed to publish event %d to NATS: %w", e.ID, err)
+		}
+	}
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to flush NATS: %w", err)
+	}
+	return nil
+}
+
+// Close implements Publisher
+func (p *NATSPublisher) Close() error {
+	p.conn.Close()
+	return nil
+}
@@ -0,0 +1,199 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/darkpool/warlock/internal/matcher"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// publishTimeout bounds publishing one batch, so a stalled broker can't hold
+// the outbox rows locked indefinitely
+const publishTimeout = 10 * time.Second
+
+// Event is one outbox row on its way to the broker
+type Event struct {
+	ID          int64           `json:"id"` // Outbox id; consumers dedupe on it
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// Publisher delivers events to a broker. Publish returns only once the broker
+// has accepted every event, or an error if it may not have; events are then
+// published again, so delivery is at least once.
+type Publisher interface {
+	Publish(ctx context.Context, subject string, events []Event) error
+	Close() error
+}
+
+// NewPublisher creates the Publisher for the configured EVENT_BROKER
+func NewPublisher(cfg *config.Config) (Publisher, error) {
+	switch cfg.EventBroker {
+	case config.EventBrokerKafka:
+		return newKafkaPublisher(cfg.EventBrokerURL)
+	default:
+		p, err := NewNATSPublisher(cfg.EventBrokerURL)
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+}
+
+// Outbox holds the events waiting to be published
+type Outbox interface {
+	// Relay hands up to limit of the oldest events to publish and removes
+	// them once it succeeds, returning how many it published. Events being
+	// relayed are skipped by concurrent calls.
+	Relay(ctx context.Context, limit int, publish func([]Event) error) (int, error)
+}
+
+// Relay moves events from an Outbox to a Publisher. The default outbox is the
+// event_outbox table, filled by triggers in the same transactions as the
+// matches and order changes, so an event is never lost to a crash or a broker
+// outage; rows are deleted only after the broker has them.
+type Relay struct {
+	outbox    Outbox
+	cfg       *config.Config
+	publisher Publisher
+	wake      chan struct{}
+}
+
+// NewRelay creates a relay publishing the event_outbox table through
+// publisher
+func NewRelay(db *pgxpool.Pool, cfg *config.Config, publisher Publisher) *Relay {
+	return &Relay{
+		outbox:    NewPostgresOutbox(db),
+		cfg:       cfg,
+		publisher: publisher,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// SetOutbox replaces the outbox events are relayed from. Call before Run.
+func (r *Relay) SetOutbox(outbox Outbox) {
+	r.outbox = outbox
+}
+
+// OnMatch implements matcher.MatchHook: the match's events are committed by
+// now, so the relay is woken to publish them without waiting for its next poll
+func (r *Relay) OnMatch(ctx context.Context, match *matcher.Match) error {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run relays events until ctx is done, then closes the publisher
+func (r *Relay) Run(ctx context.Context) {
+	defer r.publisher.Close()
+
+	poll := time.NewTicker(r.cfg.EventPollInterval)
+	defer poll.Stop()
+
+	for {
+		// Keep going while full batches come back, so a backlog drains quickly
+		n, err := r.relayBatch(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to relay events; retrying at the next poll")
+		}
+		if err == nil && n == r.cfg.EventBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-poll.C:
+		}
+	}
+}
+
+// relayBatch publishes the oldest outbox events
+func (r *Relay) relayBatch(ctx context.Context) (int, error) {
+	n, err := r.outbox.Relay(ctx, r.cfg.EventBatchSize, func(batch []Event) error {
+		publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+		defer cancel()
+		if err := r.publisher.Publish(publishCtx, r.cfg.EventTopic, batch); err != nil {
+			return fmt.Errorf("failed to publish %d events: %w", len(batch), err)
+		}
+		return nil
+	})
+	if n > 0 {
+		log.Debug().Int("events", n).Msg("Published events")
+	}
+	return n, err
+}
+
+// PostgresOutbox is the Outbox backed by the event_outbox table
+type PostgresOutbox struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresOutbox creates an outbox on the event_outbox table
+func NewPostgresOutbox(db *pgxpool.Pool) *PostgresOutbox {
+	return &PostgresOutbox{db: db}
+}
+
+// Relay implements Outbox. The rows stay locked until they are deleted, so
+// several instances can share the table.
+func (o *PostgresOutbox) Relay(ctx context.Context, limit int, publish func([]Event) error) (int, error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin outbox transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, event_type, aggregate_id::text, created_at, payload
+		FROM event_outbox
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query outbox: %w", err)
+	}
+
+	batch := make([]Event, 0)
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.Type, &e.AggregateID, &e.CreatedAt, &e.Data); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		batch = append(batch, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read outbox: %w", err)
+	}
+	if len(batch) == 0 {
+		return 0, nil
+	}
+
+	if err := publish(batch); err != nil {
+		return 0, err
+	}
+
+	ids := make([]int64, len(batch))
+	for i, e := range batch {
+		ids[i] = e.ID
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM event_outbox WHERE id = ANY($1)`, ids); err != nil {
+		return 0, fmt.Errorf("failed to delete published events: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit published events: %w", err)
+	}
+	return len(batch), nil
+}
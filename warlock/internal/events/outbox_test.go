@@ -0,0 +1,276 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/darkpool/warlock/internal/matcher"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// testConfig loads the default configuration; the database URL is required
+// but never dialed
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	t.Setenv("DATABASE_URL", "postgres://unused")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	return cfg
+}
+
+// memoryOutbox is an Outbox in memory. As a match hook it records each match
+// the way the record_match_event trigger does.
+type memoryOutbox struct {
+	mu     sync.Mutex
+	events []Event
+	nextID int64
+}
+
+func (o *memoryOutbox) add(eventType, aggregateID string, data []byte) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.nextID++
+	o.events = append(o.events, Event{
+		ID:          o.nextID,
+		Type:        eventType,
+		AggregateID: aggregateID,
+		CreatedAt:   time.Now(),
+		Data:        data,
+	})
+}
+
+func (o *memoryOutbox) pending() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.events)
+}
+
+func (o *memoryOutbox) OnMatch(ctx context.Context, match *matcher.Match) error {
+	data, err := json.Marshal(match)
+	if err != nil {
+		return err
+	}
+	o.add("match", match.ID, data)
+	return nil
+}
+
+func (o *memoryOutbox) Relay(ctx context.Context, limit int, publish func([]Event) error) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	batch := append([]Event(nil), o.events[:min(limit, len(o.events))]...)
+	if len(batch) == 0 {
+		return 0, nil
+	}
+	if err := publish(batch); err != nil {
+		return 0, err
+	}
+	o.events = o.events[len(batch):]
+	return len(batch), nil
+}
+
+// memoryBroker is a Publisher recording what it accepts, after refusing the
+// first failures batches
+type memoryBroker struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	subjects []string
+	events   []Event
+	closed   bool
+}
+
+func (b *memoryBroker) Publish(ctx context.Context, topic string, events []Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attempts++
+	if b.attempts <= b.failures {
+		return errors.New("broker unavailable")
+	}
+	for _, e := range events {
+		b.subjects = append(b.subjects, topic+"."+e.Type)
+		b.events = append(b.events, e)
+	}
+	return nil
+}
+
+func (b *memoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	return nil
+}
+
+// published waits for the broker to hold n events and returns them
+func (b *memoryBroker) published(t *testing.T, n int) ([]string, []Event) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b.mu.Lock()
+		subjects, events := append([]string(nil), b.subjects...), append([]Event(nil), b.events...)
+		b.mu.Unlock()
+		if len(events) >= n {
+			return subjects, events
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("broker holds %d events, want %d", len(events), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// runRelay runs a relay from outbox to broker until the test ends
+func runRelay(t *testing.T, cfg *config.Config, outbox Outbox, broker Publisher) *Relay {
+	t.Helper()
+	relay := NewRelay(nil, cfg, broker)
+	relay.SetOutbox(outbox)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return relay
+}
+
+// bookOrder builds a revealed order on the WETH/USDC pair at exactly price
+func bookOrder(user string, side matcher.OrderType, quantity, price string) *matcher.Order {
+	q := decimal.RequireFromString(quantity)
+	p := decimal.RequireFromString(price)
+	return &matcher.Order{
+		ID:                uuid.NewString(),
+		UserAddress:       user,
+		ChainID:           1,
+		OrderType:         side,
+		BaseToken:         "WETH",
+		QuoteToken:        "USDC",
+		Quantity:          q,
+		Price:             p,
+		MinPrice:          p,
+		MaxPrice:          p,
+		FilledQuantity:    decimal.Zero,
+		RemainingQuantity: q,
+		Status:            matcher.OrderStatusRevealed,
+		CreatedAt:         time.Now(),
+		RemainderPolicy:   matcher.RemainderRest,
+	}
+}
+
+func TestRelayPublishesEveryMatch(t *testing.T) {
+	cfg := testConfig(t)
+	// Only the wake from OnMatch can get the events out in time
+	cfg.EventPollInterval = time.Hour
+
+	outbox := &memoryOutbox{}
+	broker := &memoryBroker{}
+	relay := runRelay(t, cfg, outbox, broker)
+
+	store := matcher.NewMemoryStore()
+	e := matcher.NewEngine(nil, cfg)
+	e.SetStore(store)
+	e.SetMatchHook(matcher.MatchHooks{outbox, relay})
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := e.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		e.Stop()
+		cancel()
+	})
+
+	var matches []*matcher.Match
+	orders := []*matcher.Order{
+		bookOrder("0xalice", matcher.OrderTypeSell, "1", "100"),
+		bookOrder("0xcarol", matcher.OrderTypeSell, "2", "100"),
+		bookOrder("0xdave", matcher.OrderTypeSell, "3", "100"),
+		bookOrder("0xbob", matcher.OrderTypeBuy, "6", "100"),
+	}
+	for _, order := range orders {
+		if err := store.CreateOrders(ctx, []matcher.NewOrder{{Order: order}}); err != nil {
+			t.Fatalf("CreateOrders: %v", err)
+		}
+		m, err := e.SubmitOrderSync(ctx, order)
+		if err != nil {
+			t.Fatalf("SubmitOrderSync: %v", err)
+		}
+		matches = append(matches, m...)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("got %d matches, want 3", len(matches))
+	}
+
+	subjects, events := broker.published(t, len(matches))
+	if len(events) != len(matches) {
+		t.Fatalf("broker got %d events, want one per match", len(events))
+	}
+	for i, m := range matches {
+		if subjects[i] != cfg.EventTopic+".match" {
+			t.Errorf("event %d went to %s, want %s.match", i, subjects[i], cfg.EventTopic)
+		}
+		if events[i].AggregateID != m.ID {
+			t.Errorf("event %d is for %s, want match %s", i, events[i].AggregateID, m.ID)
+		}
+		var data matcher.Match
+		if err := json.Unmarshal(events[i].Data, &data); err != nil || !data.Quantity.Equal(m.Quantity) {
+			t.Errorf("event %d carries %s (%v), want the match's quantity %s", i, data.Quantity, err, m.Quantity)
+		}
+	}
+	if n := outbox.pending(); n != 0 {
+		t.Errorf("%d events left in the outbox after publishing", n)
+	}
+}
+
+func TestRelayRetriesUntilBrokerAccepts(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.EventPollInterval = 10 * time.Millisecond
+	cfg.EventBatchSize = 2
+
+	outbox := &memoryOutbox{}
+	for i := 0; i < 5; i++ {
+		outbox.add("order.revealed", fmt.Sprintf("order-%d", i), []byte(`{}`))
+	}
+	broker := &memoryBroker{failures: 3}
+	runRelay(t, cfg, outbox, broker)
+
+	_, events := broker.published(t, 5)
+	for i, e := range events {
+		if e.ID != int64(i+1) {
+			t.Errorf("event %d has outbox id %d, want %d: events were lost, repeated or reordered", i, e.ID, i+1)
+		}
+	}
+	if len(events) != 5 {
+		t.Errorf("broker accepted %d events, want each of the 5 once", len(events))
+	}
+	if n := outbox.pending(); n != 0 {
+		t.Errorf("%d events left in the outbox after publishing", n)
+	}
+}
+
+func TestRelayClosesPublisherOnStop(t *testing.T) {
+	cfg := testConfig(t)
+	broker := &memoryBroker{}
+	relay := NewRelay(nil, cfg, broker)
+	relay.SetOutbox(&memoryOutbox{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	relay.Run(ctx)
+
+	if !broker.closed {
+		t.Error("publisher still open after the relay stopped")
+	}
+}
@@ -0,0 +1,107 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultActivityWindow is how far back GetUserActivityStats looks without since
+const defaultActivityWindow = 24 * time.Hour
+
+// activityRatioPlaces is the decimal places activity ratios are rounded to
+const activityRatioPlaces = 4
+
+// GetUserActivityStats summarizes how the user's orders submitted in the
+// window ended, for spoofing surveillance. Cancels the engine made rather
+// than the user aren't counted as cancels: those past a no-fill deadline and
+// the remainders of REMAINDER_POLICY_CANCEL orders.
+func (s *Server) GetUserActivityStats(ctx context.Context, req *pb.GetUserActivityStatsRequest) (*pb.GetUserActivityStatsResponse, error) {
+	req.UserAddress = normalizeAddress(req.UserAddress)
+
+	if req.UserAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user_address is required")
+	}
+
+	since := time.Now().Add(-defaultActivityWindow)
+	if req.Since != nil {
+		since = req.Since.AsTime()
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	var orders, cancelled, filled, avgLifetimeMs, avgCancelMs int64
+	err := s.db.QueryRow(queryCtx, `
+		WITH user_orders AS (
+			SELECT status, filled_quantity, created_at, updated_at,
+			       status = 'CANCELLED'
+			         AND NOT (no_fill_deadline IS NOT NULL AND updated_at >= no_fill_deadline)
+			         AND NOT (remainder_policy = 'CANCEL' AND filled_quantity > 0) AS user_cancelled
+			FROM orders
+			WHERE LOWER(user_address) = LOWER($1)
+			  AND created_at >= $2
+		)
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE user_cancelled),
+		       COUNT(*) FILTER (WHERE filled_quantity > 0),
+		       COALESCE(EXTRACT(EPOCH FROM AVG(updated_at - created_at)
+		         FILTER (WHERE status IN ('FILLED', 'CANCELLED'))) * 1000, 0)::bigint,
+		       COALESCE(EXTRACT(EPOCH FROM AVG(updated_at - created_at)
+		         FILTER (WHERE user_cancelled)) * 1000, 0)::bigint
+		FROM user_orders
+	`, req.UserAddress, since).Scan(&orders, &cancelled, &filled, &avgLifetimeMs, &avgCancelMs)
+	if err != nil {
+		log.Error().Err(err).Str("user_address", req.UserAddress).Msg("Failed to query user activity")
+		return nil, queryStatus(err, "failed to query user activity")
+	}
+
+	resp := &pb.GetUserActivityStatsResponse{
+		UserAddress:         req.UserAddress,
+		Orders:              orders,
+		CancelledOrders:     cancelled,
+		FilledOrders:        filled,
+		CancelRate:          activityRatio(cancelled, orders),
+		FillRatio:           activityRatio(filled, orders),
+		CancelToFillRatio:   activityRatio(cancelled, filled),
+		AvgOrderLifetimeMs:  avgLifetimeMs,
+		AvgCancelLifetimeMs: avgCancelMs,
+	}
+	resp.Flagged, resp.FlagReason = s.spoofingFlag(orders, cancelled, time.Duration(avgCancelMs)*time.Millisecond)
+	return resp, nil
+}
+
+// spoofingFlag reports whether the activity crosses every SPOOF_* threshold,
+// and why
+func (s *Server) spoofingFlag(orders, cancelled int64, avgCancelLifetime time.Duration) (bool, string) {
+	if orders < int64(s.cfg.SpoofMinOrders) {
+		return false, ""
+	}
+	// cancelled/orders >= bps/10000, compared in integers
+	if cancelled*10000 < int64(s.cfg.SpoofCancelRateBps)*orders {
+		return false, ""
+	}
+	if s.cfg.SpoofCancelLifetime > 0 && avgCancelLifetime > s.cfg.SpoofCancelLifetime {
+		return false, ""
+	}
+
+	reason := fmt.Sprintf("%d of %d orders cancelled, at least %d bps", cancelled, orders, s.cfg.SpoofCancelRateBps)
+	if s.cfg.SpoofCancelLifetime > 0 {
+		reason += fmt.Sprintf(", on average %s after submission, within %s", avgCancelLifetime, s.cfg.SpoofCancelLifetime)
+	}
+	return true, reason
+}
+
+// activityRatio formats part/whole, or "" when whole is zero
+func activityRatio(part, whole int64) string {
+	if whole == 0 {
+		return ""
+	}
+	return decimal.NewFromInt(part).DivRound(decimal.NewFromInt(whole), activityRatioPlaces).String()
+}
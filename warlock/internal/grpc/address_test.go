@@ -0,0 +1,102 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+)
+
+const (
+	checksummedAlice = "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	lowercaseAlice   = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	checksummedBob   = "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"
+	lowercaseBob     = "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: lowercaseAlice, want: lowercaseAlice},
+		{in: checksummedAlice, want: lowercaseAlice},
+		{in: "  " + checksummedAlice + "\t", want: lowercaseAlice},
+		{in: "0X5AAEB6053F3E94C9B9A09F33669435E7EF1BEAED", want: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{in: "   ", want: ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeAddress(tt.in); got != tt.want {
+			t.Errorf("normalizeAddress(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMixedCaseAddressesAreOneAccount(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SelfTradePrevention = true
+	s, store := newTestServer(t, cfg)
+	ctx := context.Background()
+
+	resp, err := s.SubmitOrder(ctx, orderRequest(checksummedAlice, pb.OrderType_ORDER_TYPE_SELL, "5", "100"))
+	if err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+	if resp.Order.UserAddress != lowercaseAlice {
+		t.Errorf("acked user %q, want %q", resp.Order.UserAddress, lowercaseAlice)
+	}
+	if got := storedOrder(t, store, resp.Order.Id); got.UserAddress != lowercaseAlice {
+		t.Errorf("stored user %q, want %q", got.UserAddress, lowercaseAlice)
+	}
+
+	// The same account, submitted in its other form, is a self-match
+	self, err := s.SubmitOrder(ctx, orderRequest(lowercaseAlice, pb.OrderType_ORDER_TYPE_BUY, "1", "100"))
+	if err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+	if len(self.ImmediateMatches) != 0 {
+		t.Errorf("%s matched against %s", lowercaseAlice, checksummedAlice)
+	}
+	if _, err := s.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: self.Order.Id, UserAddress: checksummedAlice}); err != nil {
+		t.Fatalf("CancelOrder in checksummed form: %v", err)
+	}
+
+	if _, err := s.CancelOrder(ctx, &pb.CancelOrderRequest{OrderId: resp.Order.Id, UserAddress: "  " + lowercaseAlice}); err != nil {
+		t.Fatalf("CancelOrder in lowercase form: %v", err)
+	}
+}
+
+func TestCounterpartyAllowlistMatchesAcrossCase(t *testing.T) {
+	tests := []struct {
+		name      string
+		allowlist string
+		buyer     string
+	}{
+		{name: "checksummed allowlist, lowercase buyer", allowlist: checksummedBob, buyer: lowercaseBob},
+		{name: "lowercase allowlist, checksummed buyer", allowlist: lowercaseBob, buyer: checksummedBob},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestServer(t, testConfig(t))
+			ctx := context.Background()
+
+			sell := orderRequest(checksummedAlice, pb.OrderType_ORDER_TYPE_SELL, "5", "100")
+			sell.CounterpartyAllowlist = []string{tt.allowlist}
+			if _, err := s.SubmitOrder(ctx, sell); err != nil {
+				t.Fatalf("SubmitOrder: %v", err)
+			}
+
+			resp, err := s.SubmitOrder(ctx, orderRequest(tt.buyer, pb.OrderType_ORDER_TYPE_BUY, "2", "100"))
+			if err != nil {
+				t.Fatalf("SubmitOrder: %v", err)
+			}
+			if len(resp.ImmediateMatches) != 1 {
+				t.Fatalf("got %d matches, want 1 with the allowlisted buyer", len(resp.ImmediateMatches))
+			}
+			if m := resp.ImmediateMatches[0]; m.BuyerAddress != lowercaseBob || m.SellerAddress != lowercaseAlice {
+				t.Errorf("match between %s and %s, want %s and %s", m.BuyerAddress, m.SellerAddress, lowercaseBob, lowercaseAlice)
+			}
+		})
+	}
+}
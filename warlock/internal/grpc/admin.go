@@ -0,0 +1,398 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/darkpool/warlock/internal/db"
+	"github.com/darkpool/warlock/internal/matcher"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Admin RPCs for operating the engine. These are intended for operators, not trading clients.
+
+// RebuildBook reloads a single pair's in-memory book from the database
+func (s *Server) RebuildBook(ctx context.Context, req *pb.RebuildBookRequest) (*pb.RebuildBookResponse, error) {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+
+	log.Warn().
+		Str("base_token", req.BaseToken).
+		Str("quote_token", req.QuoteToken).
+		Msg("Received RebuildBook request")
+
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+
+	previousSize, loaded, err := s.engine.RebuildBook(ctx, req.BaseToken, req.QuoteToken)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to rebuild order book")
+		return nil, status.Errorf(codes.Internal, "failed to rebuild order book: %v", err)
+	}
+
+	return &pb.RebuildBookResponse{
+		BaseToken:    req.BaseToken,
+		QuoteToken:   req.QuoteToken,
+		PreviousSize: int32(previousSize),
+		OrdersLoaded: int32(loaded),
+	}, nil
+}
+
+// PausePair stops a pair from accepting new orders
+func (s *Server) PausePair(ctx context.Context, req *pb.PausePairRequest) (*pb.PausePairResponse, error) {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+
+	s.engine.PausePair(req.BaseToken, req.QuoteToken)
+
+	return &pb.PausePairResponse{
+		State: string(s.engine.PairState(req.BaseToken, req.QuoteToken)),
+	}, nil
+}
+
+// ResumePair lets a paused pair accept orders again
+func (s *Server) ResumePair(ctx context.Context, req *pb.ResumePairRequest) (*pb.ResumePairResponse, error) {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+
+	s.engine.ResumePair(req.BaseToken, req.QuoteToken)
+
+	return &pb.ResumePairResponse{
+		State: string(s.engine.PairState(req.BaseToken, req.QuoteToken)),
+	}, nil
+}
+
+// SetChainStatus pauses or resumes a chain
+func (s *Server) SetChainStatus(ctx context.Context, req *pb.SetChainStatusRequest) (*pb.SetChainStatusResponse, error) {
+	log.Warn().
+		Int32("chain_id", req.ChainId).
+		Bool("paused", req.Paused).
+		Msg("Received SetChainStatus request")
+
+	if req.ChainId == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "chain_id is required")
+	}
+
+	if req.Paused {
+		s.engine.PauseChain(req.ChainId)
+	} else {
+		s.engine.ResumeChain(req.ChainId)
+	}
+
+	return &pb.SetChainStatusResponse{
+		ChainId: req.ChainId,
+		Paused:  s.engine.ChainPaused(req.ChainId),
+	}, nil
+}
+
+// GetChainStatus reports whether a chain is paused
+func (s *Server) GetChainStatus(ctx context.Context, req *pb.GetChainStatusRequest) (*pb.GetChainStatusResponse, error) {
+	return &pb.GetChainStatusResponse{
+		ChainId:      req.ChainId,
+		Paused:       s.engine.ChainPaused(req.ChainId),
+		PausedChains: s.engine.PausedChains(),
+	}, nil
+}
+
+// GetMarketSession reports a pair's market session state
+func (s *Server) GetMarketSession(ctx context.Context, req *pb.GetMarketSessionRequest) (*pb.GetMarketSessionResponse, error) {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+
+	session := s.cfg.SessionFor(req.BaseToken, req.QuoteToken)
+	if session == nil {
+		return &pb.GetMarketSessionResponse{Open: true}, nil
+	}
+
+	open, next := session.State(s.engine.Now())
+	resp := &pb.GetMarketSessionResponse{
+		Configured:     true,
+		Open:           open,
+		Timezone:       session.Timezone,
+		RejectClosed:   session.RejectClosed,
+		DeferredOrders: int32(s.engine.DeferredOrders(req.BaseToken, req.QuoteToken)),
+	}
+	if !next.IsZero() {
+		resp.NextChange = timestamppb.New(next)
+	}
+	return resp, nil
+}
+
+// GetMatchTrace returns the recorded matching decisions for a traced order
+func (s *Server) GetMatchTrace(ctx context.Context, req *pb.GetMatchTraceRequest) (*pb.GetMatchTraceResponse, error) {
+	if req.OrderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order_id is required")
+	}
+
+	trace := s.engine.GetMatchTrace(req.OrderId)
+	if trace == nil {
+		return nil, status.Errorf(codes.NotFound, "no match trace for order %s", req.OrderId)
+	}
+
+	candidates := make([]*pb.CandidateTrace, 0, len(trace.Candidates))
+	for _, c := range trace.Candidates {
+		candidates = append(candidates, &pb.CandidateTrace{
+			OrderId:           c.OrderID,
+			UserAddress:       c.UserAddress,
+			MinPrice:          c.MinPrice.String(),
+			MaxPrice:          c.MaxPrice.String(),
+			RemainingQuantity: c.RemainingQuantity.String(),
+			Outcome:           string(c.Outcome),
+			Detail:            c.Detail,
+			MatchId:           c.MatchID,
+		})
+	}
+
+	return &pb.GetMatchTraceResponse{
+		OrderId:    trace.OrderID,
+		TracedAt:   timestamppb.New(trace.TracedAt),
+		Candidates: candidates,
+	}, nil
+}
+
+// GetInMemoryOrder returns the engine's in-memory copy of an order, for
+// diagnosing divergence from the database row
+func (s *Server) GetInMemoryOrder(ctx context.Context, req *pb.GetInMemoryOrderRequest) (*pb.GetInMemoryOrderResponse, error) {
+	if req.OrderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order_id is required")
+	}
+
+	order, ok := s.engine.GetInMemoryOrder(req.OrderId)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "order %s is not in any in-memory book", req.OrderId)
+	}
+
+	return &pb.GetInMemoryOrderResponse{Order: orderToProto(&order)}, nil
+}
+
+// DrainWorker retires a worker and waits, within the call's deadline, until it
+// has finished whatever it was processing
+func (s *Server) DrainWorker(ctx context.Context, req *pb.DrainWorkerRequest) (*pb.DrainWorkerResponse, error) {
+	log.Warn().Int32("worker_id", req.WorkerId).Msg("Received DrainWorker request")
+
+	done, err := s.engine.DrainWorker(int(req.WorkerId))
+	if errors.Is(err, matcher.ErrWorkerNotFound) {
+		return nil, status.Errorf(codes.NotFound, "no running worker %d (running: %v)", req.WorkerId, s.engine.WorkerIDs())
+	}
+	if errors.Is(err, matcher.ErrLastWorker) {
+		return nil, status.Errorf(codes.FailedPrecondition, "worker %d is the last one running", req.WorkerId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to drain worker: %v", err)
+	}
+
+	idle := false
+	select {
+	case <-done:
+		idle = true
+	case <-ctx.Done():
+	}
+
+	ids := s.engine.WorkerIDs()
+	remaining := make([]int32, 0, len(ids))
+	for _, id := range ids {
+		remaining = append(remaining, int32(id))
+	}
+
+	return &pb.DrainWorkerResponse{
+		WorkerId:           req.WorkerId,
+		Idle:               idle,
+		RemainingWorkerIds: remaining,
+	}, nil
+}
+
+// engineChannels maps the proto channel names to the engine's
+var engineChannels = map[pb.EngineChannel]string{
+	pb.EngineChannel_ENGINE_CHANNEL_ORDERS:  matcher.ChannelOrders,
+	pb.EngineChannel_ENGINE_CHANNEL_CANCELS: matcher.ChannelCancels,
+	pb.EngineChannel_ENGINE_CHANNEL_MATCHES: matcher.ChannelMatches,
+}
+
+// GetChannelStatus reports how full each engine channel is
+func (s *Server) GetChannelStatus(ctx context.Context, req *pb.GetChannelStatusRequest) (*pb.GetChannelStatusResponse, error) {
+	resp := &pb.GetChannelStatusResponse{}
+	for _, ch := range s.engine.ChannelStatus() {
+		for channel, name := range engineChannels {
+			if name == ch.Name {
+				resp.Channels = append(resp.Channels, &pb.ChannelStatus{
+					Channel:  channel,
+					Length:   int32(ch.Length),
+					Capacity: int32(ch.Capacity),
+				})
+			}
+		}
+	}
+	return resp, nil
+}
+
+// DrainChannel empties one engine channel. It is a last resort for a wedged
+// queue: the queued requests are discarded, so it must be confirmed.
+func (s *Server) DrainChannel(ctx context.Context, req *pb.DrainChannelRequest) (*pb.DrainChannelResponse, error) {
+	name, ok := engineChannels[req.Channel]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "channel is required")
+	}
+	if !req.Confirm {
+		return nil, status.Errorf(codes.FailedPrecondition, "draining discards the queued requests; set confirm to proceed")
+	}
+
+	log.Warn().Str("channel", name).Msg("Received DrainChannel request")
+
+	result, err := s.engine.DrainChannel(name)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to drain channel: %v", err)
+	}
+
+	return &pb.DrainChannelResponse{
+		Channel:  req.Channel,
+		Drained:  int32(result.Drained),
+		OrderIds: result.OrderIDs,
+	}, nil
+}
+
+// GetEntityForAddress reports which entity an address trades as for self-trade prevention
+func (s *Server) GetEntityForAddress(ctx context.Context, req *pb.GetEntityForAddressRequest) (*pb.GetEntityForAddressResponse, error) {
+	req.Address = normalizeAddress(req.Address)
+	if req.Address == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "address is required")
+	}
+
+	entity, grouped := s.cfg.EntityFor(req.Address)
+	addresses := []string{req.Address}
+	if grouped {
+		addresses = make([]string, 0, len(s.cfg.Entities[entity]))
+		for _, addr := range s.cfg.Entities[entity] {
+			addresses = append(addresses, normalizeAddress(addr))
+		}
+	}
+
+	return &pb.GetEntityForAddressResponse{
+		Address:   req.Address,
+		EntityId:  entity,
+		Grouped:   grouped,
+		Addresses: addresses,
+	}, nil
+}
+
+// Defaults and caps for GetCounterpartyMatrix
+const (
+	defaultCounterpartyWindow   = 24 * time.Hour
+	defaultCounterpartyMinCount = 10
+	defaultCounterpartyLimit    = 100
+	maxCounterpartyLimit        = 1000
+)
+
+// GetCounterpartyMatrix aggregates matches by unordered address pair and
+// returns the pairs that traded with each other at least min_match_count times
+func (s *Server) GetCounterpartyMatrix(ctx context.Context, req *pb.GetCounterpartyMatrixRequest) (*pb.GetCounterpartyMatrixResponse, error) {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+
+	if (req.BaseToken == "") != (req.QuoteToken == "") {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token must be set together")
+	}
+	if req.MinMatchCount < 0 || req.Limit < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "min_match_count and limit must not be negative")
+	}
+
+	since := time.Now().Add(-defaultCounterpartyWindow)
+	if req.Since != nil {
+		since = req.Since.AsTime()
+	}
+	minCount := int32(defaultCounterpartyMinCount)
+	if req.MinMatchCount > 0 {
+		minCount = req.MinMatchCount
+	}
+	limit := int32(defaultCounterpartyLimit)
+	if req.Limit > 0 {
+		limit = min(req.Limit, maxCounterpartyLimit)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.Query(queryCtx, `
+		SELECT LEAST(LOWER(b.user_address), LOWER(sl.user_address)) AS address_a,
+		       GREATEST(LOWER(b.user_address), LOWER(sl.user_address)) AS address_b,
+		       COUNT(*), SUM(m.quantity)::text, SUM(m.quantity * m.price)::text,
+		       MIN(m.matched_at), MAX(m.matched_at)
+		FROM matches m
+		JOIN orders b ON b.id = m.buy_order_id
+		JOIN orders sl ON sl.id = m.sell_order_id
+		WHERE m.matched_at >= $1
+		  AND ($2 = '' OR (m.base_token = $2 AND m.quote_token = $3))
+		GROUP BY address_a, address_b
+		HAVING COUNT(*) >= $4
+		ORDER BY COUNT(*) DESC, address_a, address_b
+		LIMIT $5
+	`, since, req.BaseToken, req.QuoteToken, minCount, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query counterparty matrix")
+		return nil, queryStatus(err, "failed to query counterparty matrix")
+	}
+	defer rows.Close()
+
+	pairs := make([]*pb.CounterpartyPair, 0)
+	for rows.Next() {
+		var p pb.CounterpartyPair
+		var firstMatchedAt, lastMatchedAt time.Time
+
+		err := rows.Scan(
+			&p.AddressA, &p.AddressB, &p.MatchCount, &p.Volume, &p.Notional,
+			&firstMatchedAt, &lastMatchedAt,
+		)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to scan counterparty pair: %v", err)
+		}
+
+		p.FirstMatchedAt = timestamppb.New(firstMatchedAt)
+		p.LastMatchedAt = timestamppb.New(lastMatchedAt)
+		pairs = append(pairs, &p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryStatus(err, "failed to read counterparty matrix")
+	}
+
+	return &pb.GetCounterpartyMatrixResponse{Pairs: pairs}, nil
+}
+
+// ApplyMigrations quiesces the engine so nothing writes against a changing
+// schema, applies pending migrations from MigrationsDir and resumes. Orders
+// and cancels submitted meanwhile queue and are processed afterwards.
+func (s *Server) ApplyMigrations(ctx context.Context, req *pb.ApplyMigrationsRequest) (*pb.ApplyMigrationsResponse, error) {
+	log.Warn().Str("dir", s.cfg.MigrationsDir).Msg("Received ApplyMigrations request")
+
+	start := time.Now()
+	resume := s.engine.Quiesce()
+	applied, err := db.RunMigrations(ctx, s.db, s.cfg.MigrationsDir, s.cfg.MigrationsBaseline)
+	resume()
+
+	if err != nil {
+		log.Error().Err(err).Strs("applied", applied).Msg("Migrations failed")
+		return nil, status.Errorf(codes.Internal, "migrations failed after applying %v: %v", applied, err)
+	}
+
+	return &pb.ApplyMigrationsResponse{
+		Applied:  applied,
+		PausedMs: time.Since(start).Milliseconds(),
+	}, nil
+}
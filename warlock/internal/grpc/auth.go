@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// submitterAddresses names, for each RPC that acts for a user, the user
+// addresses a request acts for
+var submitterAddresses = map[string]func(req any) []string{
+	pb.MatcherService_SubmitOrder_FullMethodName: func(req any) []string {
+		return []string{req.(*pb.SubmitOrderRequest).UserAddress}
+	},
+	pb.MatcherService_CancelOrder_FullMethodName: func(req any) []string {
+		return []string{req.(*pb.CancelOrderRequest).UserAddress}
+	},
+	pb.MatcherService_CancelOrdersWhere_FullMethodName: func(req any) []string {
+		return []string{req.(*pb.CancelOrdersWhereRequest).UserAddress}
+	},
+	pb.MatcherService_CancelReplace_FullMethodName: func(req any) []string {
+		r := req.(*pb.CancelReplaceRequest)
+		return []string{r.UserAddress, r.GetNewOrder().GetUserAddress()}
+	},
+	pb.MatcherService_ImportOrders_FullMethodName: func(req any) []string {
+		return importAddresses(req.(*pb.ImportOrdersRequest))
+	},
+}
+
+// publicMethods are the RPCs anyone may call: market data, reads of orders
+// and fills, health, and pair and chain status. Every RPC that is neither public nor in
+// submitterAddresses is an admin RPC.
+var publicMethods = map[string]bool{
+	pb.MatcherService_GetOrderBook_FullMethodName:            true,
+	pb.MatcherService_GetDepthChart_FullMethodName:           true,
+	pb.MatcherService_GetBookChecksum_FullMethodName:         true,
+	pb.MatcherService_EstimateFill_FullMethodName:            true,
+	pb.MatcherService_GetQueuePosition_FullMethodName:        true,
+	pb.MatcherService_EstimateFillProbability_FullMethodName: true,
+	pb.MatcherService_GetBookHistory_FullMethodName:          true,
+	pb.MatcherService_GetOrders_FullMethodName:               true,
+	pb.MatcherService_GetOrderFills_FullMethodName:           true,
+	pb.MatcherService_GetUserPnL_FullMethodName:              true,
+	pb.MatcherService_GetNettedSettlements_FullMethodName:    true,
+	pb.MatcherService_StreamMatches_FullMethodName:           true,
+	pb.MatcherService_StreamTicker_FullMethodName:            true,
+	pb.MatcherService_HealthCheck_FullMethodName:             true,
+	pb.MatcherService_GetServerTime_FullMethodName:           true,
+	pb.MatcherService_GetCapabilities_FullMethodName:         true,
+	pb.MatcherService_GetChainStatus_FullMethodName:          true,
+	pb.MatcherService_GetMarketSession_FullMethodName:        true,
+}
+
+// authenticateAPIKey checks the API key of every call that isn't public.
+// Calls that act for a user need one when api_keys are configured, and a key
+// scoped to addresses may only act for those; admin calls always need a key
+// marked admin. The key comes from the authorization metadata, bare or as
+// "Bearer <key>".
+func (s *Server) authenticateAPIKey(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	addresses, submits := submitterAddresses[info.FullMethod]
+	if !submits {
+		if err := s.requireAdmin(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+	if len(s.cfg.APIKeys) == 0 {
+		return handler(ctx, req)
+	}
+
+	name, err := s.apiKeyName(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, address := range addresses(req) {
+		if !s.cfg.APIKeyAllows(name, address) {
+			log.Warn().
+				Str("method", info.FullMethod).
+				Str("api_key", name).
+				Str("user_address", address).
+				Msg("Rejected call outside the API key's addresses")
+			return nil, status.Errorf(codes.PermissionDenied, "API key %s may not act for %s", name, address)
+		}
+	}
+
+	return handler(ctx, req)
+}
+
+// authenticateAPIKeyStream holds every stream that isn't public to an admin
+// key, as authenticateAPIKey does for unary calls
+func (s *Server) authenticateAPIKeyStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if !publicMethods[info.FullMethod] {
+		if err := s.requireAdmin(stream.Context(), info.FullMethod); err != nil {
+			return err
+		}
+	}
+	return handler(srv, stream)
+}
+
+// requireAdmin fails unless the call carries a key marked admin, so admin
+// RPCs stay closed when no such key is configured
+func (s *Server) requireAdmin(ctx context.Context, method string) error {
+	name, err := s.apiKeyName(ctx, method)
+	if err != nil {
+		return err
+	}
+	if !s.cfg.APIKeyIsAdmin(name) {
+		log.Warn().Str("method", method).Str("api_key", name).Msg("Rejected admin call by a key without admin")
+		return status.Errorf(codes.PermissionDenied, "API key %s may not call %s", name, method)
+	}
+	return nil
+}
+
+// apiKeyName returns the name of the configured key a call carries
+func (s *Server) apiKeyName(ctx context.Context, method string) (string, error) {
+	var key string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			key = strings.TrimSpace(values[0])
+			if scheme, token, found := strings.Cut(key, " "); found && strings.EqualFold(scheme, "Bearer") {
+				key = strings.TrimSpace(token)
+			}
+		}
+	}
+	if key == "" {
+		return "", status.Errorf(codes.Unauthenticated, "an API key is required in the authorization metadata")
+	}
+
+	name, ok := s.cfg.AuthenticateAPIKey(key)
+	if !ok {
+		log.Warn().Str("method", method).Msg("Rejected call with an unknown API key")
+		return "", status.Errorf(codes.Unauthenticated, "invalid API key")
+	}
+	return name, nil
+}
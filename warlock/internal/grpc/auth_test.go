@@ -0,0 +1,252 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newAPIKeyServer returns a server requiring API keys: "desk" may act only
+// for alice, "ops" for anyone, and "root" may also call admin RPCs
+func newAPIKeyServer(t *testing.T) *Server {
+	t.Helper()
+	hash := func(key string) string {
+		sum := sha256.Sum256([]byte(key))
+		return hex.EncodeToString(sum[:])
+	}
+	path := filepath.Join(t.TempDir(), "warlock.yaml")
+	yaml := fmt.Sprintf("api_keys:\n  desk:\n    sha256: %s\n    addresses: [%s]\n  ops:\n    sha256: %s\n  root:\n    sha256: %s\n    admin: true\n",
+		hash("desk-secret"), checksummedAlice, hash("ops-secret"), hash("root-secret"))
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	s, _ := newTestServer(t, testConfig(t))
+	return s
+}
+
+func importRequest(users ...string) *pb.ImportOrdersRequest {
+	data := "user_address,chain_id,order_type,base_token,quote_token,quantity,price\n"
+	for _, user := range users {
+		data += user + ",1,ORDER_TYPE_SELL,WETH,USDC,1,100\n"
+	}
+	return &pb.ImportOrdersRequest{Format: pb.ImportFormat_IMPORT_FORMAT_CSV, Data: []byte(data)}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	s := newAPIKeyServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		req    any
+		code   codes.Code
+	}{
+		{name: "scoped key for its address", method: pb.MatcherService_SubmitOrder_FullMethodName, key: "Bearer desk-secret",
+			req: orderRequest(lowercaseAlice, pb.OrderType_ORDER_TYPE_SELL, "1", "100"), code: codes.OK},
+		{name: "bare key", method: pb.MatcherService_SubmitOrder_FullMethodName, key: "desk-secret",
+			req: orderRequest(checksummedAlice, pb.OrderType_ORDER_TYPE_SELL, "1", "100"), code: codes.OK},
+		{name: "scoped key for another address", method: pb.MatcherService_SubmitOrder_FullMethodName, key: "desk-secret",
+			req: orderRequest(lowercaseBob, pb.OrderType_ORDER_TYPE_SELL, "1", "100"), code: codes.PermissionDenied},
+		{name: "unscoped key", method: pb.MatcherService_SubmitOrder_FullMethodName, key: "ops-secret",
+			req: orderRequest(lowercaseBob, pb.OrderType_ORDER_TYPE_SELL, "1", "100"), code: codes.OK},
+		{name: "invalid key", method: pb.MatcherService_CancelOrder_FullMethodName, key: "Bearer guess",
+			req: &pb.CancelOrderRequest{OrderId: "x", UserAddress: lowercaseAlice}, code: codes.Unauthenticated},
+		{name: "missing key", method: pb.MatcherService_CancelOrder_FullMethodName,
+			req: &pb.CancelOrderRequest{OrderId: "x", UserAddress: lowercaseAlice}, code: codes.Unauthenticated},
+		{name: "replacement for another address", method: pb.MatcherService_CancelReplace_FullMethodName, key: "desk-secret",
+			req: &pb.CancelReplaceRequest{OrderId: "x", UserAddress: lowercaseAlice,
+				NewOrder: orderRequest(lowercaseBob, pb.OrderType_ORDER_TYPE_SELL, "1", "100")}, code: codes.PermissionDenied},
+		{name: "import of its address", method: pb.MatcherService_ImportOrders_FullMethodName, key: "desk-secret",
+			req: importRequest(checksummedAlice, lowercaseAlice), code: codes.OK},
+		{name: "import with one foreign row", method: pb.MatcherService_ImportOrders_FullMethodName, key: "desk-secret",
+			req: importRequest(lowercaseAlice, lowercaseBob), code: codes.PermissionDenied},
+		{name: "import by unscoped key", method: pb.MatcherService_ImportOrders_FullMethodName, key: "ops-secret",
+			req: importRequest(lowercaseAlice, lowercaseBob), code: codes.OK},
+		{name: "import with invalid key", method: pb.MatcherService_ImportOrders_FullMethodName, key: "guess",
+			req: importRequest(lowercaseAlice), code: codes.Unauthenticated},
+		{name: "public method", method: pb.MatcherService_GetOrderBook_FullMethodName,
+			req: &pb.GetOrderBookRequest{}, code: codes.OK},
+		{name: "admin method by admin key", method: pb.MatcherService_RebuildBook_FullMethodName, key: "Bearer root-secret",
+			req: &pb.RebuildBookRequest{}, code: codes.OK},
+		{name: "admin method by trading key", method: pb.MatcherService_RebuildBook_FullMethodName, key: "ops-secret",
+			req: &pb.RebuildBookRequest{}, code: codes.PermissionDenied},
+		{name: "admin method without key", method: pb.MatcherService_ApplyMigrations_FullMethodName,
+			req: &pb.ApplyMigrationsRequest{}, code: codes.Unauthenticated},
+		{name: "admin method with invalid key", method: pb.MatcherService_PromoteStandby_FullMethodName, key: "guess",
+			req: &pb.PromoteStandbyRequest{}, code: codes.Unauthenticated},
+		{name: "admin key submitting", method: pb.MatcherService_SubmitOrder_FullMethodName, key: "root-secret",
+			req: orderRequest(lowercaseBob, pb.OrderType_ORDER_TYPE_SELL, "1", "100"), code: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.key))
+			}
+			called := false
+			handler := func(context.Context, any) (any, error) {
+				called = true
+				return nil, nil
+			}
+
+			_, err := s.authenticateAPIKey(ctx, tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+			if status.Code(err) != tt.code {
+				t.Fatalf("got %v, want %s", err, tt.code)
+			}
+			if called != (tt.code == codes.OK) {
+				t.Errorf("handler called: %t", called)
+			}
+		})
+	}
+}
+
+func TestAdminMethodsDenyByDefault(t *testing.T) {
+	// No api_keys: submissions stay open, admin RPCs close
+	s, _ := newTestServer(t, testConfig(t))
+	handler := func(context.Context, any) (any, error) { return nil, nil }
+
+	submit := &grpc.UnaryServerInfo{FullMethod: pb.MatcherService_SubmitOrder_FullMethodName}
+	if _, err := s.authenticateAPIKey(context.Background(), orderRequest(lowercaseAlice, pb.OrderType_ORDER_TYPE_SELL, "1", "100"), submit, handler); err != nil {
+		t.Errorf("SubmitOrder without api_keys: %v", err)
+	}
+
+	admin := []string{
+		pb.MatcherService_ApplyMigrations_FullMethodName,
+		pb.MatcherService_DrainChannel_FullMethodName,
+		pb.MatcherService_PromoteStandby_FullMethodName,
+		pb.MatcherService_RebuildBook_FullMethodName,
+		pb.MatcherService_DrainWorker_FullMethodName,
+		pb.MatcherService_SetChainStatus_FullMethodName,
+		pb.MatcherService_PausePair_FullMethodName,
+		pb.MatcherService_ResumePair_FullMethodName,
+		pb.MatcherService_GetInMemoryOrder_FullMethodName,
+		pb.MatcherService_GetMatchTrace_FullMethodName,
+		pb.MatcherService_GetCounterpartyMatrix_FullMethodName,
+		pb.MatcherService_GetRejections_FullMethodName,
+	}
+	for _, method := range admin {
+		if _, err := s.authenticateAPIKey(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s without a key: %v, want Unauthenticated", method, err)
+		}
+	}
+
+	// Methods added later are admin until listed as public or a submission
+	for _, method := range pb.MatcherService_ServiceDesc.Methods {
+		full := "/" + pb.MatcherService_ServiceDesc.ServiceName + "/" + method.MethodName
+		_, submits := submitterAddresses[full]
+		if _, err := s.authenticateAPIKey(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: full}, handler); !publicMethods[full] && !submits && status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s without a key: %v, want Unauthenticated", full, err)
+		}
+	}
+}
+
+func TestAuthenticateAPIKeyStream(t *testing.T) {
+	s := newAPIKeyServer(t)
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		code   codes.Code
+	}{
+		{name: "public stream", method: pb.MatcherService_StreamMatches_FullMethodName, code: codes.OK},
+		{name: "admin stream by admin key", method: pb.MatcherService_StreamRejections_FullMethodName, key: "root-secret", code: codes.OK},
+		{name: "admin stream by trading key", method: pb.MatcherService_StreamRejections_FullMethodName, key: "ops-secret", code: codes.PermissionDenied},
+		{name: "admin stream without key", method: pb.MatcherService_StreamStats_FullMethodName, code: codes.Unauthenticated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.key != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tt.key))
+			}
+			called := false
+			handler := func(any, grpc.ServerStream) error {
+				called = true
+				return nil
+			}
+
+			err := s.authenticateAPIKeyStream(nil, &matchStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: tt.method}, handler)
+			if status.Code(err) != tt.code {
+				t.Fatalf("got %v, want %s", err, tt.code)
+			}
+			if called != (tt.code == codes.OK) {
+				t.Errorf("handler called: %t", called)
+			}
+		})
+	}
+}
+
+func TestImportOrdersRejectsForeignRowsBeforeStoring(t *testing.T) {
+	s := newAPIKeyServer(t)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "desk-secret"))
+	info := &grpc.UnaryServerInfo{FullMethod: pb.MatcherService_ImportOrders_FullMethodName}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return s.ImportOrders(ctx, req.(*pb.ImportOrdersRequest))
+	}
+
+	if _, err := s.authenticateAPIKey(ctx, importRequest(lowercaseAlice, lowercaseBob), info, handler); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("import with a foreign row: %v, want PermissionDenied", err)
+	}
+	active, err := s.engine.Store().LoadActiveOrders(context.Background(), s.engine.Now(), "", "")
+	if err != nil || len(active) != 0 {
+		t.Errorf("rejected import stored %d orders (%v)", len(active), err)
+	}
+}
+
+func TestDiagnosticsNeedAdminKey(t *testing.T) {
+	s := newAPIKeyServer(t)
+
+	// A resting, traced order both RPCs know about
+	req := orderRequest(lowercaseAlice, pb.OrderType_ORDER_TYPE_SELL, "1", "100")
+	req.Trace = true
+	resp, err := s.SubmitOrder(context.Background(), req)
+	if err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+
+	calls := []struct {
+		method string
+		req    any
+		call   func(ctx context.Context, req any) (any, error)
+	}{
+		{method: pb.MatcherService_GetInMemoryOrder_FullMethodName, req: &pb.GetInMemoryOrderRequest{OrderId: resp.Order.Id},
+			call: func(ctx context.Context, req any) (any, error) {
+				return s.GetInMemoryOrder(ctx, req.(*pb.GetInMemoryOrderRequest))
+			}},
+		{method: pb.MatcherService_GetMatchTrace_FullMethodName, req: &pb.GetMatchTraceRequest{OrderId: resp.Order.Id},
+			call: func(ctx context.Context, req any) (any, error) {
+				return s.GetMatchTrace(ctx, req.(*pb.GetMatchTraceRequest))
+			}},
+	}
+	for _, c := range calls {
+		info := &grpc.UnaryServerInfo{FullMethod: c.method}
+		for key, want := range map[string]codes.Code{"": codes.Unauthenticated, "desk-secret": codes.PermissionDenied, "ops-secret": codes.PermissionDenied} {
+			ctx := context.Background()
+			if key != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", key))
+			}
+			if got, err := s.authenticateAPIKey(ctx, c.req, info, c.call); status.Code(err) != want || got != nil {
+				t.Errorf("%s with key %q: %v, %v, want %s", c.method, key, got, err, want)
+			}
+		}
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "root-secret"))
+		if got, err := s.authenticateAPIKey(ctx, c.req, info, c.call); err != nil || got == nil {
+			t.Errorf("%s with the admin key: %v, %v", c.method, got, err)
+		}
+	}
+}
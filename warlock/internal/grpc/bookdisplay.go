@@ -0,0 +1,49 @@
+package grpc
+
+import (
+	"github.com/darkpool/warlock/internal/config"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/shopspring/decimal"
+)
+
+// maskLevels applies BookDisplayPolicy to the levels about to be shown. The
+// levels are built fresh for each response, so masking them leaves the book
+// itself untouched.
+func (s *Server) maskLevels(bids, asks []*pb.PriceLevel) ([]*pb.PriceLevel, []*pb.PriceLevel) {
+	switch s.cfg.BookDisplayPolicy {
+	case config.BookDisplayNone:
+		return make([]*pb.PriceLevel, 0), make([]*pb.PriceLevel, 0)
+
+	case config.BookDisplayTop:
+		bids, asks = bids[:min(1, len(bids))], asks[:min(1, len(asks))]
+		hideQuantities(bids)
+		hideQuantities(asks)
+
+	case config.BookDisplayIndicative:
+		hideQuantities(bids)
+		hideQuantities(asks)
+
+	case config.BookDisplayBucketed:
+		bucketQuantities(bids, s.cfg.BookDisplayBucket)
+		bucketQuantities(asks, s.cfg.BookDisplayBucket)
+	}
+	return bids, asks
+}
+
+// hideQuantities leaves only the prices of levels
+func hideQuantities(levels []*pb.PriceLevel) {
+	for _, level := range levels {
+		level.Quantity = ""
+		level.OrderCount = 0
+	}
+}
+
+// bucketQuantities rounds each level's quantity up to whole buckets, so a
+// level never shows as empty, and hides how many orders make it up
+func bucketQuantities(levels []*pb.PriceLevel, bucket decimal.Decimal) {
+	for _, level := range levels {
+		qty, _ := decimal.NewFromString(level.Quantity)
+		level.Quantity = qty.Div(bucket).Ceil().Mul(bucket).String()
+		level.OrderCount = 0
+	}
+}
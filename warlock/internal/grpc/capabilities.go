@@ -0,0 +1,69 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/darkpool/warlock/internal/config"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+)
+
+// supportedTimeInForce is every time in force an order can have: GTC rests
+// until cancelled, GTD sets expires_in_seconds, IOC sets remainder_policy
+// CANCEL
+var supportedTimeInForce = []string{"GTC", "GTD", "IOC"}
+
+// GetCapabilities reports what this deployment accepts, so clients can check
+// for a feature instead of finding out from a rejected order
+func (s *Server) GetCapabilities(ctx context.Context, req *pb.GetCapabilitiesRequest) (*pb.GetCapabilitiesResponse, error) {
+	return &pb.GetCapabilitiesResponse{
+		SupportedOrderTypes:  []pb.OrderType{pb.OrderType_ORDER_TYPE_BUY, pb.OrderType_ORDER_TYPE_SELL},
+		SupportedTimeInForce: supportedTimeInForce,
+		Features:             enabledFeatures(s.cfg),
+		SupportedChainIds:    s.cfg.SupportedChains,
+		Pools:                s.cfg.Pools,
+		MatchingMode:         s.cfg.MatchingMode,
+		ExecutionPriceMode:   s.cfg.ExecutionPriceMode,
+		MaxDecimalPlaces:     int32(s.cfg.MaxDecimalPlaces),
+		MaxSignificantDigits: int32(s.cfg.MaxSignificantDigits),
+		Standby:              s.engine.Standby(),
+	}, nil
+}
+
+// enabledFeatures lists the optional features the configuration turns on, in
+// a fixed order. Features every deployment has aren't listed.
+func enabledFeatures(cfg *config.Config) []string {
+	features := make([]string, 0)
+	add := func(enabled bool, feature string) {
+		if enabled {
+			features = append(features, feature)
+		}
+	}
+
+	add(cfg.MatchingMode == config.MatchingModeBatchAuction, "BATCH_AUCTION")
+	add(usesVWAP(cfg), "VWAP_EXECUTION")
+	add(cfg.SelfTradePrevention, "SELF_TRADE_PREVENTION")
+	add(cfg.CounterpartyDiversity, "COUNTERPARTY_DIVERSITY")
+	add(cfg.DeterministicMatching, "DETERMINISTIC_MATCHING")
+	add(cfg.SpeedBumpMax > 0, "SPEED_BUMP")
+	add(len(cfg.Pools) > 0, "LIQUIDITY_POOLS")
+	add(len(cfg.MarketHours) > 0, "MARKET_HOURS")
+	add(cfg.TakerFeeBps > 0, "FEES")
+	add(cfg.EventBroker != "", "EVENT_STREAMING")
+	add(len(cfg.APIKeys) > 0, "API_KEYS")
+	add(len(cfg.OneSidedRejectPairs) > 0, "ONE_SIDED_REJECTION")
+	return features
+}
+
+// usesVWAP reports whether any pair executes at VWAP, by default or by
+// override
+func usesVWAP(cfg *config.Config) bool {
+	if cfg.ExecutionPriceMode == config.ExecutionPriceVWAP {
+		return true
+	}
+	for _, rules := range cfg.PairMatching {
+		if rules.ExecutionPriceMode == config.ExecutionPriceVWAP {
+			return true
+		}
+	}
+	return false
+}
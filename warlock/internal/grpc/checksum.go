@@ -0,0 +1,60 @@
+package grpc
+
+import (
+	"context"
+	"hash/crc32"
+	"strings"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetBookChecksum hashes the levels GetOrderBook would return for the same
+// book and depth, so a client can check its copy without refetching it. An
+// empty or unknown book hashes to the checksum of no levels at sequence 0.
+func (s *Server) GetBookChecksum(ctx context.Context, req *pb.GetBookChecksumRequest) (*pb.GetBookChecksumResponse, error) {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+	req.PoolId = strings.TrimSpace(req.PoolId)
+
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+
+	depth := req.Depth
+	if depth <= 0 {
+		depth = 20 // Same default as GetOrderBook
+	}
+
+	var bids, asks []*pb.PriceLevel
+	var sequence uint64
+	if orderBook := s.engine.GetOrderBook(req.PoolId, req.BaseToken, req.QuoteToken); orderBook != nil {
+		bids, asks, sequence = s.displayLevels(orderBook, int(depth))
+	}
+
+	return &pb.GetBookChecksumResponse{
+		Checksum: bookChecksum(bids, asks),
+		Sequence: sequence,
+		Depth:    depth,
+	}, nil
+}
+
+// bookChecksum is the CRC32 (IEEE) of the levels serialized one per line,
+// bids best first and then asks best first:
+//
+//	b:<price>:<quantity>\n
+//	a:<price>:<quantity>\n
+//
+// price and quantity are the exact strings GetOrderBook returns, so clients
+// hash what they received without reformatting any numbers.
+func bookChecksum(bids, asks []*pb.PriceLevel) uint32 {
+	var sb strings.Builder
+	for _, level := range bids {
+		sb.WriteString("b:" + level.Price + ":" + level.Quantity + "\n")
+	}
+	for _, level := range asks {
+		sb.WriteString("a:" + level.Price + ":" + level.Quantity + "\n")
+	}
+	return crc32.ChecksumIEEE([]byte(sb.String()))
+}
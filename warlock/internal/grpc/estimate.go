@@ -0,0 +1,87 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/darkpool/warlock/internal/matcher"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// EstimateFill walks the opposite side of the in-memory book, best first, and
+// reports what a taker of the given quantity could expect. Resting orders are
+// priced at their limit price; real fills land inside the overlap of both
+// ranges, so the estimate is approximate. Nothing is locked or reserved.
+func (s *Server) EstimateFill(ctx context.Context, req *pb.EstimateFillRequest) (*pb.EstimateFillResponse, error) {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+	req.PoolId = strings.TrimSpace(req.PoolId)
+
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+	if req.Side == pb.OrderType_ORDER_TYPE_UNSPECIFIED {
+		return nil, status.Errorf(codes.InvalidArgument, "side is required")
+	}
+	quantity, err := decimal.NewFromString(req.Quantity)
+	if err != nil || !quantity.IsPositive() {
+		return nil, status.Errorf(codes.InvalidArgument, "quantity must be a positive number")
+	}
+
+	var resting []*matcher.Order
+	if orderBook := s.engine.GetOrderBook(req.PoolId, req.BaseToken, req.QuoteToken); orderBook != nil {
+		bids, asks, _ := orderBook.Snapshot()
+		if req.Side == pb.OrderType_ORDER_TYPE_BUY {
+			resting = asks
+		} else {
+			resting = bids
+		}
+	}
+
+	return estimateFill(orderTypeFromProto(req.Side), quantity, resting), nil
+}
+
+// estimateFill takes quantity from resting orders, which must be in priority order
+func estimateFill(side matcher.OrderType, quantity decimal.Decimal, resting []*matcher.Order) *pb.EstimateFillResponse {
+	filled := decimal.Zero
+	notional := decimal.Zero
+	touched := int32(0)
+	for _, order := range resting {
+		if filled.Equal(quantity) {
+			break
+		}
+		take := decimal.Min(quantity.Sub(filled), order.RemainingQuantity)
+		filled = filled.Add(take)
+		notional = notional.Add(take.Mul(order.Price))
+		touched++
+	}
+
+	resp := &pb.EstimateFillResponse{
+		FilledQuantity:   filled.String(),
+		UnfilledQuantity: quantity.Sub(filled).String(),
+		OrdersTouched:    touched,
+	}
+	if len(resting) == 0 {
+		return resp
+	}
+
+	best := resting[0].Price
+	resp.BestPrice = best.String()
+	if filled.IsZero() {
+		return resp
+	}
+
+	avg := notional.Div(filled)
+	resp.AvgPrice = avg.String()
+
+	// Positive slippage is always worse for the taker: paying up on a buy, down on a sell
+	slippage := avg.Sub(best)
+	if side == matcher.OrderTypeSell {
+		slippage = slippage.Neg()
+	}
+	resp.SlippageBps = slippage.Div(best).Mul(decimal.NewFromInt(10000)).StringFixed(2)
+	return resp
+}
@@ -0,0 +1,72 @@
+package grpc
+
+import (
+	"sync"
+
+	"github.com/darkpool/warlock/internal/matcher"
+	"github.com/rs/zerolog/log"
+)
+
+// matchHub copies every match from the engine to each StreamMatches
+// subscriber. A subscriber that falls MaxStreamBacklog matches behind is cut
+// off rather than allowed to stall the engine or silently miss matches; its
+// stream ends with ABORTED and the client resumes from its last token.
+type matchHub struct {
+	mu      sync.Mutex
+	subs    map[*matchSubscriber]struct{}
+	backlog int
+	lastSeq int64 // Sequence of the last match fanned out
+}
+
+// matchSubscriber is one stream's view of the hub
+type matchSubscriber struct {
+	matches chan *matcher.Match
+	dropped chan struct{} // Closed once the subscriber has been cut off
+}
+
+func newMatchHub(backlog int) *matchHub {
+	return &matchHub{
+		subs:    make(map[*matchSubscriber]struct{}),
+		backlog: backlog,
+	}
+}
+
+// run fans matches out until the engine closes its match channel
+func (h *matchHub) run(matches <-chan *matcher.Match) {
+	for match := range matches {
+		h.mu.Lock()
+		h.lastSeq = match.Seq
+		for sub := range h.subs {
+			select {
+			case sub.matches <- match:
+			default:
+				delete(h.subs, sub)
+				close(sub.dropped)
+				log.Warn().Int("backlog", h.backlog).Msg("StreamMatches subscriber fell behind and was disconnected")
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+// subscribe registers a subscriber that receives every match from now on.
+// The engine publishes in sequence order, so those are exactly the matches
+// after the returned sequence; floor is the engine's MatchSeqFloor, the
+// answer until the first match goes out.
+func (h *matchHub) subscribe(floor int64) (*matchSubscriber, int64) {
+	sub := &matchSubscriber{
+		matches: make(chan *matcher.Match, h.backlog),
+		dropped: make(chan struct{}),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[sub] = struct{}{}
+	return sub, max(h.lastSeq, floor)
+}
+
+// unsubscribe removes a subscriber; it is a no-op once it was cut off
+func (h *matchHub) unsubscribe(sub *matchSubscriber) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+}
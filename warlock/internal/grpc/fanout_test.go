@@ -0,0 +1,104 @@
+package grpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stuckStream is a StreamMatches stream whose client stops reading: every
+// Send blocks until released
+type stuckStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	release chan struct{}
+}
+
+func (s *stuckStream) Context() context.Context { return s.ctx }
+
+func (s *stuckStream) Send(event *pb.MatchEvent) error {
+	<-s.release
+	return nil
+}
+
+func TestStuckSubscriberDoesNotStallOthers(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.TradePrintDelay = 0
+	cfg.MaxStreamBacklog = 2
+	s, _ := newTestServer(t, cfg)
+	go s.matches.run(s.engine.MatchChan())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stuck := &stuckStream{ctx: ctx, release: make(chan struct{})}
+	stuckDone := make(chan error, 1)
+	go func() {
+		stuckDone <- s.StreamMatches(&pb.StreamMatchesRequest{}, stuck)
+	}()
+	unstick := sync.OnceFunc(func() { close(stuck.release) })
+	defer unstick()
+	live := &matchStream{ctx: ctx, events: make(chan *pb.MatchEvent, 10)}
+	liveDone := make(chan error, 1)
+	go func() {
+		liveDone <- s.StreamMatches(&pb.StreamMatchesRequest{}, live)
+	}()
+	waitSubscribers(t, s.matches, 2)
+
+	// Far more matches than the stuck stream's backlog holds; the live stream
+	// keeps up and gets every one, in order, while the other is stuck
+	if _, err := s.SubmitOrder(ctx, orderRequest("0xalice", pb.OrderType_ORDER_TYPE_SELL, "6", "100")); err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+	var last int64
+	for i := 0; i < 6; i++ {
+		if _, err := s.SubmitOrder(ctx, orderRequest("0xbob", pb.OrderType_ORDER_TYPE_BUY, "1", "100")); err != nil {
+			t.Fatalf("SubmitOrder: %v", err)
+		}
+		seq := streamSeq(t, live.nextEvent(t))
+		if seq <= last {
+			t.Fatalf("match %d arrived after %d", seq, last)
+		}
+		last = seq
+	}
+	waitSubscribers(t, s.matches, 1)
+
+	// Once unstuck, the dropped stream ends so its client can resume
+	unstick()
+	select {
+	case err := <-stuckDone:
+		if status.Code(err) != codes.Aborted {
+			t.Errorf("stuck stream ended with %v, want ABORTED", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stuck stream never ended")
+	}
+	select {
+	case err := <-liveDone:
+		t.Fatalf("live stream ended: %v", err)
+	default:
+	}
+}
+
+// waitSubscribers waits for the hub to hold n subscribers
+func waitSubscribers(t *testing.T, hub *matchHub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		hub.mu.Lock()
+		got := len(hub.subs)
+		hub.mu.Unlock()
+		if got == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("hub has %d subscribers, want %d", got, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,119 @@
+package grpc
+
+import (
+	"context"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/darkpool/warlock/internal/matcher"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultFillLookback is the trade history used when the request gives none
+	defaultFillLookback = time.Hour
+
+	// maxFillLookback bounds how much trade history one estimate scans
+	maxFillLookback = 7 * 24 * time.Hour
+
+	// fillDistanceScaleBps is the gap to the opposite best at which the
+	// distance discount reaches 1/e
+	fillDistanceScaleBps = 100.0
+)
+
+// EstimateFillProbability gives a rough chance that a resting order fills
+// within horizon_seconds. The pair's recent traded volume is assumed to keep
+// arriving at the same rate and to fill the book in price-time order, so the
+// order fills once that volume clears everything ahead of it plus its own
+// remainder. Orders resting away from the opposite best are discounted
+// further, since the market has to move to them first.
+func (s *Server) EstimateFillProbability(ctx context.Context, req *pb.EstimateFillProbabilityRequest) (*pb.EstimateFillProbabilityResponse, error) {
+	req.OrderId = strings.TrimSpace(req.OrderId)
+	req.UserAddress = normalizeAddress(req.UserAddress)
+
+	if req.OrderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order_id is required")
+	}
+	if req.UserAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user_address is required")
+	}
+	if req.HorizonSeconds <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "horizon_seconds must be positive")
+	}
+	lookback := defaultFillLookback
+	if req.LookbackSeconds < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "lookback_seconds can't be negative")
+	}
+	if req.LookbackSeconds > 0 {
+		lookback = time.Duration(req.LookbackSeconds) * time.Second
+	}
+	if lookback > maxFillLookback {
+		return nil, status.Errorf(codes.InvalidArgument, "lookback_seconds can't exceed %d", int64(maxFillLookback.Seconds()))
+	}
+
+	pos, ok := s.engine.QueuePosition(req.OrderId)
+	if !ok || !strings.EqualFold(pos.Order.UserAddress, req.UserAddress) {
+		return nil, status.Errorf(codes.NotFound, "order %s is not resting in the book", req.OrderId)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	var tradedStr string
+	err := s.db.QueryRow(queryCtx, `
+		SELECT COALESCE(SUM(quantity), 0)::text
+		FROM matches
+		WHERE base_token = $1
+		  AND quote_token = $2
+		  AND settlement_status <> 'FAILED'
+		  AND matched_at >= $3
+	`, pos.Order.BaseToken, pos.Order.QuoteToken, time.Now().Add(-lookback)).Scan(&tradedStr)
+	if err != nil {
+		log.Error().Err(err).Str("order_id", req.OrderId).Msg("Failed to query recent volume for fill estimate")
+		return nil, queryStatus(err, "failed to query recent volume")
+	}
+	traded, _ := decimal.NewFromString(tradedStr)
+
+	toClear := pos.BetterQuantity.Add(pos.AheadQuantity).Add(pos.Order.RemainingQuantity)
+	rate := traded.Div(decimal.NewFromFloat(lookback.Seconds()))
+	expected := rate.Mul(decimal.NewFromInt(req.HorizonSeconds))
+
+	var distanceBps float64
+	if pos.OppositeBest != nil {
+		// Positive when the order rests behind the opposite best
+		gap := pos.OppositeBest.Sub(pos.Order.Price)
+		if pos.Order.OrderType == matcher.OrderTypeSell {
+			gap = gap.Neg()
+		}
+		if gap.IsPositive() && pos.OppositeBest.IsPositive() {
+			distanceBps = gap.Div(*pos.OppositeBest).Mul(decimal.NewFromInt(10000)).InexactFloat64()
+		}
+	}
+
+	return &pb.EstimateFillProbabilityResponse{
+		Probability:     fillProbability(expected, toClear, distanceBps),
+		QuantityToClear: toClear.String(),
+		VolumeRate:      rate.String(),
+		ExpectedVolume:  expected.String(),
+		DistanceBps:     distanceBps,
+	}, nil
+}
+
+// fillProbability treats the volume that must trade before the order fills
+// as an exponential race against the expected volume, then discounts for the
+// distance the market has to travel to reach the order
+func fillProbability(expected, toClear decimal.Decimal, distanceBps float64) float64 {
+	if !toClear.IsPositive() {
+		return 1
+	}
+	if !expected.IsPositive() {
+		return 0
+	}
+	ratio := expected.Div(toClear).InexactFloat64()
+	return (1 - math.Exp(-ratio)) * math.Exp(-distanceBps/fillDistanceScaleBps)
+}
@@ -0,0 +1,98 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Defaults and caps for GetBookHistory
+const (
+	defaultBookHistoryWindow = 24 * time.Hour
+	defaultBookHistoryLimit  = 1000
+	maxBookHistoryLimit      = 10000
+)
+
+// GetBookHistory returns the best bid/ask samples recorded for a pair, oldest
+// first. With interval_seconds set, only the last sample in each interval is kept.
+func (s *Server) GetBookHistory(ctx context.Context, req *pb.GetBookHistoryRequest) (*pb.GetBookHistoryResponse, error) {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+	req.PoolId = strings.TrimSpace(req.PoolId)
+
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+	if req.IntervalSeconds < 0 || req.Limit < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "interval_seconds and limit must not be negative")
+	}
+
+	since := time.Now().Add(-defaultBookHistoryWindow)
+	if req.Since != nil {
+		since = req.Since.AsTime()
+	}
+	limit := int32(defaultBookHistoryLimit)
+	if req.Limit > 0 {
+		limit = min(req.Limit, maxBookHistoryLimit)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.Query(queryCtx, `
+		SELECT best_bid::text, best_ask::text, mid_price::text, sampled_at
+		FROM (
+			SELECT DISTINCT ON (bucket) best_bid, best_ask, mid_price, sampled_at
+			FROM (
+				-- Every sample is its own bucket unless an interval is requested
+				SELECT *, CASE WHEN $5::int > 0
+				               THEN FLOOR(EXTRACT(EPOCH FROM sampled_at) / $5::int)
+				               ELSE id::numeric END AS bucket
+				FROM book_snapshots
+				WHERE base_token = $1 AND quote_token = $2 AND pool_id = $3 AND sampled_at >= $4
+			) samples
+			ORDER BY bucket, sampled_at DESC
+		) buckets
+		ORDER BY sampled_at ASC
+		LIMIT $6
+	`, req.BaseToken, req.QuoteToken, req.PoolId, since, req.IntervalSeconds, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query book history")
+		return nil, queryStatus(err, "failed to query book history")
+	}
+	defer rows.Close()
+
+	snapshots := make([]*pb.BookSnapshot, 0)
+	for rows.Next() {
+		var bestBid, bestAsk, mid *string
+		var sampledAt time.Time
+
+		if err := rows.Scan(&bestBid, &bestAsk, &mid, &sampledAt); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to scan book snapshot: %v", err)
+		}
+
+		snapshot := &pb.BookSnapshot{SampledAt: timestamppb.New(sampledAt)}
+		if bestBid != nil {
+			snapshot.BestBid = *bestBid
+		}
+		if bestAsk != nil {
+			snapshot.BestAsk = *bestAsk
+		}
+		if mid != nil {
+			snapshot.MidPrice = *mid
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryStatus(err, "failed to read book history")
+	}
+
+	return &pb.GetBookHistoryResponse{Snapshots: snapshots}, nil
+}
@@ -0,0 +1,240 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/darkpool/warlock/internal/matcher"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// maxImportRows caps the orders a single ImportOrders call may carry
+	maxImportRows = 10000
+
+	// importBatchSize is how many validated orders are inserted per transaction
+	importBatchSize = 100
+)
+
+// importRow is one order being imported
+type importRow struct {
+	result *pb.ImportOrderResult
+	req    *pb.SubmitOrderRequest
+	order  *matcher.Order
+}
+
+// ImportOrders loads orders from a CSV or JSON document. Every row goes
+// through the same validation as SubmitOrder; valid rows are inserted in
+// batches and queued for matching, and each row's outcome is reported.
+// Imported orders carry no on-chain commitment unless the row supplies one.
+func (s *Server) ImportOrders(ctx context.Context, req *pb.ImportOrdersRequest) (*pb.ImportOrdersResponse, error) {
+	if req.Format != pb.ImportFormat_IMPORT_FORMAT_JSON && req.Format != pb.ImportFormat_IMPORT_FORMAT_CSV {
+		return nil, status.Errorf(codes.InvalidArgument, "format is required")
+	}
+	rows, err := parseImport(req)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to parse import: %v", err)
+	}
+	if len(rows) > maxImportRows {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d orders per import", maxImportRows)
+	}
+
+	log.Warn().Int("rows", len(rows)).Str("format", req.Format.String()).Msg("Received ImportOrders request")
+
+	valid := make([]*importRow, 0, len(rows))
+	for _, row := range rows {
+		if row.result.Error != "" {
+			continue
+		}
+		normalizeSubmitOrderRequest(row.req)
+		order, err := newOrderFromRequest(row.req, s.cfg, s.engine.Now())
+		if err == nil {
+			err = s.checkPairOpen(order)
+		}
+		if err != nil {
+			row.result.Error = status.Convert(err).Message()
+			continue
+		}
+		s.engine.HoldBehindSpeedBump(order)
+		row.order = order
+		valid = append(valid, row)
+	}
+
+	for start := 0; start < len(valid); start += importBatchSize {
+		s.insertImportBatch(ctx, valid[start:min(start+importBatchSize, len(valid))])
+	}
+
+	resp := &pb.ImportOrdersResponse{Results: make([]*pb.ImportOrderResult, 0, len(rows))}
+	for _, row := range rows {
+		if row.result.Error == "" && row.order != nil {
+			if err := s.engine.SubmitOrder(row.order); err != nil {
+				row.result.Error = fmt.Sprintf("stored but not queued for matching (%v); RebuildBook the pair to load it", err)
+			} else {
+				row.result.Success = true
+				row.result.OrderId = row.order.ID
+			}
+		}
+		if row.result.Success {
+			resp.Imported++
+		} else {
+			resp.Failed++
+		}
+		resp.Results = append(resp.Results, row.result)
+	}
+
+	log.Info().Int32("imported", resp.Imported).Int32("failed", resp.Failed).Msg("Import finished")
+
+	return resp, nil
+}
+
+// insertImportBatch stores a batch in one transaction. If the transaction
+// fails, each order is retried on its own so only the bad rows fail.
+func (s *Server) insertImportBatch(ctx context.Context, batch []*importRow) {
+	orders := make([]matcher.NewOrder, len(batch))
+	for i, row := range batch {
+		orders[i] = newStoredOrder(row.order, row.req)
+	}
+	err := s.engine.Store().CreateOrders(ctx, orders)
+	if err == nil {
+		return
+	}
+
+	log.Warn().Err(err).Int("orders", len(batch)).Msg("Import batch failed, inserting orders one by one")
+	for _, row := range batch {
+		if err := s.engine.Store().CreateOrders(ctx, []matcher.NewOrder{newStoredOrder(row.order, row.req)}); err != nil {
+			row.result.Error = fmt.Sprintf("failed to store order: %v", err)
+			row.order = nil
+		}
+	}
+}
+
+// parseImport reads an import document in its declared format
+func parseImport(req *pb.ImportOrdersRequest) ([]*importRow, error) {
+	switch req.Format {
+	case pb.ImportFormat_IMPORT_FORMAT_JSON:
+		return parseJSONImport(req.Data)
+	case pb.ImportFormat_IMPORT_FORMAT_CSV:
+		return parseCSVImport(req.Data)
+	default:
+		return nil, fmt.Errorf("unsupported format %s", req.Format)
+	}
+}
+
+// importAddresses returns the user address of every row an import would
+// submit. Rows that fail to parse are never submitted, so they act for no one;
+// neither does a document that fails to parse.
+func importAddresses(req *pb.ImportOrdersRequest) []string {
+	rows, err := parseImport(req)
+	if err != nil {
+		return nil
+	}
+	addresses := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.result.Error == "" {
+			addresses = append(addresses, normalizeAddress(row.req.UserAddress))
+		}
+	}
+	return addresses
+}
+
+// parseJSONImport reads an array of SubmitOrderRequest objects
+func parseJSONImport(data []byte) ([]*importRow, error) {
+	var elements []json.RawMessage
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of orders: %w", err)
+	}
+
+	rows := make([]*importRow, 0, len(elements))
+	for i, element := range elements {
+		row := newImportRow(i + 1)
+		if err := protojson.Unmarshal(element, row.req); err != nil {
+			row.result.Error = fmt.Sprintf("invalid order: %v", err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseCSVImport reads a header row of SubmitOrderRequest field names and one
+// order per following row. Empty cells leave a field unset, repeated fields
+// take ';'-separated values and map fields are not supported.
+func parseCSVImport(data []byte) ([]*importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	fields := (&pb.SubmitOrderRequest{}).ProtoReflect().Descriptor().Fields()
+	columns := make([]protoreflect.FieldDescriptor, len(header))
+	for i, name := range header {
+		field := fields.ByName(protoreflect.Name(strings.TrimSpace(name)))
+		if field == nil {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		if field.IsMap() {
+			return nil, fmt.Errorf("column %q is a map and can't be imported from CSV", name)
+		}
+		columns[i] = field
+	}
+
+	rows := make([]*importRow, 0)
+	for n := 1; ; n++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row := newImportRow(n)
+		rows = append(rows, row)
+		if err != nil {
+			row.result.Error = fmt.Sprintf("invalid row: %v", err)
+			continue
+		}
+
+		object := make(map[string]any, len(record))
+		for i, cell := range record {
+			cell = strings.TrimSpace(cell)
+			if cell == "" {
+				continue
+			}
+			field := columns[i]
+			switch {
+			case field.IsList():
+				object[string(field.Name())] = strings.Split(cell, ";")
+			case field.Kind() == protoreflect.BoolKind:
+				object[string(field.Name())] = strings.EqualFold(cell, "true")
+			default:
+				// protojson accepts numbers and enum names as strings
+				object[string(field.Name())] = cell
+			}
+		}
+
+		encoded, err := json.Marshal(object)
+		if err == nil {
+			err = protojson.Unmarshal(encoded, row.req)
+		}
+		if err != nil {
+			row.result.Error = fmt.Sprintf("invalid order: %v", err)
+		}
+	}
+	return rows, nil
+}
+
+func newImportRow(n int) *importRow {
+	return &importRow{
+		result: &pb.ImportOrderResult{Row: int32(n)},
+		req:    &pb.SubmitOrderRequest{},
+	}
+}
@@ -0,0 +1,128 @@
+package grpc
+
+import (
+	"context"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetNettedSettlements sums the PENDING matches between two counterparties
+// on one pair into at most one transfer per token. Nothing is stored: the
+// settlement service settles the returned match ids as a group, and if any of
+// them fails the rest stay PENDING and the next call nets them afresh.
+func (s *Server) GetNettedSettlements(ctx context.Context, req *pb.GetNettedSettlementsRequest) (*pb.GetNettedSettlementsResponse, error) {
+	req.CounterpartyA = normalizeAddress(req.CounterpartyA)
+	req.CounterpartyB = normalizeAddress(req.CounterpartyB)
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+
+	if req.CounterpartyA == "" || req.CounterpartyB == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "counterparty_a and counterparty_b are required")
+	}
+	if req.CounterpartyA == req.CounterpartyB {
+		return nil, status.Errorf(codes.InvalidArgument, "counterparty_a and counterparty_b must differ")
+	}
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.Query(queryCtx, `
+		SELECT m.id, m.quantity, m.price, LOWER(b.user_address) = LOWER($1)
+		FROM matches m
+		JOIN orders b ON b.id = m.buy_order_id
+		JOIN orders sl ON sl.id = m.sell_order_id
+		WHERE m.base_token = $3
+		  AND m.quote_token = $4
+		  AND m.settlement_status = 'PENDING'
+		  AND ((LOWER(b.user_address) = LOWER($1) AND LOWER(sl.user_address) = LOWER($2))
+		    OR (LOWER(b.user_address) = LOWER($2) AND LOWER(sl.user_address) = LOWER($1)))
+		ORDER BY m.seq ASC
+	`, req.CounterpartyA, req.CounterpartyB, req.BaseToken, req.QuoteToken)
+	if err != nil {
+		log.Error().Err(err).
+			Str("counterparty_a", req.CounterpartyA).
+			Str("counterparty_b", req.CounterpartyB).
+			Msg("Failed to query pending matches for netting")
+		return nil, queryStatus(err, "failed to query pending matches")
+	}
+	defer rows.Close()
+
+	var net settlementNet
+	for rows.Next() {
+		var matchID, quantityStr, priceStr string
+		var aIsBuyer bool
+		if err := rows.Scan(&matchID, &quantityStr, &priceStr, &aIsBuyer); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to scan match: %v", err)
+		}
+
+		quantity, _ := decimal.NewFromString(quantityStr)
+		price, _ := decimal.NewFromString(priceStr)
+		net.add(matchID, quantity, price, aIsBuyer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryStatus(err, "failed to read pending matches")
+	}
+
+	return net.toProto(req), nil
+}
+
+// settlementNet accumulates matches between two counterparties from A's side
+type settlementNet struct {
+	matchIDs   []string
+	baseToA    decimal.Decimal // Base A receives from B; negative when A delivers
+	quoteToA   decimal.Decimal // Quote A receives from B; negative when A pays
+	grossBase  decimal.Decimal
+	grossQuote decimal.Decimal
+}
+
+// add folds one fill in. The buyer receives base and pays quote.
+func (n *settlementNet) add(matchID string, quantity, price decimal.Decimal, aIsBuyer bool) {
+	notional := quantity.Mul(price)
+
+	n.matchIDs = append(n.matchIDs, matchID)
+	n.grossBase = n.grossBase.Add(quantity)
+	n.grossQuote = n.grossQuote.Add(notional)
+	if aIsBuyer {
+		n.baseToA = n.baseToA.Add(quantity)
+		n.quoteToA = n.quoteToA.Sub(notional)
+	} else {
+		n.baseToA = n.baseToA.Sub(quantity)
+		n.quoteToA = n.quoteToA.Add(notional)
+	}
+}
+
+func (n *settlementNet) toProto(req *pb.GetNettedSettlementsRequest) *pb.GetNettedSettlementsResponse {
+	resp := &pb.GetNettedSettlementsResponse{
+		MatchIds:   n.matchIDs,
+		GrossBase:  n.grossBase.String(),
+		GrossQuote: n.grossQuote.String(),
+	}
+	if t := netTransfer(req.BaseToken, n.baseToA, req.CounterpartyA, req.CounterpartyB); t != nil {
+		resp.Transfers = append(resp.Transfers, t)
+	}
+	if t := netTransfer(req.QuoteToken, n.quoteToA, req.CounterpartyA, req.CounterpartyB); t != nil {
+		resp.Transfers = append(resp.Transfers, t)
+	}
+	return resp
+}
+
+// netTransfer turns an amount A receives into the transfer that settles it,
+// or nil when it nets to zero
+func netTransfer(token string, toA decimal.Decimal, a, b string) *pb.NetTransfer {
+	switch {
+	case toA.IsPositive():
+		return &pb.NetTransfer{Token: token, FromAddress: b, ToAddress: a, Amount: toA.String()}
+	case toA.IsNegative():
+		return &pb.NetTransfer{Token: token, FromAddress: a, ToAddress: b, Amount: toA.Neg().String()}
+	default:
+		return nil
+	}
+}
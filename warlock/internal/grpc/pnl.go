@@ -0,0 +1,183 @@
+package grpc
+
+import (
+	"context"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetUserPnL replays a user's fills on one pair in match order and reports
+// realized PnL. Failed settlements are not fills and are left out.
+func (s *Server) GetUserPnL(ctx context.Context, req *pb.GetUserPnLRequest) (*pb.GetUserPnLResponse, error) {
+	req.UserAddress = normalizeAddress(req.UserAddress)
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+
+	if req.UserAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user_address is required")
+	}
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+
+	var since interface{}
+	if req.Since != nil {
+		since = req.Since.AsTime()
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.Query(queryCtx, `
+		SELECT m.quantity, m.price,
+		       LOWER(b.user_address) = LOWER($1), LOWER(sl.user_address) = LOWER($1)
+		FROM matches m
+		JOIN orders b ON b.id = m.buy_order_id
+		JOIN orders sl ON sl.id = m.sell_order_id
+		WHERE m.base_token = $2
+		  AND m.quote_token = $3
+		  AND m.settlement_status <> 'FAILED'
+		  AND ($4::timestamptz IS NULL OR m.matched_at >= $4)
+		  AND (LOWER(b.user_address) = LOWER($1) OR LOWER(sl.user_address) = LOWER($1))
+		ORDER BY m.matched_at ASC, m.id ASC
+	`, req.UserAddress, req.BaseToken, req.QuoteToken, since)
+	if err != nil {
+		log.Error().Err(err).Str("user_address", req.UserAddress).Msg("Failed to query user fills")
+		return nil, queryStatus(err, "failed to query user fills")
+	}
+	defer rows.Close()
+
+	book := &pnlBook{average: req.Method == pb.PnlMethod_PNL_METHOD_AVERAGE}
+	for rows.Next() {
+		var quantityStr, priceStr string
+		var isBuyer, isSeller bool
+		if err := rows.Scan(&quantityStr, &priceStr, &isBuyer, &isSeller); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to scan fill: %v", err)
+		}
+
+		quantity, _ := decimal.NewFromString(quantityStr)
+		price, _ := decimal.NewFromString(priceStr)
+
+		// A self-match is both a buy and a sell
+		if isBuyer {
+			book.fill(quantity, price)
+		}
+		if isSeller {
+			book.fill(quantity.Neg(), price)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryStatus(err, "failed to read user fills")
+	}
+
+	return book.toProto(), nil
+}
+
+// pnlBook accumulates realized PnL over fills in time order. Quantities are
+// signed: positive buys and long positions, negative sells and shorts.
+type pnlBook struct {
+	average bool // Average cost instead of FIFO lots
+
+	position decimal.Decimal
+	lots     []pnlLot        // FIFO: open lots, oldest first, all on the position's side
+	avgCost  decimal.Decimal // Average: cost of the open position
+
+	realized      decimal.Decimal
+	entryQty      decimal.Decimal
+	entryNotional decimal.Decimal
+	exitQty       decimal.Decimal
+	exitNotional  decimal.Decimal
+	bought        decimal.Decimal
+	sold          decimal.Decimal
+	fills         int
+}
+
+type pnlLot struct {
+	quantity decimal.Decimal
+	price    decimal.Decimal
+}
+
+// fill applies one fill: the part against the open position realizes PnL, any
+// excess opens or extends a position on the fill's side
+func (b *pnlBook) fill(quantity, price decimal.Decimal) {
+	b.fills++
+	if quantity.IsPositive() {
+		b.bought = b.bought.Add(quantity)
+	} else {
+		b.sold = b.sold.Sub(quantity)
+	}
+
+	closing := decimal.Zero
+	if !b.position.IsZero() && b.position.Sign() != quantity.Sign() {
+		closing = decimal.Min(quantity.Abs(), b.position.Abs())
+	}
+	opening := quantity.Abs().Sub(closing)
+	side := decimal.NewFromInt(int64(quantity.Sign()))
+
+	if closing.IsPositive() {
+		b.exitQty = b.exitQty.Add(closing)
+		b.exitNotional = b.exitNotional.Add(closing.Mul(price))
+		b.realize(closing, price)
+		b.position = b.position.Add(closing.Mul(side))
+	}
+
+	if opening.IsPositive() {
+		b.entryQty = b.entryQty.Add(opening)
+		b.entryNotional = b.entryNotional.Add(opening.Mul(price))
+		if b.average {
+			held := b.position.Abs()
+			b.avgCost = held.Mul(b.avgCost).Add(opening.Mul(price)).Div(held.Add(opening))
+		} else {
+			b.lots = append(b.lots, pnlLot{quantity: opening.Mul(side), price: price})
+		}
+		b.position = b.position.Add(opening.Mul(side))
+	}
+}
+
+// realize books the PnL of closing quantity of the open position at price
+func (b *pnlBook) realize(closing, price decimal.Decimal) {
+	if b.average {
+		direction := decimal.NewFromInt(int64(b.position.Sign()))
+		b.realized = b.realized.Add(closing.Mul(price.Sub(b.avgCost)).Mul(direction))
+		return
+	}
+
+	for closing.IsPositive() && len(b.lots) > 0 {
+		lot := &b.lots[0]
+		direction := decimal.NewFromInt(int64(lot.quantity.Sign()))
+		take := decimal.Min(closing, lot.quantity.Abs())
+
+		b.realized = b.realized.Add(take.Mul(price.Sub(lot.price)).Mul(direction))
+		lot.quantity = lot.quantity.Sub(take.Mul(direction))
+		closing = closing.Sub(take)
+
+		if lot.quantity.IsZero() {
+			b.lots = b.lots[1:]
+		}
+	}
+}
+
+func (b *pnlBook) toProto() *pb.GetUserPnLResponse {
+	return &pb.GetUserPnLResponse{
+		RealizedPnl:    b.realized.String(),
+		Position:       b.position.String(),
+		AvgEntryPrice:  averagePrice(b.entryNotional, b.entryQty).String(),
+		AvgExitPrice:   averagePrice(b.exitNotional, b.exitQty).String(),
+		BoughtQuantity: b.bought.String(),
+		SoldQuantity:   b.sold.String(),
+		FillCount:      int32(b.fills),
+	}
+}
+
+// averagePrice returns notional / quantity, or zero with no quantity
+func averagePrice(notional, quantity decimal.Decimal) decimal.Decimal {
+	if quantity.IsZero() {
+		return decimal.Zero
+	}
+	return notional.Div(quantity)
+}
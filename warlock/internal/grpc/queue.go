@@ -0,0 +1,39 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GetQueuePosition reports where a resting order stands in its price level,
+// read from the in-memory book. Only the owner may ask, and orders owned by
+// someone else look the same as orders that aren't resting.
+func (s *Server) GetQueuePosition(ctx context.Context, req *pb.GetQueuePositionRequest) (*pb.GetQueuePositionResponse, error) {
+	req.OrderId = strings.TrimSpace(req.OrderId)
+	req.UserAddress = normalizeAddress(req.UserAddress)
+
+	if req.OrderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order_id is required")
+	}
+	if req.UserAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user_address is required")
+	}
+
+	pos, ok := s.engine.QueuePosition(req.OrderId)
+	if !ok || !strings.EqualFold(pos.Order.UserAddress, req.UserAddress) {
+		return nil, status.Errorf(codes.NotFound, "order %s is not resting in the book", req.OrderId)
+	}
+
+	return &pb.GetQueuePositionResponse{
+		Level:          pos.Order.Price.String(),
+		AheadQuantity:  pos.AheadQuantity.String(),
+		AheadOrders:    int32(pos.AheadOrders),
+		LevelQuantity:  pos.LevelQuantity.String(),
+		LevelOrders:    int32(pos.LevelOrders),
+		BetterQuantity: pos.BetterQuantity.String(),
+	}, nil
+}
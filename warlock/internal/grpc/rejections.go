@@ -0,0 +1,254 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/darkpool/warlock/internal/matcher"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Defaults and caps for GetRejections
+const (
+	defaultRejectionsWindow = 24 * time.Hour
+	defaultRejectionsLimit  = 1000
+	maxRejectionsLimit      = 10000
+)
+
+// maxRejectedFieldLength caps each identifier stored for a rejection, since
+// it comes straight from a submission that may be malformed
+const maxRejectedFieldLength = 256
+
+// recordRejection stores why a submission was turned away before it became
+// an order. The reason is the status code the submitter got, e.g.
+// INVALID_ARGUMENT, and the message its text.
+func (s *Server) recordRejection(ctx context.Context, req *pb.SubmitOrderRequest, err error) {
+	st := status.Convert(err)
+
+	var orderType matcher.OrderType
+	if req.OrderType != pb.OrderType_ORDER_TYPE_UNSPECIFIED {
+		orderType = orderTypeFromProto(req.OrderType)
+	}
+
+	s.engine.RecordRejection(ctx, &matcher.Rejection{
+		UserAddress: truncate(req.UserAddress, maxRejectedFieldLength),
+		BaseToken:   truncate(req.BaseToken, maxRejectedFieldLength),
+		QuoteToken:  truncate(req.QuoteToken, maxRejectedFieldLength),
+		OrderType:   orderType,
+		Stage:       matcher.RejectionStageValidation,
+		Reason:      statusCodeName(st.Code()),
+		Message:     st.Message(),
+	})
+}
+
+// statusCodeName spells a status code the way the gRPC spec does, e.g.
+// INVALID_ARGUMENT for InvalidArgument
+func statusCodeName(code codes.Code) string {
+	var b strings.Builder
+	prevLower := false
+	for _, r := range code.String() {
+		if unicode.IsUpper(r) && prevLower {
+			b.WriteByte('_')
+		}
+		prevLower = unicode.IsLower(r)
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return b.String()
+}
+
+// truncate cuts s to at most n bytes, dropping any character split by the cut
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+// GetRejections lists recorded rejections matching every filter given, newest
+// first
+func (s *Server) GetRejections(ctx context.Context, req *pb.GetRejectionsRequest) (*pb.GetRejectionsResponse, error) {
+	req.UserAddress = normalizeAddress(req.UserAddress)
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+	req.Reason = strings.TrimSpace(req.Reason)
+
+	if (req.BaseToken == "") != (req.QuoteToken == "") {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token must be set together")
+	}
+	if req.Limit < 0 || req.AfterId < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "limit and after_id must not be negative")
+	}
+
+	since := time.Now().Add(-defaultRejectionsWindow)
+	if req.Since != nil {
+		since = req.Since.AsTime()
+	}
+	limit := int32(defaultRejectionsLimit)
+	if req.Limit > 0 {
+		limit = min(req.Limit, maxRejectionsLimit)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.Query(queryCtx, `
+		SELECT id, COALESCE(order_id::text, ''), user_address, base_token, quote_token,
+		       order_type, stage, reason, message, rejected_at
+		FROM rejected_orders
+		WHERE rejected_at >= $1
+		  AND id > $2
+		  AND ($3 = '' OR reason = $3)
+		  AND ($4 = '' OR LOWER(user_address) = LOWER($4))
+		  AND ($5 = '' OR (base_token = $5 AND quote_token = $6))
+		ORDER BY id DESC
+		LIMIT $7
+	`, since, req.AfterId, req.Reason, req.UserAddress, req.BaseToken, req.QuoteToken, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query rejections")
+		return nil, queryStatus(err, "failed to query rejections")
+	}
+	defer rows.Close()
+
+	rejections := make([]*pb.Rejection, 0)
+	for rows.Next() {
+		var r matcher.Rejection
+		var orderType string
+		if err := rows.Scan(&r.ID, &r.OrderID, &r.UserAddress, &r.BaseToken, &r.QuoteToken,
+			&orderType, &r.Stage, &r.Reason, &r.Message, &r.RejectedAt); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to scan rejection: %v", err)
+		}
+		r.OrderType = matcher.OrderType(orderType)
+		rejections = append(rejections, rejectionToProto(&r))
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryStatus(err, "failed to read rejections")
+	}
+
+	return &pb.GetRejectionsResponse{Rejections: rejections}, nil
+}
+
+// StreamRejections sends every rejection recorded from now on. A subscriber
+// that falls MaxStreamBacklog rejections behind is cut off with ABORTED and
+// catches up with GetRejections after the last id it received.
+func (s *Server) StreamRejections(req *pb.StreamRejectionsRequest, stream pb.MatcherService_StreamRejectionsServer) error {
+	reason := strings.TrimSpace(req.Reason)
+
+	release, err := s.acquireStream(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	log.Info().Str("reason", reason).Msg("Client connected to StreamRejections")
+
+	sub := s.rejections.subscribe()
+	defer s.rejections.unsubscribe(sub)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Info().Msg("Client disconnected from StreamRejections")
+			return nil
+
+		case <-sub.dropped:
+			return status.Errorf(codes.Aborted, "fell more than %d rejections behind; catch up with GetRejections", s.cfg.MaxStreamBacklog)
+
+		case r := <-sub.rejections:
+			if reason != "" && r.Reason != reason {
+				continue
+			}
+			if err := stream.Send(rejectionToProto(r)); err != nil {
+				log.Error().Err(err).Msg("Failed to send rejection")
+				return err
+			}
+		}
+	}
+}
+
+func rejectionToProto(r *matcher.Rejection) *pb.Rejection {
+	stage := pb.RejectionStage_REJECTION_STAGE_UNSPECIFIED
+	switch r.Stage {
+	case matcher.RejectionStageValidation:
+		stage = pb.RejectionStage_REJECTION_STAGE_VALIDATION
+	case matcher.RejectionStageMatching:
+		stage = pb.RejectionStage_REJECTION_STAGE_MATCHING
+	}
+
+	return &pb.Rejection{
+		Id:          r.ID,
+		OrderId:     r.OrderID,
+		UserAddress: r.UserAddress,
+		BaseToken:   r.BaseToken,
+		QuoteToken:  r.QuoteToken,
+		OrderType:   orderTypeToProto(r.OrderType),
+		Stage:       stage,
+		Reason:      r.Reason,
+		Message:     r.Message,
+		RejectedAt:  timestamppb.New(r.RejectedAt),
+	}
+}
+
+// rejectionHub copies every recorded rejection to each StreamRejections
+// subscriber, cutting off those that fall too far behind like matchHub
+type rejectionHub struct {
+	mu      sync.Mutex
+	subs    map[*rejectionSubscriber]struct{}
+	backlog int
+}
+
+// rejectionSubscriber is one stream's view of the hub
+type rejectionSubscriber struct {
+	rejections chan *matcher.Rejection
+	dropped    chan struct{} // Closed once the subscriber has been cut off
+}
+
+func newRejectionHub(backlog int) *rejectionHub {
+	return &rejectionHub{
+		subs:    make(map[*rejectionSubscriber]struct{}),
+		backlog: backlog,
+	}
+}
+
+// run fans rejections out for the life of the process
+func (h *rejectionHub) run(rejections <-chan *matcher.Rejection) {
+	for r := range rejections {
+		h.mu.Lock()
+		for sub := range h.subs {
+			select {
+			case sub.rejections <- r:
+			default:
+				delete(h.subs, sub)
+				close(sub.dropped)
+				log.Warn().Int("backlog", h.backlog).Msg("StreamRejections subscriber fell behind and was disconnected")
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+// subscribe registers a subscriber that receives every rejection from now on
+func (h *rejectionHub) subscribe() *rejectionSubscriber {
+	sub := &rejectionSubscriber{
+		rejections: make(chan *matcher.Rejection, h.backlog),
+		dropped:    make(chan struct{}),
+	}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// unsubscribe removes a subscriber; it is a no-op once it was cut off
+func (h *rejectionHub) unsubscribe(sub *rejectionSubscriber) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+}
@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/darkpool/warlock/internal/matcher"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// resumeTokenPrefix versions the token format so it can change without
+// misreading tokens clients still hold
+const resumeTokenPrefix = "m1:"
+
+// encodeResumeToken wraps a match sequence number in an opaque token
+func encodeResumeToken(seq int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(resumeTokenPrefix + strconv.FormatInt(seq, 10)))
+}
+
+// decodeResumeToken returns the match sequence number a token was issued for
+func decodeResumeToken(token string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+	seq, ok := strings.CutPrefix(string(raw), resumeTokenPrefix)
+	if !ok {
+		return 0, fmt.Errorf("unknown resume token format")
+	}
+	return strconv.ParseInt(seq, 10, 64)
+}
+
+// replayMatches loads the matches after afterSeq, up to throughSeq, that pass
+// the stream's filters, in sequence order. A gap wider than StreamReplayLimit
+// fails with OUT_OF_RANGE; the client must resync from GetOrderFills.
+func (s *Server) replayMatches(ctx context.Context, req *pb.StreamMatchesRequest, afterSeq, throughSeq int64) ([]*matcher.Match, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	filter := matcher.MatchFilter{BaseToken: req.BaseToken, QuoteToken: req.QuoteToken, UserAddress: req.UserAddress}
+	matches, err := s.engine.Store().LoadMatches(queryCtx, filter, afterSeq, throughSeq, s.cfg.StreamReplayLimit+1)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to load matches to replay")
+		return nil, queryStatus(err, "failed to load matches to replay")
+	}
+
+	if len(matches) > s.cfg.StreamReplayLimit {
+		return nil, status.Errorf(codes.OutOfRange, "more than %d matches to replay; resync with GetOrderFills", s.cfg.StreamReplayLimit)
+	}
+	return matches, nil
+}
@@ -0,0 +1,123 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"google.golang.org/grpc"
+)
+
+// matchStream is a StreamMatches stream handing each event sent to the test
+type matchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *pb.MatchEvent
+}
+
+func (s *matchStream) Context() context.Context { return s.ctx }
+
+func (s *matchStream) Send(event *pb.MatchEvent) error {
+	s.events <- event
+	return nil
+}
+
+// nextEvent waits for the stream's next event
+func (s *matchStream) nextEvent(t *testing.T) *pb.MatchEvent {
+	t.Helper()
+	select {
+	case event := <-s.events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no match event")
+		return nil
+	}
+}
+
+func TestStreamMatchesResumesExactlyOnce(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.TradePrintDelay = 0
+	s, store := newTestServer(t, cfg)
+	go s.matches.run(s.engine.MatchChan())
+	ctx := context.Background()
+
+	if _, err := s.SubmitOrder(ctx, orderRequest("0xalice", pb.OrderType_ORDER_TYPE_SELL, "4", "100")); err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := s.SubmitOrder(ctx, orderRequest("0xbob", pb.OrderType_ORDER_TYPE_BUY, "1", "100")); err != nil {
+			t.Fatalf("SubmitOrder: %v", err)
+		}
+	}
+	recorded := store.Matches()
+	if len(recorded) != 3 {
+		t.Fatalf("recorded %d matches, want 3", len(recorded))
+	}
+	// Already fanned out, so only a replay can deliver them
+	waitFannedOut(t, s.matches, recorded[2].Seq)
+
+	// The client saw the first match before it disconnected
+	streamCtx, cancel := context.WithCancel(ctx)
+	stream := &matchStream{ctx: streamCtx, events: make(chan *pb.MatchEvent, 10)}
+	done := make(chan error, 1)
+	go func() {
+		done <- s.StreamMatches(&pb.StreamMatchesRequest{ResumeToken: encodeResumeToken(recorded[0].Seq)}, stream)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	var got []int64
+	for i := 0; i < 2; i++ {
+		got = append(got, streamSeq(t, stream.nextEvent(t)))
+	}
+
+	// Matches after the resume arrive live
+	if _, err := s.SubmitOrder(ctx, orderRequest("0xcarol", pb.OrderType_ORDER_TYPE_BUY, "1", "100")); err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+	got = append(got, streamSeq(t, stream.nextEvent(t)))
+
+	recorded = store.Matches()
+	want := []int64{recorded[1].Seq, recorded[2].Seq, recorded[3].Seq}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("stream carried matches %v, want %v", got, want)
+		}
+	}
+	select {
+	case event := <-stream.events:
+		t.Errorf("match %d sent twice", streamSeq(t, event))
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// waitFannedOut waits for the hub to have sent out match seq
+func waitFannedOut(t *testing.T, hub *matchHub, seq int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		hub.mu.Lock()
+		last := hub.lastSeq
+		hub.mu.Unlock()
+		if last >= seq {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("hub reached match %d, want %d", last, seq)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// streamSeq reads the match sequence from an event's resume token
+func streamSeq(t *testing.T, event *pb.MatchEvent) int64 {
+	t.Helper()
+	seq, err := decodeResumeToken(event.ResumeToken)
+	if err != nil {
+		t.Fatalf("decodeResumeToken: %v", err)
+	}
+	return seq
+}
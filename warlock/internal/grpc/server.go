@@ -2,8 +2,10 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"strings"
 	"time"
 
 	"github.com/darkpool/warlock/internal/config"
@@ -22,20 +24,28 @@ import (
 // Server implements the gRPC MatcherService
 type Server struct {
 	pb.UnimplementedMatcherServiceServer
-	engine    *matcher.Engine
-	db        *pgxpool.Pool
-	cfg       *config.Config
-	grpcSrv   *grpc.Server
-	startTime time.Time
+	engine     *matcher.Engine
+	db         *pgxpool.Pool
+	cfg        *config.Config
+	grpcSrv    *grpc.Server
+	startTime  time.Time
+	streams    *streamRegistry
+	matches    *matchHub
+	rejections *rejectionHub
+	standby    *standbyChecker
 }
 
 // NewServer creates a new gRPC server
 func NewServer(engine *matcher.Engine, db *pgxpool.Pool, cfg *config.Config) *Server {
 	return &Server{
-		engine:    engine,
-		db:        db,
-		cfg:       cfg,
-		startTime: time.Now(),
+		engine:     engine,
+		db:         db,
+		cfg:        cfg,
+		startTime:  time.Now(),
+		streams:    newStreamRegistry(cfg.MaxStreamsPerClient),
+		matches:    newMatchHub(cfg.MaxStreamBacklog),
+		rejections: newRejectionHub(cfg.MaxStreamBacklog),
+		standby:    newStandbyChecker(),
 	}
 }
 
@@ -49,10 +59,18 @@ func (s *Server) Start() error {
 	s.grpcSrv = grpc.NewServer(
 		grpc.MaxRecvMsgSize(10 * 1024 * 1024), // 10MB
 		grpc.MaxSendMsgSize(10 * 1024 * 1024), // 10MB
+		grpc.ChainUnaryInterceptor(s.authenticateAPIKey, s.rejectOnStandby),
+		grpc.ChainStreamInterceptor(s.authenticateAPIKeyStream),
 	)
 
 	pb.RegisterMatcherServiceServer(s.grpcSrv, s)
 
+	go s.matches.run(s.engine.MatchChan())
+	go s.rejections.run(s.engine.RejectionChan())
+	if s.engine.Standby() && s.cfg.PrimaryAddr != "" {
+		go s.checkPrimary()
+	}
+
 	log.Info().Int("port", s.cfg.GRPCPort).Msg("gRPC server starting")
 
 	if err := s.grpcSrv.Serve(lis); err != nil {
@@ -62,16 +80,39 @@ func (s *Server) Start() error {
 	return nil
 }
 
-// Stop gracefully stops the gRPC server
-func (s *Server) Stop() {
-	if s.grpcSrv != nil {
-		log.Info().Msg("Stopping gRPC server")
+// Shutdown stops the gRPC server, letting in-flight RPCs finish until ctx is
+// done. Streams such as StreamMatches never finish on their own, so whatever
+// is still open then is closed and its clients see UNAVAILABLE.
+func (s *Server) Shutdown(ctx context.Context) {
+	if s.grpcSrv == nil {
+		return
+	}
+	log.Info().Msg("Stopping gRPC server")
+
+	stopped := make(chan struct{})
+	go func() {
 		s.grpcSrv.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		log.Warn().Msg("gRPC server did not drain in time, closing remaining connections")
+		s.grpcSrv.Stop()
+		<-stopped
 	}
 }
 
+// orderVisibilityDelay is how long an asynchronous submission waits after its
+// insert before queueing, to ensure cross-connection visibility in the
+// connection pool
+var orderVisibilityDelay = 50 * time.Millisecond
+
 // SubmitOrder handles order submission
 func (s *Server) SubmitOrder(ctx context.Context, req *pb.SubmitOrderRequest) (*pb.SubmitOrderResponse, error) {
+	normalizeSubmitOrderRequest(req)
+
 	log.Info().
 		Str("user_address", req.UserAddress).
 		Str("order_type", req.OrderType.String()).
@@ -79,101 +120,85 @@ func (s *Server) SubmitOrder(ctx context.Context, req *pb.SubmitOrderRequest) (*
 		Str("quote_token", req.QuoteToken).
 		Msg("Received SubmitOrder request")
 
-	// Validate request
-	if err := validateSubmitOrderRequest(req); err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
-	}
-
-	// Parse decimal values
-	quantity, err := decimal.NewFromString(req.Quantity)
+	order, err := newOrderFromRequest(req, s.cfg, s.engine.Now())
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid quantity: %v", err)
+		s.recordRejection(ctx, req, err)
+		return nil, err
 	}
 
-	price, err := decimal.NewFromString(req.Price)
-	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "invalid price: %v", err)
+	if err := s.checkPairOpen(order); err != nil {
+		s.recordRejection(ctx, req, err)
+		return nil, err
 	}
 
-	// Calculate min and max price based on variance
-	varianceFactor := decimal.NewFromInt(int64(req.VarianceBps)).Div(decimal.NewFromInt(10000))
-	minPrice := price.Mul(decimal.NewFromInt(1).Sub(varianceFactor))
-	maxPrice := price.Mul(decimal.NewFromInt(1).Add(varianceFactor))
-
-	// Calculate expiration time
-	// ExpiresInSeconds carries the absolute Unix timestamp from the frontend
-	// (the same value baked into the Poseidon commitment hash)
-	var expiresAt time.Time
-	if req.ExpiresInSeconds > 0 {
-		expiresAt = time.Unix(req.ExpiresInSeconds, 0)
+	if err := s.checkOneSided(order, req.Intent); err != nil {
+		s.recordRejection(ctx, req, err)
+		return nil, err
 	}
 
 	// Create order in database
-	orderID := uuid.New().String()
-	_, err = s.db.Exec(ctx, `
-		INSERT INTO orders (
-			id, user_address, chain_id, order_type, base_token, quote_token,
-			quantity, price, variance_bps, min_price, max_price,
-			filled_quantity, remaining_quantity, status,
-			commitment_hash, order_id, sell_amount, min_buy_amount, expires_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
-	`,
-		orderID, req.UserAddress, req.ChainId, orderTypeToString(req.OrderType),
-		req.BaseToken, req.QuoteToken,
-		quantity.String(), price.String(), req.VarianceBps, minPrice.String(), maxPrice.String(),
-		"0", quantity.String(), "REVEALED",
-		req.CommitmentHash, req.OrderId, req.SellAmount, req.MinBuyAmount, nullTimeOrValue(expiresAt),
-	)
-	if err != nil {
+	s.engine.HoldBehindSpeedBump(order)
+	if err := s.engine.Store().CreateOrders(ctx, []matcher.NewOrder{newStoredOrder(order, req)}); err != nil {
 		log.Error().Err(err).Msg("Failed to insert order")
 		return nil, status.Errorf(codes.Internal, "failed to create order: %v", err)
 	}
 
+	if req.AckOnly {
+		return s.acknowledgeOrder(order)
+	}
+
 	// Wait for transaction to be committed and visible to concurrent readers
 	// This eliminates the race condition where a matching order might query the DB
-	// before this transaction is committed
-	// Note: Using 50ms to ensure cross-connection visibility in the connection pool
-	time.Sleep(50 * time.Millisecond)
-
-	// Create order object
-	order := &matcher.Order{
-		ID:                orderID,
-		UserAddress:       req.UserAddress,
-		ChainID:           req.ChainId,
-		OrderType:         orderTypeFromProto(req.OrderType),
-		BaseToken:         req.BaseToken,
-		QuoteToken:        req.QuoteToken,
-		Quantity:          quantity,
-		Price:             price,
-		VarianceBPS:       req.VarianceBps,
-		MinPrice:          minPrice,
-		MaxPrice:          maxPrice,
-		FilledQuantity:    decimal.Zero,
-		RemainingQuantity: quantity,
-		Status:            matcher.OrderStatusRevealed,
-		CreatedAt:         time.Now(),
-		ExpiresAt:         expiresAt,
+	// before this transaction is committed. Synchronous submitters wait on the
+	// match itself instead.
+	if !req.Synchronous {
+		time.Sleep(orderVisibilityDelay)
 	}
 
 	// Submit to matching engine
-	if err := s.engine.SubmitOrder(order); err != nil {
-		log.Error().Err(err).Msg("Failed to submit order to engine")
-		return nil, status.Errorf(codes.Internal, "failed to submit order: %v", err)
+	matches, err := s.submitToEngine(ctx, order, req.Synchronous)
+	if err != nil {
+		return nil, err
 	}
 
 	// Build response
 	resp := &pb.SubmitOrderResponse{
 		Order:            orderToProto(order),
-		ImmediateMatches: make([]*pb.Match, 0),
+		ImmediateMatches: matches,
 	}
 
-	log.Info().Str("order_id", orderID).Msg("Order submitted successfully")
+	log.Info().Str("order_id", order.ID).Msg("Order submitted successfully")
 
 	return resp, nil
 }
 
+// acknowledgeOrder queues a stored order for matching and returns at once,
+// skipping the visibility wait. The insert has committed, so the order is
+// already readable from any connection; the wait only gives concurrent
+// submissions a head start, which ack-only clients trade for latency.
+func (s *Server) acknowledgeOrder(order *matcher.Order) (*pb.SubmitOrderResponse, error) {
+	// Taken before a worker can touch the order, so the ack shows it unmatched
+	acked := orderToProto(order)
+
+	seq, err := s.engine.SubmitOrderAck(order)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to submit order to engine")
+		return nil, status.Errorf(codes.Internal, "failed to submit order: %v", err)
+	}
+
+	log.Info().Str("order_id", order.ID).Uint64("ack_sequence", seq).Msg("Order acknowledged")
+
+	return &pb.SubmitOrderResponse{
+		Order:            acked,
+		ImmediateMatches: make([]*pb.Match, 0),
+		AckSequence:      seq,
+	}, nil
+}
+
 // CancelOrder handles order cancellation
 func (s *Server) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*pb.CancelOrderResponse, error) {
+	req.UserAddress = normalizeAddress(req.UserAddress)
+
 	log.Info().
 		Str("order_id", req.OrderId).
 		Str("user_address", req.UserAddress).
@@ -187,8 +212,20 @@ func (s *Server) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*
 		return nil, status.Errorf(codes.InvalidArgument, "user_address is required")
 	}
 
-	// Submit cancel request to engine
-	if err := s.engine.CancelOrder(req.OrderId, req.UserAddress); err != nil {
+	// Submit cancel request to engine and wait for the outcome
+	err := s.engine.CancelOrder(ctx, req.OrderId, req.UserAddress)
+	switch {
+	case err == nil:
+	case errors.Is(err, matcher.ErrOrderNotFound):
+		return nil, status.Errorf(codes.NotFound, "order %s not found", req.OrderId)
+	case errors.Is(err, matcher.ErrCancelTooLate), errors.Is(err, matcher.ErrOrderNotCancelable):
+		return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+	case errors.Is(err, matcher.ErrChannelDrained):
+		return nil, status.Errorf(codes.Aborted, "cancel for order %s was discarded by an operator; retry", req.OrderId)
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		// The cancel is queued and will still be applied
+		return nil, status.Errorf(codes.DeadlineExceeded, "cancel for order %s queued but not confirmed in time", req.OrderId)
+	default:
 		return nil, status.Errorf(codes.Internal, "failed to cancel order: %v", err)
 	}
 
@@ -198,8 +235,148 @@ func (s *Server) CancelOrder(ctx context.Context, req *pb.CancelOrderRequest) (*
 	}, nil
 }
 
+// CancelOrdersWhere cancels every open order of a user that matches all the given filters
+func (s *Server) CancelOrdersWhere(ctx context.Context, req *pb.CancelOrdersWhereRequest) (*pb.CancelOrdersWhereResponse, error) {
+	req.UserAddress = normalizeAddress(req.UserAddress)
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+
+	log.Info().
+		Str("user_address", req.UserAddress).
+		Msg("Received CancelOrdersWhere request")
+
+	if req.UserAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user_address is required")
+	}
+	if (req.BaseToken == "") != (req.QuoteToken == "") {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token must be set together")
+	}
+
+	filter := matcher.CancelFilter{
+		UserAddress: req.UserAddress,
+		BaseToken:   req.BaseToken,
+		QuoteToken:  req.QuoteToken,
+	}
+	if req.Side != pb.OrderType_ORDER_TYPE_UNSPECIFIED {
+		filter.Side = orderTypeFromProto(req.Side)
+	}
+	var err error
+	if req.PriceBelow != "" {
+		if filter.PriceBelow, err = decimal.NewFromString(req.PriceBelow); err != nil || !filter.PriceBelow.IsPositive() {
+			return nil, status.Errorf(codes.InvalidArgument, "price_below must be a positive number")
+		}
+	}
+	if req.PriceAbove != "" {
+		if filter.PriceAbove, err = decimal.NewFromString(req.PriceAbove); err != nil || !filter.PriceAbove.IsPositive() {
+			return nil, status.Errorf(codes.InvalidArgument, "price_above must be a positive number")
+		}
+	}
+	if req.OlderThan != nil {
+		filter.OlderThan = req.OlderThan.AsTime()
+	}
+
+	orderIDs, err := s.engine.CancelOrdersWhere(ctx, filter)
+	if errors.Is(err, matcher.ErrEmptyCancelFilter) {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to cancel orders")
+		return nil, status.Errorf(codes.Internal, "failed to cancel orders: %v", err)
+	}
+
+	return &pb.CancelOrdersWhereResponse{
+		CancelledCount:    int32(len(orderIDs)),
+		CancelledOrderIds: orderIDs,
+	}, nil
+}
+
+// CancelReplace cancels an order and inserts its replacement in a single transaction.
+// The replacement is validated first so a bad new order never leaves the user without a quote.
+func (s *Server) CancelReplace(ctx context.Context, req *pb.CancelReplaceRequest) (*pb.CancelReplaceResponse, error) {
+	req.UserAddress = normalizeAddress(req.UserAddress)
+	if req.NewOrder != nil {
+		normalizeSubmitOrderRequest(req.NewOrder)
+	}
+
+	log.Info().
+		Str("order_id", req.OrderId).
+		Str("user_address", req.UserAddress).
+		Msg("Received CancelReplace request")
+
+	if req.OrderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order_id is required")
+	}
+
+	if req.UserAddress == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "user_address is required")
+	}
+
+	if req.NewOrder == nil {
+		return nil, status.Errorf(codes.InvalidArgument, "new_order is required")
+	}
+
+	if !strings.EqualFold(req.NewOrder.UserAddress, req.UserAddress) {
+		return nil, status.Errorf(codes.InvalidArgument, "new_order.user_address must match user_address")
+	}
+
+	order, err := newOrderFromRequest(req.NewOrder, s.cfg, s.engine.Now())
+	if err != nil {
+		s.recordRejection(ctx, req.NewOrder, err)
+		return nil, err
+	}
+
+	if err := s.checkPairOpen(order); err != nil {
+		s.recordRejection(ctx, req.NewOrder, err)
+		return nil, err
+	}
+
+	if err := s.checkOneSided(order, req.NewOrder.Intent); err != nil {
+		s.recordRejection(ctx, req.NewOrder, err)
+		return nil, err
+	}
+
+	s.engine.HoldBehindSpeedBump(order)
+	err = s.engine.Store().ReplaceOrder(ctx, req.OrderId, req.UserAddress, newStoredOrder(order, req.NewOrder))
+	switch {
+	case err == nil:
+	case errors.Is(err, matcher.ErrOrderNotFound), errors.Is(err, matcher.ErrCancelTooLate), errors.Is(err, matcher.ErrOrderNotCancelable):
+		return nil, status.Errorf(codes.FailedPrecondition, "order %s not found or cannot be cancelled", req.OrderId)
+	default:
+		log.Error().Err(err).Str("order_id", req.OrderId).Msg("Failed to cancel-replace order")
+		return nil, status.Errorf(codes.Internal, "failed to cancel-replace order: %v", err)
+	}
+
+	// Both legs are durable; bring the in-memory books in line
+	s.engine.EvictOrder(req.OrderId)
+
+	matches, err := s.submitToEngine(ctx, order, req.NewOrder.Synchronous)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("cancelled_order_id", req.OrderId).
+		Str("new_order_id", order.ID).
+		Msg("Order cancel-replaced successfully")
+
+	return &pb.CancelReplaceResponse{
+		Cancel: &pb.CancelOrderResponse{
+			Success: true,
+			Message: "Order cancelled successfully",
+		},
+		Submit: &pb.SubmitOrderResponse{
+			Order:            orderToProto(order),
+			ImmediateMatches: matches,
+		},
+	}, nil
+}
+
 // GetOrderBook retrieves the order book for a token pair
 func (s *Server) GetOrderBook(ctx context.Context, req *pb.GetOrderBookRequest) (*pb.GetOrderBookResponse, error) {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+	req.PoolId = strings.TrimSpace(req.PoolId)
+
 	if req.BaseToken == "" || req.QuoteToken == "" {
 		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
 	}
@@ -209,7 +386,10 @@ func (s *Server) GetOrderBook(ctx context.Context, req *pb.GetOrderBookRequest)
 		depth = 20 // default
 	}
 
-	orderBook := s.engine.GetOrderBook(req.BaseToken, req.QuoteToken)
+	warming := !s.engine.IsPairReady(req.BaseToken, req.QuoteToken)
+	state := string(s.engine.PairState(req.BaseToken, req.QuoteToken))
+
+	orderBook := s.engine.GetOrderBook(req.PoolId, req.BaseToken, req.QuoteToken)
 	if orderBook == nil {
 		// Return empty order book
 		return &pb.GetOrderBookResponse{
@@ -218,12 +398,14 @@ func (s *Server) GetOrderBook(ctx context.Context, req *pb.GetOrderBookRequest)
 			Bids:       make([]*pb.PriceLevel, 0),
 			Asks:       make([]*pb.PriceLevel, 0),
 			Timestamp:  timestamppb.Now(),
+			Warming:    warming,
+			State:      state,
+			PoolId:     req.PoolId,
 		}, nil
 	}
 
 	// Get bids and asks
-	bids := buildPriceLevels(orderBook.GetBids(), int(depth))
-	asks := buildPriceLevels(orderBook.GetAsks(), int(depth))
+	bids, asks, sequence := s.displayLevels(orderBook, int(depth))
 
 	return &pb.GetOrderBookResponse{
 		BaseToken:  req.BaseToken,
@@ -231,18 +413,293 @@ func (s *Server) GetOrderBook(ctx context.Context, req *pb.GetOrderBookRequest)
 		Bids:       bids,
 		Asks:       asks,
 		Timestamp:  timestamppb.Now(),
+		Warming:    warming,
+		State:      state,
+		PoolId:     req.PoolId,
+		Sequence:   sequence,
 	}, nil
 }
 
+// GetDepthChart returns each side of a book as cumulative depth, so clients can
+// plot it without aggregating levels themselves
+func (s *Server) GetDepthChart(ctx context.Context, req *pb.GetDepthChartRequest) (*pb.GetDepthChartResponse, error) {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+	req.PoolId = strings.TrimSpace(req.PoolId)
+
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+
+	levels := req.Levels
+	if levels <= 0 {
+		levels = 20 // default
+	}
+
+	resp := &pb.GetDepthChartResponse{
+		BaseToken:  req.BaseToken,
+		QuoteToken: req.QuoteToken,
+		Bids:       make([]*pb.DepthPoint, 0),
+		Asks:       make([]*pb.DepthPoint, 0),
+		Timestamp:  timestamppb.Now(),
+		PoolId:     req.PoolId,
+	}
+
+	orderBook := s.engine.GetOrderBook(req.PoolId, req.BaseToken, req.QuoteToken)
+	if orderBook == nil {
+		return resp, nil
+	}
+
+	bids, asks, _ := s.displayLevels(orderBook, int(levels))
+	resp.Bids = cumulativeDepth(bids)
+	resp.Asks = cumulativeDepth(asks)
+	return resp, nil
+}
+
+// displayLevels aggregates both sides of a book into at most depth price
+// levels each, uncrossed first when UncrossBookDisplay is set and masked per
+// BookDisplayPolicy. Both sides come from one snapshot, returned with the
+// book version it reflects.
+func (s *Server) displayLevels(orderBook *matcher.OrderBook, depth int) ([]*pb.PriceLevel, []*pb.PriceLevel, uint64) {
+	bidOrders, askOrders, version := orderBook.Snapshot()
+	if !s.cfg.UncrossBookDisplay {
+		bids, asks := s.maskLevels(
+			buildPriceLevels(bidOrders, depth, s.cfg.DisplayPriceDecimals),
+			buildPriceLevels(askOrders, depth, s.cfg.DisplayPriceDecimals),
+		)
+		return bids, asks, version
+	}
+
+	// Uncross over the whole book so the levels netted away don't shorten the result
+	bids, asks := uncrossLevels(
+		buildPriceLevels(bidOrders, len(bidOrders), s.cfg.DisplayPriceDecimals),
+		buildPriceLevels(askOrders, len(askOrders), s.cfg.DisplayPriceDecimals),
+	)
+	bids, asks = s.maskLevels(bids[:min(depth, len(bids))], asks[:min(depth, len(asks))])
+	return bids, asks, version
+}
+
+// maxGetOrdersIDs caps the ids a single GetOrders call may request
+const maxGetOrdersIDs = 500
+
+// GetOrders fetches the requested orders with one query. Ids that don't parse
+// or don't exist are reported in MissingOrderIds.
+func (s *Server) GetOrders(ctx context.Context, req *pb.GetOrdersRequest) (*pb.GetOrdersResponse, error) {
+	if len(req.OrderIds) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "order_ids is required")
+	}
+
+	if len(req.OrderIds) > maxGetOrdersIDs {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d order_ids per request", maxGetOrdersIDs)
+	}
+
+	ids := make([]string, 0, len(req.OrderIds))
+	missing := make([]string, 0)
+	for _, id := range req.OrderIds {
+		parsed, err := uuid.Parse(strings.TrimSpace(id))
+		if err != nil {
+			missing = append(missing, id)
+			continue
+		}
+		// Canonical form, as the database returns it
+		ids = append(ids, parsed.String())
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	orders, err := s.engine.LoadOrders(queryCtx, ids)
+	if err != nil {
+		log.Error().Err(err).Int("ids", len(ids)).Msg("Failed to load orders")
+		return nil, queryStatus(err, "failed to load orders")
+	}
+
+	found := make(map[string]bool, len(orders))
+	resp := &pb.GetOrdersResponse{Orders: make([]*pb.Order, 0, len(orders))}
+	for _, o := range orders {
+		found[o.ID] = true
+		resp.Orders = append(resp.Orders, orderToProto(o))
+	}
+
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	resp.MissingOrderIds = missing
+
+	return resp, nil
+}
+
+// GetOrderFills returns all matches involving an order, oldest first
+func (s *Server) GetOrderFills(ctx context.Context, req *pb.GetOrderFillsRequest) (*pb.GetOrderFillsResponse, error) {
+	req.OrderId = strings.TrimSpace(req.OrderId)
+
+	if req.OrderId == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "order_id is required")
+	}
+
+	if _, err := uuid.Parse(req.OrderId); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid order_id: %v", err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	rows, err := s.db.Query(queryCtx, `
+		SELECT m.id, m.buy_order_id, m.sell_order_id, m.base_token, m.quote_token,
+		       m.quantity, m.price, m.settlement_status, m.yellow_session_id,
+		       m.matched_at, m.settled_at, b.user_address, sl.user_address,
+		       b.metadata, sl.metadata, COALESCE(m.taker_side, ''), m.taker_fee::text, m.maker_rebate::text,
+		       m.rounded_price
+		FROM matches m
+		JOIN orders b ON b.id = m.buy_order_id
+		JOIN orders sl ON sl.id = m.sell_order_id
+		WHERE m.buy_order_id = $1 OR m.sell_order_id = $1
+		ORDER BY m.matched_at ASC
+	`, req.OrderId)
+	if err != nil {
+		log.Error().Err(err).Str("order_id", req.OrderId).Msg("Failed to query order fills")
+		return nil, queryStatus(err, "failed to query order fills")
+	}
+	defer rows.Close()
+
+	matches := make([]*pb.Match, 0)
+	for rows.Next() {
+		var m matcher.Match
+		var quantityStr, priceStr, takerSide, takerFeeStr, makerRebateStr string
+		var yellowSessionID *string
+		var settledAt *time.Time
+
+		err := rows.Scan(
+			&m.ID, &m.BuyOrderID, &m.SellOrderID, &m.BaseToken, &m.QuoteToken,
+			&quantityStr, &priceStr, &m.SettlementStatus, &yellowSessionID,
+			&m.MatchedAt, &settledAt, &m.BuyerAddress, &m.SellerAddress,
+			&m.BuyMetadata, &m.SellMetadata, &takerSide, &takerFeeStr, &makerRebateStr,
+			&m.RoundedPrice,
+		)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to scan match: %v", err)
+		}
+
+		m.Quantity, _ = decimal.NewFromString(quantityStr)
+		m.Price, _ = decimal.NewFromString(priceStr)
+		m.TakerSide = matcher.OrderType(takerSide)
+		m.TakerFee, _ = decimal.NewFromString(takerFeeStr)
+		m.MakerRebate, _ = decimal.NewFromString(makerRebateStr)
+
+		pm := matchToProto(&m)
+		if yellowSessionID != nil {
+			pm.YellowSessionId = *yellowSessionID
+		}
+		if settledAt != nil {
+			pm.SettledAt = timestamppb.New(*settledAt)
+		}
+		matches = append(matches, pm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, queryStatus(err, "failed to read order fills")
+	}
+
+	return &pb.GetOrderFillsResponse{Matches: matches}, nil
+}
+
+// queryStatus maps a failed lookup or reporting query to a gRPC status,
+// distinguishing a ReadQueryTimeout expiry from other failures
+func queryStatus(err error, msg string) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.Errorf(codes.DeadlineExceeded, "%s: query timed out", msg)
+	}
+	return status.Errorf(codes.Internal, "%s: %v", msg, err)
+}
+
 // StreamMatches streams match events
 func (s *Server) StreamMatches(req *pb.StreamMatchesRequest, stream pb.MatcherService_StreamMatchesServer) error {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+	req.UserAddress = normalizeAddress(req.UserAddress)
+
+	release, err := s.acquireStream(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	log.Info().
 		Str("base_token", req.BaseToken).
 		Str("quote_token", req.QuoteToken).
 		Str("user_address", req.UserAddress).
 		Msg("Client connected to StreamMatches")
 
-	matchChan := s.engine.MatchChan()
+	send := func(match *matcher.Match) error {
+		// The stream is display-facing, so the price is rounded
+		pm := matchToProto(match)
+		pm.Price = formatDisplayPrice(match.Price, s.cfg.DisplayPriceDecimals)
+		event := &pb.MatchEvent{
+			Match:       pm,
+			EventTime:   timestamppb.Now(),
+			ResumeToken: encodeResumeToken(match.Seq),
+		}
+
+		if err := stream.Send(event); err != nil {
+			log.Error().Err(err).Msg("Failed to send match event")
+			return err
+		}
+		return nil
+	}
+
+	// A stream filtered to one user only ever carries that user's own fills,
+	// so it gets them at once; every other subscriber sees the delayed print
+	delay := s.cfg.TradePrintDelay
+	if req.UserAddress != "" {
+		delay = 0
+	}
+	var pending []delayedPrint
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+
+	// enqueue sends a match once releaseAt has passed, keeping stream order
+	enqueue := func(match *matcher.Match, releaseAt time.Time) error {
+		if len(pending) == 0 && !releaseAt.After(time.Now()) {
+			return send(match)
+		}
+		pending = append(pending, delayedPrint{match: match, releaseAt: releaseAt})
+		if len(pending) == 1 {
+			timer.Reset(time.Until(releaseAt))
+		}
+		return nil
+	}
+
+	// Subscribe before replaying so nothing recorded meanwhile is missed. The
+	// subscription carries every match after liveFrom, in sequence order, so
+	// replaying the ones up to it sends each match exactly once.
+	sub, liveFrom := s.matches.subscribe(s.engine.MatchSeqFloor())
+	defer s.matches.unsubscribe(sub)
+
+	// The live feed may still carry matches the client saw before the token
+	var afterSeq int64
+	if req.ResumeToken != "" {
+		var err error
+		afterSeq, err = decodeResumeToken(req.ResumeToken)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid resume_token")
+		}
+		if afterSeq < liveFrom {
+			missed, err := s.replayMatches(stream.Context(), req, afterSeq, liveFrom)
+			if err != nil {
+				return err
+			}
+			for _, match := range missed {
+				if err := enqueue(match, match.MatchedAt.Add(delay)); err != nil {
+					return err
+				}
+			}
+		}
+	}
 
 	for {
 		select {
@@ -250,7 +707,27 @@ func (s *Server) StreamMatches(req *pb.StreamMatchesRequest, stream pb.MatcherSe
 			log.Info().Msg("Client disconnected from StreamMatches")
 			return nil
 
-		case match := <-matchChan:
+		case <-sub.dropped:
+			return status.Errorf(codes.Aborted,
+				"stream fell more than %d matches behind; reconnect with the last resume_token", s.cfg.MaxStreamBacklog)
+
+		case <-timer.C:
+			now := time.Now()
+			for len(pending) > 0 && !pending[0].releaseAt.After(now) {
+				if err := send(pending[0].match); err != nil {
+					return err
+				}
+				pending = pending[1:]
+			}
+			if len(pending) > 0 {
+				timer.Reset(time.Until(pending[0].releaseAt))
+			}
+
+		case match := <-sub.matches:
+			if match.Seq <= afterSeq {
+				continue
+			}
+
 			// Apply filters
 			if req.BaseToken != "" && match.BaseToken != req.BaseToken {
 				continue
@@ -259,41 +736,340 @@ func (s *Server) StreamMatches(req *pb.StreamMatchesRequest, stream pb.MatcherSe
 				continue
 			}
 			if req.UserAddress != "" &&
-				match.BuyerAddress != req.UserAddress &&
-				match.SellerAddress != req.UserAddress {
+				!strings.EqualFold(match.BuyerAddress, req.UserAddress) &&
+				!strings.EqualFold(match.SellerAddress, req.UserAddress) {
 				continue
 			}
 
-			// Send match event
-			event := &pb.MatchEvent{
-				Match:     matchToProto(match),
-				EventTime: timestamppb.Now(),
-			}
-
-			if err := stream.Send(event); err != nil {
-				log.Error().Err(err).Msg("Failed to send match event")
+			// Every print waits the same delay, so the queue stays in release order
+			if err := enqueue(match, time.Now().Add(delay)); err != nil {
 				return err
 			}
 		}
 	}
 }
 
+// delayedPrint is a match held back from public StreamMatches subscribers
+// until TradePrintDelay has passed
+type delayedPrint struct {
+	match     *matcher.Match
+	releaseAt time.Time
+}
+
 // HealthCheck returns service health status
 func (s *Server) HealthCheck(ctx context.Context, req *pb.HealthCheckRequest) (*pb.HealthCheckResponse, error) {
 	stats := s.engine.GetStats()
 
 	return &pb.HealthCheckResponse{
-		Healthy:       true,
+		Healthy:       !s.engine.Degraded(),
 		Version:       s.cfg.ServiceVersion,
 		UptimeSeconds: int64(time.Since(s.startTime).Seconds()),
 		TotalOrders:   stats.TotalOrders,
 		TotalMatches:  stats.TotalMatches,
+
+		MatchedBaseVolume:  stats.MatchedBaseVolume.String(),
+		MatchedQuoteVolume: stats.MatchedQuoteVolume.String(),
 	}, nil
 }
 
+// Bounds on the StreamStats push interval
+const (
+	defaultStatsInterval = time.Second
+	minStatsInterval     = 100 * time.Millisecond
+)
+
+// StreamStats sends an engine stats snapshot immediately and then every interval
+func (s *Server) StreamStats(req *pb.StreamStatsRequest, stream pb.MatcherService_StreamStatsServer) error {
+	interval := defaultStatsInterval
+	if req.IntervalMs > 0 {
+		interval = time.Duration(req.IntervalMs) * time.Millisecond
+	}
+	if interval < minStatsInterval {
+		interval = minStatsInterval
+	}
+
+	release, err := s.acquireStream(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	log.Info().Dur("interval", interval).Msg("Client connected to StreamStats")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := stream.Send(s.statsSnapshot()); err != nil {
+			log.Error().Err(err).Msg("Failed to send stats snapshot")
+			return err
+		}
+
+		select {
+		case <-stream.Context().Done():
+			log.Info().Msg("Client disconnected from StreamStats")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// statsSnapshot collects engine totals, book sizes and channel backlogs
+func (s *Server) statsSnapshot() *pb.EngineStatsSnapshot {
+	stats := s.engine.GetStats()
+
+	sizes := s.engine.BookSizes()
+	books := make([]*pb.BookSize, 0, len(sizes))
+	for _, size := range sizes {
+		books = append(books, &pb.BookSize{
+			BaseToken:  size.BaseToken,
+			QuoteToken: size.QuoteToken,
+			PoolId:     size.PoolID,
+			BidCount:   int32(size.Bids),
+			AskCount:   int32(size.Asks),
+		})
+	}
+
+	return &pb.EngineStatsSnapshot{
+		TotalOrders:   stats.TotalOrders,
+		TotalMatches:  stats.TotalMatches,
+		TotalCancels:  stats.TotalCancels,
+		ActiveWorkers: int32(stats.ActiveWorkers),
+		UptimeSeconds: int64(time.Since(s.startTime).Seconds()),
+		OrderBacklog:  int32(stats.OrderBacklog),
+		CancelBacklog: int32(stats.CancelBacklog),
+		MatchBacklog:  int32(stats.MatchBacklog),
+		Books:         books,
+		SnapshotTime:  timestamppb.Now(),
+		HeapDesyncs:   stats.HeapDesyncs,
+
+		MatchedBaseVolume:  stats.MatchedBaseVolume.String(),
+		MatchedQuoteVolume: stats.MatchedQuoteVolume.String(),
+	}
+}
+
+// submitToEngine hands a persisted order to the engine. Asynchronous submissions
+// return straight away; synchronous ones wait up to SyncSubmitTimeout for matching
+// and return the matches it produced, leaving order in its post-match state.
+func (s *Server) submitToEngine(ctx context.Context, order *matcher.Order, synchronous bool) ([]*pb.Match, error) {
+	if !synchronous {
+		if err := s.engine.SubmitOrder(order); err != nil {
+			log.Error().Err(err).Msg("Failed to submit order to engine")
+			return nil, status.Errorf(codes.Internal, "failed to submit order: %v", err)
+		}
+		return make([]*pb.Match, 0), nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, s.cfg.SyncSubmitTimeout)
+	defer cancel()
+
+	matches, err := s.engine.SubmitOrderSync(waitCtx, order)
+	if errors.Is(err, matcher.ErrDegraded) {
+		return nil, status.Errorf(codes.Unavailable, "order %s accepted but matching is suspended while the database is unavailable", order.ID)
+	}
+	if errors.Is(err, matcher.ErrChannelDrained) {
+		return nil, status.Errorf(codes.Aborted, "order %s is stored but was dropped from the matching queue by an operator", order.ID)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		// The order is stored and queued; it will still be matched
+		return nil, status.Errorf(codes.DeadlineExceeded, "order %s accepted but matching not confirmed in time", order.ID)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to submit order to engine")
+		return nil, status.Errorf(codes.Internal, "failed to submit order: %v", err)
+	}
+
+	result := make([]*pb.Match, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, matchToProto(m))
+	}
+	return result, nil
+}
+
+// checkPairOpen rejects orders for pairs ops have retired or paused, for
+// paused chains, for pairs outside market hours that reject such orders, for
+// pairs whose book is still warming up after startup (they would otherwise be
+// loaded twice, once by warm-up and once by the engine), for new pairs past
+// MaxPairs, and for every pair while the engine is degraded
+func (s *Server) checkPairOpen(order *matcher.Order) error {
+	if !s.cfg.PairTradable(order.BaseToken, order.QuoteToken) {
+		return status.Errorf(codes.FailedPrecondition, "pair %s/%s is not tradable", order.BaseToken, order.QuoteToken)
+	}
+	if s.engine.PairState(order.BaseToken, order.QuoteToken) == matcher.BookStatePaused {
+		return status.Errorf(codes.FailedPrecondition, "pair %s/%s is paused", order.BaseToken, order.QuoteToken)
+	}
+	if s.engine.ChainPaused(order.ChainID) {
+		return status.Errorf(codes.FailedPrecondition, "chain %d is paused", order.ChainID)
+	}
+	if session := s.cfg.SessionFor(order.BaseToken, order.QuoteToken); session != nil && session.RejectClosed {
+		if open, next := session.State(s.engine.Now()); !open {
+			return status.Errorf(codes.FailedPrecondition, "pair %s/%s is outside market hours; next open %s",
+				order.BaseToken, order.QuoteToken, next.Format(time.RFC3339))
+		}
+	}
+	if s.engine.Degraded() {
+		return status.Errorf(codes.Unavailable, "matching is suspended while the database is unavailable, retry shortly")
+	}
+	if s.engine.BookStale() {
+		return status.Errorf(codes.Unavailable, "order books not reconciled with the database since %s, retry shortly",
+			s.engine.LastReconcile().Format(time.RFC3339))
+	}
+	if !s.engine.IsPairReady(order.BaseToken, order.QuoteToken) {
+		return status.Errorf(codes.Unavailable, "pair %s/%s is warming up, retry shortly", order.BaseToken, order.QuoteToken)
+	}
+	if s.engine.PairAtCapacity(order.BaseToken, order.QuoteToken) {
+		return status.Errorf(codes.ResourceExhausted, "pair %s/%s has no book and the pair limit is reached", order.BaseToken, order.QuoteToken)
+	}
+	return nil
+}
+
+// checkOneSided rejects a taker order on a ONE_SIDED_REJECT_PAIRS pair when
+// nothing resting on the other side can match it, so it doesn't rest alone.
+// Maker orders always rest.
+func (s *Server) checkOneSided(order *matcher.Order, intent pb.LiquidityIntent) error {
+	if intent == pb.LiquidityIntent_LIQUIDITY_INTENT_MAKER || !s.cfg.RejectsOneSided(order.BaseToken, order.QuoteToken) {
+		return nil
+	}
+	if !s.engine.HasCompatibleLiquidity(order) {
+		return status.Errorf(codes.FailedPrecondition, "nothing on the other side of the %s/%s book can match this order; submit with intent MAKER to rest it",
+			order.BaseToken, order.QuoteToken)
+	}
+	return nil
+}
+
+// maxCounterpartyAllowlist caps the addresses a single order may allowlist
+const maxCounterpartyAllowlist = 100
+
+// Caps on order metadata
+const (
+	maxMetadataEntries  = 16
+	maxMetadataKeyLen   = 64
+	maxMetadataValueLen = 256
+)
+
 // Helper functions
 
-func validateSubmitOrderRequest(req *pb.SubmitOrderRequest) error {
+// normalizeToken canonicalizes a token identifier so the same token always keys
+// into the same order book: surrounding whitespace is dropped and EVM hex
+// addresses are lowercased. Non-hex identifiers are only trimmed.
+func normalizeToken(token string) string {
+	token = strings.TrimSpace(token)
+	if strings.HasPrefix(token, "0x") || strings.HasPrefix(token, "0X") {
+		return strings.ToLower(token)
+	}
+	return token
+}
+
+// normalizeAddress canonicalizes a user address so the same account always
+// compares equal: surrounding whitespace is dropped and the address is
+// lowercased, discarding any EIP-55 checksum casing.
+func normalizeAddress(address string) string {
+	return strings.ToLower(strings.TrimSpace(address))
+}
+
+// normalizeSubmitOrderRequest canonicalizes identifiers in place before validation,
+// so whitespace-only values are rejected as missing
+func normalizeSubmitOrderRequest(req *pb.SubmitOrderRequest) {
+	req.UserAddress = normalizeAddress(req.UserAddress)
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+	for i, addr := range req.CounterpartyAllowlist {
+		req.CounterpartyAllowlist[i] = normalizeAddress(addr)
+	}
+	req.PoolId = strings.TrimSpace(req.PoolId)
+}
+
+// newOrderFromRequest validates a submission and builds the engine order for it,
+// created at createdAt by the engine's clock. The request must already be
+// normalized.
+func newOrderFromRequest(req *pb.SubmitOrderRequest, cfg *config.Config, createdAt time.Time) (*matcher.Order, error) {
+	// Validate request
+	if err := validateSubmitOrderRequest(req, cfg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+
+	if !cfg.PoolAllowed(req.PoolId) {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown pool_id %q", req.PoolId)
+	}
+
+	// Parse decimal values
+	quantity, err := decimal.NewFromString(req.Quantity)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid quantity: %v", err)
+	}
+
+	price, err := decimal.NewFromString(req.Price)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid price: %v", err)
+	}
+
+	// Calculate min and max price based on variance
+	varianceFactor := decimal.NewFromInt(int64(req.VarianceBps)).Div(decimal.NewFromInt(10000))
+	minPrice := price.Mul(decimal.NewFromInt(1).Sub(varianceFactor))
+	maxPrice := price.Mul(decimal.NewFromInt(1).Add(varianceFactor))
+
+	// Calculate expiration time
+	// ExpiresInSeconds carries the absolute Unix timestamp from the frontend
+	// (the same value baked into the Poseidon commitment hash)
+	var expiresAt time.Time
+	if req.ExpiresInSeconds > 0 {
+		expiresAt = time.Unix(req.ExpiresInSeconds, 0)
+
+		// Judged by the server clock; a skewed client aligns via GetServerTime
+		if earliest := createdAt.Add(cfg.ExpiryClockSkewTolerance); !expiresAt.After(earliest) {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"expires_in_seconds %d must be after %d (server time plus %s clock skew tolerance)",
+				req.ExpiresInSeconds, earliest.Unix(), cfg.ExpiryClockSkewTolerance)
+		}
+	}
+
+	var noFillDeadline time.Time
+	if req.NoFillTimeoutSeconds > 0 {
+		noFillDeadline = createdAt.Add(time.Duration(req.NoFillTimeoutSeconds) * time.Second)
+	}
+
+	return &matcher.Order{
+		ID:                uuid.New().String(),
+		UserAddress:       req.UserAddress,
+		ChainID:           req.ChainId,
+		OrderType:         orderTypeFromProto(req.OrderType),
+		BaseToken:         req.BaseToken,
+		QuoteToken:        req.QuoteToken,
+		Quantity:          quantity,
+		Price:             price,
+		VarianceBPS:       req.VarianceBps,
+		MinPrice:          minPrice,
+		MaxPrice:          maxPrice,
+		FilledQuantity:    decimal.Zero,
+		RemainingQuantity: quantity,
+		Status:            matcher.OrderStatusRevealed,
+		CreatedAt:         createdAt,
+		ExpiresAt:         expiresAt,
+		NoFillDeadline:    noFillDeadline,
+		Metadata:          req.Metadata,
+
+		CounterpartyAllowlist: req.CounterpartyAllowlist,
+		PoolID:                req.PoolId,
+		RemainderPolicy:       remainderPolicyFromProto(req.RemainderPolicy),
+		TraceMatching:         req.Trace,
+	}, nil
+}
+
+// newStoredOrder pairs a freshly built order with the on-chain commitment
+// fields of its request, for the store
+func newStoredOrder(order *matcher.Order, req *pb.SubmitOrderRequest) matcher.NewOrder {
+	return matcher.NewOrder{
+		Order: order,
+		Commitment: matcher.OrderCommitment{
+			Hash:         req.CommitmentHash,
+			OrderID:      req.OrderId,
+			SellAmount:   req.SellAmount,
+			MinBuyAmount: req.MinBuyAmount,
+		},
+	}
+}
+
+func validateSubmitOrderRequest(req *pb.SubmitOrderRequest, cfg *config.Config) error {
 	if req.UserAddress == "" {
 		return fmt.Errorf("user_address is required")
 	}
@@ -309,20 +1085,99 @@ func validateSubmitOrderRequest(req *pb.SubmitOrderRequest) error {
 	if req.Price == "" || req.Price == "0" {
 		return fmt.Errorf("price must be > 0")
 	}
+	if err := checkDecimalPrecision("quantity", req.Quantity, cfg); err != nil {
+		return err
+	}
+	if err := checkDecimalPrecision("price", req.Price, cfg); err != nil {
+		return err
+	}
 	if req.VarianceBps < 0 || req.VarianceBps > 10000 {
 		return fmt.Errorf("variance_bps must be between 0 and 10000")
 	}
 	if req.OrderType == pb.OrderType_ORDER_TYPE_UNSPECIFIED {
 		return fmt.Errorf("order_type is required")
 	}
+	if req.AckOnly && req.Synchronous {
+		return fmt.Errorf("ack_only and synchronous are exclusive")
+	}
+	if !cfg.ChainSupported(req.ChainId) {
+		return fmt.Errorf("chain_id %d is not supported", req.ChainId)
+	}
+	if req.NoFillTimeoutSeconds < 0 {
+		return fmt.Errorf("no_fill_timeout_seconds must not be negative")
+	}
+	if len(req.CounterpartyAllowlist) > maxCounterpartyAllowlist {
+		return fmt.Errorf("counterparty_allowlist may list at most %d addresses", maxCounterpartyAllowlist)
+	}
+	for _, addr := range req.CounterpartyAllowlist {
+		if addr == "" {
+			return fmt.Errorf("counterparty_allowlist entries must not be empty")
+		}
+	}
+	if len(req.Metadata) > maxMetadataEntries {
+		return fmt.Errorf("metadata may have at most %d entries", maxMetadataEntries)
+	}
+	for key, value := range req.Metadata {
+		if key == "" || len(key) > maxMetadataKeyLen {
+			return fmt.Errorf("metadata keys must be 1-%d bytes", maxMetadataKeyLen)
+		}
+		if len(value) > maxMetadataValueLen {
+			return fmt.Errorf("metadata value for %q exceeds %d bytes", key, maxMetadataValueLen)
+		}
+	}
+	return checkOrderBounds(req, cfg.BoundsFor(req.BaseToken, req.QuoteToken))
+}
+
+// checkDecimalPrecision rejects a price or quantity written with more decimal
+// places or significant digits than allowed. It works on the text, before any
+// decimal parsing, so an oversized value costs no more than its length;
+// exponent notation is refused since a short exponent can stand for
+// arbitrarily many digits. Values that aren't plain numbers at all are left
+// for the caller's decimal parsing to report.
+func checkDecimalPrecision(field, value string, cfg *config.Config) error {
+	if strings.ContainsAny(value, "eE") {
+		return fmt.Errorf("%s must not use exponent notation", field)
+	}
+
+	digits := strings.TrimLeft(value, "+-")
+	whole, frac, _ := strings.Cut(digits, ".")
+	if len(frac) > cfg.MaxDecimalPlaces {
+		return fmt.Errorf("%s has more than %d decimal places", field, cfg.MaxDecimalPlaces)
+	}
+
+	// Leading zeros, before or after the point, are not significant
+	if significant := strings.TrimLeft(whole+frac, "0"); len(significant) > cfg.MaxSignificantDigits {
+		return fmt.Errorf("%s has more than %d significant digits", field, cfg.MaxSignificantDigits)
+	}
 	return nil
 }
 
-func orderTypeToString(ot pb.OrderType) string {
-	if ot == pb.OrderType_ORDER_TYPE_BUY {
-		return "BUY"
+// checkOrderBounds rejects fat-finger prices and notionals, and quantities
+// that aren't a whole number of lots. Values that don't parse are left for
+// the caller's decimal parsing to report.
+func checkOrderBounds(req *pb.SubmitOrderRequest, bounds config.OrderBounds) error {
+	price, err := decimal.NewFromString(req.Price)
+	if err != nil {
+		return nil
 	}
-	return "SELL"
+	quantity, err := decimal.NewFromString(req.Quantity)
+	if err != nil {
+		return nil
+	}
+
+	if !bounds.MaxPrice.IsZero() && price.GreaterThan(bounds.MaxPrice) {
+		return fmt.Errorf("price %s exceeds maximum %s for %s/%s", price, bounds.MaxPrice, req.BaseToken, req.QuoteToken)
+	}
+
+	if notional := price.Mul(quantity); !bounds.MaxNotional.IsZero() && notional.GreaterThan(bounds.MaxNotional) {
+		return fmt.Errorf("notional %s exceeds maximum %s for %s/%s", notional, bounds.MaxNotional, req.BaseToken, req.QuoteToken)
+	}
+
+	if !bounds.RoundToLot(quantity).Equal(quantity) {
+		return fmt.Errorf("quantity %s is not a multiple of the lot size %s for %s/%s", quantity, bounds.LotSize, req.BaseToken, req.QuoteToken)
+	}
+
+	return nil
 }
 
 func orderTypeFromProto(ot pb.OrderType) matcher.OrderType {
@@ -333,10 +1188,14 @@ func orderTypeFromProto(ot pb.OrderType) matcher.OrderType {
 }
 
 func orderTypeToProto(ot matcher.OrderType) pb.OrderType {
-	if ot == matcher.OrderTypeBuy {
+	switch ot {
+	case matcher.OrderTypeBuy:
 		return pb.OrderType_ORDER_TYPE_BUY
+	case matcher.OrderTypeSell:
+		return pb.OrderType_ORDER_TYPE_SELL
+	default:
+		return pb.OrderType_ORDER_TYPE_UNSPECIFIED
 	}
-	return pb.OrderType_ORDER_TYPE_SELL
 }
 
 func orderStatusToProto(os matcher.OrderStatus) pb.OrderStatus {
@@ -376,6 +1235,36 @@ func orderToProto(o *matcher.Order) *pb.Order {
 		Status:            orderStatusToProto(o.Status),
 		CreatedAt:         timestamppb.New(o.CreatedAt),
 		ExpiresAt:         timestamppb.New(o.ExpiresAt),
+
+		CounterpartyAllowlist: o.CounterpartyAllowlist,
+		PoolId:                o.PoolID,
+		RemainderPolicy:       remainderPolicyToProto(o.RemainderPolicy),
+		NoFillDeadline:        optionalTimestamp(o.NoFillDeadline),
+		Metadata:              o.Metadata,
+	}
+}
+
+func remainderPolicyFromProto(p pb.RemainderPolicy) matcher.RemainderPolicy {
+	switch p {
+	case pb.RemainderPolicy_REMAINDER_POLICY_CANCEL:
+		return matcher.RemainderCancel
+	case pb.RemainderPolicy_REMAINDER_POLICY_REPEG:
+		return matcher.RemainderRepeg
+	default:
+		return matcher.RemainderRest
+	}
+}
+
+func remainderPolicyToProto(p matcher.RemainderPolicy) pb.RemainderPolicy {
+	switch p {
+	case matcher.RemainderRest:
+		return pb.RemainderPolicy_REMAINDER_POLICY_REST
+	case matcher.RemainderCancel:
+		return pb.RemainderPolicy_REMAINDER_POLICY_CANCEL
+	case matcher.RemainderRepeg:
+		return pb.RemainderPolicy_REMAINDER_POLICY_REPEG
+	default:
+		return pb.RemainderPolicy_REMAINDER_POLICY_UNSPECIFIED
 	}
 }
 
@@ -392,6 +1281,15 @@ func matchToProto(m *matcher.Match) *pb.Match {
 		MatchedAt:        timestamppb.New(m.MatchedAt),
 		BuyerAddress:     m.BuyerAddress,
 		SellerAddress:    m.SellerAddress,
+		BuyMetadata:      m.BuyMetadata,
+		SellMetadata:     m.SellMetadata,
+		TakerSide:        orderTypeToProto(m.TakerSide),
+		BuyIsMaker:       m.IsMaker(matcher.OrderTypeBuy),
+		SellIsMaker:      m.IsMaker(matcher.OrderTypeSell),
+		TakerFee:         m.TakerFee.String(),
+		MakerRebate:      m.MakerRebate.String(),
+		VenueFee:         m.VenueFee().String(),
+		RoundedPrice:     m.RoundedPrice,
 	}
 }
 
@@ -410,13 +1308,15 @@ func settlementStatusToProto(status string) pb.SettlementStatus {
 	}
 }
 
-func buildPriceLevels(orders []*matcher.Order, depth int) []*pb.PriceLevel {
+// buildPriceLevels aggregates orders by their displayed price, so levels that
+// differ only beyond priceDecimals are merged
+func buildPriceLevels(orders []*matcher.Order, depth int, priceDecimals int) []*pb.PriceLevel {
 	// Aggregate orders by price
 	priceMap := make(map[string]*pb.PriceLevel)
 	prices := make([]string, 0)
 
 	for _, order := range orders {
-		priceStr := order.Price.String()
+		priceStr := formatDisplayPrice(order.Price, priceDecimals)
 
 		if level, exists := priceMap[priceStr]; exists {
 			qty, _ := decimal.NewFromString(level.Quantity)
@@ -445,9 +1345,71 @@ func buildPriceLevels(orders []*matcher.Order, depth int) []*pb.PriceLevel {
 	return result
 }
 
-func nullTimeOrValue(t time.Time) interface{} {
+// uncrossLevels nets out bid and ask levels that overlap, best first, as if
+// the crossing quantity had traded. Any two orders whose prices cross are
+// price-compatible, but they can still rest together when something else keeps
+// them apart (a counterparty allowlist, match size limits, a failed fill), and
+// the displayed book must never show best bid >= best ask.
+func uncrossLevels(bids, asks []*pb.PriceLevel) ([]*pb.PriceLevel, []*pb.PriceLevel) {
+	for len(bids) > 0 && len(asks) > 0 {
+		bidPrice, _ := decimal.NewFromString(bids[0].Price)
+		askPrice, _ := decimal.NewFromString(asks[0].Price)
+		if bidPrice.LessThan(askPrice) {
+			break
+		}
+
+		bidQty, _ := decimal.NewFromString(bids[0].Quantity)
+		askQty, _ := decimal.NewFromString(asks[0].Quantity)
+		netted := decimal.Min(bidQty, askQty)
+
+		// Order counts are left as they are: the orders behind a partly netted level still rest
+		if bidQty.Equal(netted) {
+			bids = bids[1:]
+		} else {
+			bids[0].Quantity = bidQty.Sub(netted).String()
+		}
+		if askQty.Equal(netted) {
+			asks = asks[1:]
+		} else {
+			asks[0].Quantity = askQty.Sub(netted).String()
+		}
+	}
+	return bids, asks
+}
+
+// cumulativeDepth turns price levels, best first, into running quantity totals
+func cumulativeDepth(levels []*pb.PriceLevel) []*pb.DepthPoint {
+	points := make([]*pb.DepthPoint, 0, len(levels))
+	total := decimal.Zero
+	for _, level := range levels {
+		// Quantities hidden by BookDisplayPolicy stay hidden
+		if level.Quantity == "" {
+			points = append(points, &pb.DepthPoint{Price: level.Price})
+			continue
+		}
+		qty, _ := decimal.NewFromString(level.Quantity)
+		total = total.Add(qty)
+		points = append(points, &pb.DepthPoint{
+			Price:              level.Price,
+			CumulativeQuantity: total.String(),
+		})
+	}
+	return points
+}
+
+// formatDisplayPrice rounds a price for display-facing responses.
+// A negative places keeps full precision; stored and settlement values are never rounded.
+func formatDisplayPrice(price decimal.Decimal, places int) string {
+	if places < 0 {
+		return price.String()
+	}
+	return price.StringFixed(int32(places))
+}
+
+// optionalTimestamp converts a time to a proto timestamp, leaving zero times unset
+func optionalTimestamp(t time.Time) *timestamppb.Timestamp {
 	if t.IsZero() {
 		return nil
 	}
-	return t
+	return timestamppb.New(t)
 }
@@ -0,0 +1,251 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/darkpool/warlock/internal/matcher"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// testConfig loads the default configuration; the database URL is required
+// but never dialed
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	t.Setenv("DATABASE_URL", "postgres://unused")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	return cfg
+}
+
+// newTestServer returns a server over a started engine on a MemoryStore,
+// with no database
+func newTestServer(t *testing.T, cfg *config.Config) (*Server, *matcher.MemoryStore) {
+	t.Helper()
+	return newClockedTestServer(t, cfg, matcher.SystemClock{})
+}
+
+// newClockedTestServer is newTestServer with the engine on clock
+func newClockedTestServer(t *testing.T, cfg *config.Config, clock matcher.Clock) (*Server, *matcher.MemoryStore) {
+	t.Helper()
+	store := matcher.NewMemoryStore()
+	engine := matcher.NewEngine(nil, cfg)
+	engine.SetStore(store)
+	engine.SetClock(clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := engine.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		engine.Stop()
+		cancel()
+	})
+	return NewServer(engine, nil, cfg), store
+}
+
+// orderRequest is a valid synchronous submission on the WETH/USDC pair
+func orderRequest(user string, side pb.OrderType, quantity, price string) *pb.SubmitOrderRequest {
+	return &pb.SubmitOrderRequest{
+		UserAddress: user,
+		ChainId:     1,
+		OrderType:   side,
+		BaseToken:   "WETH",
+		QuoteToken:  "USDC",
+		Quantity:    quantity,
+		Price:       price,
+		VarianceBps: 100,
+		Synchronous: true,
+	}
+}
+
+// storedOrder reads the stored copy of an order
+func storedOrder(t *testing.T, store matcher.Store, id string) *matcher.Order {
+	t.Helper()
+	orders, err := store.LoadOrders(context.Background(), []string{id})
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("LoadOrders(%s) = %v, %v", id, orders, err)
+	}
+	return orders[0]
+}
+
+func TestCancelReplaceSwapsOrdersAtomically(t *testing.T) {
+	s, store := newTestServer(t, testConfig(t))
+	ctx := context.Background()
+
+	original, err := s.SubmitOrder(ctx, orderRequest("0xalice", pb.OrderType_ORDER_TYPE_SELL, "5", "100"))
+	if err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+	originalID := original.Order.Id
+
+	resp, err := s.CancelReplace(ctx, &pb.CancelReplaceRequest{
+		OrderId:     originalID,
+		UserAddress: "0xalice",
+		NewOrder:    orderRequest("0xalice", pb.OrderType_ORDER_TYPE_SELL, "3", "101"),
+	})
+	if err != nil {
+		t.Fatalf("CancelReplace: %v", err)
+	}
+	if !resp.Cancel.Success {
+		t.Errorf("cancel leg reported failure: %s", resp.Cancel.Message)
+	}
+
+	if got := storedOrder(t, store, originalID); got.Status != matcher.OrderStatusCancelled {
+		t.Errorf("original status %s, want CANCELLED", got.Status)
+	}
+	replacement := storedOrder(t, store, resp.Submit.Order.Id)
+	if replacement.Status != matcher.OrderStatusRevealed || replacement.Price.String() != "101" {
+		t.Errorf("replacement %s at %s, want REVEALED at 101", replacement.Status, replacement.Price)
+	}
+
+	book, err := s.GetOrderBook(ctx, &pb.GetOrderBookRequest{BaseToken: "WETH", QuoteToken: "USDC"})
+	if err != nil {
+		t.Fatalf("GetOrderBook: %v", err)
+	}
+	if len(book.Asks) != 1 || book.Asks[0].Price != "101" {
+		t.Errorf("asks %v, want only the replacement at 101", book.Asks)
+	}
+}
+
+func TestCancelReplaceRollsBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		orderID  func(original string) string
+		user     string
+		newOrder *pb.SubmitOrderRequest
+		code     codes.Code
+	}{
+		{
+			name:     "invalid replacement",
+			orderID:  func(original string) string { return original },
+			user:     "0xalice",
+			newOrder: orderRequest("0xalice", pb.OrderType_ORDER_TYPE_SELL, "0", "101"),
+			code:     codes.InvalidArgument,
+		},
+		{
+			name:     "replacement for another user",
+			orderID:  func(original string) string { return original },
+			user:     "0xalice",
+			newOrder: orderRequest("0xbob", pb.OrderType_ORDER_TYPE_SELL, "3", "101"),
+			code:     codes.InvalidArgument,
+		},
+		{
+			name:     "order of another user",
+			orderID:  func(original string) string { return original },
+			user:     "0xbob",
+			newOrder: orderRequest("0xbob", pb.OrderType_ORDER_TYPE_SELL, "3", "101"),
+			code:     codes.FailedPrecondition,
+		},
+		{
+			name:     "unknown order",
+			orderID:  func(string) string { return "00000000-0000-0000-0000-000000000000" },
+			user:     "0xalice",
+			newOrder: orderRequest("0xalice", pb.OrderType_ORDER_TYPE_SELL, "3", "101"),
+			code:     codes.FailedPrecondition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store := newTestServer(t, testConfig(t))
+			ctx := context.Background()
+
+			original, err := s.SubmitOrder(ctx, orderRequest("0xalice", pb.OrderType_ORDER_TYPE_SELL, "5", "100"))
+			if err != nil {
+				t.Fatalf("SubmitOrder: %v", err)
+			}
+
+			_, err = s.CancelReplace(ctx, &pb.CancelReplaceRequest{
+				OrderId:     tt.orderID(original.Order.Id),
+				UserAddress: tt.user,
+				NewOrder:    tt.newOrder,
+			})
+			if status.Code(err) != tt.code {
+				t.Fatalf("CancelReplace: %v, want %s", err, tt.code)
+			}
+
+			// Neither leg happened: the original still rests, alone
+			if got := storedOrder(t, store, original.Order.Id); got.Status != matcher.OrderStatusRevealed {
+				t.Errorf("original status %s, want REVEALED", got.Status)
+			}
+			active, err := store.LoadActiveOrders(ctx, s.engine.Now(), "", "")
+			if err != nil || len(active) != 1 {
+				t.Errorf("%d active orders (%v), want only the original", len(active), err)
+			}
+			book, err := s.GetOrderBook(ctx, &pb.GetOrderBookRequest{BaseToken: "WETH", QuoteToken: "USDC"})
+			if err != nil {
+				t.Fatalf("GetOrderBook: %v", err)
+			}
+			if len(book.Asks) != 1 || book.Asks[0].Price != "100" {
+				t.Errorf("asks %v, want only the original at 100", book.Asks)
+			}
+		})
+	}
+}
+
+func TestSubmitOrderModes(t *testing.T) {
+	// Long enough that a submission paying it would blow the deadline below
+	defer func(delay time.Duration) { orderVisibilityDelay = delay }(orderVisibilityDelay)
+	orderVisibilityDelay = 2 * time.Second
+
+	tests := []struct {
+		name        string
+		synchronous bool
+		maxLatency  time.Duration
+	}{
+		{name: "synchronous", synchronous: true, maxLatency: time.Second},
+		{name: "asynchronous", synchronous: false, maxLatency: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, store := newTestServer(t, testConfig(t))
+			ctx := context.Background()
+
+			if _, err := s.SubmitOrder(ctx, orderRequest("0xalice", pb.OrderType_ORDER_TYPE_SELL, "5", "100")); err != nil {
+				t.Fatalf("SubmitOrder: %v", err)
+			}
+
+			req := orderRequest("0xbob", pb.OrderType_ORDER_TYPE_BUY, "2", "100")
+			req.Synchronous = tt.synchronous
+			start := time.Now()
+			resp, err := s.SubmitOrder(ctx, req)
+			elapsed := time.Since(start)
+			if err != nil {
+				t.Fatalf("SubmitOrder: %v", err)
+			}
+			if elapsed > tt.maxLatency {
+				t.Errorf("took %s, want under %s", elapsed, tt.maxLatency)
+			}
+			if !tt.synchronous && elapsed < orderVisibilityDelay {
+				t.Errorf("took %s, want the %s visibility wait", elapsed, orderVisibilityDelay)
+			}
+
+			if tt.synchronous {
+				if len(resp.ImmediateMatches) != 1 || resp.Order.Status != pb.OrderStatus_ORDER_STATUS_FILLED {
+					t.Fatalf("got %d immediate matches with status %s, want 1 and FILLED", len(resp.ImmediateMatches), resp.Order.Status)
+				}
+				return
+			}
+
+			if len(resp.ImmediateMatches) != 0 {
+				t.Errorf("asynchronous submission returned %d immediate matches", len(resp.ImmediateMatches))
+			}
+			deadline := time.Now().Add(5 * time.Second)
+			for storedOrder(t, store, resp.Order.Id).Status != matcher.OrderStatusFilled {
+				if time.Now().After(deadline) {
+					t.Fatal("asynchronous order was never matched")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package grpc
+
+import (
+	"context"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GetServerTime reports the clock order expiries are judged by, so clients
+// can correct for their own skew when choosing expires_in_seconds
+func (s *Server) GetServerTime(ctx context.Context, req *pb.GetServerTimeRequest) (*pb.GetServerTimeResponse, error) {
+	return &pb.GetServerTimeResponse{
+		ServerTime:                 timestamppb.New(s.engine.Now()),
+		ExpiryClockSkewToleranceMs: s.cfg.ExpiryClockSkewTolerance.Milliseconds(),
+	}, nil
+}
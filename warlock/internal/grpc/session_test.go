@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// mockClock is a matcher.Clock that only moves when set
+type mockClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *mockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *mockClock) Set(now time.Time) {
+	c.mu.Lock()
+	c.now = now
+	c.mu.Unlock()
+}
+
+func TestMarketHoursFollowEngineClock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "warlock.yaml")
+	yaml := "market_hours:\n  WETH/USDC:\n    reject_closed: true\n    windows: [{open: \"09:00\", close: \"17:00\"}]\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+
+	// A Monday; the window closes at 17:00 sharp
+	closing := time.Date(2026, time.October, 12, 17, 0, 0, 0, time.UTC)
+	clock := &mockClock{}
+	s, _ := newClockedTestServer(t, testConfig(t), clock)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		now  time.Time
+		open bool
+	}{
+		{name: "before the open", now: closing.Add(-8*time.Hour - time.Nanosecond), open: false},
+		{name: "at the open", now: closing.Add(-8 * time.Hour), open: true},
+		{name: "just before the close", now: closing.Add(-time.Nanosecond), open: true},
+		{name: "at the close", now: closing, open: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock.Set(tt.now)
+
+			session, err := s.GetMarketSession(ctx, &pb.GetMarketSessionRequest{BaseToken: "WETH", QuoteToken: "USDC"})
+			if err != nil {
+				t.Fatalf("GetMarketSession: %v", err)
+			}
+			if session.Open != tt.open {
+				t.Errorf("GetMarketSession open = %t, want %t", session.Open, tt.open)
+			}
+
+			_, err = s.SubmitOrder(ctx, orderRequest("0xalice", pb.OrderType_ORDER_TYPE_SELL, "1", "100"))
+			if tt.open && err != nil {
+				t.Errorf("SubmitOrder while open: %v", err)
+			}
+			if !tt.open && status.Code(err) != codes.FailedPrecondition {
+				t.Errorf("SubmitOrder while closed: %v, want FailedPrecondition", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/darkpool/warlock/internal/matcher"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// standbyCheckDepth is how many levels per side the standby compares with the
+// primary's
+const standbyCheckDepth = 100
+
+// primaryOnlyMethods are the RPCs that write orders, which only the primary
+// may serve
+var primaryOnlyMethods = map[string]bool{
+	pb.MatcherService_SubmitOrder_FullMethodName:       true,
+	pb.MatcherService_CancelOrder_FullMethodName:       true,
+	pb.MatcherService_CancelReplace_FullMethodName:     true,
+	pb.MatcherService_CancelOrdersWhere_FullMethodName: true,
+	pb.MatcherService_ImportOrders_FullMethodName:      true,
+}
+
+// rejectOnStandby turns away order writes while the engine is a standby, so
+// nothing reaches the database that the primary doesn't know about
+func (s *Server) rejectOnStandby(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if primaryOnlyMethods[info.FullMethod] && s.engine.Standby() {
+		return nil, status.Errorf(codes.FailedPrecondition, "this engine is a standby; send orders to the primary")
+	}
+	return handler(ctx, req)
+}
+
+// GetStandbyStatus reports the engine's role and how closely a standby
+// follows the primary
+func (s *Server) GetStandbyStatus(ctx context.Context, req *pb.GetStandbyStatusRequest) (*pb.GetStandbyStatusResponse, error) {
+	resp := &pb.GetStandbyStatusResponse{
+		Standby:     s.engine.Standby(),
+		PrimaryAddr: s.cfg.PrimaryAddr,
+	}
+	if !resp.Standby {
+		return resp, nil
+	}
+
+	resp.LastReplicatedAt = timestamppb.New(s.engine.LastReplicated())
+	checkedAt, mismatches := s.standby.snapshot()
+	if !checkedAt.IsZero() {
+		resp.LastCheckedAt = timestamppb.New(checkedAt)
+	}
+	resp.Mismatches = mismatches
+	return resp, nil
+}
+
+// PromoteStandby makes a standby the primary
+func (s *Server) PromoteStandby(ctx context.Context, req *pb.PromoteStandbyRequest) (*pb.PromoteStandbyResponse, error) {
+	if !req.Confirm {
+		return nil, status.Errorf(codes.FailedPrecondition, "promoting while the primary still runs leaves two engines matching; set confirm to proceed")
+	}
+
+	log.Warn().Msg("Received PromoteStandby request")
+
+	if err := s.engine.Promote(ctx); err != nil {
+		if errors.Is(err, matcher.ErrNotStandby) {
+			return nil, status.Errorf(codes.FailedPrecondition, "engine is already the primary")
+		}
+		return nil, status.Errorf(codes.Unavailable, "%v", err)
+	}
+	return &pb.PromoteStandbyResponse{}, nil
+}
+
+// standbyChecker compares a standby's book checksums with the primary's. A
+// book may differ for up to STANDBY_SYNC_INTERVAL while a change replicates,
+// so one mismatch is only logged at debug; a book still differing at the
+// next comparison is logged as a warning.
+type standbyChecker struct {
+	mu         sync.Mutex
+	checkedAt  time.Time
+	mismatches map[string]*pb.BookMismatch
+}
+
+func newStandbyChecker() *standbyChecker {
+	return &standbyChecker{mismatches: make(map[string]*pb.BookMismatch)}
+}
+
+// snapshot returns the last comparison time and the books that differed then
+func (c *standbyChecker) snapshot() (time.Time, []*pb.BookMismatch) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	mismatches := make([]*pb.BookMismatch, 0, len(c.mismatches))
+	for _, m := range c.mismatches {
+		mismatches = append(mismatches, &pb.BookMismatch{
+			PoolId:          m.PoolId,
+			BaseToken:       m.BaseToken,
+			QuoteToken:      m.QuoteToken,
+			StandbyChecksum: m.StandbyChecksum,
+			PrimaryChecksum: m.PrimaryChecksum,
+			Consecutive:     m.Consecutive,
+		})
+	}
+	return c.checkedAt, mismatches
+}
+
+// checkPrimary compares checksums with the primary every
+// StandbyCheckInterval until the engine is promoted
+func (s *Server) checkPrimary() {
+	conn, err := grpc.Dial(s.cfg.PrimaryAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		log.Error().Err(err).Str("primary_addr", s.cfg.PrimaryAddr).Msg("Failed to connect to the primary; checksums won't be compared")
+		return
+	}
+	defer conn.Close()
+	primary := pb.NewMatcherServiceClient(conn)
+
+	ticker := time.NewTicker(s.cfg.StandbyCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.engine.Standby() {
+			return
+		}
+		s.compareWithPrimary(primary)
+	}
+}
+
+// compareWithPrimary compares every book the standby holds with the
+// primary's copy. Books only the primary holds aren't seen, though their
+// orders reach the standby on its next sync.
+func (s *Server) compareWithPrimary(primary pb.MatcherServiceClient) {
+	seen := make(map[string]*pb.BookMismatch)
+	for _, book := range s.engine.BookSizes() {
+		req := &pb.GetBookChecksumRequest{
+			BaseToken:  book.BaseToken,
+			QuoteToken: book.QuoteToken,
+			PoolId:     book.PoolID,
+			Depth:      standbyCheckDepth,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.ReadQueryTimeout)
+		remote, err := primary.GetBookChecksum(ctx, req)
+		cancel()
+		if err != nil {
+			log.Warn().Err(err).Str("primary_addr", s.cfg.PrimaryAddr).Msg("Failed to fetch the primary's book checksum")
+			return
+		}
+		local, err := s.GetBookChecksum(context.Background(), req)
+		if err != nil {
+			return
+		}
+		if local.Checksum == remote.Checksum {
+			continue
+		}
+
+		key := book.PoolID + "|" + book.BaseToken + "/" + book.QuoteToken
+		seen[key] = &pb.BookMismatch{
+			PoolId:          book.PoolID,
+			BaseToken:       book.BaseToken,
+			QuoteToken:      book.QuoteToken,
+			StandbyChecksum: local.Checksum,
+			PrimaryChecksum: remote.Checksum,
+			Consecutive:     1,
+		}
+	}
+
+	s.standby.mu.Lock()
+	defer s.standby.mu.Unlock()
+
+	for key, m := range seen {
+		if previous, ok := s.standby.mismatches[key]; ok {
+			m.Consecutive = previous.Consecutive + 1
+		}
+		event := log.Debug()
+		if m.Consecutive > 1 {
+			event = log.Warn()
+		}
+		event.Str("pool_id", m.PoolId).
+			Str("base_token", m.BaseToken).
+			Str("quote_token", m.QuoteToken).
+			Uint32("standby_checksum", m.StandbyChecksum).
+			Uint32("primary_checksum", m.PrimaryChecksum).
+			Int32("consecutive", m.Consecutive).
+			Msg("Standby book differs from the primary's")
+	}
+	s.standby.mismatches = seen
+	s.standby.checkedAt = time.Now()
+}
@@ -0,0 +1,86 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// streamRegistry counts the streaming subscriptions each client holds open,
+// so one client can't tie up server goroutines with thousands of streams
+type streamRegistry struct {
+	mu    sync.Mutex
+	limit int            // 0 disables the limit
+	open  map[string]int // client -> open streams
+}
+
+func newStreamRegistry(limit int) *streamRegistry {
+	return &streamRegistry{
+		limit: limit,
+		open:  make(map[string]int),
+	}
+}
+
+// acquire reserves a stream for client, reporting false if it already holds
+// the limit
+func (r *streamRegistry) acquire(client string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.limit > 0 && r.open[client] >= r.limit {
+		return false
+	}
+	r.open[client]++
+	return true
+}
+
+// release returns a stream reserved by acquire
+func (r *streamRegistry) release(client string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.open[client] <= 1 {
+		delete(r.open, client)
+		return
+	}
+	r.open[client]--
+}
+
+// streamClient identifies the caller of a stream: the subject of its TLS
+// client certificate when it presented one, otherwise its IP address. The
+// port is dropped since every connection from a host gets a new one.
+func streamClient(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return "unknown"
+	}
+
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		if certs := tlsInfo.State.PeerCertificates; len(certs) > 0 {
+			return "cert:" + certs[0].Subject.String()
+		}
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// acquireStream reserves a stream slot for the caller, failing with
+// RESOURCE_EXHAUSTED once it holds MaxStreamsPerClient. The returned func
+// frees the slot and must be called when the stream ends.
+func (s *Server) acquireStream(ctx context.Context) (func(), error) {
+	client := streamClient(ctx)
+	if !s.streams.acquire(client) {
+		return nil, status.Errorf(codes.ResourceExhausted,
+			"client %s already has %d open streams", client, s.cfg.MaxStreamsPerClient)
+	}
+	return func() { s.streams.release(client) }, nil
+}
@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/darkpool/warlock/internal/matcher"
+	pb "github.com/darkpool/warlock/pkg/api/proto"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// StreamTicker pushes a pair's last trade and best bid/ask, first as a
+// snapshot and then whenever a trade prints or the top of book moves. Changes
+// are coalesced so updates are at least TickerMinInterval apart. Trades count
+// once they'd appear on the public StreamMatches feed, so the ticker never
+// leaks a print before TradePrintDelay.
+func (s *Server) StreamTicker(req *pb.StreamTickerRequest, stream pb.MatcherService_StreamTickerServer) error {
+	req.BaseToken = normalizeToken(req.BaseToken)
+	req.QuoteToken = normalizeToken(req.QuoteToken)
+	req.PoolId = strings.TrimSpace(req.PoolId)
+
+	if req.BaseToken == "" || req.QuoteToken == "" {
+		return status.Errorf(codes.InvalidArgument, "base_token and quote_token are required")
+	}
+
+	release, err := s.acquireStream(stream.Context())
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	log.Info().
+		Str("base_token", req.BaseToken).
+		Str("quote_token", req.QuoteToken).
+		Msg("Client connected to StreamTicker")
+
+	// Subscribe before reading the last trade so nothing recorded meanwhile is missed
+	sub, _ := s.matches.subscribe(0)
+	defer s.matches.unsubscribe(sub)
+
+	ticker := &pb.Ticker{BaseToken: req.BaseToken, QuoteToken: req.QuoteToken}
+	if err := s.loadLastTrade(stream.Context(), ticker); err != nil {
+		return err
+	}
+	s.readTopOfBook(req, ticker)
+
+	send := func() error {
+		ticker.Timestamp = timestamppb.Now()
+		if err := stream.Send(ticker); err != nil {
+			log.Error().Err(err).Msg("Failed to send ticker")
+			return err
+		}
+		return nil
+	}
+	if err := send(); err != nil {
+		return err
+	}
+
+	poll := time.NewTicker(s.cfg.TickerMinInterval)
+	defer poll.Stop()
+
+	var pending []delayedPrint
+	traded := false
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Info().Msg("Client disconnected from StreamTicker")
+			return nil
+
+		case <-sub.dropped:
+			return status.Errorf(codes.Aborted,
+				"stream fell more than %d matches behind; reconnect", s.cfg.MaxStreamBacklog)
+
+		case match := <-sub.matches:
+			if match.BaseToken != req.BaseToken || match.QuoteToken != req.QuoteToken {
+				continue
+			}
+			pending = append(pending, delayedPrint{match: match, releaseAt: match.MatchedAt.Add(s.cfg.TradePrintDelay)})
+
+		case <-poll.C:
+			now := time.Now()
+			for len(pending) > 0 && !pending[0].releaseAt.After(now) {
+				s.applyTrade(ticker, pending[0].match)
+				pending = pending[1:]
+				traded = true
+			}
+			moved := s.readTopOfBook(req, ticker)
+			if !traded && !moved {
+				continue
+			}
+			traded = false
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// loadLastTrade fills in the pair's most recent public trade
+func (s *Server) loadLastTrade(ctx context.Context, ticker *pb.Ticker) error {
+	queryCtx, cancel := context.WithTimeout(ctx, s.cfg.ReadQueryTimeout)
+	defer cancel()
+
+	var quantity, price string
+	var matchedAt time.Time
+	err := s.db.QueryRow(queryCtx, `
+		SELECT quantity::text, price::text, matched_at
+		FROM matches
+		WHERE base_token = $1
+		  AND quote_token = $2
+		  AND settlement_status <> 'FAILED'
+		  AND matched_at <= $3
+		ORDER BY seq DESC
+		LIMIT 1
+	`, ticker.BaseToken, ticker.QuoteToken, time.Now().Add(-s.cfg.TradePrintDelay)).Scan(&quantity, &price, &matchedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to query last trade for ticker")
+		return queryStatus(err, "failed to query last trade")
+	}
+
+	match := &matcher.Match{MatchedAt: matchedAt}
+	match.Quantity, _ = decimal.NewFromString(quantity)
+	match.Price, _ = decimal.NewFromString(price)
+	s.applyTrade(ticker, match)
+	return nil
+}
+
+// applyTrade records a trade as the ticker's last
+func (s *Server) applyTrade(ticker *pb.Ticker, match *matcher.Match) {
+	ticker.LastPrice = formatDisplayPrice(match.Price, s.cfg.DisplayPriceDecimals)
+	ticker.LastQuantity = match.Quantity.String()
+	ticker.LastTradeAt = timestamppb.New(match.MatchedAt)
+}
+
+// readTopOfBook refreshes the best bid and ask from the in-memory book,
+// reporting whether either changed. Books displayed with the NONE policy
+// leave them empty.
+func (s *Server) readTopOfBook(req *pb.StreamTickerRequest, ticker *pb.Ticker) bool {
+	var bid, ask string
+	book := s.engine.GetOrderBook(req.PoolId, req.BaseToken, req.QuoteToken)
+	if book != nil && s.cfg.BookDisplayPolicy != config.BookDisplayNone {
+		if best := book.PeekBestBid(); best != nil {
+			bid = formatDisplayPrice(best.Price, s.cfg.DisplayPriceDecimals)
+		}
+		if best := book.PeekBestAsk(); best != nil {
+			ask = formatDisplayPrice(best.Price, s.cfg.DisplayPriceDecimals)
+		}
+	}
+
+	moved := bid != ticker.BestBid || ask != ticker.BestAsk
+	ticker.BestBid, ticker.BestAsk = bid, ask
+	return moved
+}
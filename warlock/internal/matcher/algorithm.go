@@ -2,39 +2,72 @@ package matcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/darkpool/warlock/internal/config"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 	"github.com/shopspring/decimal"
 )
 
 // Match represents an executed trade
 type Match struct {
-	ID               string
-	BuyOrderID       string
-	SellOrderID      string
-	BaseToken        string
-	QuoteToken       string
-	Quantity         decimal.Decimal
-	Price            decimal.Decimal
-	SettlementStatus string
-	MatchedAt        time.Time
-	BuyerAddress     string
-	SellerAddress    string
+	ID                 string
+	Seq                int64 // Publication order, reserved by the engine; backs StreamMatches resume tokens
+	BuyOrderID         string
+	SellOrderID        string
+	BaseToken          string
+	QuoteToken         string
+	Quantity           decimal.Decimal
+	Price              decimal.Decimal
+	SettlementStatus   string
+	SettlementDeadline time.Time // Zero when no deadline is configured
+	MatchedAt          time.Time
+	BuyerAddress       string
+	SellerAddress      string
+	BuyMetadata        map[string]string
+	SellMetadata       map[string]string
+
+	// Fees in the quote token; see matchFees. TakerSide is empty for matches
+	// recorded before fees were tracked.
+	TakerSide   OrderType
+	TakerFee    decimal.Decimal
+	MakerRebate decimal.Decimal
+
+	// The bands only met once rounded to PriceSignificantDigits, so the
+	// match executed at the price both limits round to, outside one side's
+	// unrounded band
+	RoundedPrice bool
 }
 
 // MatchResult contains the results of matching an order
 type MatchResult struct {
 	Matches      []*Match
 	UpdatedOrder *Order
+
+	// The last match that failed to execute, if any. Those candidates are
+	// skipped and the remaining ones still tried.
+	ExecutionErr error
+
+	// When matching was put off rather than attempted, the time to try again
+	DeferredUntil time.Time
+
+	// Why the order's remainder was cancelled instead of left to rest, if it was
+	Rejected string
 }
 
+// errClaimCommit means a MatchSkipLocked claim failed to commit after its
+// fills were applied to the book
+var errClaimCommit = errors.New("failed to commit match claim")
+
 // MatchOrder attempts to match an incoming order against the order book
-// Returns any matches and the updated order
-func MatchOrder(ctx context.Context, db *pgxpool.Pool, orderBook *OrderBook, incomingOrder *Order) (*MatchResult, error) {
+// Returns any matches and the updated order. Orders on pausedChains neither
+// match nor serve as candidates, and candidates expired as of now are skipped.
+func MatchOrder(ctx context.Context, store Store, cfg *config.Config, orderBook *OrderBook, stats *EngineStats, incomingOrder *Order, pausedChains []int32, now time.Time) (*MatchResult, error) {
 	result := &MatchResult{
 		Matches:      make([]*Match, 0),
 		UpdatedOrder: incomingOrder,
@@ -45,11 +78,73 @@ func MatchOrder(ctx context.Context, db *pgxpool.Pool, orderBook *OrderBook, inc
 		return result, nil
 	}
 
+	// Orders on a paused chain rest until it resumes
+	if slices.Contains(pausedChains, incomingOrder.ChainID) {
+		log.Info().
+			Str("order_id", incomingOrder.ID).
+			Int32("chain_id", incomingOrder.ChainID).
+			Msg("Chain paused, order rests without matching")
+		return result, nil
+	}
+
+	// Pairs with market hours only cross while their session is open; the
+	// order rests and is tried again at the next open
+	if session := cfg.SessionFor(incomingOrder.BaseToken, incomingOrder.QuoteToken); session != nil {
+		if open, next := session.State(now); !open {
+			log.Info().
+				Str("order_id", incomingOrder.ID).
+				Time("next_open", next).
+				Msg("Market session closed, order rests until the next open")
+			result.DeferredUntil = next
+			return result, nil
+		}
+	}
+
+	// Pairs may override the global execution price mode, tie-break and ranking
+	cfg = cfg.ForPair(incomingOrder.BaseToken, incomingOrder.QuoteToken)
+
+	// With MatchSkipLocked the candidates are claimed until every match is
+	// recorded, so other workers skip them. Its fills only count toward the
+	// matched volume once the claim commits.
+	var scope MatchScope = store
+	var claim Claim
+	fillStats := stats
+	if cfg.MatchSkipLocked {
+		var err error
+		claim, err = store.BeginClaim(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin match claim: %w", err)
+		}
+		defer claim.Rollback(ctx)
+		scope, fillStats = claim, nil
+	}
+
 	// Find matching candidates from the opposite side
-	candidates, err := findMatchingCandidates(ctx, db, incomingOrder)
+	queryCtx, cancel := context.WithTimeout(ctx, cfg.MatchQueryTimeout)
+	candidates, err := scope.FindCandidates(queryCtx, cfg, incomingOrder, pausedChains, now)
+	cancel()
 	if err != nil {
 		return nil, fmt.Errorf("failed to find matching candidates: %w", err)
 	}
+	// Matches only keep copies of candidate fields, so nothing outlives this call
+	defer releaseOrders(candidates)
+
+	if cfg.PriorityDecayAfter > 0 {
+		sortCandidates(candidates, decayedPriority(cfg.PriorityDecayAfter))
+	}
+	switch cfg.CandidateRanking {
+	case config.CandidateRankingImprovement:
+		rankByPriceImprovement(cfg, incomingOrder, candidates)
+	case config.CandidateRankingTime:
+		earlier := createdBefore
+		if cfg.DeterministicMatching {
+			earlier = sequencedBefore
+		} else if cfg.PriorityDecayAfter > 0 {
+			earlier = decayedPriority(cfg.PriorityDecayAfter)
+		}
+		rankByTimeWithinTolerance(candidates, cfg.CandidateToleranceBps, earlier)
+	}
+	defer incomingOrder.trace.recordUnreached(candidates)
 
 	log.Info().
 		Str("order_id", incomingOrder.ID).
@@ -59,6 +154,41 @@ func MatchOrder(ctx context.Context, db *pgxpool.Pool, orderBook *OrderBook, inc
 		Int("candidates", len(candidates)).
 		Msg("Found matching candidates")
 
+	if cfg.ExecutionPriceMode == config.ExecutionPriceVWAP {
+		result.Matches, result.ExecutionErr = matchAtVWAP(ctx, scope, cfg, orderBook, fillStats, now, incomingOrder, candidates)
+	} else {
+		result.Matches, result.ExecutionErr = matchAtMidpoint(ctx, scope, cfg, orderBook, fillStats, now, incomingOrder, candidates)
+	}
+
+	if claim != nil {
+		if err := claim.Commit(ctx); err != nil {
+			// The book already took the fills the store just rolled back;
+			// processOrder rebuilds the pair when it sees errClaimCommit
+			return nil, fmt.Errorf("%w: %w", errClaimCommit, err)
+		}
+		for _, m := range result.Matches {
+			stats.addVolume(m)
+		}
+	}
+
+	// Whatever is left rests on the book and must not narrow the spread too far
+	result.Rejected = enforceMinRestingSpread(ctx, store, cfg, orderBook, incomingOrder)
+
+	return result, nil
+}
+
+// matchAtMidpoint fills the incoming order candidate by candidate, each fill
+// priced on its own by calculateExecutionPrice. The error is the last fill
+// that failed to execute, if any.
+func matchAtMidpoint(ctx context.Context, store MatchScope, cfg *config.Config, book *OrderBook, stats *EngineStats, now time.Time, incomingOrder *Order, candidates []*Order) ([]*Match, error) {
+	if cfg.CounterpartyDiversity {
+		return matchDiversified(ctx, store, cfg, book, stats, now, incomingOrder, candidates)
+	}
+
+	matches := make([]*Match, 0)
+	var execErr error
+	bounds := cfg.BoundsFor(incomingOrder.BaseToken, incomingOrder.QuoteToken)
+
 	// Process each candidate
 	for _, candidate := range candidates {
 		// Check if incoming order is fully filled
@@ -66,42 +196,145 @@ func MatchOrder(ctx context.Context, db *pgxpool.Pool, orderBook *OrderBook, inc
 			break
 		}
 
-		// Check if prices are compatible with variance tolerance
-		compatible := isPriceCompatible(incomingOrder, candidate)
+		if !isCandidateEligible(cfg, incomingOrder, candidate) {
+			continue
+		}
+
+		filled, err := fillCandidate(ctx, store, cfg, book, stats, now, bounds, incomingOrder, candidate, incomingOrder.RemainingQuantity)
+		matches = append(matches, filled...)
+		if err != nil {
+			execErr = err
+		}
+	}
 
+	return matches, execErr
+}
+
+// matchDiversified spreads the incoming order across as many eligible makers
+// as it can. A first pass in priority order gives each maker at most an even
+// share of what is still unfilled, so a maker smaller than its share leaves
+// more for the rest; a second pass fills whatever the shares and rounding
+// left over, in priority order as usual.
+func matchDiversified(ctx context.Context, store MatchScope, cfg *config.Config, book *OrderBook, stats *EngineStats, now time.Time, incomingOrder *Order, candidates []*Order) ([]*Match, error) {
+	matches := make([]*Match, 0)
+	var execErr error
+	bounds := cfg.BoundsFor(incomingOrder.BaseToken, incomingOrder.QuoteToken)
+
+	eligible := make([]*Order, 0, len(candidates))
+	for _, candidate := range candidates {
+		if isCandidateEligible(cfg, incomingOrder, candidate) {
+			eligible = append(eligible, candidate)
+		}
+	}
+
+	for _, capped := range []bool{true, false} {
+		for i, candidate := range eligible {
+			if incomingOrder.RemainingQuantity.IsZero() {
+				break
+			}
+			if candidate.RemainingQuantity.IsZero() {
+				continue
+			}
+
+			limit := incomingOrder.RemainingQuantity
+			if capped {
+				limit = decimal.Min(limit, diversifiedShare(limit, len(eligible)-i, bounds))
+			}
+			filled, err := fillCandidate(ctx, store, cfg, book, stats, now, bounds, incomingOrder, candidate, limit)
+			matches = append(matches, filled...)
+			if err != nil {
+				execErr = err
+			}
+		}
+	}
+
+	log.Debug().
+		Str("order_id", incomingOrder.ID).
+		Int("makers", len(eligible)).
+		Int("matches", len(matches)).
+		Msg("Diversified fill across makers")
+
+	return matches, execErr
+}
+
+// diversifiedShare splits what is left to fill evenly across the makers still
+// to be tried, rounded up to a whole lot and to at least MinMatchSize and
+// MinMakerFill so the share itself is always fillable
+func diversifiedShare(remaining decimal.Decimal, makers int, bounds config.OrderBounds) decimal.Decimal {
+	share := remaining.Div(decimal.NewFromInt(int64(makers)))
+	if bounds.LotSize.IsPositive() {
+		share = share.Div(bounds.LotSize).Ceil().Mul(bounds.LotSize)
+	}
+	return decimal.Max(share, bounds.MinMatchSize, bounds.MinMakerFill)
+}
+
+// fillCandidate fills up to limit of the incoming order against one eligible
+// candidate, split to the pair's match size limits and priced within the
+// overlap of both ranges. The error is the fill that failed to execute, if
+// any; a fill lost to a concurrent worker just stops this candidate.
+func fillCandidate(ctx context.Context, store MatchScope, cfg *config.Config, book *OrderBook, stats *EngineStats, now time.Time, bounds config.OrderBounds, incomingOrder, candidate *Order, limit decimal.Decimal) ([]*Match, error) {
+	// Calculate match quantity, split to the pair's match size limits
+	crossQty := decimal.Min(limit, candidate.RemainingQuantity)
+	fills := splitFill(crossQty, bounds)
+	if len(fills) == 0 {
 		log.Info().
 			Str("incoming_order_id", incomingOrder.ID).
 			Str("candidate_order_id", candidate.ID).
-			Str("incoming_type", string(incomingOrder.OrderType)).
-			Str("candidate_type", string(candidate.OrderType)).
-			Str("incoming_min_price", incomingOrder.MinPrice.String()).
-			Str("incoming_max_price", incomingOrder.MaxPrice.String()).
-			Str("candidate_min_price", candidate.MinPrice.String()).
-			Str("candidate_max_price", candidate.MaxPrice.String()).
-			Bool("price_compatible", compatible).
-			Msg("Checking price compatibility")
-
-		if !compatible {
-			continue
-		}
+			Str("quantity", crossQty.String()).
+			Msg("Skipping match below minimum match size")
+		incomingOrder.trace.record(candidate, TraceBelowMinMatchSize, "quantity "+crossQty.String())
+		return nil, nil
+	}
 
-		// Calculate match quantity
-		matchQty := decimal.Min(incomingOrder.RemainingQuantity, candidate.RemainingQuantity)
+	// Calculate execution price within the overlap of both ranges
+	taker, maker := liquidityRoles(cfg, incomingOrder, candidate)
+	executionPrice := calculateExecutionPrice(taker, maker, cfg.PriceTieBreak, cfg.PriceSignificantDigits)
 
-		// Calculate execution price (average of buy and sell prices)
-		executionPrice := calculateExecutionPrice(incomingOrder, candidate)
+	fills = settleableFills(fills, executionPrice, bounds)
+	if len(fills) == 0 {
+		log.Info().
+			Str("incoming_order_id", incomingOrder.ID).
+			Str("candidate_order_id", candidate.ID).
+			Str("quantity", crossQty.String()).
+			Str("price", executionPrice.String()).
+			Msg("Skipping match below minimum settlement notional")
+		incomingOrder.trace.record(candidate, TraceBelowMinSettlement, "notional "+crossQty.Mul(executionPrice).String())
+		return nil, nil
+	}
+
+	if belowMakerFill(fills, maker, bounds) {
+		log.Info().
+			Str("incoming_order_id", incomingOrder.ID).
+			Str("candidate_order_id", candidate.ID).
+			Str("quantity", sumFills(fills).String()).
+			Msg("Skipping match below minimum maker fill")
+		incomingOrder.trace.record(candidate, TraceBelowMinMakerFill, "quantity "+sumFills(fills).String())
+		return nil, nil
+	}
 
+	matches := make([]*Match, 0, len(fills))
+	for _, matchQty := range fills {
 		// Execute the match in a database transaction
-		match, err := executeMatch(ctx, db, incomingOrder, candidate, matchQty, executionPrice)
+		match, err := executeMatch(ctx, store, cfg, book, stats, now, incomingOrder, candidate, matchQty, executionPrice)
+		if errors.Is(err, ErrDuplicateMatch) {
+			log.Warn().
+				Str("incoming_order_id", incomingOrder.ID).
+				Str("candidate_order_id", candidate.ID).
+				Msg("Skipping duplicate match")
+			incomingOrder.trace.record(candidate, TraceDuplicateMatch, "")
+			return matches, nil
+		}
 		if err != nil {
 			log.Error().Err(err).
 				Str("incoming_order_id", incomingOrder.ID).
 				Str("candidate_order_id", candidate.ID).
 				Msg("Failed to execute match")
-			continue
+			incomingOrder.trace.record(candidate, TraceExecutionFailed, err.Error())
+			return matches, err
 		}
 
-		result.Matches = append(result.Matches, match)
+		matches = append(matches, match)
+		incomingOrder.trace.recordMatch(candidate, match)
 
 		log.Info().
 			Str("match_id", match.ID).
@@ -111,93 +344,172 @@ func MatchOrder(ctx context.Context, db *pgxpool.Pool, orderBook *OrderBook, inc
 			Str("price", executionPrice.String()).
 			Msg("Match executed")
 	}
+	return matches, nil
+}
 
-	return result, nil
+// splitFill breaks the quantity two orders cross by into fills within the
+// pair's match size limits: none larger than MaxMatchSize, each a whole number
+// of lots, and any part smaller than MinMatchSize or one lot left unfilled
+func splitFill(qty decimal.Decimal, bounds config.OrderBounds) []decimal.Decimal {
+	fills := make([]decimal.Decimal, 0, 1)
+	for qty.IsPositive() {
+		fill := qty
+		if bounds.MaxMatchSize.IsPositive() && fill.GreaterThan(bounds.MaxMatchSize) {
+			fill = bounds.MaxMatchSize
+		}
+		fill = bounds.RoundToLot(fill)
+		if !fill.IsPositive() || fill.LessThan(bounds.MinMatchSize) {
+			break
+		}
+		fills = append(fills, fill)
+		qty = qty.Sub(fill)
+	}
+	return fills
 }
 
-// findMatchingCandidates queries the database for potential matching orders
-func findMatchingCandidates(ctx context.Context, db *pgxpool.Pool, order *Order) ([]*Order, error) {
-	var query string
-	var args []interface{}
-
-	if order.OrderType == OrderTypeBuy {
-		// Find SELL orders where sell.min_price <= buy.max_price
-		query = `
-			SELECT id, user_address, chain_id, order_type, base_token, quote_token,
-			       quantity, price, variance_bps, min_price, max_price,
-			       filled_quantity, remaining_quantity, status, created_at, expires_at
-			FROM orders
-			WHERE base_token = $1
-			  AND quote_token = $2
-			  AND order_type = 'SELL'
-			  AND status IN ('REVEALED', 'PARTIALLY_FILLED')
-			  AND min_price <= $3
-			  AND (expires_at IS NULL OR expires_at > NOW())
-			ORDER BY min_price ASC, created_at ASC
-			LIMIT 100
-		`
-		args = []interface{}{order.BaseToken, order.QuoteToken, order.MaxPrice.String()}
-	} else {
-		// Find BUY orders where buy.max_price >= sell.min_price
-		query = `
-			SELECT id, user_address, chain_id, order_type, base_token, quote_token,
-			       quantity, price, variance_bps, min_price, max_price,
-			       filled_quantity, remaining_quantity, status, created_at, expires_at
-			FROM orders
-			WHERE base_token = $1
-			  AND quote_token = $2
-			  AND order_type = 'BUY'
-			  AND status IN ('REVEALED', 'PARTIALLY_FILLED')
-			  AND max_price >= $3
-			  AND (expires_at IS NULL OR expires_at > NOW())
-			ORDER BY max_price DESC, created_at ASC
-			LIMIT 100
-		`
-		args = []interface{}{order.BaseToken, order.QuoteToken, order.MinPrice.String()}
-	}
-
-	rows, err := db.Query(ctx, query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query candidates: %w", err)
+// belowMakerFill reports whether fills add up to less than the pair's
+// MinMakerFill against a maker they would not empty. Keeping dust takers off a
+// maker leaves its quantity for fills worth a match record and a settlement
+// each; a fill that takes the maker's whole remainder is always allowed, so
+// the last of it can still clear.
+func belowMakerFill(fills []decimal.Decimal, maker *Order, bounds config.OrderBounds) bool {
+	if !bounds.MinMakerFill.IsPositive() {
+		return false
 	}
-	defer rows.Close()
+	total := sumFills(fills)
+	return total.LessThan(bounds.MinMakerFill) && total.LessThan(maker.RemainingQuantity)
+}
 
-	candidates := make([]*Order, 0)
-	for rows.Next() {
-		var o Order
-		var quantityStr, priceStr, minPriceStr, maxPriceStr, filledStr, remainingStr string
-		var expiresAt *time.Time
+// sumFills totals the quantities of fills
+func sumFills(fills []decimal.Decimal) decimal.Decimal {
+	total := decimal.Zero
+	for _, fill := range fills {
+		total = total.Add(fill)
+	}
+	return total
+}
 
-		err := rows.Scan(
-			&o.ID, &o.UserAddress, &o.ChainID, &o.OrderType, &o.BaseToken, &o.QuoteToken,
-			&quantityStr, &priceStr, &o.VarianceBPS, &minPriceStr, &maxPriceStr,
-			&filledStr, &remainingStr, &o.Status, &o.CreatedAt, &expiresAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan candidate: %w", err)
+// settleableFills drops fills whose notional at price is below the pair's
+// MinSettlementNotional. The quantity stays on both orders, so it can still
+// fill later against a counterparty large enough to make it worth settling.
+func settleableFills(fills []decimal.Decimal, price decimal.Decimal, bounds config.OrderBounds) []decimal.Decimal {
+	if !bounds.MinSettlementNotional.IsPositive() {
+		return fills
+	}
+	kept := fills[:0]
+	for _, fill := range fills {
+		if fill.Mul(price).GreaterThanOrEqual(bounds.MinSettlementNotional) {
+			kept = append(kept, fill)
 		}
+	}
+	return kept
+}
 
-		// Handle nullable expires_at
-		if expiresAt != nil {
-			o.ExpiresAt = *expiresAt
-		}
+// isCandidateEligible reports whether a candidate may trade with the incoming
+// order at all: the owners must not be the same entity under self-trade
+// prevention, both allowlists must accept the other side and prices must cross
+func isCandidateEligible(cfg *config.Config, incomingOrder, candidate *Order) bool {
+	if cfg.SelfTradePrevention && cfg.SameEntity(incomingOrder.UserAddress, candidate.UserAddress) {
+		log.Info().
+			Str("incoming_order_id", incomingOrder.ID).
+			Str("candidate_order_id", candidate.ID).
+			Msg("Candidate excluded by self-trade prevention")
+		incomingOrder.trace.record(candidate, TraceSelfTrade, "")
+		return false
+	}
+
+	// Both sides must accept each other as counterparties
+	if !counterpartiesAllowed(incomingOrder, candidate) {
+		log.Info().
+			Str("incoming_order_id", incomingOrder.ID).
+			Str("candidate_order_id", candidate.ID).
+			Msg("Candidate excluded by counterparty allowlist")
+		incomingOrder.trace.record(candidate, TraceCounterpartyNotAllowed, "")
+		return false
+	}
 
-		// Parse decimal values
-		o.Quantity, _ = decimal.NewFromString(quantityStr)
-		o.Price, _ = decimal.NewFromString(priceStr)
-		o.MinPrice, _ = decimal.NewFromString(minPriceStr)
-		o.MaxPrice, _ = decimal.NewFromString(maxPriceStr)
-		o.FilledQuantity, _ = decimal.NewFromString(filledStr)
-		o.RemainingQuantity, _ = decimal.NewFromString(remainingStr)
+	// Check if prices are compatible with variance tolerance
+	compatible := isPriceCompatible(incomingOrder, candidate, cfg.PriceSignificantDigits, cfg.MinBandOverlapBps)
 
-		candidates = append(candidates, &o)
+	log.Info().
+		Str("incoming_order_id", incomingOrder.ID).
+		Str("candidate_order_id", candidate.ID).
+		Str("incoming_type", string(incomingOrder.OrderType)).
+		Str("candidate_type", string(candidate.OrderType)).
+		Str("incoming_min_price", incomingOrder.MinPrice.String()).
+		Str("incoming_max_price", incomingOrder.MaxPrice.String()).
+		Str("candidate_min_price", candidate.MinPrice.String()).
+		Str("candidate_max_price", candidate.MaxPrice.String()).
+		Bool("price_compatible", compatible).
+		Msg("Checking price compatibility")
+
+	if !compatible {
+		incomingOrder.trace.record(candidate, TracePriceIncompatible, priceGap(incomingOrder, candidate, cfg.MinBandOverlapBps))
 	}
 
-	return candidates, nil
+	return compatible
+}
+
+// priceGap describes why two orders' price ranges don't cross, or overlap
+// by too little
+func priceGap(order1, order2 *Order, minOverlapBps int) string {
+	buyOrder, sellOrder := order1, order2
+	if order1.OrderType != OrderTypeBuy {
+		buyOrder, sellOrder = order2, order1
+	}
+	if buyOrder.MaxPrice.GreaterThanOrEqual(sellOrder.MinPrice) {
+		return "band overlap " + buyOrder.MaxPrice.Sub(sellOrder.MinPrice).String() +
+			" under " + strconv.Itoa(minOverlapBps) + " bps"
+	}
+	return "buy max " + buyOrder.MaxPrice.String() + " < sell min " + sellOrder.MinPrice.String()
 }
 
-// isPriceCompatible checks if two orders can match based on variance tolerance
-func isPriceCompatible(order1, order2 *Order) bool {
+// orderColumns is the column list scanOrder expects, in order
+const orderColumns = `id, user_address, chain_id, order_type, base_token, quote_token,
+	quantity, price, variance_bps, min_price, max_price,
+	filled_quantity, remaining_quantity, status, created_at, expires_at,
+	counterparty_allowlist, pool_id, remainder_policy, no_fill_deadline, metadata, seq`
+
+// scanOrder reads an order row selected with orderColumns
+func scanOrder(row pgx.Row, o *Order) error {
+	var quantityStr, priceStr, minPriceStr, maxPriceStr, filledStr, remainingStr string
+	var expiresAt, noFillDeadline *time.Time
+
+	err := row.Scan(
+		&o.ID, &o.UserAddress, &o.ChainID, &o.OrderType, &o.BaseToken, &o.QuoteToken,
+		&quantityStr, &priceStr, &o.VarianceBPS, &minPriceStr, &maxPriceStr,
+		&filledStr, &remainingStr, &o.Status, &o.CreatedAt, &expiresAt,
+		&o.CounterpartyAllowlist, &o.PoolID, &o.RemainderPolicy, &noFillDeadline, &o.Metadata, &o.Seq,
+	)
+	if err != nil {
+		return err
+	}
+
+	// Handle nullable expires_at
+	if expiresAt != nil {
+		o.ExpiresAt = *expiresAt
+	}
+	if noFillDeadline != nil {
+		o.NoFillDeadline = *noFillDeadline
+	}
+
+	// Parse decimal values
+	o.Quantity, _ = decimal.NewFromString(quantityStr)
+	o.Price, _ = decimal.NewFromString(priceStr)
+	o.MinPrice, _ = decimal.NewFromString(minPriceStr)
+	o.MaxPrice, _ = decimal.NewFromString(maxPriceStr)
+	o.FilledQuantity, _ = decimal.NewFromString(filledStr)
+	o.RemainingQuantity, _ = decimal.NewFromString(remainingStr)
+
+	return nil
+}
+
+// isPriceCompatible checks if two orders can match based on variance tolerance.
+// With digits set, both limits are first rounded to that many significant
+// digits. With minOverlapBps set, the bands must also overlap by that many
+// basis points of the overlap's midpoint, or by the whole narrower band when
+// that is less, so a band lying entirely inside the other always qualifies.
+func isPriceCompatible(order1, order2 *Order, digits, minOverlapBps int) bool {
 	var buyOrder, sellOrder *Order
 
 	if order1.OrderType == OrderTypeBuy {
@@ -209,12 +521,69 @@ func isPriceCompatible(order1, order2 *Order) bool {
 	}
 
 	// Check if buy.max_price >= sell.min_price
-	return buyOrder.MaxPrice.GreaterThanOrEqual(sellOrder.MinPrice)
+	buyMax := roundSignificant(buyOrder.MaxPrice, digits)
+	sellMin := roundSignificant(sellOrder.MinPrice, digits)
+	if buyMax.LessThan(sellMin) {
+		return false
+	}
+	if minOverlapBps <= 0 {
+		return true
+	}
+
+	overlap := buyMax.Sub(sellMin)
+	required := buyMax.Add(sellMin).Div(decimal.NewFromInt(2)).
+		Mul(decimal.NewFromInt(int64(minOverlapBps))).Div(decimal.NewFromInt(10000))
+	narrower := decimal.Min(buyOrder.MaxPrice.Sub(buyOrder.MinPrice), sellOrder.MaxPrice.Sub(sellOrder.MinPrice))
+	return overlap.GreaterThanOrEqual(decimal.Min(required, narrower))
 }
 
-// calculateExecutionPrice determines the price at which the match executes
-// Uses the average of buy and sell prices (can be customized)
-func calculateExecutionPrice(order1, order2 *Order) decimal.Decimal {
+// roundSignificant rounds a price half away from zero to the given number of
+// significant digits; 0 leaves it at full precision
+func roundSignificant(price decimal.Decimal, digits int) decimal.Decimal {
+	if digits <= 0 || price.IsZero() {
+		return price
+	}
+	// Position of the most significant digit relative to the decimal point
+	msd := int32(price.NumDigits()) + price.Exponent() - 1
+	return price.Round(int32(digits) - 1 - msd)
+}
+
+// significantPriceBound widens a rounded limit into a bound on raw stored
+// prices that keeps every row which could round across it. Rounding moves a
+// price by less than 5 parts in 10^digits of itself, so a slack of
+// 10^(1-digits) of the limit covers it; isPriceCompatible does the exact check.
+func significantPriceBound(limit decimal.Decimal, digits int, upper bool) decimal.Decimal {
+	if digits <= 0 {
+		return limit
+	}
+	slack := limit.Abs().Mul(decimal.New(1, int32(1-digits)))
+	if upper {
+		return limit.Add(slack)
+	}
+	return limit.Sub(slack)
+}
+
+// roundedOverlapOnly reports whether a compatible buy and sell only cross
+// after rounding, i.e. the buy's unrounded max_price is below the sell's
+// min_price
+func roundedOverlapOnly(buyOrder, sellOrder *Order) bool {
+	return buyOrder.MaxPrice.LessThan(sellOrder.MinPrice)
+}
+
+// counterpartiesAllowed enforces allowlists symmetrically: each order with an
+// allowlist only matches counterparties on it. An empty allowlist accepts anyone.
+func counterpartiesAllowed(order1, order2 *Order) bool {
+	return order1.AllowsCounterparty(order2.UserAddress) && order2.AllowsCounterparty(order1.UserAddress)
+}
+
+// calculateExecutionPrice determines the price at which the match executes.
+// order1 is the taker and order2 the maker; see liquidityRoles.
+// tieBreak picks the point in the overlap [sell.min_price, buy.max_price]:
+// SPLIT uses the average of both limit prices, MAKER_FAVORABLE / TAKER_FAVORABLE
+// use the overlap edge that is best for that side. Bands that only overlap
+// once rounded to digits significant digits execute at the price both limits
+// round to, whatever the tie-break.
+func calculateExecutionPrice(order1, order2 *Order, tieBreak string, digits int) decimal.Decimal {
 	var buyOrder, sellOrder *Order
 
 	if order1.OrderType == OrderTypeBuy {
@@ -225,6 +594,28 @@ func calculateExecutionPrice(order1, order2 *Order) decimal.Decimal {
 		sellOrder = order1
 	}
 
+	// There is no unrounded overlap to pick from, and any price but the
+	// common rounded one breaches a rounded limit. Rounding is monotonic, so
+	// when buy.max_price < sell.min_price only rounds them level, both round
+	// to the same price.
+	if roundedOverlapOnly(buyOrder, sellOrder) {
+		return roundSignificant(buyOrder.MaxPrice, digits)
+	}
+
+	switch tieBreak {
+	case config.PriceTieBreakMaker:
+		// The maker gets the overlap edge in its favour
+		if order2.OrderType == OrderTypeSell {
+			return buyOrder.MaxPrice
+		}
+		return sellOrder.MinPrice
+	case config.PriceTieBreakTaker:
+		if order1.OrderType == OrderTypeBuy {
+			return sellOrder.MinPrice
+		}
+		return buyOrder.MaxPrice
+	}
+
 	// Average of buy and sell prices
 	avgPrice := buyOrder.Price.Add(sellOrder.Price).Div(decimal.NewFromInt(2))
 
@@ -240,8 +631,10 @@ func calculateExecutionPrice(order1, order2 *Order) decimal.Decimal {
 	return executionPrice
 }
 
-// executeMatch creates a match and updates both orders in a database transaction
-func executeMatch(ctx context.Context, db *pgxpool.Pool, order1, order2 *Order, quantity, price decimal.Decimal) (*Match, error) {
+// executeMatch records a match and both orders' fills in one store
+// transaction, then applies the fills to the in-memory orders and the book's
+// entries for them at once
+func executeMatch(ctx context.Context, store MatchScope, cfg *config.Config, book *OrderBook, stats *EngineStats, now time.Time, order1, order2 *Order, quantity, price decimal.Decimal) (*Match, error) {
 	var buyOrder, sellOrder *Order
 	if order1.OrderType == OrderTypeBuy {
 		buyOrder = order1
@@ -252,31 +645,69 @@ func executeMatch(ctx context.Context, db *pgxpool.Pool, order1, order2 *Order,
 	}
 
 	// Start transaction
-	tx, err := db.Begin(ctx)
+	tx, err := store.BeginFill(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		return nil, err
 	}
 	defer tx.Rollback(ctx)
 
+	// Serialize with any other worker filling either order
+	if err := tx.LockOrders(ctx, buyOrder, sellOrder); err != nil {
+		return nil, err
+	}
+
+	// Settlement deadline is stamped per match, by the engine clock the
+	// reaper later judges it by
+	var settlementDeadline time.Time
+	if cfg.SettlementTimeout > 0 {
+		settlementDeadline = now.Add(cfg.SettlementTimeout)
+	}
+
+	// The taker pays the fee and the maker is rebated out of it
+	taker, _ := liquidityRoles(cfg, order1, order2)
+	takerFee, makerRebate := matchFees(cfg, quantity, price)
+
+	match := &Match{
+		BuyOrderID:         buyOrder.ID,
+		SellOrderID:        sellOrder.ID,
+		BaseToken:          order1.BaseToken,
+		QuoteToken:         order1.QuoteToken,
+		Quantity:           quantity,
+		Price:              price,
+		SettlementStatus:   "PENDING",
+		SettlementDeadline: settlementDeadline,
+		BuyerAddress:       buyOrder.UserAddress,
+		SellerAddress:      sellOrder.UserAddress,
+		BuyMetadata:        buyOrder.Metadata,
+		SellMetadata:       sellOrder.Metadata,
+		TakerSide:          taker.OrderType,
+		TakerFee:           takerFee,
+		MakerRebate:        makerRebate,
+		RoundedPrice:       roundedOverlapOnly(buyOrder, sellOrder),
+	}
+	if match.RoundedPrice {
+		log.Info().
+			Str("buy_order_id", buyOrder.ID).
+			Str("sell_order_id", sellOrder.ID).
+			Str("buy_max_price", buyOrder.MaxPrice.String()).
+			Str("sell_min_price", sellOrder.MinPrice.String()).
+			Str("price", price.String()).
+			Msg("Executing at the rounded common price of bands that only cross after rounding")
+	}
+
 	// Create match record
-	var matchID string
-	err = tx.QueryRow(ctx, `
-		INSERT INTO matches (buy_order_id, sell_order_id, base_token, quote_token, quantity, price, settlement_status)
-		VALUES ($1, $2, $3, $4, $5, $6, 'PENDING')
-		RETURNING id
-	`, buyOrder.ID, sellOrder.ID, order1.BaseToken, order1.QuoteToken, quantity.String(), price.String()).Scan(&matchID)
-	if err != nil {
+	if err := tx.CreateMatch(ctx, match); err != nil {
 		return nil, fmt.Errorf("failed to insert match: %w", err)
 	}
 
 	// Update buy order
-	err = updateOrderFill(ctx, tx, buyOrder, quantity)
+	err = tx.UpdateOrderFill(ctx, buyOrder, quantity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update buy order: %w", err)
 	}
 
 	// Update sell order
-	err = updateOrderFill(ctx, tx, sellOrder, quantity)
+	err = tx.UpdateOrderFill(ctx, sellOrder, quantity)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update sell order: %w", err)
 	}
@@ -285,60 +716,20 @@ func executeMatch(ctx context.Context, db *pgxpool.Pool, order1, order2 *Order,
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	match.MatchedAt = time.Now()
 
-	// Update in-memory order quantities
-	order1.FilledQuantity = order1.FilledQuantity.Add(quantity)
-	order1.RemainingQuantity = order1.RemainingQuantity.Sub(quantity)
-	if order1.RemainingQuantity.IsZero() {
-		order1.Status = OrderStatusFilled
-	} else {
-		order1.Status = OrderStatusPartiallyFilled
-	}
-
-	order2.FilledQuantity = order2.FilledQuantity.Add(quantity)
-	order2.RemainingQuantity = order2.RemainingQuantity.Sub(quantity)
-	if order2.RemainingQuantity.IsZero() {
-		order2.Status = OrderStatusFilled
-	} else {
-		order2.Status = OrderStatusPartiallyFilled
-	}
-
-	match := &Match{
-		ID:               matchID,
-		BuyOrderID:       buyOrder.ID,
-		SellOrderID:      sellOrder.ID,
-		BaseToken:        order1.BaseToken,
-		QuoteToken:       order1.QuoteToken,
-		Quantity:         quantity,
-		Price:            price,
-		SettlementStatus: "PENDING",
-		MatchedAt:        time.Now(),
-		BuyerAddress:     buyOrder.UserAddress,
-		SellerAddress:    sellOrder.UserAddress,
-	}
+	// Update in-memory order quantities in one step, so book snapshots never
+	// see the fill half applied
+	book.ApplyFill(quantity, order1, order2)
+	stats.addVolume(match)
 
 	return match, nil
 }
 
-// updateOrderFill updates an order's fill quantities and status
-func updateOrderFill(ctx context.Context, tx pgx.Tx, order *Order, quantity decimal.Decimal) error {
-	newFilled := order.FilledQuantity.Add(quantity)
-	newRemaining := order.RemainingQuantity.Sub(quantity)
-
-	var newStatus OrderStatus
-	if newRemaining.IsZero() {
-		newStatus = OrderStatusFilled
-	} else {
-		newStatus = OrderStatusPartiallyFilled
+// nullTimeOrValue maps a zero time to SQL NULL
+func nullTimeOrValue(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
 	}
-
-	_, err := tx.Exec(ctx, `
-		UPDATE orders
-		SET filled_quantity = $1,
-		    remaining_quantity = $2,
-		    status = $3
-		WHERE id = $4
-	`, newFilled.String(), newRemaining.String(), newStatus, order.ID)
-
-	return err
+	return t
 }
@@ -0,0 +1,155 @@
+package matcher
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/shopspring/decimal"
+)
+
+// bandOrder builds an order accepting [min, max], priced at the midpoint
+func bandOrder(side OrderType, min, max string) *Order {
+	lo, hi := decimal.RequireFromString(min), decimal.RequireFromString(max)
+	order := testOrder("0x"+string(side), side, "1", "1", 0)
+	order.MinPrice, order.MaxPrice = lo, hi
+	order.Price = lo.Add(hi).Div(decimal.NewFromInt(2))
+	return order
+}
+
+func TestRoundSignificant(t *testing.T) {
+	tests := []struct {
+		price  string
+		digits int
+		want   string
+	}{
+		{price: "1999.99999999", digits: 4, want: "2000"},
+		{price: "99.995", digits: 4, want: "100"},
+		{price: "99.99499", digits: 4, want: "99.99"},
+		{price: "100.05", digits: 4, want: "100.1"},
+		{price: "100.0499", digits: 4, want: "100"},
+		{price: "0.000123456", digits: 3, want: "0.000123"},
+		{price: "123.456", digits: 0, want: "123.456"},
+		{price: "0", digits: 4, want: "0"},
+	}
+	for _, tt := range tests {
+		got := roundSignificant(decimal.RequireFromString(tt.price), tt.digits)
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("roundSignificant(%s, %d) = %s, want %s", tt.price, tt.digits, got, tt.want)
+		}
+	}
+}
+
+func TestRoundedCompatibilityExecutesAtCommonPrice(t *testing.T) {
+	tests := []struct {
+		name       string
+		buyMax     string
+		sellMin    string
+		digits     int
+		compatible bool
+	}{
+		{name: "round to the same price", buyMax: "99.996", sellMin: "100.04", digits: 4, compatible: true},
+		{name: "buy limit rounds up at the half", buyMax: "99.995", sellMin: "100.04", digits: 4, compatible: true},
+		{name: "buy limit just below the half", buyMax: "99.99499", sellMin: "100.04", digits: 4, compatible: false},
+		{name: "sell limit rounds up at the half", buyMax: "99.996", sellMin: "100.05", digits: 4, compatible: false},
+		{name: "sell limit just below the half", buyMax: "99.996", sellMin: "100.0499", digits: 4, compatible: true},
+		{name: "full precision", buyMax: "99.996", sellMin: "100.04", digits: 0, compatible: false},
+	}
+	tieBreaks := []string{config.PriceTieBreakSplit, config.PriceTieBreakMaker, config.PriceTieBreakTaker}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buy := bandOrder(OrderTypeBuy, "95", tt.buyMax)
+			sell := bandOrder(OrderTypeSell, tt.sellMin, "105")
+
+			if got := isPriceCompatible(buy, sell, tt.digits, 0); got != tt.compatible {
+				t.Fatalf("isPriceCompatible = %t, want %t", got, tt.compatible)
+			}
+			if !tt.compatible {
+				return
+			}
+			if !roundedOverlapOnly(buy, sell) {
+				t.Fatal("bands overlap unrounded")
+			}
+
+			roundedSellMin := roundSignificant(sell.MinPrice, tt.digits)
+			roundedBuyMax := roundSignificant(buy.MaxPrice, tt.digits)
+			for _, tieBreak := range tieBreaks {
+				for _, taker := range []*Order{buy, sell} {
+					maker := sell
+					if taker == sell {
+						maker = buy
+					}
+					price := calculateExecutionPrice(taker, maker, tieBreak, tt.digits)
+					if !price.Equal(decimal.NewFromInt(100)) {
+						t.Errorf("%s with %s taking: price %s, want the common rounded price 100", tieBreak, taker.OrderType, price)
+					}
+					// Neither side's rounded limit is breached
+					if price.LessThan(roundedSellMin) || price.GreaterThan(roundedBuyMax) {
+						t.Errorf("%s with %s taking: price %s outside rounded limits [%s, %s]", tieBreak, taker.OrderType, price, roundedSellMin, roundedBuyMax)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestUnroundedOverlapKeepsTieBreaks(t *testing.T) {
+	buy := bandOrder(OrderTypeBuy, "95", "101")
+	sell := bandOrder(OrderTypeSell, "99", "105")
+
+	tests := []struct {
+		tieBreak string
+		taker    *Order
+		want     string
+	}{
+		{tieBreak: config.PriceTieBreakSplit, taker: buy, want: "100"},
+		{tieBreak: config.PriceTieBreakMaker, taker: buy, want: "101"},
+		{tieBreak: config.PriceTieBreakMaker, taker: sell, want: "99"},
+		{tieBreak: config.PriceTieBreakTaker, taker: buy, want: "99"},
+		{tieBreak: config.PriceTieBreakTaker, taker: sell, want: "101"},
+	}
+	for _, tt := range tests {
+		maker := sell
+		if tt.taker == sell {
+			maker = buy
+		}
+		// Rounding the limits doesn't change the unrounded overlap's price
+		for _, digits := range []int{0, 2} {
+			price := calculateExecutionPrice(tt.taker, maker, tt.tieBreak, digits)
+			if !price.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("%s, %s taking, %d digits: price %s, want %s", tt.tieBreak, tt.taker.OrderType, digits, price, tt.want)
+			}
+		}
+	}
+}
+
+func TestRoundedPriceMatchIsRecorded(t *testing.T) {
+	for _, digits := range []int{0, 4} {
+		t.Run(fmt.Sprintf("digits=%d", digits), func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.PriceSignificantDigits = digits
+			e, store := newTestEngine(t, cfg)
+
+			submit(t, e, testOrder("0xalice", OrderTypeSell, "1", "100.04", 0))
+			matches := submit(t, e, testOrder("0xbob", OrderTypeBuy, "1", "99.996", 0))
+
+			if digits == 0 {
+				if len(matches) != 0 {
+					t.Fatalf("got %d matches at full precision, want none", len(matches))
+				}
+				return
+			}
+			if len(matches) != 1 {
+				t.Fatalf("got %d matches, want 1", len(matches))
+			}
+			m := matches[0]
+			if !m.Price.Equal(decimal.NewFromInt(100)) || !m.RoundedPrice {
+				t.Errorf("match at %s with rounded_price %t, want 100 and true", m.Price, m.RoundedPrice)
+			}
+			if stored := store.Matches(); len(stored) != 1 || !stored[0].RoundedPrice {
+				t.Errorf("stored matches %v, want the one flagged rounded_price", stored)
+			}
+		})
+	}
+}
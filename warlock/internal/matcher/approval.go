@@ -0,0 +1,78 @@
+package matcher
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// ApprovalChecker reports whether a user's on-chain token approval covers at
+// least amount, i.e. whether settlement could still pull the funds
+type ApprovalChecker interface {
+	HasApproval(ctx context.Context, userAddress, token string, amount decimal.Decimal) (bool, error)
+}
+
+// AlwaysApproved is the default ApprovalChecker; it never cancels anything
+type AlwaysApproved struct{}
+
+// HasApproval implements ApprovalChecker
+func (AlwaysApproved) HasApproval(ctx context.Context, userAddress, token string, amount decimal.Decimal) (bool, error) {
+	return true, nil
+}
+
+// SetApprovalChecker replaces the checker used when CheckApprovals is set.
+// Call before Start.
+func (e *Engine) SetApprovalChecker(checker ApprovalChecker) {
+	e.approvals = checker
+}
+
+// settlementObligation returns the token and amount an order could owe at
+// settlement: sellers deliver base, buyers pay quote at up to their max price
+func settlementObligation(o *Order) (string, decimal.Decimal) {
+	if o.OrderType == OrderTypeSell {
+		return o.BaseToken, o.RemainingQuantity
+	}
+	return o.QuoteToken, o.RemainingQuantity.Mul(o.MaxPrice)
+}
+
+// cancelUnapprovedOrders cancels resting orders whose owner's approval no
+// longer covers their settlement obligation. Orders whose approval can't be
+// checked are left alone.
+func (e *Engine) cancelUnapprovedOrders(ctx context.Context) {
+	for _, book := range e.bookMgr.Books() {
+		orders := append(book.GetBids(), book.GetAsks()...)
+		for _, o := range orders {
+			token, amount := settlementObligation(o)
+
+			approved, err := e.approvals.HasApproval(ctx, o.UserAddress, token, amount)
+			if err != nil {
+				log.Error().Err(err).
+					Str("order_id", o.ID).
+					Str("token", token).
+					Msg("Failed to check token approval")
+				continue
+			}
+			if approved {
+				continue
+			}
+
+			if err := e.store.CancelOrder(ctx, o.ID, ""); err != nil {
+				if !orderClosed(err) {
+					log.Error().Err(err).
+						Str("order_id", o.ID).
+						Msg("Failed to cancel order without approval")
+				}
+				continue
+			}
+
+			e.EvictOrder(o.ID)
+			log.Warn().
+				Str("order_id", o.ID).
+				Str("user_address", o.UserAddress).
+				Str("token", token).
+				Str("amount", amount.String()).
+				Msg("Cancelled order whose token approval no longer covers settlement")
+		}
+	}
+}
@@ -0,0 +1,281 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// auctioneer clears every book once per AuctionInterval in batch auction mode
+func (e *Engine) auctioneer(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.cfg.AuctionInterval)
+	defer ticker.Stop()
+
+	log.Info().Dur("interval", e.cfg.AuctionInterval).Msg("Batch auctions started")
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+
+		case <-ticker.C:
+			e.runAuctions(ctx)
+		}
+	}
+}
+
+// runAuctions holds one auction for each pair with a book
+func (e *Engine) runAuctions(ctx context.Context) {
+	e.quiesceMu.RLock()
+	defer e.quiesceMu.RUnlock()
+
+	// Orders keep accumulating while the database is out; they are crossed
+	// at the first auction after it recovers
+	if e.breaker.degraded() {
+		return
+	}
+
+	pairs := make(map[string][2]string)
+	for _, book := range e.bookMgr.Books() {
+		pairs[makePairKey(book.baseToken, book.quoteToken)] = [2]string{book.baseToken, book.quoteToken}
+	}
+
+	for _, pair := range pairs {
+		store := e.sequenced()
+		matches, err := e.runAuction(ctx, store, pair[0], pair[1])
+		e.recordMatchAttempt(&MatchResult{Matches: matches}, err)
+		if err != nil {
+			log.Error().Err(err).
+				Str("base_token", pair[0]).
+				Str("quote_token", pair[1]).
+				Msg("Batch auction failed")
+		}
+
+		e.runMatchHook(ctx, matches)
+		if !e.publishMatches(store, matches) {
+			return
+		}
+	}
+}
+
+// runAuction crosses one pair's stored orders, pool by pool, at each pool's
+// clearing price, recording the matches in store. New orders for the pair
+// wait until it is done.
+func (e *Engine) runAuction(ctx context.Context, store *sequencedStore, baseToken, quoteToken string) ([]*Match, error) {
+	pairLock := e.pairLock(baseToken, quoteToken)
+	pairLock.Lock()
+	defer pairLock.Unlock()
+
+	now := e.Now()
+	if session := e.cfg.SessionFor(baseToken, quoteToken); session != nil {
+		if open, _ := session.State(now); !open {
+			return nil, nil
+		}
+	}
+
+	// The store is the record of what is still open, whatever the books say
+	stored, err := store.LoadActiveOrders(ctx, now, baseToken, quoteToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load auction orders: %w", err)
+	}
+	pausedChains := e.PausedChains()
+	pools := make(map[string][]*Order)
+	for _, o := range stored {
+		if !slices.Contains(pausedChains, o.ChainID) {
+			pools[o.PoolID] = append(pools[o.PoolID], o)
+		}
+	}
+
+	cfg := e.cfg.ForPair(baseToken, quoteToken)
+	matches := make([]*Match, 0)
+	var execErr error
+	for poolID, orders := range pools {
+		bids, asks := splitSides(orders)
+		price, volume, ok := clearingPrice(bids, asks)
+		if !ok {
+			continue
+		}
+
+		book := e.bookMgr.GetOrCreateBook(poolID, baseToken, quoteToken)
+		crossed, err := crossAtPrice(ctx, store, cfg, book, &e.stats, now, bids, asks, price)
+		matches = append(matches, crossed...)
+		if err != nil {
+			execErr = err
+		}
+
+		// Crossed orders go back into the book with their new fills
+		for _, o := range orders {
+			book.RemoveOrder(o.ID)
+			if o.IsActive() {
+				book.AddOrder(o)
+			}
+		}
+
+		log.Info().
+			Str("pool_id", poolID).
+			Str("base_token", baseToken).
+			Str("quote_token", quoteToken).
+			Str("clearing_price", price.String()).
+			Str("clearable_volume", volume.String()).
+			Int("matches", len(crossed)).
+			Msg("Batch auction cleared")
+	}
+	return matches, execErr
+}
+
+// splitSides separates a book's orders into bids and asks
+func splitSides(orders []*Order) (bids, asks []*Order) {
+	for _, o := range orders {
+		if o.OrderType == OrderTypeBuy {
+			bids = append(bids, o)
+		} else {
+			asks = append(asks, o)
+		}
+	}
+	return bids, asks
+}
+
+// clearingPrice finds the single price at which the most volume crosses: at
+// price p every bid whose max price is at least p and every ask whose min
+// price is at most p can trade, and the volume is the smaller of the two
+// sides' remaining quantity. Among prices with the same volume, the one
+// leaving the least imbalance between the sides wins; if several remain, the
+// midpoint of the lowest and highest is used, which crosses at least as much.
+// Only limit prices need checking, as volume only changes at them. ok is false
+// when nothing crosses.
+func clearingPrice(bids, asks []*Order) (price, volume decimal.Decimal, ok bool) {
+	if len(bids) == 0 || len(asks) == 0 {
+		return decimal.Zero, decimal.Zero, false
+	}
+
+	prices := make([]decimal.Decimal, 0, len(bids)+len(asks))
+	demand := decimal.Zero
+	for _, b := range bids {
+		prices = append(prices, b.MaxPrice)
+		demand = demand.Add(b.RemainingQuantity)
+	}
+	for _, a := range asks {
+		prices = append(prices, a.MinPrice)
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].LessThan(prices[j]) })
+
+	bidsByLimit := append([]*Order(nil), bids...)
+	sort.Slice(bidsByLimit, func(i, j int) bool { return bidsByLimit[i].MaxPrice.LessThan(bidsByLimit[j].MaxPrice) })
+	asksByLimit := append([]*Order(nil), asks...)
+	sort.Slice(asksByLimit, func(i, j int) bool { return asksByLimit[i].MinPrice.LessThan(asksByLimit[j].MinPrice) })
+
+	// Sweep prices upwards: bids drop out as p passes their max, asks join as
+	// p reaches their min
+	supply := decimal.Zero
+	var low, high, bestImbalance decimal.Decimal
+	i, j := 0, 0
+	for k, p := range prices {
+		if k > 0 && p.Equal(prices[k-1]) {
+			continue
+		}
+		for ; i < len(bidsByLimit) && bidsByLimit[i].MaxPrice.LessThan(p); i++ {
+			demand = demand.Sub(bidsByLimit[i].RemainingQuantity)
+		}
+		for ; j < len(asksByLimit) && asksByLimit[j].MinPrice.LessThanOrEqual(p); j++ {
+			supply = supply.Add(asksByLimit[j].RemainingQuantity)
+		}
+
+		crossed := decimal.Min(demand, supply)
+		imbalance := demand.Sub(supply).Abs()
+		switch {
+		case !crossed.IsPositive():
+		case !ok || crossed.GreaterThan(volume) || (crossed.Equal(volume) && imbalance.LessThan(bestImbalance)):
+			volume, bestImbalance, low, high, ok = crossed, imbalance, p, p, true
+		case crossed.Equal(volume) && imbalance.Equal(bestImbalance):
+			high = p
+		}
+	}
+	if !ok {
+		return decimal.Zero, decimal.Zero, false
+	}
+	return low.Add(high).Div(decimal.NewFromInt(2)), volume, true
+}
+
+// crossAtPrice fills every bid and ask that cross at price, all at that price.
+// Bids go best first (highest max price) and asks lowest min price first,
+// earlier arrivals first at equal limits. Self-trade prevention,
+// counterparty allowlists, match size limits, minimum maker fills and lot
+// sizes still apply, so less than the clearable volume may trade. The
+// later-arriving order of each fill counts as the taker. The error is the
+// last fill that failed.
+func crossAtPrice(ctx context.Context, store MatchScope, cfg *config.Config, book *OrderBook, stats *EngineStats, now time.Time, bids, asks []*Order, price decimal.Decimal) ([]*Match, error) {
+	bids = crossingOrders(cfg, bids, func(o *Order) bool { return o.MaxPrice.GreaterThanOrEqual(price) })
+	asks = crossingOrders(cfg, asks, func(o *Order) bool { return o.MinPrice.LessThanOrEqual(price) })
+	sort.SliceStable(bids, func(i, j int) bool { return bids[i].MaxPrice.GreaterThan(bids[j].MaxPrice) })
+	sort.SliceStable(asks, func(i, j int) bool { return asks[i].MinPrice.LessThan(asks[j].MinPrice) })
+	if len(bids) == 0 || len(asks) == 0 {
+		return nil, nil
+	}
+
+	bounds := cfg.BoundsFor(bids[0].BaseToken, bids[0].QuoteToken)
+	matches := make([]*Match, 0)
+	var execErr error
+	for _, bid := range bids {
+		for _, ask := range asks {
+			if bid.RemainingQuantity.IsZero() {
+				break
+			}
+			if ask.RemainingQuantity.IsZero() {
+				continue
+			}
+			if cfg.SelfTradePrevention && cfg.SameEntity(bid.UserAddress, ask.UserAddress) {
+				continue
+			}
+			if !counterpartiesAllowed(bid, ask) {
+				continue
+			}
+
+			taker, maker := ask, bid
+			if arrivedBefore(cfg, ask, bid) {
+				taker, maker = bid, ask
+			}
+			fills := settleableFills(splitFill(decimal.Min(bid.RemainingQuantity, ask.RemainingQuantity), bounds), price, bounds)
+			if belowMakerFill(fills, maker, bounds) {
+				continue
+			}
+			for _, qty := range fills {
+				match, err := executeMatch(ctx, store, cfg, book, stats, now, taker, maker, qty, price)
+				if errors.Is(err, ErrDuplicateMatch) {
+					break
+				}
+				if err != nil {
+					log.Error().Err(err).
+						Str("buy_order_id", bid.ID).
+						Str("sell_order_id", ask.ID).
+						Msg("Failed to execute auction fill")
+					execErr = err
+					break
+				}
+				matches = append(matches, match)
+			}
+		}
+	}
+	return matches, execErr
+}
+
+// crossingOrders returns the orders that cross at the clearing price, in
+// arrival order
+func crossingOrders(cfg *config.Config, orders []*Order, crosses func(*Order) bool) []*Order {
+	crossing := make([]*Order, 0, len(orders))
+	for _, o := range orders {
+		if crosses(o) {
+			crossing = append(crossing, o)
+		}
+	}
+	sort.SliceStable(crossing, func(i, j int) bool { return arrivedBefore(cfg, crossing[i], crossing[j]) })
+	return crossing
+}
@@ -0,0 +1,164 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// limitOrder builds an auction order: a bid accepting up to limit or an ask
+// accepting down to it
+func limitOrder(user string, side OrderType, quantity, limit string) *Order {
+	order := testOrder(user, side, quantity, limit, 0)
+	if side == OrderTypeBuy {
+		order.MinPrice = decimal.NewFromInt(1)
+	} else {
+		order.MaxPrice = decimal.NewFromInt(1000)
+	}
+	return order
+}
+
+func TestClearingPrice(t *testing.T) {
+	type level struct{ quantity, limit string }
+	tests := []struct {
+		name   string
+		bids   []level
+		asks   []level
+		price  string
+		volume string
+		ok     bool
+	}{
+		{
+			name: "nothing crosses",
+			bids: []level{{"5", "99"}},
+			asks: []level{{"5", "100"}},
+		},
+		{
+			name: "one side empty",
+			bids: []level{{"5", "100"}},
+		},
+		{
+			name:  "single price",
+			bids:  []level{{"5", "100"}},
+			asks:  []level{{"5", "100"}},
+			price: "100", volume: "5", ok: true,
+		},
+		{
+			// 100 clears 4, 104 clears 10
+			name:  "maximizes volume",
+			bids:  []level{{"10", "104"}},
+			asks:  []level{{"4", "100"}, {"10", "104"}},
+			price: "104", volume: "10", ok: true,
+		},
+		{
+			// 99, 100 and 102 all clear 5; only 102 leaves no demand unmet
+			name:  "equal volume, least imbalance",
+			bids:  []level{{"5", "102"}, {"1", "100"}},
+			asks:  []level{{"5", "99"}},
+			price: "102", volume: "5", ok: true,
+		},
+		{
+			// 100 and 105 clear 5 with no imbalance: the midpoint of the two
+			name:  "midpoint of the low and high",
+			bids:  []level{{"5", "105"}},
+			asks:  []level{{"5", "100"}},
+			price: "102.5", volume: "5", ok: true,
+		},
+		{
+			// 102 and 103 both clear 8, balanced; 100 and 105 clear less
+			name:  "midpoint among several levels",
+			bids:  []level{{"5", "105"}, {"3", "103"}},
+			asks:  []level{{"4", "100"}, {"4", "102"}},
+			price: "102.5", volume: "8", ok: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bids, asks []*Order
+			for _, l := range tt.bids {
+				bids = append(bids, limitOrder("0xbuyer", OrderTypeBuy, l.quantity, l.limit))
+			}
+			for _, l := range tt.asks {
+				asks = append(asks, limitOrder("0xseller", OrderTypeSell, l.quantity, l.limit))
+			}
+
+			price, volume, ok := clearingPrice(bids, asks)
+			if ok != tt.ok {
+				t.Fatalf("ok = %t, want %t", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if !price.Equal(decimal.RequireFromString(tt.price)) || !volume.Equal(decimal.RequireFromString(tt.volume)) {
+				t.Errorf("clearingPrice = %s for %s, want %s for %s", price, volume, tt.price, tt.volume)
+			}
+		})
+	}
+}
+
+func TestCrossAtPrice(t *testing.T) {
+	tests := []struct {
+		name        string
+		price       string
+		wantVolume  string
+		wantMatched []string // Users that trade
+	}{
+		{name: "clearing price", price: "102.5", wantVolume: "8", wantMatched: []string{"0xb105", "0xb103", "0xa100", "0xa102"}},
+		{name: "price above some asks only", price: "101", wantVolume: "4", wantMatched: []string{"0xb105", "0xa100"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			store := NewMemoryStore()
+			ctx := context.Background()
+
+			bids := []*Order{
+				limitOrder("0xb105", OrderTypeBuy, "5", "105"),
+				limitOrder("0xb103", OrderTypeBuy, "3", "103"),
+				limitOrder("0xb099", OrderTypeBuy, "2", "99"),
+			}
+			asks := []*Order{
+				limitOrder("0xa100", OrderTypeSell, "4", "100"),
+				limitOrder("0xa102", OrderTypeSell, "4", "102"),
+				limitOrder("0xa110", OrderTypeSell, "1", "110"),
+			}
+			for _, o := range append(append([]*Order{}, bids...), asks...) {
+				if err := store.CreateOrders(ctx, []NewOrder{{Order: o}}); err != nil {
+					t.Fatalf("CreateOrders: %v", err)
+				}
+			}
+
+			price := decimal.RequireFromString(tt.price)
+			matches, err := crossAtPrice(ctx, store, cfg, nil, nil, time.Now(), bids, asks, price)
+			if err != nil {
+				t.Fatalf("crossAtPrice: %v", err)
+			}
+
+			volume := decimal.Zero
+			traded := make(map[string]bool)
+			for _, m := range matches {
+				if !m.Price.Equal(price) {
+					t.Errorf("match at %s, want every match at the uniform price %s", m.Price, price)
+				}
+				volume = volume.Add(m.Quantity)
+				traded[m.BuyerAddress] = true
+				traded[m.SellerAddress] = true
+			}
+			if !volume.Equal(decimal.RequireFromString(tt.wantVolume)) {
+				t.Errorf("crossed %s, want %s", volume, tt.wantVolume)
+			}
+			if len(traded) != len(tt.wantMatched) {
+				t.Errorf("traded %v, want %v", traded, tt.wantMatched)
+			}
+			for _, user := range tt.wantMatched {
+				if !traded[user] {
+					t.Errorf("%s did not trade", user)
+				}
+			}
+		})
+	}
+}
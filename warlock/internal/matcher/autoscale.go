@@ -0,0 +1,93 @@
+package matcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// spawnWorker starts one more worker goroutine
+func (e *Engine) spawnWorker(ctx context.Context) {
+	e.workersMu.Lock()
+	defer e.workersMu.Unlock()
+
+	w := &workerHandle{
+		id:   e.nextWorkerID,
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	e.nextWorkerID++
+	e.workers = append(e.workers, w)
+
+	e.wg.Add(1)
+	go func() {
+		defer close(w.done)
+		e.worker(ctx, w.id, w.quit)
+	}()
+}
+
+// retireWorker stops the most recently spawned worker once it finishes its current item
+func (e *Engine) retireWorker() {
+	e.workersMu.Lock()
+	defer e.workersMu.Unlock()
+
+	n := len(e.workers)
+	if n == 0 {
+		return
+	}
+
+	close(e.workers[n-1].quit)
+	e.workers = e.workers[:n-1]
+}
+
+// WorkerCount returns the number of running workers
+func (e *Engine) WorkerCount() int {
+	e.workersMu.Lock()
+	defer e.workersMu.Unlock()
+	return len(e.workers)
+}
+
+// autoscaler grows the worker pool while the order queue is above the high
+// watermark and shrinks it once the queue drains below the low watermark.
+// It moves by one worker per tick to avoid oscillating on bursty load.
+func (e *Engine) autoscaler(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.cfg.AutoscaleInterval)
+	defer ticker.Stop()
+
+	log.Info().
+		Int("min_workers", e.cfg.WorkersMin).
+		Int("max_workers", e.cfg.WorkersMax).
+		Int("high_watermark", e.cfg.QueueHighWatermark).
+		Int("low_watermark", e.cfg.QueueLowWatermark).
+		Msg("Worker autoscaling enabled")
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+
+		case <-ticker.C:
+			depth := len(e.orderChan)
+			workers := e.WorkerCount()
+
+			switch {
+			case depth > e.cfg.QueueHighWatermark && workers < e.cfg.WorkersMax:
+				e.spawnWorker(ctx)
+				log.Info().
+					Int("queue_depth", depth).
+					Int("workers", workers+1).
+					Msg("Scaled workers up")
+
+			case depth < e.cfg.QueueLowWatermark && workers > e.cfg.WorkersMin:
+				e.retireWorker()
+				log.Info().
+					Int("queue_depth", depth).
+					Int("workers", workers-1).
+					Msg("Scaled workers down")
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package matcher
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PairAtCapacity reports whether an order for this pair would need a new book
+// while MaxPairs pairs already have one. Pairs with a book, and pairs pinned
+// in HotPairs or TradablePairs, are never at capacity. The check is advisory:
+// concurrent submissions for different new pairs can overshoot it slightly.
+func (e *Engine) PairAtCapacity(baseToken, quoteToken string) bool {
+	if e.cfg.MaxPairs <= 0 || e.cfg.PairPinned(baseToken, quoteToken) {
+		return false
+	}
+	if len(e.bookMgr.PairBooks(baseToken, quoteToken)) > 0 {
+		return false
+	}
+	return e.bookMgr.PairCount() >= e.cfg.MaxPairs
+}
+
+// compactIdleBooks shrinks the in-memory footprint of books untouched for
+// BookCompactAfter; see OrderBook.Compact
+func (e *Engine) compactIdleBooks() {
+	compacted := 0
+	for _, book := range e.bookMgr.Books() {
+		if book.Compact(e.cfg.BookCompactAfter) {
+			compacted++
+		}
+	}
+
+	if compacted > 0 {
+		log.Debug().Int("books", compacted).Msg("Compacted idle order books")
+	}
+}
+
+// evictIdleBooks drops books that have been empty for longer than BookIdleTTL.
+// Each removal holds the pair's write lock so no worker is using the book.
+func (e *Engine) evictIdleBooks() {
+	cutoff := time.Now().Add(-e.cfg.BookIdleTTL)
+
+	for _, book := range e.bookMgr.Books() {
+		if lastActive, empty := book.IdleSince(); !empty || lastActive.After(cutoff) {
+			continue
+		}
+
+		pairLock := e.pairLock(book.baseToken, book.quoteToken)
+		pairLock.Lock()
+		// An order may have arrived while we waited for the lock
+		lastActive, empty := book.IdleSince()
+		evicted := empty && !lastActive.After(cutoff) && e.bookMgr.RemoveBook(book)
+		pairLock.Unlock()
+
+		if evicted {
+			log.Debug().
+				Str("pool_id", book.poolID).
+				Str("base_token", book.baseToken).
+				Str("quote_token", book.quoteToken).
+				Msg("Evicted idle empty order book")
+		}
+	}
+}
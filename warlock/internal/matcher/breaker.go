@@ -0,0 +1,141 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrDegraded is returned for orders that reach a worker while matching is
+// suspended because the database is unavailable
+var ErrDegraded = errors.New("matching suspended: database unavailable")
+
+// dbBreaker counts consecutive match attempts that failed against the
+// database. Once DBFailureThreshold is reached the engine is degraded and
+// stops matching, so fills can't be skipped while orders keep resting in
+// memory; the probe closes it again once the database answers.
+type dbBreaker struct {
+	mu         sync.Mutex
+	threshold  int
+	failures   int
+	degradedAt time.Time // Zero while healthy
+}
+
+// failure records a failed attempt and reports whether it tripped the breaker
+func (b *dbBreaker) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 || !b.degradedAt.IsZero() {
+		return false
+	}
+	b.failures++
+	if b.failures < b.threshold {
+		return false
+	}
+	b.degradedAt = time.Now()
+	return true
+}
+
+// success resets the failure count after an attempt that reached the database
+func (b *dbBreaker) success() {
+	b.mu.Lock()
+	b.failures = 0
+	b.mu.Unlock()
+}
+
+// degraded reports whether matching is suspended
+func (b *dbBreaker) degraded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.degradedAt.IsZero()
+}
+
+// reset closes the breaker and returns how long it was open
+func (b *dbBreaker) reset() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	down := time.Since(b.degradedAt)
+	b.failures = 0
+	b.degradedAt = time.Time{}
+	return down
+}
+
+// Degraded reports whether matching is suspended because the database is unavailable
+func (e *Engine) Degraded() bool {
+	return e.breaker.degraded()
+}
+
+// recordMatchAttempt feeds the outcome of a MatchOrder call to the breaker
+func (e *Engine) recordMatchAttempt(result *MatchResult, err error) {
+	if err == nil && result.ExecutionErr == nil {
+		e.breaker.success()
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		// Shutdown, not an outage
+		return
+	}
+	if e.breaker.failure() {
+		log.Error().
+			Int("failures", e.cfg.DBFailureThreshold).
+			Msg("Database failing repeatedly, matching suspended")
+	}
+}
+
+// dbProbe checks the database every DBProbeInterval while the engine is
+// degraded. Once it answers, every book is rebuilt from the orders table,
+// since fills that failed mid-outage may have left memory out of step, and
+// matching resumes.
+func (e *Engine) dbProbe(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.cfg.DBProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+
+		case <-ticker.C:
+			if !e.breaker.degraded() {
+				continue
+			}
+			if err := e.recoverFromOutage(ctx); err != nil {
+				log.Warn().Err(err).Msg("Database still unavailable, matching remains suspended")
+				continue
+			}
+			log.Info().
+				Dur("degraded_for", e.breaker.reset()).
+				Msg("Database reachable again, matching resumed")
+		}
+	}
+}
+
+// recoverFromOutage pings the store and reloads every pair with a book
+func (e *Engine) recoverFromOutage(ctx context.Context) error {
+	pingCtx, cancel := context.WithTimeout(ctx, e.cfg.MatchQueryTimeout)
+	err := e.store.Ping(pingCtx)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	for _, book := range e.bookMgr.Books() {
+		key := makePairKey(book.baseToken, book.quoteToken)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, _, err := e.RebuildBook(ctx, book.baseToken, book.quoteToken); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+)
+
+var errStoreDown = errors.New("store down")
+
+// outageStore is a MemoryStore that can be taken down: matching and pings
+// fail while it is, and reloads fail while reloadDown is set
+type outageStore struct {
+	*MemoryStore
+	down       atomic.Bool
+	reloadDown atomic.Bool
+	pings      atomic.Int32
+}
+
+func (s *outageStore) FindCandidates(ctx context.Context, cfg *config.Config, order *Order, pausedChains []int32, now time.Time) ([]*Order, error) {
+	if s.down.Load() {
+		return nil, errStoreDown
+	}
+	return s.MemoryStore.FindCandidates(ctx, cfg, order, pausedChains, now)
+}
+
+func (s *outageStore) BeginClaim(ctx context.Context) (Claim, error) {
+	if s.down.Load() {
+		return nil, errStoreDown
+	}
+	return s.MemoryStore.BeginClaim(ctx)
+}
+
+func (s *outageStore) LoadActiveOrders(ctx context.Context, now time.Time, baseToken, quoteToken string) ([]*Order, error) {
+	if s.reloadDown.Load() {
+		return nil, errStoreDown
+	}
+	return s.MemoryStore.LoadActiveOrders(ctx, now, baseToken, quoteToken)
+}
+
+func (s *outageStore) Ping(ctx context.Context) error {
+	s.pings.Add(1)
+	if s.down.Load() {
+		return errStoreDown
+	}
+	return nil
+}
+
+// waitPings waits for the probe to ping the store n more times
+func waitPings(t *testing.T, store *outageStore, n int32) {
+	t.Helper()
+	want := store.pings.Load() + n
+	deadline := time.Now().Add(5 * time.Second)
+	for store.pings.Load() < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("probe pinged %d times, want %d", store.pings.Load(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBreakerOpensOnOutageAndClosesOnceTheStoreAnswers(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.DBFailureThreshold = 2
+	cfg.DBProbeInterval = 5 * time.Millisecond
+	store := &outageStore{MemoryStore: NewMemoryStore()}
+	e := NewEngine(nil, cfg)
+	e.SetStore(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := e.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		e.Stop()
+		cancel()
+	})
+
+	submit(t, e, testOrder("0xalice", OrderTypeSell, "5", "100", 100))
+
+	// Closed: failures below the threshold leave matching running
+	store.down.Store(true)
+	for i := 0; i < cfg.DBFailureThreshold; i++ {
+		if e.Degraded() {
+			t.Fatalf("degraded after %d failures, want %d", i, cfg.DBFailureThreshold)
+		}
+		buy := testOrder("0xbob", OrderTypeBuy, "1", "100", 100)
+		if err := store.CreateOrders(ctx, []NewOrder{{Order: buy}}); err != nil {
+			t.Fatalf("CreateOrders: %v", err)
+		}
+		if _, err := e.SubmitOrderSync(ctx, buy); !errors.Is(err, errStoreDown) {
+			t.Fatalf("match during the outage: %v, want errStoreDown", err)
+		}
+	}
+
+	// Open: orders rest without matching
+	if !e.Degraded() {
+		t.Fatal("breaker still closed after reaching the threshold")
+	}
+	resting := testOrder("0xcarol", OrderTypeBuy, "1", "100", 100)
+	if err := store.CreateOrders(ctx, []NewOrder{{Order: resting}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+	if _, err := e.SubmitOrderSync(ctx, resting); !errors.Is(err, ErrDegraded) {
+		t.Fatalf("match while open: %v, want ErrDegraded", err)
+	}
+
+	// Half-open: probes keep failing while the store is down, and a ping that
+	// answers doesn't close it until every book reloads
+	waitPings(t, store, 2)
+	if !e.Degraded() {
+		t.Fatal("breaker closed while pings failed")
+	}
+	store.reloadDown.Store(true)
+	store.down.Store(false)
+	waitPings(t, store, 2)
+	if !e.Degraded() {
+		t.Fatal("breaker closed while books failed to reload")
+	}
+
+	// Closed again once the books reload
+	store.reloadDown.Store(false)
+	deadline := time.Now().Add(5 * time.Second)
+	for e.Degraded() {
+		if time.Now().After(deadline) {
+			t.Fatal("breaker never closed after the store recovered")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, inBook := e.GetInMemoryOrder(resting.ID); !inBook {
+		t.Error("order that rested during the outage missing from the reloaded book")
+	}
+	if matches := submit(t, e, testOrder("0xdave", OrderTypeBuy, "1", "100", 100)); len(matches) != 1 {
+		t.Errorf("got %d matches after recovery, want 1", len(matches))
+	}
+}
@@ -0,0 +1,82 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// ErrEmptyCancelFilter is returned when a bulk cancel names no condition
+// besides the owner, which would cancel every one of their orders
+var ErrEmptyCancelFilter = errors.New("at least one filter besides user_address is required")
+
+// CancelFilter selects a user's matchable orders for CancelOrdersWhere.
+// Zero fields are ignored; set ones must all hold.
+type CancelFilter struct {
+	UserAddress string
+	BaseToken   string // With QuoteToken, restricts to one pair
+	QuoteToken  string
+	Side        OrderType
+	PriceBelow  decimal.Decimal // Price strictly below
+	PriceAbove  decimal.Decimal // Price strictly above
+	OlderThan   time.Time       // Created strictly before
+}
+
+// empty reports whether the filter has no condition besides the owner
+func (f CancelFilter) empty() bool {
+	return f.BaseToken == "" && f.Side == "" && f.PriceBelow.IsZero() &&
+		f.PriceAbove.IsZero() && f.OlderThan.IsZero()
+}
+
+// selects reports whether the filter holds for an order, whatever its status
+func (f CancelFilter) selects(o *Order) bool {
+	switch {
+	case !strings.EqualFold(o.UserAddress, f.UserAddress):
+		return false
+	case f.BaseToken != "" && (o.BaseToken != f.BaseToken || o.QuoteToken != f.QuoteToken):
+		return false
+	case f.Side != "" && o.OrderType != f.Side:
+		return false
+	case !f.PriceBelow.IsZero() && !o.Price.LessThan(f.PriceBelow):
+		return false
+	case !f.PriceAbove.IsZero() && !o.Price.GreaterThan(f.PriceAbove):
+		return false
+	case !f.OlderThan.IsZero() && !o.CreatedAt.Before(f.OlderThan):
+		return false
+	}
+	return true
+}
+
+// CancelOrdersWhere cancels every matchable order of the user that matches the
+// filter and drops them from the books. The store waits on fills in flight, so
+// orders they filled completely are no longer matchable and are left alone.
+// Returns the ids of the cancelled orders.
+func (e *Engine) CancelOrdersWhere(ctx context.Context, f CancelFilter) ([]string, error) {
+	if f.empty() {
+		return nil, ErrEmptyCancelFilter
+	}
+
+	orderIDs, err := e.store.CancelOrdersWhere(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	e.stats.mu.Lock()
+	e.stats.TotalCancels += int64(len(orderIDs))
+	e.stats.mu.Unlock()
+
+	for _, orderID := range orderIDs {
+		e.EvictOrder(orderID)
+	}
+
+	log.Info().
+		Str("user_address", f.UserAddress).
+		Int("cancelled", len(orderIDs)).
+		Msg("Cancelled orders matching filter")
+
+	return orderIDs, nil
+}
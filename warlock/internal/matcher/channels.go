@@ -0,0 +1,108 @@
+package matcher
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Engine channels, as named by ChannelStatus and DrainChannel
+const (
+	ChannelOrders  = "orders"
+	ChannelCancels = "cancels"
+	ChannelMatches = "matches"
+)
+
+// ErrChannelDrained is the outcome given to a synchronous order or cancel
+// whose request was discarded by DrainChannel
+var ErrChannelDrained = errors.New("request discarded by an operator channel drain")
+
+// ChannelStatus is the depth of one engine channel
+type ChannelStatus struct {
+	Name     string
+	Length   int
+	Capacity int
+}
+
+// DrainResult reports what DrainChannel discarded
+type DrainResult struct {
+	Drained  int
+	OrderIDs []string // Orders, or orders whose cancels were discarded; none for matches
+}
+
+// ChannelStatus returns the depth and capacity of the order, cancel and match
+// channels
+func (e *Engine) ChannelStatus() []ChannelStatus {
+	return []ChannelStatus{
+		{Name: ChannelOrders, Length: len(e.orderChan), Capacity: cap(e.orderChan)},
+		{Name: ChannelCancels, Length: len(e.cancelChan), Capacity: cap(e.cancelChan)},
+		{Name: ChannelMatches, Length: len(e.matchChan), Capacity: cap(e.matchChan)},
+	}
+}
+
+// DrainChannel discards everything queued on one channel. For orders and
+// cancels new submissions are held off until the drain is done, so the
+// channel is left empty; drained orders stay stored and can be reloaded into
+// the book with RebuildBook. Synchronous submitters waiting on a drained order
+// or cancel get ErrChannelDrained. Matches are already recorded, so draining
+// them only drops the notifications; workers keep producing them, so only
+// those queued when the drain starts are taken.
+func (e *Engine) DrainChannel(name string) (DrainResult, error) {
+	var result DrainResult
+
+	switch name {
+	case ChannelOrders:
+		e.intakeMu.Lock()
+		defer e.intakeMu.Unlock()
+	orders:
+		for {
+			select {
+			case order := <-e.orderChan:
+				result.Drained++
+				result.OrderIDs = append(result.OrderIDs, order.ID)
+				if order.done != nil {
+					order.done <- orderOutcome{err: ErrChannelDrained}
+				}
+			default:
+				break orders
+			}
+		}
+
+	case ChannelCancels:
+		e.intakeMu.Lock()
+		defer e.intakeMu.Unlock()
+	cancels:
+		for {
+			select {
+			case cancel := <-e.cancelChan:
+				result.Drained++
+				result.OrderIDs = append(result.OrderIDs, cancel.OrderID)
+				cancel.done <- ErrChannelDrained
+			default:
+				break cancels
+			}
+		}
+
+	case ChannelMatches:
+	matches:
+		for n := len(e.matchChan); n > 0; n-- {
+			select {
+			case <-e.matchChan:
+				result.Drained++
+			default:
+				break matches
+			}
+		}
+
+	default:
+		return result, fmt.Errorf("unknown channel %q", name)
+	}
+
+	log.Warn().
+		Str("channel", name).
+		Int("drained", result.Drained).
+		Strs("order_ids", result.OrderIDs).
+		Msg("Drained engine channel")
+	return result, nil
+}
@@ -0,0 +1,31 @@
+package matcher
+
+import "time"
+
+// Clock is the time source order expiry, no-fill and settlement deadlines
+// and market hours are judged by. Every such check, in memory or in a query,
+// reads the same clock, so an order can't be expired or closed for one path
+// and matchable for another.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the default Clock; it reads the process clock
+type SystemClock struct{}
+
+// Now implements Clock
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SetClock replaces the engine's clock, e.g. with a fixed one in tests.
+// Call before Start.
+func (e *Engine) SetClock(clock Clock) {
+	e.clock = clock
+}
+
+// Now returns the engine clock's current time. Orders are stamped with it on
+// submission so their expiries are judged against the same clock.
+func (e *Engine) Now() time.Time {
+	return e.clock.Now()
+}
@@ -0,0 +1,152 @@
+package matcher
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// mockClock is a Clock that only moves when set
+type mockClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *mockClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *mockClock) Set(now time.Time) {
+	c.mu.Lock()
+	c.now = now
+	c.mu.Unlock()
+}
+
+// useConfigFile makes the next config.Load read yaml as its config file
+func useConfigFile(t *testing.T, yaml string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "warlock.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+}
+
+// newClockedEngine starts an engine like newTestEngine, on clock
+func newClockedEngine(t *testing.T, clock Clock) (*Engine, *MemoryStore) {
+	t.Helper()
+	cfg := testConfig(t)
+	store := NewMemoryStore()
+	e := NewEngine(nil, cfg)
+	e.SetStore(store)
+	e.SetClock(clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := e.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		e.Stop()
+		cancel()
+	})
+	return e, store
+}
+
+func TestMarketHoursFollowEngineClock(t *testing.T) {
+	useConfigFile(t, `
+market_hours:
+  WETH/USDC:
+    windows: [{open: "09:00", close: "17:00"}]
+`)
+	// A Monday, a moment before the session opens
+	open := time.Date(2026, time.October, 12, 9, 0, 0, 0, time.UTC)
+	clock := &mockClock{now: open.Add(-time.Millisecond)}
+	e, store := newClockedEngine(t, clock)
+
+	submit(t, e, testOrder("0xalice", OrderTypeSell, "1", "100", 100))
+	if matches := submit(t, e, testOrder("0xbob", OrderTypeBuy, "1", "100", 100)); len(matches) != 0 {
+		t.Fatalf("matched %d times before the open", len(matches))
+	}
+	if n := e.DeferredOrders("WETH", "USDC"); n != 2 {
+		t.Fatalf("%d orders deferred, want both", n)
+	}
+
+	// Real time passing changes nothing: the deferral is due by the engine clock
+	e.deferred.wake <- struct{}{}
+	time.Sleep(50 * time.Millisecond)
+	if len(store.Matches()) != 0 {
+		t.Fatal("deferred orders matched before the engine clock reached the open")
+	}
+
+	clock.Set(open)
+	e.deferred.wake <- struct{}{}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(store.Matches()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("deferred orders never matched at the open")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := e.DeferredOrders("WETH", "USDC"); n != 0 {
+		t.Errorf("%d orders still deferred after the open", n)
+	}
+}
+
+func TestSettlementDeadlineFollowsEngineClock(t *testing.T) {
+	t.Setenv("SETTLEMENT_TIMEOUT", "10m")
+	now := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	e, _ := newClockedEngine(t, &mockClock{now: now})
+
+	submit(t, e, testOrder("0xalice", OrderTypeSell, "1", "100", 100))
+	matches := submit(t, e, testOrder("0xbob", OrderTypeBuy, "1", "100", 100))
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	if want := now.Add(10 * time.Minute); !matches[0].SettlementDeadline.Equal(want) {
+		t.Errorf("settlement deadline %s, want %s", matches[0].SettlementDeadline, want)
+	}
+	if !matches[0].Quantity.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("matched %s, want 1", matches[0].Quantity)
+	}
+}
+
+func TestMatchRateFollowsEngineClock(t *testing.T) {
+	t.Setenv("MAX_MATCHES_PER_SECOND", "1")
+	now := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := &mockClock{now: now}
+	e, store := newClockedEngine(t, clock)
+
+	submit(t, e, testOrder("0xalice", OrderTypeSell, "5", "100", 100))
+	if matches := submit(t, e, testOrder("0xbob", OrderTypeBuy, "1", "100", 100)); len(matches) != 1 {
+		t.Fatalf("first buy got %d matches, want 1", len(matches))
+	}
+
+	// The second of engine time the match used has not passed, however long
+	// the test takes
+	time.Sleep(20 * time.Millisecond)
+	if matches := submit(t, e, testOrder("0xcarol", OrderTypeBuy, "1", "100", 100)); len(matches) != 0 {
+		t.Fatalf("buy within the same engine second got %d matches, want 0", len(matches))
+	}
+	if n := e.DeferredOrders("WETH", "USDC"); n != 1 {
+		t.Fatalf("%d orders deferred, want 1", n)
+	}
+
+	// A second later by the engine clock the deferred buy matches
+	clock.Set(now.Add(time.Second))
+	e.deferred.wake <- struct{}{}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(store.Matches()) != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("deferred buy never matched a second later by the engine clock")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
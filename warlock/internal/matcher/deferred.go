@@ -0,0 +1,183 @@
+package matcher
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// deferRetryDelay is how long a deferred order waits for another try when it
+// couldn't be loaded
+const deferRetryDelay = time.Second
+
+// deferredMatches tracks resting orders whose matching was put off, e.g.
+// because their pair's market session was closed, and when to retry them
+type deferredMatches struct {
+	mu      sync.Mutex
+	entries map[string]deferredEntry // order id -> entry
+	wake    chan struct{}            // Signalled when an earlier retry is added
+}
+
+type deferredEntry struct {
+	pair string
+	due  time.Time
+}
+
+func newDeferredMatches() *deferredMatches {
+	return &deferredMatches{
+		entries: make(map[string]deferredEntry),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// add schedules an order to be matched again at due, keeping an earlier
+// retry if one is already scheduled
+func (d *deferredMatches) add(order *Order, due time.Time) {
+	d.mu.Lock()
+	if existing, ok := d.entries[order.ID]; ok && existing.due.Before(due) {
+		d.mu.Unlock()
+		return
+	}
+	d.entries[order.ID] = deferredEntry{pair: makePairKey(order.BaseToken, order.QuoteToken), due: due}
+	d.mu.Unlock()
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// takeDue removes and returns the orders due by now, and reports the next
+// due time still pending (zero if none)
+func (d *deferredMatches) takeDue(now time.Time) (map[string]deferredEntry, time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	due := make(map[string]deferredEntry)
+	var next time.Time
+	for id, entry := range d.entries {
+		if !entry.due.After(now) {
+			due[id] = entry
+			delete(d.entries, id)
+		} else if next.IsZero() || entry.due.Before(next) {
+			next = entry.due
+		}
+	}
+	return due, next
+}
+
+// restore puts taken entries back, due again at due
+func (d *deferredMatches) restore(entries map[string]deferredEntry, due time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for id, entry := range entries {
+		entry.due = due
+		d.entries[id] = entry
+	}
+}
+
+// count reports how many orders of a pair are waiting
+func (d *deferredMatches) count(baseToken, quoteToken string) int {
+	key := makePairKey(baseToken, quoteToken)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := 0
+	for _, entry := range d.entries {
+		if entry.pair == key {
+			n++
+		}
+	}
+	return n
+}
+
+// DeferredOrders reports how many resting orders of a pair are waiting to be
+// matched again
+func (e *Engine) DeferredOrders(baseToken, quoteToken string) int {
+	return e.deferred.count(baseToken, quoteToken)
+}
+
+// runDeferred matches deferred orders again as they fall due
+func (e *Engine) runDeferred(ctx context.Context) {
+	defer e.wg.Done()
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		due, next := e.deferred.takeDue(e.Now())
+		if len(due) > 0 {
+			e.matchDeferred(ctx, due)
+			continue
+		}
+
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = next.Sub(e.Now())
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-e.stopChan:
+			return
+		case <-e.deferred.wake:
+		case <-timer.C:
+		}
+	}
+}
+
+// matchDeferred matches deferred orders again, oldest first so time priority
+// holds. Each is reloaded just before it is matched, since an earlier one may
+// have filled it; orders that filled, expired or were cancelled meanwhile are
+// dropped.
+func (e *Engine) matchDeferred(ctx context.Context, due map[string]deferredEntry) {
+	orderIDs := make([]string, 0, len(due))
+	for id := range due {
+		orderIDs = append(orderIDs, id)
+	}
+
+	orders, err := e.LoadOrders(ctx, orderIDs)
+	if err != nil {
+		log.Error().Err(err).Int("orders", len(orderIDs)).Msg("Failed to load deferred orders, retrying")
+		e.deferred.restore(due, e.Now().Add(deferRetryDelay))
+		// Don't spin on a failing database
+		select {
+		case <-e.stopChan:
+		case <-time.After(deferRetryDelay):
+		}
+		return
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.Before(orders[j].CreatedAt) })
+
+	for _, stale := range orders {
+		select {
+		case <-e.stopChan:
+			return
+		default:
+		}
+
+		fresh, err := e.LoadOrders(ctx, []string{stale.ID})
+		if err != nil {
+			log.Error().Err(err).Str("order_id", stale.ID).Msg("Failed to reload deferred order, retrying")
+			e.deferred.add(stale, e.Now().Add(deferRetryDelay))
+			continue
+		}
+		if len(fresh) == 0 || !fresh[0].IsActive() || fresh[0].Expired(e.Now()) {
+			continue
+		}
+		e.processOrder(ctx, fresh[0])
+	}
+
+	log.Info().Int("orders", len(orders)).Msg("Matched deferred orders")
+}
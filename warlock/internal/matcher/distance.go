@@ -0,0 +1,69 @@
+package matcher
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// cancelReasonFarFromMarket tags cancellations of orders resting too far from
+// the opposite best
+const cancelReasonFarFromMarket = "FAR_FROM_MARKET"
+
+// cancelFarFromMarket cancels resting orders priced more than
+// MaxMarketDistanceBps away from the opposite best of their book. Such orders
+// rarely match but still cost memory and scan time.
+func (e *Engine) cancelFarFromMarket(ctx context.Context) {
+	maxBps := decimal.NewFromInt(int64(e.cfg.MaxMarketDistanceBps))
+
+	for _, book := range e.bookMgr.Books() {
+		// Each side is judged against the other side's best
+		if bestAsk := book.PeekBestAsk(); bestAsk != nil {
+			for _, o := range book.GetBids() {
+				if distanceBasisPoints(bestAsk.Price.Sub(o.Price), bestAsk.Price).GreaterThan(maxBps) {
+					e.cancelFarOrder(ctx, o, bestAsk.Price)
+				}
+			}
+		}
+		if bestBid := book.PeekBestBid(); bestBid != nil {
+			for _, o := range book.GetAsks() {
+				if distanceBasisPoints(o.Price.Sub(bestBid.Price), bestBid.Price).GreaterThan(maxBps) {
+					e.cancelFarOrder(ctx, o, bestBid.Price)
+				}
+			}
+		}
+	}
+}
+
+// cancelFarOrder cancels one order found too far from the market
+func (e *Engine) cancelFarOrder(ctx context.Context, o *Order, oppositeBest decimal.Decimal) {
+	if err := e.store.CancelOrder(ctx, o.ID, ""); err != nil {
+		if !orderClosed(err) {
+			log.Error().Err(err).
+				Str("order_id", o.ID).
+				Msg("Failed to cancel order far from the market")
+		}
+		return
+	}
+
+	e.EvictOrder(o.ID)
+	log.Info().
+		Str("order_id", o.ID).
+		Str("order_type", string(o.OrderType)).
+		Str("price", o.Price.String()).
+		Str("opposite_best", oppositeBest.String()).
+		Int("max_distance_bps", e.cfg.MaxMarketDistanceBps).
+		Str("reason", cancelReasonFarFromMarket).
+		Msg("Cancelled order resting too far from the market")
+}
+
+// distanceBasisPoints expresses gap, the amount an order is priced away from
+// the opposite best, in basis points of that best. Orders crossing the best
+// have a negative gap and are never far.
+func distanceBasisPoints(gap, reference decimal.Decimal) decimal.Decimal {
+	if !reference.IsPositive() {
+		return decimal.Zero
+	}
+	return gap.Div(reference).Mul(decimal.NewFromInt(10000))
+}
@@ -0,0 +1,62 @@
+package matcher
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// workerHandle controls one worker goroutine
+type workerHandle struct {
+	id   int
+	quit chan struct{} // Closed to retire the worker
+	done chan struct{} // Closed once the worker has exited
+}
+
+// Drain outcomes returned by DrainWorker
+var (
+	ErrWorkerNotFound = errors.New("worker not found")
+	ErrLastWorker     = errors.New("cannot drain the last worker")
+)
+
+// WorkerIDs returns the ids of the running workers in ascending order
+func (e *Engine) WorkerIDs() []int {
+	e.workersMu.Lock()
+	defer e.workersMu.Unlock()
+
+	ids := make([]int, 0, len(e.workers))
+	for _, w := range e.workers {
+		ids = append(ids, w.id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// DrainWorker retires one worker: it stops pulling from the queues and exits
+// once its current order or cancel is done. Workers all share the queues, so
+// the remaining ones pick up every pair the drained worker would have served.
+// The returned channel is closed when the worker has exited.
+func (e *Engine) DrainWorker(workerID int) (<-chan struct{}, error) {
+	e.workersMu.Lock()
+	defer e.workersMu.Unlock()
+
+	for i, w := range e.workers {
+		if w.id != workerID {
+			continue
+		}
+		if len(e.workers) == 1 {
+			return nil, ErrLastWorker
+		}
+
+		close(w.quit)
+		e.workers = append(e.workers[:i], e.workers[i+1:]...)
+
+		log.Info().
+			Int("worker_id", workerID).
+			Int("remaining_workers", len(e.workers)).
+			Msg("Draining worker")
+		return w.done, nil
+	}
+	return nil, ErrWorkerNotFound
+}
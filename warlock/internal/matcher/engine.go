@@ -2,6 +2,7 @@ package matcher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -15,15 +16,87 @@ import (
 // Engine is the core matching engine
 type Engine struct {
 	db         *pgxpool.Pool
+	store      Store // Order loads and fills; see SetStore
+	clock      Clock // Judges expiry, deadlines and market hours; see SetClock
 	cfg        *config.Config
 	bookMgr    *OrderBookManager
 	orderChan  chan *Order
 	cancelChan chan *CancelRequest
 	matchChan  chan *Match
 	stopChan   chan struct{}
-	wg         sync.WaitGroup
-	started    bool
-	mu         sync.Mutex
+
+	// Recorded rejections on their way to StreamRejections; see RecordRejection
+	rejectionChan chan *Rejection
+
+	wg      sync.WaitGroup
+	started bool
+	mu      sync.Mutex
+
+	// Worker pool; each worker has its own quit channel so it can be retired
+	workersMu    sync.Mutex
+	workers      []*workerHandle
+	nextWorkerID int
+
+	// Per-pair lock and state; see pairControl
+	pairsMu sync.Mutex
+	pairs   map[string]*pairControl
+
+	// Chains paused for settlement maintenance; see PauseChain
+	chainsMu     sync.RWMutex
+	pausedChains map[int32]bool
+
+	// Warm-up readiness: once warmedUp is set every pair is ready, before that
+	// only pairs in readyPairs have their books loaded
+	readyMu    sync.RWMutex
+	readyPairs map[string]bool
+	warmedUp   bool
+
+	// Recent match traces for GetMatchTrace
+	traces *traceStore
+
+	// Numbers matches and publishes them in that order
+	sequencer *matchSequencer
+
+	// Log of book mutations for exact recovery; nil unless BookWALDir is set
+	wal *bookWAL
+
+	// Consulted by the reaper when CheckApprovals is set
+	approvals ApprovalChecker
+
+	// Run for every recorded match; see SetMatchHook
+	hook MatchHook
+
+	// Suspends matching while the database is failing
+	breaker *dbBreaker
+
+	// Held for reading by every unit of work that touches the database; see Quiesce
+	quiesceMu sync.RWMutex
+
+	// Held for reading while an order or cancel is queued; see DrainChannel
+	intakeMu sync.RWMutex
+
+	// When the books last matched the database; see reconcileBooks
+	reconcileMu   sync.RWMutex
+	lastReconcile time.Time
+
+	// Resting orders waiting to be matched again; see runDeferred
+	deferred *deferredMatches
+
+	// Per-pair match rate limits; see MaxMatchesPerSecond
+	throttle *matchThrottle
+
+	// Sequence handed to ack-only submissions; see SubmitOrderAck
+	ackMu  sync.Mutex
+	ackSeq uint64
+
+	// Warm standby state; see startStandby and Promote
+	runCtx         context.Context
+	roleMu         sync.RWMutex
+	standby        bool
+	replicaStop    chan struct{}
+	replicaDone    chan struct{}
+	replicaCursor  uint64 // Transaction id replication resumes from; see replicateChanges
+	lastReplicated time.Time
 
 	// Statistics
 	stats EngineStats
@@ -34,26 +107,125 @@ type EngineStats struct {
 	TotalOrders   int64
 	TotalMatches  int64
 	TotalCancels  int64
+	ActiveWorkers int
 	StartTime     time.Time
-	mu            sync.RWMutex
+
+	// Quantity and quote value (quantity * price) of every match recorded,
+	// summed across pairs; see addVolume
+	MatchedBaseVolume  decimal.Decimal
+	MatchedQuoteVolume decimal.Decimal
+
+	// Channel backlogs at the time of the snapshot
+	OrderBacklog  int
+	CancelBacklog int
+	MatchBacklog  int
+
+	// Removals that found an order missing from its heap, across live books
+	HeapDesyncs int64
+
+	mu sync.RWMutex
 }
 
+// addVolume counts a recorded match toward the matched volume, as soon as
+// its fill is durable rather than when it is published. A nil receiver
+// counts nothing.
+func (s *EngineStats) addVolume(m *Match) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.MatchedBaseVolume = s.MatchedBaseVolume.Add(m.Quantity)
+	s.MatchedQuoteVolume = s.MatchedQuoteVolume.Add(m.Quantity.Mul(m.Price))
+}
+
+// BookSize counts the resting orders in one book
+type BookSize struct {
+	PoolID     string
+	BaseToken  string
+	QuoteToken string
+	Bids       int
+	Asks       int
+}
+
+// activeOrdersQuery selects matchable orders; callers append filters and
+// ordering, and bind the engine's current time as $1
+const activeOrdersQuery = `
+	SELECT ` + orderColumns + `
+	FROM orders
+	WHERE status IN ('REVEALED', 'PARTIALLY_FILLED')
+	  AND (expires_at IS NULL OR expires_at > $1)
+`
+
 // CancelRequest represents a request to cancel an order
 type CancelRequest struct {
 	OrderID     string
 	UserAddress string
+
+	// done receives the outcome once the cancel has been applied or rejected
+	done chan<- error
+}
+
+// Cancel outcomes returned by CancelOrder
+var (
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrCancelTooLate      = errors.New("too late to cancel: order already filled")
+	ErrOrderNotCancelable = errors.New("order is not in a cancelable state")
+)
+
+// orderClosed reports whether a cancel failed only because the order was no
+// longer open, so there was nothing left to cancel
+func orderClosed(err error) bool {
+	return errors.Is(err, ErrOrderNotFound) || errors.Is(err, ErrCancelTooLate) || errors.Is(err, ErrOrderNotCancelable)
 }
 
-// NewEngine creates a new matching engine
+// NewEngine creates a new matching engine. With a nil pool, e.g. over a
+// MemoryStore set with SetStore, the engine only matches: the sweeps that
+// query Postgres directly (reaper, book snapshots, the database probe and
+// cancelling retired pairs' orders) don't run, and rejections are streamed
+// but not stored.
 func NewEngine(db *pgxpool.Pool, cfg *config.Config) *Engine {
+	bookMgr := NewOrderBookManager()
+	if cfg.DeterministicMatching {
+		bookMgr.earlier = sequencedBefore
+	} else if cfg.PriorityDecayAfter > 0 {
+		bookMgr.earlier = decayedPriority(cfg.PriorityDecayAfter)
+	}
+
+	pausedChains := make(map[int32]bool, len(cfg.PausedChains))
+	for _, id := range cfg.PausedChains {
+		pausedChains[id] = true
+	}
+
+	// A standby's books follow the orders table, and it logs nothing
+	var wal *bookWAL
+	if cfg.BookWALDir != "" && cfg.EngineRole != config.EngineRoleStandby {
+		wal = newBookWAL(cfg.BookWALDir)
+		bookMgr.wal = wal
+	}
+
 	return &Engine{
-		db:         db,
-		cfg:        cfg,
-		bookMgr:    NewOrderBookManager(),
-		orderChan:  make(chan *Order, cfg.OrderChannelSize),
-		cancelChan: make(chan *CancelRequest, cfg.CancelChannelSize),
-		matchChan:  make(chan *Match, cfg.MatchChannelSize),
-		stopChan:   make(chan struct{}),
+		db:            db,
+		store:         NewPostgresStore(db),
+		clock:         SystemClock{},
+		cfg:           cfg,
+		bookMgr:       bookMgr,
+		orderChan:     make(chan *Order, cfg.OrderChannelSize),
+		cancelChan:    make(chan *CancelRequest, cfg.CancelChannelSize),
+		matchChan:     make(chan *Match, cfg.MatchChannelSize),
+		rejectionChan: make(chan *Rejection, rejectionChannelSize),
+		stopChan:      make(chan struct{}),
+		pairs:         make(map[string]*pairControl),
+		pausedChains:  pausedChains,
+		readyPairs:    make(map[string]bool),
+		traces:        newTraceStore(),
+		sequencer:     newMatchSequencer(),
+		wal:           wal,
+		deferred:      newDeferredMatches(),
+		throttle:      newMatchThrottle(),
+		approvals:     AlwaysApproved{},
+		hook:          NopMatchHook{},
+		breaker:       &dbBreaker{threshold: cfg.DBFailureThreshold},
 		stats: EngineStats{
 			StartTime: time.Now(),
 		},
@@ -73,23 +245,134 @@ func (e *Engine) Start(ctx context.Context) error {
 		Int("workers", e.cfg.Workers).
 		Msg("Starting matching engine")
 
-	// Load existing orders from database into memory
-	if err := e.loadExistingOrders(ctx); err != nil {
-		return fmt.Errorf("failed to load existing orders: %w", err)
+	standby := e.cfg.EngineRole == config.EngineRoleStandby
+
+	// Retired pairs must not come back into the books; a standby leaves the
+	// writing to the primary, which cancels them on its own start
+	if !standby && e.db != nil {
+		if err := e.cancelRetiredPairOrders(ctx); err != nil {
+			return fmt.Errorf("failed to cancel orders on retired pairs: %w", err)
+		}
 	}
 
-	// Start worker pool
-	for i := 0; i < e.cfg.Workers; i++ {
-		e.wg.Add(1)
-		go e.worker(ctx, i)
+	// Changes from here on are replayed onto the books about to be loaded
+	if standby {
+		if err := e.initReplication(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Books recovered from the WAL need only the changes since its last record
+	recovered := false
+	if e.wal != nil {
+		var err error
+		if recovered, err = e.recoverBooks(ctx); err != nil {
+			return err
+		}
+	}
+
+	// Load existing orders from database into memory; with hot pairs configured
+	// only those load now and the rest warm up once workers are running. A
+	// standby has all the time it needs, so it loads everything.
+	var hotPairs []tokenPair
+	if !standby && !recovered {
+		hotPairs = e.hotPairs()
+	}
+	if recovered {
+		e.markWarmedUp()
+	} else if len(hotPairs) > 0 {
+		if err := e.loadPairs(ctx, hotPairs); err != nil {
+			return fmt.Errorf("failed to load hot pairs: %w", err)
+		}
+	} else {
+		if err := e.loadExistingOrders(ctx); err != nil {
+			return fmt.Errorf("failed to load existing orders: %w", err)
+		}
+		e.markWarmedUp()
+	}
+	e.markReconciled()
+
+	// Records only count from a checkpoint of the books as loaded
+	if e.wal != nil {
+		if err := e.checkpointBooks(ctx); err != nil {
+			return fmt.Errorf("failed to checkpoint order books: %w", err)
+		}
 	}
 
+	// A standby only keeps its books in step with the primary's until promoted
+	e.runCtx = ctx
+	if standby {
+		e.startStandby(ctx)
+		e.started = true
+		log.Info().Msg("Matching engine started as a standby")
+		return nil
+	}
+
+	if err := e.startSequencer(ctx); err != nil {
+		return err
+	}
+	e.startMatching(ctx, hotPairs)
+
 	e.started = true
 	log.Info().Msg("Matching engine started successfully")
 
 	return nil
 }
 
+// startMatching starts the workers and background tasks of an engine that
+// matches orders. hotPairs, if any, are the pairs already loaded; the rest
+// warm up in the background.
+func (e *Engine) startMatching(ctx context.Context, hotPairs []tokenPair) {
+	// Start worker pool
+	for i := 0; i < e.cfg.Workers; i++ {
+		e.spawnWorker(ctx)
+	}
+
+	if e.cfg.WorkerAutoscale {
+		e.wg.Add(1)
+		go e.autoscaler(ctx)
+	}
+
+	// Start background maintenance
+	if e.db != nil {
+		e.wg.Add(1)
+		go e.reaper(ctx)
+	}
+
+	if e.cfg.DBFailureThreshold > 0 {
+		e.wg.Add(1)
+		go e.dbProbe(ctx)
+	}
+
+	if e.cfg.BookSnapshotInterval > 0 && e.db != nil {
+		e.wg.Add(1)
+		go e.bookSampler(ctx)
+	}
+
+	if e.cfg.BookReconcileInterval > 0 {
+		e.wg.Add(1)
+		go e.reconciler(ctx)
+	}
+
+	if e.cfg.MatchingMode == config.MatchingModeBatchAuction {
+		e.wg.Add(1)
+		go e.auctioneer(ctx)
+	}
+
+	if len(hotPairs) > 0 {
+		e.wg.Add(1)
+		go e.warmRemainingPairs(ctx, hotPairs)
+	}
+
+	if e.wal != nil {
+		e.wg.Add(1)
+		go e.walWriter(ctx)
+	}
+
+	e.wg.Add(1)
+	go e.runDeferred(ctx)
+}
+
 // Stop gracefully stops the matching engine
 func (e *Engine) Stop() {
 	e.mu.Lock()
@@ -104,6 +387,12 @@ func (e *Engine) Stop() {
 	close(e.stopChan)
 	e.wg.Wait()
 
+	if e.wal != nil {
+		if err := e.wal.close(); err != nil {
+			log.Error().Err(err).Msg("Failed to close the book WAL")
+		}
+	}
+
 	close(e.orderChan)
 	close(e.cancelChan)
 	close(e.matchChan)
@@ -114,6 +403,9 @@ func (e *Engine) Stop() {
 
 // SubmitOrder submits a new order to the matching engine
 func (e *Engine) SubmitOrder(order *Order) error {
+	e.intakeMu.RLock()
+	defer e.intakeMu.RUnlock()
+
 	select {
 	case e.orderChan <- order:
 		e.stats.mu.Lock()
@@ -127,17 +419,83 @@ func (e *Engine) SubmitOrder(order *Order) error {
 	}
 }
 
-// CancelOrder submits a cancel request
-func (e *Engine) CancelOrder(orderID, userAddress string) error {
+// SubmitOrderAck submits an order like SubmitOrder and returns its ack
+// sequence, which increases with every acknowledged order in the order they
+// were queued. It restarts from 1 when the process does.
+func (e *Engine) SubmitOrderAck(order *Order) (uint64, error) {
+	e.ackMu.Lock()
+	defer e.ackMu.Unlock()
+
+	if err := e.SubmitOrder(order); err != nil {
+		return 0, err
+	}
+	e.ackSeq++
+	return e.ackSeq, nil
+}
+
+// orderOutcome reports what happened to a synchronously submitted order
+type orderOutcome struct {
+	matches []*Match
+	err     error
+}
+
+// SubmitOrderSync submits an order and waits until it has been added to the
+// book and matching attempted. The order reflects its post-match state on return.
+func (e *Engine) SubmitOrderSync(ctx context.Context, order *Order) ([]*Match, error) {
+	done := make(chan orderOutcome, 1)
+	order.done = done
+
+	if err := e.SubmitOrder(order); err != nil {
+		return nil, err
+	}
+
 	select {
-	case e.cancelChan <- &CancelRequest{OrderID: orderID, UserAddress: userAddress}:
-		e.stats.mu.Lock()
-		e.stats.TotalCancels++
-		e.stats.mu.Unlock()
+	case outcome := <-done:
+		return outcome.matches, outcome.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-e.stopChan:
+		return nil, fmt.Errorf("engine is stopped")
+	}
+}
+
+// CancelOrder submits a cancel request and waits for a worker to apply it.
+// Unlike orders, a cancel is not dropped when the channel is full; it waits up
+// to CancelSubmitTimeout for room. A cancel racing a match either lands first,
+// so the match is skipped, or sees the fill: a fully filled order gives
+// ErrCancelTooLate, a partially filled one has its remainder cancelled.
+func (e *Engine) CancelOrder(ctx context.Context, orderID, userAddress string) error {
+	done := make(chan error, 1)
+
+	if err := e.queueCancel(&CancelRequest{OrderID: orderID, UserAddress: userAddress, done: done}); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-e.stopChan:
+		return fmt.Errorf("engine is stopped")
+	}
+}
+
+// queueCancel puts a cancel on the channel, waiting up to CancelSubmitTimeout
+// for room
+func (e *Engine) queueCancel(cancel *CancelRequest) error {
+	e.intakeMu.RLock()
+	defer e.intakeMu.RUnlock()
+
+	timer := time.NewTimer(e.cfg.CancelSubmitTimeout)
+	defer timer.Stop()
+
+	select {
+	case e.cancelChan <- cancel:
 		return nil
 	case <-e.stopChan:
 		return fmt.Errorf("engine is stopped")
-	default:
+	case <-timer.C:
 		return fmt.Errorf("cancel channel is full")
 	}
 }
@@ -147,26 +505,84 @@ func (e *Engine) MatchChan() <-chan *Match {
 	return e.matchChan
 }
 
-// GetStats returns engine statistics
+// GetStats returns a snapshot of engine statistics
 func (e *Engine) GetStats() EngineStats {
 	e.stats.mu.RLock()
 	defer e.stats.mu.RUnlock()
-	return e.stats
+	return EngineStats{
+		TotalOrders:        e.stats.TotalOrders,
+		TotalMatches:       e.stats.TotalMatches,
+		TotalCancels:       e.stats.TotalCancels,
+		ActiveWorkers:      e.WorkerCount(),
+		StartTime:          e.stats.StartTime,
+		MatchedBaseVolume:  e.stats.MatchedBaseVolume,
+		MatchedQuoteVolume: e.stats.MatchedQuoteVolume,
+		OrderBacklog:       len(e.orderChan),
+		CancelBacklog:      len(e.cancelChan),
+		MatchBacklog:       len(e.matchChan),
+		HeapDesyncs:        e.heapDesyncs(),
+	}
+}
+
+// heapDesyncs sums the desync counters of every live book
+func (e *Engine) heapDesyncs() int64 {
+	var total int64
+	for _, book := range e.bookMgr.Books() {
+		total += book.Desyncs()
+	}
+	return total
+}
+
+// BookSizes returns the number of resting orders on each side of every book
+func (e *Engine) BookSizes() []BookSize {
+	books := e.bookMgr.Books()
+	sizes := make([]BookSize, 0, len(books))
+	for _, book := range books {
+		bids, asks := book.SideCounts()
+		sizes = append(sizes, BookSize{
+			PoolID:     book.poolID,
+			BaseToken:  book.baseToken,
+			QuoteToken: book.quoteToken,
+			Bids:       bids,
+			Asks:       asks,
+		})
+	}
+	return sizes
 }
 
-// worker processes orders and cancel requests
-func (e *Engine) worker(ctx context.Context, workerID int) {
+// worker processes orders and cancel requests until the engine stops or the worker is retired
+func (e *Engine) worker(ctx context.Context, workerID int, quit <-chan struct{}) {
 	defer e.wg.Done()
 
 	log.Debug().Int("worker_id", workerID).Msg("Worker started")
 
 	for {
+		// Drain cancels first: select picks randomly among ready cases, so
+		// without this a flood of orders could starve a waiting cancel
+		select {
+		case cancel := <-e.cancelChan:
+			e.processCancelRequest(ctx, cancel)
+			continue
+		default:
+		}
+
 		select {
 		case <-e.stopChan:
 			log.Debug().Int("worker_id", workerID).Msg("Worker stopped")
 			return
 
+		case <-quit:
+			log.Debug().Int("worker_id", workerID).Msg("Worker retired")
+			return
+
 		case order := <-e.orderChan:
+			if !e.speedBump(order) {
+				// Tell a synchronous submitter rather than leave it waiting
+				if order.done != nil {
+					order.done <- orderOutcome{err: fmt.Errorf("engine is stopped")}
+				}
+				return
+			}
 			e.processOrder(ctx, order)
 
 		case cancel := <-e.cancelChan:
@@ -177,6 +593,9 @@ func (e *Engine) worker(ctx context.Context, workerID int) {
 
 // processOrder processes an incoming order
 func (e *Engine) processOrder(ctx context.Context, order *Order) {
+	e.quiesceMu.RLock()
+	defer e.quiesceMu.RUnlock()
+
 	log.Debug().
 		Str("order_id", order.ID).
 		Str("type", string(order.OrderType)).
@@ -187,40 +606,104 @@ func (e *Engine) processOrder(ctx context.Context, order *Order) {
 		Int32("variance_bps", order.VarianceBPS).
 		Msg("Processing order")
 
+	// Synchronous submitters wait on this; the channel is buffered so it never blocks
+	var matches []*Match
+	var matchErr error
+	if order.done != nil {
+		defer func() {
+			order.done <- orderOutcome{matches: matches, err: matchErr}
+		}()
+	}
+
+	// A claim that failed to commit leaves the book holding fills the store
+	// rolled back; reload the pair once the pair lock below is released
+	defer func() {
+		if !errors.Is(matchErr, errClaimCommit) {
+			return
+		}
+		if _, _, err := e.RebuildBook(ctx, order.BaseToken, order.QuoteToken); err != nil {
+			log.Error().Err(err).
+				Str("base_token", order.BaseToken).
+				Str("quote_token", order.QuoteToken).
+				Msg("Failed to rebuild book after match claim rollback")
+		}
+	}()
+
+	// Hold the pair lock so a concurrent rebuild can't swap the book mid-match
+	pairLock := e.pairLock(order.BaseToken, order.QuoteToken)
+	pairLock.RLock()
+	defer pairLock.RUnlock()
+
 	// Get or create order book for this token pair
-	orderBook := e.bookMgr.GetOrCreateBook(order.BaseToken, order.QuoteToken)
+	orderBook := e.bookMgr.GetOrCreateBook(order.PoolID, order.BaseToken, order.QuoteToken)
 
 	// Add order to the order book
 	orderBook.AddOrder(order)
 
-	// Attempt to match the order
-	result, err := MatchOrder(ctx, e.db, orderBook, order)
+	if order.TraceMatching || e.cfg.PairTraced(order.BaseToken, order.QuoteToken) {
+		order.trace = &MatchTrace{OrderID: order.ID, TracedAt: time.Now()}
+		defer e.traces.put(order.trace)
+	}
+
+	// The order is stored and rests on the book; it is matched against once
+	// the database is back, as a candidate for later orders
+	if e.breaker.degraded() {
+		matchErr = ErrDegraded
+		return
+	}
+
+	// In batch auction mode orders only rest; the auctioneer crosses them
+	if e.cfg.MatchingMode == config.MatchingModeBatchAuction {
+		return
+	}
+
+	// A pair over its match rate keeps the order resting and matches it once
+	// the rate allows, rather than dropping it
+	pairKey := makePairKey(order.BaseToken, order.QuoteToken)
+	rate := e.cfg.ForPair(order.BaseToken, order.QuoteToken).MaxMatchesPerSecond
+	if rate > 0 {
+		if wait := e.throttle.wait(pairKey, rate, e.Now()); wait > 0 {
+			log.Debug().
+				Str("order_id", order.ID).
+				Dur("wait", wait).
+				Msg("Pair over its match rate, deferring order")
+			e.deferred.add(order, e.Now().Add(wait))
+			return
+		}
+	}
+
+	// Attempt to match the order. Every match sequence the pass reserves is
+	// resolved on the way out, or matches after it would never be published.
+	store := e.sequenced()
+	defer e.publishMatches(store, nil)
+	result, err := MatchOrder(ctx, store, e.cfg, orderBook, &e.stats, order, e.PausedChains(), e.Now())
+	e.recordMatchAttempt(result, err)
+	if rate > 0 && result != nil {
+		e.throttle.spend(pairKey, rate, len(result.Matches), e.Now())
+	}
 	if err != nil {
 		log.Error().Err(err).
 			Str("order_id", order.ID).
 			Msg("Failed to match order")
+		matchErr = err
 		return
 	}
+	matches = result.Matches
+	if !result.DeferredUntil.IsZero() {
+		e.deferred.add(order, result.DeferredUntil)
+	}
 
-	// Send match notifications
-	for _, match := range result.Matches {
-		select {
-		case e.matchChan <- match:
-			e.stats.mu.Lock()
-			e.stats.TotalMatches++
-			e.stats.mu.Unlock()
-
-			log.Info().
-				Str("match_id", match.ID).
-				Str("buy_order", match.BuyOrderID).
-				Str("sell_order", match.SellOrderID).
-				Str("quantity", match.Quantity.String()).
-				Str("price", match.Price.String()).
-				Msg("Match notification sent")
+	e.runMatchHook(ctx, result.Matches)
+	if !e.publishMatches(store, result.Matches) {
+		return
+	}
 
-		case <-e.stopChan:
-			return
-		}
+	if result.Rejected != "" {
+		e.recordMatchingRejection(ctx, order, RejectReasonMinRestingSpread, result.Rejected)
+	}
+
+	if len(result.Matches) > 0 {
+		e.applyRemainderPolicy(ctx, orderBook, order, result.Matches)
 	}
 
 	// Remove filled orders from order book
@@ -230,111 +713,247 @@ func (e *Engine) processOrder(ctx context.Context, order *Order) {
 	}
 }
 
-// processCancelRequest processes a cancel request
+// publishMatches resolves the sequences store reserved: matches are the ones
+// that committed and the rest were rolled back. Notifications go out once no
+// lower sequence is pending, possibly from another worker's pass. It reports
+// false if the engine stopped first.
+func (e *Engine) publishMatches(store *sequencedStore, matches []*Match) bool {
+	committed := make(map[int64]bool, len(matches))
+	for _, match := range matches {
+		committed[match.Seq] = true
+	}
+	var rolledBack []int64
+	for _, seq := range store.reserved {
+		if !committed[seq] {
+			rolledBack = append(rolledBack, seq)
+		}
+	}
+	store.reserved = nil
+
+	return e.sequencer.resolve(matches, rolledBack, e.publishMatch)
+}
+
+// publishMatch sends a match notification, reporting false if the engine
+// stopped first
+func (e *Engine) publishMatch(match *Match) bool {
+	select {
+	case e.matchChan <- match:
+		e.stats.mu.Lock()
+		e.stats.TotalMatches++
+		e.stats.mu.Unlock()
+
+		log.Info().
+			Str("match_id", match.ID).
+			Int64("seq", match.Seq).
+			Str("buy_order", match.BuyOrderID).
+			Str("sell_order", match.SellOrderID).
+			Str("quantity", match.Quantity.String()).
+			Str("price", match.Price.String()).
+			Msg("Match notification sent")
+		return true
+
+	case <-e.stopChan:
+		return false
+	}
+}
+
+// processCancelRequest processes a cancel request. The order row is locked
+// first, so the cancel serializes with any match in flight on the same order.
 func (e *Engine) processCancelRequest(ctx context.Context, cancel *CancelRequest) {
+	e.quiesceMu.RLock()
+	defer e.quiesceMu.RUnlock()
+
 	log.Debug().
 		Str("order_id", cancel.OrderID).
 		Str("user_address", cancel.UserAddress).
 		Msg("Processing cancel request")
 
-	// Update order status in database
-	result, err := e.db.Exec(ctx, `
-		UPDATE orders
-		SET status = 'CANCELLED'
-		WHERE id = $1
-		  AND user_address = $2
-		  AND status IN ('REVEALED', 'PARTIALLY_FILLED')
-	`, cancel.OrderID, cancel.UserAddress)
-
+	err := e.store.CancelOrder(ctx, cancel.OrderID, cancel.UserAddress)
+	cancel.done <- err
 	if err != nil {
-		log.Error().Err(err).
+		log.Warn().Err(err).
 			Str("order_id", cancel.OrderID).
-			Msg("Failed to cancel order in database")
+			Msg("Order not cancelled")
 		return
 	}
 
-	rowsAffected := result.RowsAffected()
-	if rowsAffected == 0 {
-		log.Warn().
+	e.stats.mu.Lock()
+	e.stats.TotalCancels++
+	e.stats.mu.Unlock()
+
+	if e.EvictOrder(cancel.OrderID) {
+		log.Info().
 			Str("order_id", cancel.OrderID).
-			Msg("Order not found or cannot be cancelled")
-		return
+			Msg("Order cancelled and removed from book")
+	}
+}
+
+// EvictOrder removes an order from the in-memory books without touching the database.
+// Used once the order's cancellation has already been persisted.
+func (e *Engine) EvictOrder(orderID string) bool {
+	found, _ := e.bookMgr.FindOrder(orderID)
+	if found == nil {
+		return false
 	}
 
-	// Remove from all order books
-	// We need to check all books since we don't know which one it's in
-	// This is not efficient but works for now - can optimize later
-	e.bookMgr.mu.RLock()
-	for _, book := range e.bookMgr.books {
-		if order := book.GetOrder(cancel.OrderID); order != nil {
-			book.RemoveOrder(cancel.OrderID)
-			log.Info().
-				Str("order_id", cancel.OrderID).
-				Msg("Order cancelled and removed from book")
-			break
-		}
+	// Wait out a rebuild of the pair, then remove from whichever book is current
+	pairLock := e.pairLock(found.baseToken, found.quoteToken)
+	pairLock.RLock()
+	defer pairLock.RUnlock()
+
+	if book := e.bookMgr.GetBook(found.poolID, found.baseToken, found.quoteToken); book != nil {
+		return book.RemoveOrder(orderID) != nil
 	}
-	e.bookMgr.mu.RUnlock()
+	return false
 }
 
 // loadExistingOrders loads existing active orders from database into memory
 func (e *Engine) loadExistingOrders(ctx context.Context) error {
 	log.Info().Msg("Loading existing orders from database")
 
-	rows, err := e.db.Query(ctx, `
-		SELECT id, user_address, chain_id, order_type, base_token, quote_token,
-		       quantity, price, variance_bps, min_price, max_price,
-		       filled_quantity, remaining_quantity, status, created_at, expires_at
-		FROM orders
-		WHERE status IN ('REVEALED', 'PARTIALLY_FILLED')
-		  AND (expires_at IS NULL OR expires_at > NOW())
-		ORDER BY created_at ASC
-	`)
+	orders, err := e.store.LoadActiveOrders(ctx, e.Now(), "", "")
 	if err != nil {
-		return fmt.Errorf("failed to query existing orders: %w", err)
+		return fmt.Errorf("failed to load existing orders: %w", err)
+	}
+
+	for _, o := range orders {
+		// Add to order book
+		orderBook := e.bookMgr.GetOrCreateBook(o.PoolID, o.BaseToken, o.QuoteToken)
+		orderBook.AddOrder(o)
 	}
-	defer rows.Close()
 
-	count := 0
-	for rows.Next() {
-		var o Order
-		var quantityStr, priceStr, minPriceStr, maxPriceStr, filledStr, remainingStr string
-		var expiresAt *time.Time
+	log.Info().Int("count", len(orders)).Msg("Loaded existing orders into memory")
+	return nil
+}
+
+// cancelRetiredPairOrders cancels resting orders on pairs that config no longer
+// allows trading, so loading skips them
+func (e *Engine) cancelRetiredPairOrders(ctx context.Context) error {
+	pairs, err := e.activePairs(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pairs {
+		if e.cfg.PairTradable(p.base, p.quote) {
+			continue
+		}
 
-		err := rows.Scan(
-			&o.ID, &o.UserAddress, &o.ChainID, &o.OrderType, &o.BaseToken, &o.QuoteToken,
-			&quantityStr, &priceStr, &o.VarianceBPS, &minPriceStr, &maxPriceStr,
-			&filledStr, &remainingStr, &o.Status, &o.CreatedAt, &expiresAt,
-		)
+		result, err := e.db.Exec(ctx, `
+			UPDATE orders
+			SET status = 'CANCELLED'
+			WHERE base_token = $1
+			  AND quote_token = $2
+			  AND status IN ('REVEALED', 'PARTIALLY_FILLED')
+		`, p.base, p.quote)
 		if err != nil {
-			return fmt.Errorf("failed to scan order: %w", err)
+			return fmt.Errorf("failed to cancel orders for %s/%s: %w", p.base, p.quote, err)
 		}
 
-		// Handle nullable expires_at
-		if expiresAt != nil {
-			o.ExpiresAt = *expiresAt
+		log.Warn().
+			Str("base_token", p.base).
+			Str("quote_token", p.quote).
+			Int64("cancelled", result.RowsAffected()).
+			Msg("Cancelled resting orders on retired pair")
+	}
+
+	return nil
+}
+
+// RebuildBook discards the in-memory books for a pair, in every pool, and reloads
+// its active orders from the database. Matching for the pair is paused until it
+// completes. Returns the size of the discarded books and the number of orders loaded.
+func (e *Engine) RebuildBook(ctx context.Context, baseToken, quoteToken string) (int, int, error) {
+	pairLock := e.pairLock(baseToken, quoteToken)
+	pairLock.Lock()
+	defer pairLock.Unlock()
+
+	// Queued orders wait on the lock; readers keep seeing the old book meanwhile
+	e.setRebuilding(baseToken, quoteToken, true)
+	defer e.setRebuilding(baseToken, quoteToken, false)
+
+	log.Info().
+		Str("base_token", baseToken).
+		Str("quote_token", quoteToken).
+		Msg("Rebuilding order book from database")
+
+	previousSize := 0
+	for _, old := range e.bookMgr.PairBooks(baseToken, quoteToken) {
+		previousSize += old.Size()
+	}
+
+	orders, err := e.store.LoadActiveOrders(ctx, e.Now(), baseToken, quoteToken)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load orders for pair: %w", err)
+	}
+
+	// Build the replacements off to the side so readers never see a half-loaded
+	// book; one per pool with resting orders
+	pools := make(map[string]*OrderBook)
+	books := make([]*OrderBook, 0, 1)
+	for _, o := range orders {
+		book, exists := pools[o.PoolID]
+		if !exists {
+			book = e.bookMgr.NewBook(o.PoolID, baseToken, quoteToken)
+			pools[o.PoolID] = book
+			books = append(books, book)
 		}
+		book.AddOrder(o)
+	}
+	loaded := len(orders)
 
-		// Parse decimal values
-		o.Quantity, _ = decimal.NewFromString(quantityStr)
-		o.Price, _ = decimal.NewFromString(priceStr)
-		o.MinPrice, _ = decimal.NewFromString(minPriceStr)
-		o.MaxPrice, _ = decimal.NewFromString(maxPriceStr)
-		o.FilledQuantity, _ = decimal.NewFromString(filledStr)
-		o.RemainingQuantity, _ = decimal.NewFromString(remainingStr)
+	e.bookMgr.ReplacePairBooks(baseToken, quoteToken, books)
 
-		// Add to order book
-		orderBook := e.bookMgr.GetOrCreateBook(o.BaseToken, o.QuoteToken)
-		orderBook.AddOrder(&o)
+	log.Info().
+		Str("base_token", baseToken).
+		Str("quote_token", quoteToken).
+		Int("previous_size", previousSize).
+		Int("loaded", loaded).
+		Msg("Order book rebuilt")
+
+	return previousSize, loaded, nil
+}
+
+// LoadOrders reads orders by id from the database, in any status.
+// Ids with no matching order are simply absent from the result.
+func (e *Engine) LoadOrders(ctx context.Context, orderIDs []string) ([]*Order, error) {
+	return e.store.LoadOrders(ctx, orderIDs)
+}
 
-		count++
+// GetInMemoryOrder returns a snapshot of the order as the engine's books hold
+// it, for comparing against the database. False if no book has the order.
+func (e *Engine) GetInMemoryOrder(orderID string) (Order, bool) {
+	book, order := e.bookMgr.FindOrder(orderID)
+	if order == nil {
+		return Order{}, false
 	}
 
-	log.Info().Int("count", count).Msg("Loaded existing orders into memory")
-	return nil
+	book.mu.RLock()
+	snapshot := *order
+	book.mu.RUnlock()
+
+	snapshot.trace = nil
+	snapshot.done = nil
+	return snapshot, true
+}
+
+// QueuePosition locates a resting order within its price level in whichever
+// book holds it. False if no book has the order.
+func (e *Engine) QueuePosition(orderID string) (QueuePosition, bool) {
+	book, order := e.bookMgr.FindOrder(orderID)
+	if order == nil {
+		return QueuePosition{}, false
+	}
+	return book.QueuePosition(orderID)
+}
+
+// GetMatchTrace returns the recorded match trace for an order, or nil if the
+// order wasn't traced or its trace has been evicted
+func (e *Engine) GetMatchTrace(orderID string) *MatchTrace {
+	return e.traces.get(orderID)
 }
 
-// GetOrderBook retrieves the order book for a token pair
-func (e *Engine) GetOrderBook(baseToken, quoteToken string) *OrderBook {
-	return e.bookMgr.GetBook(baseToken, quoteToken)
+// GetOrderBook retrieves the order book for a token pair in a pool
+func (e *Engine) GetOrderBook(poolID, baseToken, quoteToken string) *OrderBook {
+	return e.bookMgr.GetBook(poolID, baseToken, quoteToken)
 }
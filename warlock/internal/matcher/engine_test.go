@@ -0,0 +1,377 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// testConfig loads the default configuration; the database URL is required
+// but never dialed
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	t.Setenv("DATABASE_URL", "postgres://unused")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	return cfg
+}
+
+// newTestEngine starts an engine over a MemoryStore, with no database
+func newTestEngine(t *testing.T, cfg *config.Config) (*Engine, *MemoryStore) {
+	t.Helper()
+	store := NewMemoryStore()
+	e := NewEngine(nil, cfg)
+	e.SetStore(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := e.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		e.Stop()
+		cancel()
+	})
+	return e, store
+}
+
+// testOrder builds a revealed order on the WETH/USDC pair, with its price
+// range variance bps either side of price
+func testOrder(user string, side OrderType, quantity, price string, variance int32) *Order {
+	q := decimal.RequireFromString(quantity)
+	p := decimal.RequireFromString(price)
+	v := decimal.NewFromInt(int64(variance)).Div(decimal.NewFromInt(10000))
+	return &Order{
+		ID:                uuid.NewString(),
+		UserAddress:       user,
+		ChainID:           1,
+		OrderType:         side,
+		BaseToken:         "WETH",
+		QuoteToken:        "USDC",
+		Quantity:          q,
+		Price:             p,
+		VarianceBPS:       variance,
+		MinPrice:          p.Mul(decimal.NewFromInt(1).Sub(v)),
+		MaxPrice:          p.Mul(decimal.NewFromInt(1).Add(v)),
+		FilledQuantity:    decimal.Zero,
+		RemainingQuantity: q,
+		Status:            OrderStatusRevealed,
+		CreatedAt:         time.Now(),
+		RemainderPolicy:   RemainderRest,
+	}
+}
+
+// submit stores the order and matches it synchronously, as SubmitOrder does
+func submit(t *testing.T, e *Engine, order *Order) []*Match {
+	t.Helper()
+	ctx := context.Background()
+	if err := e.Store().CreateOrders(ctx, []NewOrder{{Order: order}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+	matches, err := e.SubmitOrderSync(ctx, order)
+	if err != nil {
+		t.Fatalf("SubmitOrderSync: %v", err)
+	}
+	return matches
+}
+
+// loadOrder reads the stored copy of an order
+func loadOrder(t *testing.T, store Store, id string) *Order {
+	t.Helper()
+	orders, err := store.LoadOrders(context.Background(), []string{id})
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("LoadOrders(%s) = %v, %v", id, orders, err)
+	}
+	return orders[0]
+}
+
+func TestEngineFullFlowInMemory(t *testing.T) {
+	cfg := testConfig(t)
+	e, store := newTestEngine(t, cfg)
+	ctx := context.Background()
+
+	sell := testOrder("0xalice", OrderTypeSell, "10", "100", 100)
+	if matches := submit(t, e, sell); len(matches) != 0 {
+		t.Fatalf("first order matched %d times with an empty book", len(matches))
+	}
+
+	buy := testOrder("0xbob", OrderTypeBuy, "4", "100", 100)
+	matches := submit(t, e, buy)
+	if len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	m := matches[0]
+	if m.BuyOrderID != buy.ID || m.SellOrderID != sell.ID {
+		t.Errorf("match pairs %s/%s, want %s/%s", m.BuyOrderID, m.SellOrderID, buy.ID, sell.ID)
+	}
+	if !m.Quantity.Equal(decimal.NewFromInt(4)) || !m.Price.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("match %s @ %s, want 4 @ 100", m.Quantity, m.Price)
+	}
+
+	select {
+	case published := <-e.MatchChan():
+		if published.ID != m.ID {
+			t.Errorf("published match %s, want %s", published.ID, m.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("match was not published")
+	}
+
+	if recorded := store.Matches(); len(recorded) != 1 || recorded[0].ID != m.ID {
+		t.Fatalf("store recorded %v, want the one match", recorded)
+	}
+	if got := loadOrder(t, store, buy.ID); got.Status != OrderStatusFilled {
+		t.Errorf("buy status %s, want FILLED", got.Status)
+	}
+	got := loadOrder(t, store, sell.ID)
+	if got.Status != OrderStatusPartiallyFilled || !got.RemainingQuantity.Equal(decimal.NewFromInt(6)) {
+		t.Errorf("sell %s with %s remaining, want PARTIALLY_FILLED with 6", got.Status, got.RemainingQuantity)
+	}
+
+	book := e.bookMgr.GetBook("", "WETH", "USDC")
+	if best := book.PeekBestAsk(); best == nil || best.ID != sell.ID || !best.RemainingQuantity.Equal(decimal.NewFromInt(6)) {
+		t.Fatalf("best ask %v, want the sell with 6 remaining", best)
+	}
+	if book.PeekBestBid() != nil {
+		t.Error("filled buy still rests on the book")
+	}
+
+	if err := e.CancelOrder(ctx, sell.ID, "0xbob"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("cancel by another user: %v, want ErrOrderNotFound", err)
+	}
+	if err := e.CancelOrder(ctx, sell.ID, "0xalice"); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if got := loadOrder(t, store, sell.ID); got.Status != OrderStatusCancelled {
+		t.Errorf("sell status %s after cancel, want CANCELLED", got.Status)
+	}
+	if book.PeekBestAsk() != nil {
+		t.Error("cancelled sell still rests on the book")
+	}
+	if err := e.CancelOrder(ctx, sell.ID, "0xalice"); !errors.Is(err, ErrOrderNotCancelable) {
+		t.Errorf("second cancel: %v, want ErrOrderNotCancelable", err)
+	}
+	if err := e.CancelOrder(ctx, buy.ID, "0xbob"); !errors.Is(err, ErrCancelTooLate) {
+		t.Errorf("cancel of a filled order: %v, want ErrCancelTooLate", err)
+	}
+
+	// A later buy finds nothing: the cancelled sell is no longer a candidate
+	if matches := submit(t, e, testOrder("0xcarol", OrderTypeBuy, "1", "100", 100)); len(matches) != 0 {
+		t.Errorf("buy matched %d times against a cancelled order", len(matches))
+	}
+}
+
+func TestEngineReloadsBooksFromMemoryStore(t *testing.T) {
+	cfg := testConfig(t)
+	e, store := newTestEngine(t, cfg)
+
+	sell := testOrder("0xalice", OrderTypeSell, "5", "100", 100)
+	submit(t, e, sell)
+	submit(t, e, testOrder("0xbob", OrderTypeBuy, "2", "100", 100))
+
+	discarded, loaded, err := e.RebuildBook(context.Background(), "WETH", "USDC")
+	if err != nil {
+		t.Fatalf("RebuildBook: %v", err)
+	}
+	if discarded != 1 || loaded != 1 {
+		t.Errorf("RebuildBook discarded %d and loaded %d, want 1 and 1", discarded, loaded)
+	}
+
+	best := e.bookMgr.GetBook("", "WETH", "USDC").PeekBestAsk()
+	want := loadOrder(t, store, sell.ID)
+	if best == nil || !best.RemainingQuantity.Equal(want.RemainingQuantity) {
+		t.Errorf("reloaded ask %v, want %s remaining", best, want.RemainingQuantity)
+	}
+}
+
+func TestMatchedVolumeAccumulatesAcrossMatches(t *testing.T) {
+	for _, skipLocked := range []bool{false, true} {
+		t.Run(fmt.Sprintf("skip_locked=%t", skipLocked), func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.MatchSkipLocked = skipLocked
+			e, _ := newTestEngine(t, cfg)
+
+			submit(t, e, testOrder("0xalice", OrderTypeSell, "2", "100", 100))
+			submit(t, e, testOrder("0xcarol", OrderTypeSell, "3", "101", 100))
+			first := submit(t, e, testOrder("0xbob", OrderTypeBuy, "4", "101", 100))
+			second := submit(t, e, testOrder("0xdave", OrderTypeBuy, "1", "101", 100))
+
+			matches := append(first, second...)
+			if len(matches) != 3 {
+				t.Fatalf("got %d matches, want 3", len(matches))
+			}
+			quote := decimal.Zero
+			for _, m := range matches {
+				quote = quote.Add(m.Quantity.Mul(m.Price))
+			}
+
+			stats := e.GetStats()
+			if !stats.MatchedBaseVolume.Equal(decimal.NewFromInt(5)) {
+				t.Errorf("base volume %s, want 5", stats.MatchedBaseVolume)
+			}
+			if !stats.MatchedQuoteVolume.Equal(quote) {
+				t.Errorf("quote volume %s, want %s", stats.MatchedQuoteVolume, quote)
+			}
+		})
+	}
+}
+
+func TestRebuildBookWhileMatchingKeepsStoreAndBookAligned(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Workers = 4
+	e, store := newTestEngine(t, cfg)
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		submit(t, e, testOrder(fmt.Sprintf("0xmaker%d", i), OrderTypeSell, "3", "100", 100))
+	}
+
+	// Takers fill and rest against the makers while the book is rebuilt under them
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 40; i++ {
+		side := OrderTypeBuy
+		if i%4 == 0 {
+			side = OrderTypeSell
+		}
+		taker := testOrder(fmt.Sprintf("0xtaker%d", i), side, "2", "100", 100)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.CreateOrders(ctx, []NewOrder{{Order: taker}}); err != nil {
+				errs <- err
+				return
+			}
+			if _, err := e.SubmitOrderSync(ctx, taker); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	done := make(chan struct{})
+	rebuilt := make(chan int)
+	go func() {
+		n := 0
+		for {
+			select {
+			case <-done:
+				rebuilt <- n
+				return
+			default:
+			}
+			if _, _, err := e.RebuildBook(ctx, "WETH", "USDC"); err != nil {
+				errs <- err
+			}
+			n++
+		}
+	}()
+	wg.Wait()
+	close(done)
+	if n := <-rebuilt; n == 0 {
+		t.Fatal("book was never rebuilt while matching")
+	}
+	close(errs)
+	for err := range errs {
+		t.Fatalf("matching under rebuild: %v", err)
+	}
+
+	active, err := store.LoadActiveOrders(ctx, e.Now(), "WETH", "USDC")
+	if err != nil {
+		t.Fatalf("LoadActiveOrders: %v", err)
+	}
+	inBook := 0
+	for _, book := range e.bookMgr.PairBooks("WETH", "USDC") {
+		inBook += book.Size()
+	}
+	if inBook != len(active) {
+		t.Errorf("book holds %d orders, store has %d active", inBook, len(active))
+	}
+	for _, want := range active {
+		got, ok := e.GetInMemoryOrder(want.ID)
+		if !ok {
+			t.Errorf("active order %s missing from the book", want.ID)
+			continue
+		}
+		if !got.RemainingQuantity.Equal(want.RemainingQuantity) {
+			t.Errorf("order %s has %s remaining in the book, %s in the store", want.ID, got.RemainingQuantity, want.RemainingQuantity)
+		}
+	}
+}
+
+func TestCancelOvertakesAFullOrderChannel(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Workers = 1
+	cfg.OrderChannelSize = 4
+	e, store, release := startGatedEngine(t, cfg, "WETH")
+	ctx := context.Background()
+
+	maker := testOrder("0xalice", OrderTypeSell, "10", "100", 100)
+	submit(t, e, maker)
+
+	// The only worker stalls mid-fill while buys for the maker fill the channel
+	stalled := testOrder("0xbob", OrderTypeBuy, "1", "100", 100)
+	if err := store.CreateOrders(ctx, []NewOrder{{Order: stalled}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+	stalledDone := make(chan error, 1)
+	go func() {
+		_, err := e.SubmitOrderSync(ctx, stalled)
+		stalledDone <- err
+	}()
+	waitGated(t, store)
+
+	for i := 0; ; i++ {
+		buy := testOrder(fmt.Sprintf("0xbuyer%d", i), OrderTypeBuy, "1", "100", 100)
+		if err := store.CreateOrders(ctx, []NewOrder{{Order: buy}}); err != nil {
+			t.Fatalf("CreateOrders: %v", err)
+		}
+		if err := e.SubmitOrder(buy); err != nil {
+			if i != cfg.OrderChannelSize {
+				t.Fatalf("order %d refused: %v", i, err)
+			}
+			break
+		}
+	}
+
+	cancelled := make(chan error, 1)
+	go func() { cancelled <- e.CancelOrder(ctx, maker.ID, maker.UserAddress) }()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(e.cancelChan) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("cancel never queued")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The worker takes the cancel ahead of every queued buy
+	release()
+	if err := <-stalledDone; err != nil {
+		t.Fatalf("SubmitOrderSync: %v", err)
+	}
+	select {
+	case err := <-cancelled:
+		if err != nil {
+			t.Fatalf("CancelOrder: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancel never processed")
+	}
+
+	// Queued in order behind the buys, so it runs once they have
+	submit(t, e, testOrder("0xcarol", OrderTypeSell, "1", "200", 100))
+	stored := loadOrder(t, store, maker.ID)
+	if stored.Status != OrderStatusCancelled || !stored.FilledQuantity.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("maker %s with %s filled, want CANCELLED with only the stalled fill", stored.Status, stored.FilledQuantity)
+	}
+	if n := len(store.Matches()); n != 1 {
+		t.Errorf("%d matches recorded, want only the stalled fill", n)
+	}
+}
@@ -0,0 +1,28 @@
+package matcher
+
+import (
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/shopspring/decimal"
+)
+
+var bpsDenominator = decimal.NewFromInt(10000)
+
+// feeScale matches the scale of the fee columns, so the in-memory match
+// carries exactly what is stored. Fees round straight to it: a plain Div
+// stops at 16 decimals and would drop the smallest fees.
+const feeScale = 18
+
+// matchFees returns the fee the taker pays and the rebate the maker receives
+// on a fill, both in the quote token. The rebate is paid out of the taker fee
+// at no more than its rate, so the venue's share is never negative.
+func matchFees(cfg *config.Config, quantity, price decimal.Decimal) (takerFee, makerRebate decimal.Decimal) {
+	notional := quantity.Mul(price)
+	takerFee = notional.Mul(decimal.NewFromInt(int64(cfg.TakerFeeBps))).DivRound(bpsDenominator, feeScale)
+	makerRebate = notional.Mul(decimal.NewFromInt(int64(cfg.MakerRebateBps))).DivRound(bpsDenominator, feeScale)
+	return takerFee, decimal.Min(makerRebate, takerFee)
+}
+
+// VenueFee is what the venue keeps from a match: the taker fee less the maker rebate
+func (m *Match) VenueFee() decimal.Decimal {
+	return m.TakerFee.Sub(m.MakerRebate)
+}
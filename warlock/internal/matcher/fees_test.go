@@ -0,0 +1,57 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMatchFeesNetToZero(t *testing.T) {
+	tests := []struct {
+		name       string
+		takerBps   int
+		rebateBps  int
+		quantity   string
+		price      string
+		wantFee    string
+		wantRebate string
+	}{
+		{name: "plain", takerBps: 10, rebateBps: 2, quantity: "4", price: "100", wantFee: "0.4", wantRebate: "0.08"},
+		{name: "no fees", takerBps: 0, rebateBps: 0, quantity: "4", price: "100", wantFee: "0", wantRebate: "0"},
+		{name: "rebate capped at the fee", takerBps: 2, rebateBps: 10, quantity: "4", price: "100", wantFee: "0.08", wantRebate: "0.08"},
+		{name: "equal rates", takerBps: 5, rebateBps: 5, quantity: "3", price: "7", wantFee: "0.0105", wantRebate: "0.0105"},
+		// 1e-18 * 1 bp is 1e-22, well under the fee scale
+		{name: "rounds to zero", takerBps: 1, rebateBps: 1, quantity: "0.000000000000000001", price: "1", wantFee: "0", wantRebate: "0"},
+		// 5e-15 * 1 bp is exactly half a unit at the fee scale and rounds up
+		{name: "half unit rounds up", takerBps: 1, rebateBps: 1, quantity: "0.000000000000005", price: "1", wantFee: "0.000000000000000001", wantRebate: "0.000000000000000001"},
+		// The rebate's half unit rounds up to the fee's whole unit, never past it
+		{name: "rebate half unit", takerBps: 2, rebateBps: 1, quantity: "0.000000000000005", price: "1", wantFee: "0.000000000000000001", wantRebate: "0.000000000000000001"},
+		{name: "just under half a unit", takerBps: 1, rebateBps: 1, quantity: "0.0000000000000049", price: "1", wantFee: "0", wantRebate: "0"},
+		{name: "many decimals", takerBps: 7, rebateBps: 3, quantity: "0.333333333333333333", price: "2999.999999999999999999", wantFee: "0.699999999999999999", wantRebate: "0.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.TakerFeeBps = tt.takerBps
+			cfg.MakerRebateBps = tt.rebateBps
+
+			fee, rebate := matchFees(cfg, decimal.RequireFromString(tt.quantity), decimal.RequireFromString(tt.price))
+			if !fee.Equal(decimal.RequireFromString(tt.wantFee)) || !rebate.Equal(decimal.RequireFromString(tt.wantRebate)) {
+				t.Errorf("fee %s, rebate %s; want %s, %s", fee, rebate, tt.wantFee, tt.wantRebate)
+			}
+
+			// What the taker pays is exactly what the maker and the venue receive
+			m := &Match{TakerFee: fee, MakerRebate: rebate}
+			if !m.MakerRebate.Add(m.VenueFee()).Sub(m.TakerFee).IsZero() {
+				t.Errorf("fee %s does not net against rebate %s and venue fee %s", fee, rebate, m.VenueFee())
+			}
+			if rebate.GreaterThan(fee) || rebate.IsNegative() || m.VenueFee().IsNegative() {
+				t.Errorf("fee %s, rebate %s, venue fee %s; want 0 <= rebate <= fee", fee, rebate, m.VenueFee())
+			}
+			if fee.Exponent() < -feeScale || rebate.Exponent() < -feeScale {
+				t.Errorf("fee %s or rebate %s exceeds %d decimals", fee, rebate, feeScale)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package matcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// matchHookRetryDelay is how long BLOCK mode waits before calling a failed
+// hook again
+const matchHookRetryDelay = time.Second
+
+// MatchHook runs venue-specific logic for every match, e.g. publishing it to
+// a message bus or posting it to a ledger. It is called once the match is
+// committed, so an error can't undo the match; see MatchHookFailureMode for
+// what it does instead.
+type MatchHook interface {
+	OnMatch(ctx context.Context, match *Match) error
+}
+
+// NopMatchHook is the default MatchHook; it does nothing
+type NopMatchHook struct{}
+
+// OnMatch implements MatchHook
+func (NopMatchHook) OnMatch(ctx context.Context, match *Match) error {
+	return nil
+}
+
+// MatchHooks runs several hooks in turn and joins their errors. Under BLOCK a
+// retry calls every hook again, so each must tolerate seeing a match twice.
+type MatchHooks []MatchHook
+
+// OnMatch implements MatchHook
+func (hooks MatchHooks) OnMatch(ctx context.Context, match *Match) error {
+	var errs []error
+	for _, hook := range hooks {
+		if err := hook.OnMatch(ctx, match); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SetMatchHook replaces the hook run for every match. Call before Start.
+func (e *Engine) SetMatchHook(hook MatchHook) {
+	e.hook = hook
+}
+
+// runMatchHook hands each match to the hook in order. Each call gets a copy,
+// so a hook can't alter the match that is streamed to clients. With BLOCK a
+// failing call is retried until it succeeds or the engine stops, holding up
+// the worker and the pair meanwhile.
+func (e *Engine) runMatchHook(ctx context.Context, matches []*Match) {
+	for _, match := range matches {
+		for attempt := 1; ; attempt++ {
+			hookCtx, cancel := context.WithTimeout(ctx, e.cfg.MatchHookTimeout)
+			matchCopy := *match
+			matchCopy.BuyMetadata = maps.Clone(match.BuyMetadata)
+			matchCopy.SellMetadata = maps.Clone(match.SellMetadata)
+			err := e.hook.OnMatch(hookCtx, &matchCopy)
+			cancel()
+			if err == nil {
+				break
+			}
+
+			log.Error().Err(err).
+				Str("match_id", match.ID).
+				Int("attempt", attempt).
+				Str("failure_mode", e.cfg.MatchHookFailureMode).
+				Msg("Match hook failed")
+			if e.cfg.MatchHookFailureMode != config.MatchHookFailureBlock {
+				break
+			}
+
+			select {
+			case <-e.stopChan:
+				return
+			case <-time.After(matchHookRetryDelay):
+			}
+		}
+	}
+}
+
+// WebhookHook POSTs every match as JSON to a URL and fails on any response
+// other than 2xx
+type WebhookHook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookHook creates a hook posting to url
+func NewWebhookHook(url string) *WebhookHook {
+	return &WebhookHook{url: url, client: &http.Client{}}
+}
+
+// webhookMatch is the JSON body WebhookHook sends
+type webhookMatch struct {
+	ID                 string            `json:"id"`
+	BuyOrderID         string            `json:"buy_order_id"`
+	SellOrderID        string            `json:"sell_order_id"`
+	BaseToken          string            `json:"base_token"`
+	QuoteToken         string            `json:"quote_token"`
+	Quantity           string            `json:"quantity"`
+	Price              string            `json:"price"`
+	BuyerAddress       string            `json:"buyer_address"`
+	SellerAddress      string            `json:"seller_address"`
+	TakerSide          OrderType         `json:"taker_side,omitempty"`
+	TakerFee           string            `json:"taker_fee"`
+	MakerRebate        string            `json:"maker_rebate"`
+	RoundedPrice       bool              `json:"rounded_price,omitempty"`
+	SettlementDeadline *time.Time        `json:"settlement_deadline,omitempty"`
+	MatchedAt          time.Time         `json:"matched_at"`
+	BuyMetadata        map[string]string `json:"buy_metadata,omitempty"`
+	SellMetadata       map[string]string `json:"sell_metadata,omitempty"`
+}
+
+// OnMatch implements MatchHook
+func (h *WebhookHook) OnMatch(ctx context.Context, match *Match) error {
+	payload := webhookMatch{
+		ID:            match.ID,
+		BuyOrderID:    match.BuyOrderID,
+		SellOrderID:   match.SellOrderID,
+		BaseToken:     match.BaseToken,
+		QuoteToken:    match.QuoteToken,
+		Quantity:      match.Quantity.String(),
+		Price:         match.Price.String(),
+		BuyerAddress:  match.BuyerAddress,
+		SellerAddress: match.SellerAddress,
+		TakerSide:     match.TakerSide,
+		TakerFee:      match.TakerFee.String(),
+		MakerRebate:   match.MakerRebate.String(),
+		RoundedPrice:  match.RoundedPrice,
+		MatchedAt:     match.MatchedAt,
+		BuyMetadata:   match.BuyMetadata,
+		SellMetadata:  match.SellMetadata,
+	}
+	if !match.SettlementDeadline.IsZero() {
+		payload.SettlementDeadline = &match.SettlementDeadline
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode match: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post match: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
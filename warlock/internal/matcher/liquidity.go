@@ -0,0 +1,32 @@
+package matcher
+
+import "github.com/darkpool/warlock/internal/config"
+
+// liquidityRoles decides which of two crossing orders took liquidity, for
+// fees, the price tie-break and the match's taker_side. Under INCOMING the
+// order being matched is the taker. Under EARLIER_ORDER the order that reached
+// the venue first is the maker, however the engine got to them: two orders
+// arriving together may be picked up by workers in either order, and a
+// deferred order is matched again only after later orders have rested. Ties
+// leave the incoming order the taker.
+func liquidityRoles(cfg *config.Config, incoming, resting *Order) (taker, maker *Order) {
+	if cfg.MakerTakerPolicy == config.MakerTakerEarlierOrder && arrivedBefore(cfg, incoming, resting) {
+		return resting, incoming
+	}
+	return incoming, resting
+}
+
+// arrivedBefore reports whether a reached the venue before b, by sequence
+// under DeterministicMatching and by creation time otherwise
+func arrivedBefore(cfg *config.Config, a, b *Order) bool {
+	if cfg.DeterministicMatching {
+		return sequencedBefore(a, b)
+	}
+	return createdBefore(a, b)
+}
+
+// IsMaker reports whether the given side of the match provided liquidity.
+// Neither side is for matches recorded before taker tracking.
+func (m *Match) IsMaker(side OrderType) bool {
+	return m.TakerSide != "" && m.TakerSide != side
+}
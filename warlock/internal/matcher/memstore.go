@@ -0,0 +1,633 @@
+package matcher
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// maxCandidates is how many candidates FindCandidates returns at most
+const maxCandidates = 100
+
+// MemoryStore is a Store that keeps orders and matches in process, for tests
+// and trials without Postgres. Fills, claims and cancels lock orders the way
+// Postgres locks rows, so concurrent workers behave as they do against the
+// database. Nothing survives a restart.
+type MemoryStore struct {
+	mu       sync.Mutex
+	unlocked *sync.Cond // Broadcast whenever order locks are released
+	orders   map[string]*memOrder
+	matches  []*Match
+
+	orderSeq int64
+	matchSeq int64
+}
+
+// memOrder is a stored order and who holds its lock
+type memOrder struct {
+	order  Order
+	holder *memTx
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{orders: make(map[string]*memOrder)}
+	s.unlocked = sync.NewCond(&s.mu)
+	return s
+}
+
+// Matches returns every committed match, in the order they were recorded
+func (s *MemoryStore) Matches() []*Match {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := make([]*Match, len(s.matches))
+	for i, m := range s.matches {
+		c := *m
+		matches[i] = &c
+	}
+	return matches
+}
+
+// LastMatchSeq implements Store
+func (s *MemoryStore) LastMatchSeq(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.matchSeq, nil
+}
+
+// Ping implements Store
+func (s *MemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// LoadMatches implements Store
+func (s *MemoryStore) LoadMatches(ctx context.Context, filter MatchFilter, afterSeq, throughSeq int64, limit int) ([]*Match, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := make([]*Match, 0)
+	for _, m := range s.matches {
+		if m.Seq <= afterSeq || m.Seq > throughSeq {
+			continue
+		}
+		if filter.BaseToken != "" && m.BaseToken != filter.BaseToken {
+			continue
+		}
+		if filter.QuoteToken != "" && m.QuoteToken != filter.QuoteToken {
+			continue
+		}
+		if filter.UserAddress != "" && !strings.EqualFold(m.BuyerAddress, filter.UserAddress) &&
+			!strings.EqualFold(m.SellerAddress, filter.UserAddress) {
+			continue
+		}
+		c := *m
+		matches = append(matches, &c)
+	}
+	slices.SortFunc(matches, func(a, b *Match) int { return cmp.Compare(a.Seq, b.Seq) })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// LoadOrders implements Store
+func (s *MemoryStore) LoadOrders(ctx context.Context, ids []string) ([]*Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := make([]*Order, 0, len(ids))
+	for _, id := range ids {
+		if stored := s.orders[id]; stored != nil {
+			o := stored.order
+			orders = append(orders, &o)
+		}
+	}
+	return orders, nil
+}
+
+// LoadActiveOrders implements Store
+func (s *MemoryStore) LoadActiveOrders(ctx context.Context, now time.Time, baseToken, quoteToken string) ([]*Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := make([]*Order, 0)
+	for _, stored := range s.orders {
+		o := stored.order
+		if !o.IsActive() || o.Expired(now) {
+			continue
+		}
+		if baseToken != "" && (o.BaseToken != baseToken || o.QuoteToken != quoteToken) {
+			continue
+		}
+		orders = append(orders, &o)
+	}
+	slices.SortFunc(orders, func(a, b *Order) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Seq, b.Seq)
+	})
+	return orders, nil
+}
+
+// ActivePairs implements Store
+func (s *MemoryStore) ActivePairs(ctx context.Context, now time.Time) ([]TokenPair, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[TokenPair]bool)
+	pairs := make([]TokenPair, 0)
+	for _, stored := range s.orders {
+		o := &stored.order
+		p := TokenPair{BaseToken: o.BaseToken, QuoteToken: o.QuoteToken}
+		if !o.IsActive() || o.Expired(now) || seen[p] {
+			continue
+		}
+		seen[p] = true
+		pairs = append(pairs, p)
+	}
+	return pairs, nil
+}
+
+// FindCandidates implements MatchScope
+func (s *MemoryStore) FindCandidates(ctx context.Context, cfg *config.Config, order *Order, pausedChains []int32, now time.Time) ([]*Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findCandidates(cfg, order, pausedChains, now, nil), nil
+}
+
+// findCandidates selects candidates as PostgresStore's query does. With a
+// claim, orders another claim or fill holds are passed over and the rest are
+// locked for the claim. Called with mu held.
+func (s *MemoryStore) findCandidates(cfg *config.Config, order *Order, pausedChains []int32, now time.Time, claim *memTx) []*Order {
+	side := OrderTypeSell
+	if order.OrderType == OrderTypeSell {
+		side = OrderTypeBuy
+	}
+	limit := candidatePriceLimit(cfg, order)
+
+	matching := make([]*memOrder, 0)
+	for _, stored := range s.orders {
+		o := &stored.order
+		if o.BaseToken != order.BaseToken || o.QuoteToken != order.QuoteToken ||
+			o.PoolID != order.PoolID || o.OrderType != side ||
+			!o.IsActive() || o.Expired(now) || o.VisibleAt.After(now) || slices.Contains(pausedChains, o.ChainID) {
+			continue
+		}
+		if side == OrderTypeSell && o.MinPrice.GreaterThan(limit) {
+			continue
+		}
+		if side == OrderTypeBuy && o.MaxPrice.LessThan(limit) {
+			continue
+		}
+		if claim != nil && stored.holder != nil && stored.holder != claim {
+			continue
+		}
+		matching = append(matching, stored)
+	}
+
+	earlier := func(a, b *Order) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	if cfg.DeterministicMatching {
+		earlier = func(a, b *Order) int { return cmp.Compare(a.Seq, b.Seq) }
+	}
+	slices.SortFunc(matching, func(a, b *memOrder) int {
+		var c int
+		if side == OrderTypeSell {
+			c = a.order.MinPrice.Cmp(b.order.MinPrice)
+		} else {
+			c = b.order.MaxPrice.Cmp(a.order.MaxPrice)
+		}
+		if c != 0 {
+			return c
+		}
+		return earlier(&a.order, &b.order)
+	})
+	if len(matching) > maxCandidates {
+		matching = matching[:maxCandidates]
+	}
+
+	candidates := make([]*Order, 0, len(matching))
+	for _, stored := range matching {
+		if claim != nil {
+			claim.lock(stored)
+		}
+		o := acquireOrder()
+		*o = claim.view(stored)
+		candidates = append(candidates, o)
+	}
+	return candidates
+}
+
+// CreateOrders implements Store
+func (s *MemoryStore) CreateOrders(ctx context.Context, orders []NewOrder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, o := range orders {
+		if s.orders[o.Order.ID] != nil || slices.ContainsFunc(orders[:i], func(n NewOrder) bool { return n.Order.ID == o.Order.ID }) {
+			return fmt.Errorf("failed to insert order %s: duplicate id", o.Order.ID)
+		}
+	}
+	for _, o := range orders {
+		s.insert(o.Order)
+	}
+	return nil
+}
+
+// insert stores a new order as PostgresStore's insert does: revealed and
+// unfilled, stamped with the next sequence. Called with mu held.
+func (s *MemoryStore) insert(order *Order) {
+	s.orderSeq++
+	order.Seq = s.orderSeq
+
+	stored := *order
+	stored.FilledQuantity = decimal.Zero
+	stored.RemainingQuantity = order.Quantity
+	stored.Status = OrderStatusRevealed
+	stored.CounterpartyAllowlist = slices.Clone(order.CounterpartyAllowlist)
+	stored.Metadata = maps.Clone(order.Metadata)
+	stored.trace = nil
+	stored.done = nil
+	if stored.CreatedAt.IsZero() {
+		stored.CreatedAt = time.Now()
+	}
+	s.orders[order.ID] = &memOrder{order: stored}
+}
+
+// CancelOrder implements Store. Like the row lock PostgresStore takes, it
+// waits for any fill or claim holding the order to end.
+func (s *MemoryStore) CancelOrder(ctx context.Context, orderID, userAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.cancelable(orderID, userAddress)
+	if err != nil {
+		return err
+	}
+	stored.order.Status = OrderStatusCancelled
+	return nil
+}
+
+// ReplaceOrder implements Store
+func (s *MemoryStore) ReplaceOrder(ctx context.Context, orderID, userAddress string, replacement NewOrder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.cancelable(orderID, userAddress)
+	if err != nil {
+		return err
+	}
+	if s.orders[replacement.Order.ID] != nil {
+		return fmt.Errorf("failed to insert order %s: duplicate id", replacement.Order.ID)
+	}
+	stored.order.Status = OrderStatusCancelled
+	s.insert(replacement.Order)
+	return nil
+}
+
+// RepegOrder implements Store
+func (s *MemoryStore) RepegOrder(ctx context.Context, orderID string, price, minPrice, maxPrice decimal.Decimal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, err := s.cancelable(orderID, "")
+	if err != nil {
+		// No longer open
+		return nil
+	}
+	stored.order.Price = price
+	stored.order.MinPrice = minPrice
+	stored.order.MaxPrice = maxPrice
+	return nil
+}
+
+// CancelOrdersWhere implements Store
+func (s *MemoryStore) CancelOrdersWhere(ctx context.Context, f CancelFilter) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0)
+	for id, stored := range s.orders {
+		if stored.order.IsActive() && f.selects(&stored.order) {
+			ids = append(ids, id)
+		}
+	}
+
+	// Each waits out fills in flight, which may leave it no longer open
+	orderIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		stored, err := s.cancelable(id, f.UserAddress)
+		if err != nil || !f.selects(&stored.order) {
+			continue
+		}
+		stored.order.Status = OrderStatusCancelled
+		orderIDs = append(orderIDs, id)
+	}
+	return orderIDs, nil
+}
+
+// StaleMatches implements Store
+func (s *MemoryStore) StaleMatches(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stale := make([]*Match, 0)
+	for _, m := range s.matches {
+		if awaitingSettlement(m) && !m.SettlementDeadline.IsZero() && m.SettlementDeadline.Before(now) {
+			stale = append(stale, m)
+		}
+	}
+	slices.SortStableFunc(stale, func(a, b *Match) int {
+		return a.SettlementDeadline.Compare(b.SettlementDeadline)
+	})
+
+	matchIDs := make([]string, 0, min(len(stale), limit))
+	for _, m := range stale[:min(len(stale), limit)] {
+		matchIDs = append(matchIDs, m.ID)
+	}
+	return matchIDs, nil
+}
+
+// FailMatch implements Store
+func (s *MemoryStore) FailMatch(ctx context.Context, matchID, reason string) ([]*Order, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := slices.IndexFunc(s.matches, func(m *Match) bool { return m.ID == matchID })
+	if idx < 0 {
+		return nil, fmt.Errorf("failed to mark match failed: match %s not found", matchID)
+	}
+	m := s.matches[idx]
+
+	// Wait out fills holding either order, as the row locks would
+	for {
+		if !awaitingSettlement(m) {
+			return nil, fmt.Errorf("failed to mark match failed: match %s is %s", matchID, m.SettlementStatus)
+		}
+		buy, sell := s.orders[m.BuyOrderID], s.orders[m.SellOrderID]
+		if buy == nil || sell == nil {
+			return nil, fmt.Errorf("failed to restore orders of match %s: order not found", matchID)
+		}
+		if buy.holder == nil && sell.holder == nil {
+			break
+		}
+		s.unlocked.Wait()
+	}
+	m.SettlementStatus = "FAILED"
+
+	restored := make([]*Order, 0, 2)
+	for _, id := range []string{m.BuyOrderID, m.SellOrderID} {
+		o := &s.orders[id].order
+		o.FilledQuantity = o.FilledQuantity.Sub(m.Quantity)
+		o.RemainingQuantity = o.RemainingQuantity.Add(m.Quantity)
+		switch {
+		case o.Status == OrderStatusCancelled:
+		case o.FilledQuantity.IsPositive():
+			o.Status = OrderStatusPartiallyFilled
+		default:
+			o.Status = OrderStatusRevealed
+		}
+		c := *o
+		restored = append(restored, &c)
+	}
+	return restored, nil
+}
+
+// awaitingSettlement reports whether a match can still settle or fail
+func awaitingSettlement(m *Match) bool {
+	return m.SettlementStatus == "PENDING" || m.SettlementStatus == "SETTLING"
+}
+
+// cancelable waits until the order is unlocked and returns it if the user may
+// cancel it. Called with mu held.
+func (s *MemoryStore) cancelable(orderID, userAddress string) (*memOrder, error) {
+	for {
+		stored := s.orders[orderID]
+		if stored == nil || (userAddress != "" && !strings.EqualFold(stored.order.UserAddress, userAddress)) {
+			return nil, ErrOrderNotFound
+		}
+		if stored.holder != nil {
+			s.unlocked.Wait()
+			continue
+		}
+
+		switch stored.order.Status {
+		case OrderStatusRevealed, OrderStatusPartiallyFilled:
+			return stored, nil
+		case OrderStatusFilled:
+			return nil, ErrCancelTooLate
+		default:
+			return nil, ErrOrderNotCancelable
+		}
+	}
+}
+
+// BeginClaim implements Store
+func (s *MemoryStore) BeginClaim(ctx context.Context) (Claim, error) {
+	return &memTx{store: s, claim: true, orders: make(map[string]Order)}, nil
+}
+
+// BeginFill implements MatchScope
+func (s *MemoryStore) BeginFill(ctx context.Context) (FillTx, error) {
+	return &memTx{store: s, orders: make(map[string]Order)}, nil
+}
+
+// memTx is a MemoryStore claim, or a fill: on its own or within a claim.
+// Writes are buffered in it until Commit, a fill's into its claim.
+type memTx struct {
+	store  *MemoryStore
+	claim  bool
+	parent *memTx // The claim a fill is part of
+
+	orders  map[string]Order // Orders written, by id
+	matches []*Match
+	locked  []*memOrder // Orders whose lock this claim or standalone fill took
+	done    bool
+}
+
+// owner is who the tx's locks belong to: its claim's for a fill in one, so
+// they are held until the claim ends as Postgres row locks are
+func (tx *memTx) owner() *memTx {
+	if tx.parent != nil {
+		return tx.parent
+	}
+	return tx
+}
+
+// lock takes the order's lock for the tx's owner. Called with mu held and
+// the order unlocked or already the owner's.
+func (tx *memTx) lock(stored *memOrder) {
+	owner := tx.owner()
+	if stored.holder != owner {
+		stored.holder = owner
+		owner.locked = append(owner.locked, stored)
+	}
+}
+
+// view is the order as the tx sees it, its own uncommitted writes included.
+// A nil tx sees what is committed.
+func (tx *memTx) view(stored *memOrder) Order {
+	for t := tx; t != nil; t = t.parent {
+		if o, ok := t.orders[stored.order.ID]; ok {
+			return o
+		}
+	}
+	return stored.order
+}
+
+// FindCandidates implements MatchScope for a claim
+func (tx *memTx) FindCandidates(ctx context.Context, cfg *config.Config, order *Order, pausedChains []int32, now time.Time) ([]*Order, error) {
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+	return tx.store.findCandidates(cfg, order, pausedChains, now, tx), nil
+}
+
+// BeginFill implements MatchScope for a claim
+func (tx *memTx) BeginFill(ctx context.Context) (FillTx, error) {
+	return &memTx{store: tx.store, parent: tx, orders: make(map[string]Order)}, nil
+}
+
+// LockOrders implements FillTx. It waits for a fill holding either order to
+// commit, as a row lock would. Orders another claim holds give
+// ErrDuplicateMatch at once: claims last a whole MatchOrder and wait on fills
+// themselves, so waiting on one could deadlock.
+func (tx *memTx) LockOrders(ctx context.Context, buyOrder, sellOrder *Order) error {
+	s := tx.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner := tx.owner()
+	expected := []*Order{buyOrder, sellOrder}
+	stored := make([]*memOrder, len(expected))
+	for {
+		blocked := false
+		for i, o := range expected {
+			stored[i] = s.orders[o.ID]
+			if stored[i] == nil {
+				return ErrDuplicateMatch
+			}
+			holder := stored[i].holder
+			if holder == nil || holder == owner {
+				continue
+			}
+			if holder.claim {
+				return ErrDuplicateMatch
+			}
+			blocked = true
+		}
+		if !blocked {
+			break
+		}
+		s.unlocked.Wait()
+	}
+
+	for i, o := range expected {
+		tx.lock(stored[i])
+		current := tx.view(stored[i])
+		if !current.IsActive() || !current.RemainingQuantity.Equal(o.RemainingQuantity) {
+			return ErrDuplicateMatch
+		}
+	}
+	return nil
+}
+
+// CreateMatch implements FillTx
+func (tx *memTx) CreateMatch(ctx context.Context, m *Match) error {
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+
+	// The engine reserves sequences itself; matches created outside one are
+	// numbered here
+	if m.Seq == 0 {
+		m.Seq = tx.store.matchSeq + 1
+	}
+	tx.store.matchSeq = max(tx.store.matchSeq, m.Seq)
+	m.ID = uuid.NewString()
+
+	c := *m
+	c.MatchedAt = time.Now()
+	tx.matches = append(tx.matches, &c)
+	return nil
+}
+
+// UpdateOrderFill implements FillTx
+func (tx *memTx) UpdateOrderFill(ctx context.Context, order *Order, quantity decimal.Decimal) error {
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+
+	stored := tx.store.orders[order.ID]
+	if stored == nil {
+		return fmt.Errorf("order %s not found", order.ID)
+	}
+
+	o := tx.view(stored)
+	o.FilledQuantity = o.FilledQuantity.Add(quantity)
+	o.RemainingQuantity = o.RemainingQuantity.Sub(quantity)
+	if o.RemainingQuantity.IsZero() {
+		o.Status = OrderStatusFilled
+	} else {
+		o.Status = OrderStatusPartiallyFilled
+	}
+	tx.orders[order.ID] = o
+	return nil
+}
+
+// Commit implements FillTx and Claim. A fill in a claim hands its writes to
+// the claim; anything else makes them visible and releases its locks.
+func (tx *memTx) Commit(ctx context.Context) error {
+	s := tx.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if tx.done {
+		return fmt.Errorf("transaction already ended")
+	}
+	tx.done = true
+
+	if tx.parent != nil {
+		maps.Copy(tx.parent.orders, tx.orders)
+		tx.parent.matches = append(tx.parent.matches, tx.matches...)
+		return nil
+	}
+
+	for id, o := range tx.orders {
+		s.orders[id].order = o
+	}
+	s.matches = append(s.matches, tx.matches...)
+	tx.release()
+	return nil
+}
+
+// Rollback implements FillTx and Claim
+func (tx *memTx) Rollback(ctx context.Context) error {
+	tx.store.mu.Lock()
+	defer tx.store.mu.Unlock()
+
+	if tx.done {
+		return nil
+	}
+	tx.done = true
+	if tx.parent == nil {
+		tx.release()
+	}
+	return nil
+}
+
+// release drops every lock the tx took. Called with mu held.
+func (tx *memTx) release() {
+	for _, stored := range tx.locked {
+		stored.holder = nil
+	}
+	tx.locked = nil
+	tx.store.unlocked.Broadcast()
+}
@@ -0,0 +1,387 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestClaimsNeverShareCandidates(t *testing.T) {
+	cfg := testConfig(t)
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		sell := testOrder(fmt.Sprintf("0xseller%d", i), OrderTypeSell, "1", "100", 100)
+		if err := store.CreateOrders(ctx, []NewOrder{{Order: sell}}); err != nil {
+			t.Fatalf("CreateOrders: %v", err)
+		}
+	}
+	buy := testOrder("0xbuyer", OrderTypeBuy, "5", "100", 100)
+
+	first, _ := store.BeginClaim(ctx)
+	defer first.Rollback(ctx)
+	claimed, err := first.FindCandidates(ctx, cfg, buy, nil, time.Now())
+	if err != nil || len(claimed) != 5 {
+		t.Fatalf("first claim found %d candidates (%v), want 5", len(claimed), err)
+	}
+
+	second, _ := store.BeginClaim(ctx)
+	if others, _ := second.FindCandidates(ctx, cfg, buy, nil, time.Now()); len(others) != 0 {
+		t.Errorf("second claim found %d candidates the first holds", len(others))
+	}
+	second.Rollback(ctx)
+
+	// Outside a claim nothing is skipped
+	if all, _ := store.FindCandidates(ctx, cfg, buy, nil, time.Now()); len(all) != 5 {
+		t.Errorf("unclaimed read found %d candidates, want 5", len(all))
+	}
+
+	first.Rollback(ctx)
+	third, _ := store.BeginClaim(ctx)
+	defer third.Rollback(ctx)
+	if freed, _ := third.FindCandidates(ctx, cfg, buy, nil, time.Now()); len(freed) != 5 {
+		t.Errorf("claim after rollback found %d candidates, want 5", len(freed))
+	}
+}
+
+func TestConcurrentSkipLockedMatchingNeverDoubleFills(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MatchSkipLocked = true
+	cfg.Workers = 8
+	e, store := newTestEngine(t, cfg)
+	ctx := context.Background()
+
+	const sells, buys = 20, 40
+	for i := 0; i < sells; i++ {
+		submit(t, e, testOrder(fmt.Sprintf("0xseller%d", i), OrderTypeSell, "1", "100", 100))
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, buys)
+	for i := 0; i < buys; i++ {
+		buy := testOrder(fmt.Sprintf("0xbuyer%d", i), OrderTypeBuy, "1", "100", 100)
+		if err := store.CreateOrders(ctx, []NewOrder{{Order: buy}}); err != nil {
+			t.Fatalf("CreateOrders: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := e.SubmitOrderSync(ctx, buy); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("SubmitOrderSync: %v", err)
+	}
+
+	filled := make(map[string]decimal.Decimal)
+	for _, m := range store.Matches() {
+		filled[m.BuyOrderID] = filled[m.BuyOrderID].Add(m.Quantity)
+		filled[m.SellOrderID] = filled[m.SellOrderID].Add(m.Quantity)
+	}
+	if n := len(store.Matches()); n == 0 || n > sells {
+		t.Fatalf("%d matches against %d units for sale", n, sells)
+	}
+	for id, quantity := range filled {
+		if quantity.GreaterThan(decimal.NewFromInt(1)) {
+			t.Errorf("order %s filled %s of 1", id, quantity)
+		}
+		if stored := loadOrder(t, store, id); !stored.FilledQuantity.Equal(quantity) {
+			t.Errorf("order %s stored %s filled, matches add up to %s", id, stored.FilledQuantity, quantity)
+		}
+	}
+}
+
+// failingClaimStore is a MemoryStore whose claims roll back instead of
+// committing
+type failingClaimStore struct {
+	*MemoryStore
+}
+
+func (s failingClaimStore) BeginClaim(ctx context.Context) (Claim, error) {
+	claim, err := s.MemoryStore.BeginClaim(ctx)
+	return failingClaim{claim}, err
+}
+
+type failingClaim struct {
+	Claim
+}
+
+func (c failingClaim) Commit(ctx context.Context) error {
+	c.Claim.Rollback(ctx)
+	return errors.New("connection reset")
+}
+
+func TestFailedClaimCommitRebuildsBook(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MatchSkipLocked = true
+	e, store := newTestEngine(t, cfg)
+	e.SetStore(failingClaimStore{store})
+	ctx := context.Background()
+
+	// Rest the sell without matching it, since every claim fails
+	sell := testOrder("0xalice", OrderTypeSell, "5", "100", 100)
+	if err := store.CreateOrders(ctx, []NewOrder{{Order: sell}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+	if _, _, err := e.RebuildBook(ctx, "WETH", "USDC"); err != nil {
+		t.Fatalf("RebuildBook: %v", err)
+	}
+
+	buy := testOrder("0xbob", OrderTypeBuy, "2", "100", 100)
+	if err := store.CreateOrders(ctx, []NewOrder{{Order: buy}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+	if _, err := e.SubmitOrderSync(ctx, buy); !errors.Is(err, errClaimCommit) {
+		t.Fatalf("SubmitOrderSync: %v, want errClaimCommit", err)
+	}
+
+	if len(store.Matches()) != 0 {
+		t.Fatalf("rolled back claim left %d matches", len(store.Matches()))
+	}
+
+	// The book matches the store again: nothing filled on either side
+	book := e.bookMgr.GetBook("", "WETH", "USDC")
+	ask, bid := book.PeekBestAsk(), book.PeekBestBid()
+	if ask == nil || !ask.RemainingQuantity.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("best ask %v, want the sell with all 5 remaining", ask)
+	}
+	if bid == nil || bid.ID != buy.ID || !bid.RemainingQuantity.Equal(decimal.NewFromInt(2)) {
+		t.Errorf("best bid %v, want the buy with all 2 remaining", bid)
+	}
+}
+
+func TestLockOrdersLetsOneOfTwoTakersFillAMaker(t *testing.T) {
+	cfg := testConfig(t)
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	maker := testOrder("0xmaker", OrderTypeSell, "1", "100", 100)
+	first := testOrder("0xfirst", OrderTypeBuy, "1", "100", 100)
+	second := testOrder("0xsecond", OrderTypeBuy, "1", "100", 100)
+	if err := store.CreateOrders(ctx, []NewOrder{{Order: maker}, {Order: first}, {Order: second}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+
+	// Both takers read the maker with its full quantity
+	candidates := make(map[*Order]*Order)
+	for _, taker := range []*Order{first, second} {
+		found, err := store.FindCandidates(ctx, cfg, taker, nil, time.Now())
+		if err != nil || len(found) != 1 {
+			t.Fatalf("FindCandidates = %v, %v", found, err)
+		}
+		candidates[taker] = found[0]
+	}
+
+	fill := func(taker *Order) error {
+		tx, err := store.BeginFill(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+		seen := candidates[taker]
+		if err := tx.LockOrders(ctx, taker, seen); err != nil {
+			return err
+		}
+		quantity := seen.RemainingQuantity
+		if err := tx.CreateMatch(ctx, &Match{BuyOrderID: taker.ID, SellOrderID: seen.ID, Quantity: quantity, Price: seen.Price}); err != nil {
+			return err
+		}
+		if err := tx.UpdateOrderFill(ctx, taker, quantity); err != nil {
+			return err
+		}
+		if err := tx.UpdateOrderFill(ctx, seen, quantity); err != nil {
+			return err
+		}
+		// Hold the locks a while so the other taker has to wait on them
+		time.Sleep(20 * time.Millisecond)
+		return tx.Commit(ctx)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]error, 2)
+	for i, taker := range []*Order{first, second} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = fill(taker)
+		}()
+	}
+	wg.Wait()
+
+	filled, duplicates := 0, 0
+	for _, err := range results {
+		switch {
+		case err == nil:
+			filled++
+		case errors.Is(err, ErrDuplicateMatch):
+			duplicates++
+		default:
+			t.Fatalf("fill failed: %v", err)
+		}
+	}
+	if filled != 1 || duplicates != 1 {
+		t.Fatalf("%d fills and %d duplicates, want one of each", filled, duplicates)
+	}
+	if n := len(store.Matches()); n != 1 {
+		t.Errorf("%d matches recorded, want 1", n)
+	}
+	stored := loadOrder(t, store, maker.ID)
+	if !stored.FilledQuantity.Equal(decimal.NewFromInt(1)) || !stored.RemainingQuantity.IsZero() || stored.Status != OrderStatusFilled {
+		t.Errorf("maker stored %s filled, %s remaining, %s; want 1, 0, FILLED",
+			stored.FilledQuantity, stored.RemainingQuantity, stored.Status)
+	}
+}
+
+func TestRacingTakersNeverOverfillAMaker(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MatchSkipLocked = false
+	cfg.Workers = 4
+	e, store := newTestEngine(t, cfg)
+	ctx := context.Background()
+
+	for round := 0; round < 20; round++ {
+		maker := testOrder(fmt.Sprintf("0xmaker%d", round), OrderTypeSell, "1", "100", 100)
+		submit(t, e, maker)
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			taker := testOrder(fmt.Sprintf("0xtaker%d-%d", round, i), OrderTypeBuy, "1", "100", 100)
+			if err := store.CreateOrders(ctx, []NewOrder{{Order: taker}}); err != nil {
+				t.Fatalf("CreateOrders: %v", err)
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := e.SubmitOrderSync(ctx, taker); err != nil {
+					errs <- err
+				}
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Fatalf("SubmitOrderSync: %v", err)
+		}
+
+		stored := loadOrder(t, store, maker.ID)
+		if !stored.FilledQuantity.Equal(decimal.NewFromInt(1)) || !stored.RemainingQuantity.IsZero() {
+			t.Fatalf("round %d: maker stored %s filled, %s remaining; want 1, 0", round, stored.FilledQuantity, stored.RemainingQuantity)
+		}
+	}
+
+	// Each maker is filled once; the losing taker of each round rests
+	makerFills := make(map[string]int)
+	for _, m := range store.Matches() {
+		makerFills[m.SellOrderID]++
+	}
+	for id, fills := range makerFills {
+		if fills != 1 {
+			t.Errorf("maker %s filled by %d matches, want 1", id, fills)
+		}
+	}
+	if len(makerFills) != 20 {
+		t.Errorf("%d makers filled, want 20", len(makerFills))
+	}
+}
+
+func TestCancelledMakerCannotBeLockedForAFill(t *testing.T) {
+	cfg := testConfig(t)
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	maker := testOrder("0xmaker", OrderTypeSell, "1", "100", 100)
+	taker := testOrder("0xtaker", OrderTypeBuy, "1", "100", 100)
+	if err := store.CreateOrders(ctx, []NewOrder{{Order: maker}, {Order: taker}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+	found, err := store.FindCandidates(ctx, cfg, taker, nil, time.Now())
+	if err != nil || len(found) != 1 {
+		t.Fatalf("FindCandidates = %v, %v", found, err)
+	}
+
+	// The cancel commits after the taker read the maker but before it locks
+	if err := store.CancelOrder(ctx, maker.ID, maker.UserAddress); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	tx, err := store.BeginFill(ctx)
+	if err != nil {
+		t.Fatalf("BeginFill: %v", err)
+	}
+	defer tx.Rollback(ctx)
+	if err := tx.LockOrders(ctx, taker, found[0]); !errors.Is(err, ErrDuplicateMatch) {
+		t.Fatalf("LockOrders on a cancelled maker: %v, want ErrDuplicateMatch", err)
+	}
+}
+
+func TestCancelWaitsOutAnInFlightFillAndWinsAfterIt(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Workers = 2
+	e, store, release := startGatedEngine(t, cfg, "WETH")
+	ctx := context.Background()
+
+	maker := testOrder("0xalice", OrderTypeSell, "2", "100", 100)
+	submit(t, e, maker)
+
+	// A fill of the maker holds its locks, stalled before committing
+	taker := testOrder("0xbob", OrderTypeBuy, "1", "100", 100)
+	if err := store.CreateOrders(ctx, []NewOrder{{Order: taker}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+	var wg sync.WaitGroup
+	var fillErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, fillErr = e.SubmitOrderSync(ctx, taker)
+	}()
+	waitGated(t, store)
+
+	// The cancel can't commit over the open fill
+	cancelled := make(chan error, 1)
+	go func() { cancelled <- e.CancelOrder(ctx, maker.ID, maker.UserAddress) }()
+	select {
+	case err := <-cancelled:
+		t.Fatalf("cancel returned %v while a fill held the maker", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release()
+	wg.Wait()
+	if fillErr != nil {
+		t.Fatalf("SubmitOrderSync: %v", fillErr)
+	}
+	select {
+	case err := <-cancelled:
+		if err != nil {
+			t.Fatalf("CancelOrder: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancel never returned after the fill committed")
+	}
+
+	// Nothing fills the maker once the cancel has committed
+	if matches := submit(t, e, testOrder("0xcarol", OrderTypeBuy, "1", "100", 100)); len(matches) != 0 {
+		t.Fatalf("buy matched %d times against the cancelled maker", len(matches))
+	}
+	stored := loadOrder(t, store, maker.ID)
+	if stored.Status != OrderStatusCancelled || !stored.FilledQuantity.Equal(decimal.NewFromInt(1)) {
+		t.Errorf("maker %s with %s filled, want CANCELLED with 1", stored.Status, stored.FilledQuantity)
+	}
+	if n := len(store.Matches()); n != 1 {
+		t.Errorf("%d matches recorded, want 1", n)
+	}
+	if _, inBook := e.GetInMemoryOrder(maker.ID); inBook {
+		t.Error("cancelled maker still in the book")
+	}
+}
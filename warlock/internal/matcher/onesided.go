@@ -0,0 +1,37 @@
+package matcher
+
+import "slices"
+
+// HasCompatibleLiquidity reports whether the order's book holds a resting
+// order on the other side it could match now: unexpired, not on a paused
+// chain, allowed as a counterparty both ways, not the same entity under
+// self-trade prevention, and price compatible. Orders still queued for
+// matching aren't in the book yet and so aren't seen.
+func (e *Engine) HasCompatibleLiquidity(order *Order) bool {
+	book := e.bookMgr.GetBook(order.PoolID, order.BaseToken, order.QuoteToken)
+	if book == nil {
+		return false
+	}
+
+	cfg := e.cfg.ForPair(order.BaseToken, order.QuoteToken)
+	now := e.Now()
+	pausedChains := e.PausedChains()
+
+	opposite := OrderTypeSell
+	if order.OrderType == OrderTypeSell {
+		opposite = OrderTypeBuy
+	}
+	return book.AnyResting(opposite, func(resting *Order) bool {
+		switch {
+		case !resting.IsActive() || resting.Expired(now):
+			return false
+		case slices.Contains(pausedChains, resting.ChainID):
+			return false
+		case cfg.SelfTradePrevention && cfg.SameEntity(order.UserAddress, resting.UserAddress):
+			return false
+		case !counterpartiesAllowed(order, resting):
+			return false
+		}
+		return isPriceCompatible(order, resting, cfg.PriceSignificantDigits, cfg.MinBandOverlapBps)
+	})
+}
@@ -2,30 +2,65 @@ package matcher
 
 import (
 	"container/heap"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/rs/zerolog/log"
 	"github.com/shopspring/decimal"
 )
 
 // Order represents an order in the order book
 type Order struct {
-	ID               string
-	UserAddress      string
-	ChainID          int32
-	OrderType        OrderType
-	BaseToken        string
-	QuoteToken       string
-	Quantity         decimal.Decimal
-	Price            decimal.Decimal
-	VarianceBPS      int32
-	MinPrice         decimal.Decimal
-	MaxPrice         decimal.Decimal
-	FilledQuantity   decimal.Decimal
+	ID                string
+	UserAddress       string
+	ChainID           int32
+	OrderType         OrderType
+	BaseToken         string
+	QuoteToken        string
+	Quantity          decimal.Decimal
+	Price             decimal.Decimal
+	VarianceBPS       int32
+	MinPrice          decimal.Decimal
+	MaxPrice          decimal.Decimal
+	FilledQuantity    decimal.Decimal
 	RemainingQuantity decimal.Decimal
-	Status           OrderStatus
-	CreatedAt        time.Time
-	ExpiresAt        time.Time
+	Status            OrderStatus
+	CreatedAt         time.Time
+	ExpiresAt         time.Time
+	Seq               int64 // Insertion order from orders.seq
+
+	// CounterpartyAllowlist restricts matching to these addresses (empty = anyone)
+	CounterpartyAllowlist []string
+
+	// PoolID routes the order to a segregated liquidity pool; orders only match
+	// within their pool. Empty is the shared pool.
+	PoolID string
+
+	// NoFillDeadline cancels the order if it has had no fill by then; a
+	// partial fill keeps it alive. Zero means no deadline.
+	NoFillDeadline time.Time
+
+	// VisibleAt is when a speed bump lets the order be matched; before it, the
+	// order is no candidate for others. Zero means at once.
+	VisibleAt time.Time
+
+	// Metadata is opaque client bookkeeping; matching ignores it
+	Metadata map[string]string
+
+	// RemainderPolicy governs the unfilled part after a partial fill on submission
+	RemainderPolicy RemainderPolicy
+
+	// TraceMatching requests a match trace for this order, readable via Engine.GetMatchTrace
+	TraceMatching bool
+
+	// trace collects the match trace while the order is being matched
+	trace *MatchTrace
+
+	// done is set for synchronous submissions and receives the outcome once
+	// the order has been added to the book and matching attempted
+	done chan<- orderOutcome
 }
 
 // OrderType represents buy or sell
@@ -36,6 +71,16 @@ const (
 	OrderTypeSell OrderType = "SELL"
 )
 
+// RemainderPolicy says what happens to an order's unfilled part when it is
+// partially filled on submission
+type RemainderPolicy string
+
+const (
+	RemainderRest   RemainderPolicy = "REST"   // Leave it resting at the original price
+	RemainderCancel RemainderPolicy = "CANCEL" // Cancel it
+	RemainderRepeg  RemainderPolicy = "REPEG"  // Re-center its price range on the fill price
+)
+
 // OrderStatus represents the order lifecycle
 type OrderStatus string
 
@@ -53,32 +98,76 @@ func (o *Order) IsActive() bool {
 	return o.Status == OrderStatusRevealed || o.Status == OrderStatusPartiallyFilled
 }
 
-// OrderBook maintains buy and sell orders for a token pair
+// Expired reports whether the order's expiry has passed at now. Callers pass
+// the engine's clock, never a client's, so every check agrees on the cutoff.
+func (o *Order) Expired(now time.Time) bool {
+	return !o.ExpiresAt.IsZero() && !o.ExpiresAt.After(now)
+}
+
+// addFill records quantity more of the order as filled
+func (o *Order) addFill(quantity decimal.Decimal) {
+	o.FilledQuantity = o.FilledQuantity.Add(quantity)
+	o.RemainingQuantity = o.RemainingQuantity.Sub(quantity)
+	if o.RemainingQuantity.IsZero() {
+		o.Status = OrderStatusFilled
+	} else {
+		o.Status = OrderStatusPartiallyFilled
+	}
+}
+
+// AllowsCounterparty reports whether this order may trade against the given address
+func (o *Order) AllowsCounterparty(address string) bool {
+	if len(o.CounterpartyAllowlist) == 0 {
+		return true
+	}
+	for _, allowed := range o.CounterpartyAllowlist {
+		if strings.EqualFold(allowed, address) {
+			return true
+		}
+	}
+	return false
+}
+
+// OrderBook maintains buy and sell orders for a token pair within one pool
 type OrderBook struct {
+	poolID     string
 	baseToken  string
 	quoteToken string
 	bids       *PriorityQueue // BUY orders (highest price first)
 	asks       *PriorityQueue // SELL orders (lowest price first)
 	ordersByID map[string]*Order
+	index      *orderIndex // Manager's order index while installed; nil otherwise
+	wal        *bookWAL    // Manager's WAL while installed; nil otherwise
+	lastActive time.Time   // Last time an order was added or removed
+	desyncs    int64       // Orders found in ordersByID but missing from their heap
+	version    uint64      // Bumped whenever an order is added, removed or filled
+	compacted  uint64      // version at the last Compact
 	mu         sync.RWMutex
 }
 
-// NewOrderBook creates a new order book for a token pair
-func NewOrderBook(baseToken, quoteToken string) *OrderBook {
+// NewOrderBook creates a new order book for a token pair in a pool
+func NewOrderBook(poolID, baseToken, quoteToken string) *OrderBook {
 	return &OrderBook{
+		poolID:     poolID,
 		baseToken:  baseToken,
 		quoteToken: quoteToken,
 		bids:       NewPriorityQueue(true),  // true = descending (highest bid first)
 		asks:       NewPriorityQueue(false), // false = ascending (lowest ask first)
 		ordersByID: make(map[string]*Order),
+		lastActive: time.Now(),
 	}
 }
 
-// AddOrder adds an order to the order book
+// AddOrder adds an order to the order book, replacing any entry with the same ID
 func (ob *OrderBook) AddOrder(order *Order) {
 	ob.mu.Lock()
 	defer ob.mu.Unlock()
 
+	// The heaps index orders by ID, so each ID may appear only once
+	if existing, exists := ob.ordersByID[order.ID]; exists {
+		ob.removeFromHeap(existing)
+	}
+
 	if order.OrderType == OrderTypeBuy {
 		heap.Push(ob.bids, order)
 	} else {
@@ -86,6 +175,10 @@ func (ob *OrderBook) AddOrder(order *Order) {
 	}
 
 	ob.ordersByID[order.ID] = order
+	ob.index.add(order.ID, ob.key())
+	ob.wal.add(ob, order)
+	ob.lastActive = time.Now()
+	ob.version++
 }
 
 // RemoveOrder removes an order from the order book
@@ -99,15 +192,133 @@ func (ob *OrderBook) RemoveOrder(orderID string) *Order {
 	}
 
 	delete(ob.ordersByID, orderID)
+	ob.index.remove(orderID, ob.key())
+	ob.wal.remove(ob, orderID)
+	ob.lastActive = time.Now()
+	ob.version++
+	ob.removeFromHeap(order)
+
+	return order
+}
+
+// ApplyFill records a committed fill of quantity on both orders, and on the
+// book's entries for them, in one critical section, so a snapshot holds
+// either none of the fill or all of it. The book's entry may be a different
+// copy than the order filled, e.g. a maker loaded as a candidate; it takes
+// the filled order's quantities. Entries left fully filled leave the book. A
+// nil book only updates the orders.
+func (ob *OrderBook) ApplyFill(quantity decimal.Decimal, orders ...*Order) {
+	if ob == nil {
+		for _, o := range orders {
+			o.addFill(quantity)
+		}
+		return
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	var filled []*Order
+	for _, o := range orders {
+		o.addFill(quantity)
+
+		entry, exists := ob.ordersByID[o.ID]
+		if !exists {
+			continue
+		}
+		if entry != o {
+			entry.FilledQuantity = o.FilledQuantity
+			entry.RemainingQuantity = o.RemainingQuantity
+			entry.Status = o.Status
+		}
+		if !entry.IsActive() {
+			delete(ob.ordersByID, o.ID)
+			ob.index.remove(o.ID, ob.key())
+			ob.removeFromHeap(entry)
+		}
+		if ob.wal != nil {
+			filled = append(filled, entry)
+		}
+	}
+	ob.wal.fill(ob, filled)
+	ob.lastActive = time.Now()
+	ob.version++
+}
+
+// key returns the book's key in its manager
+func (ob *OrderBook) key() string {
+	return makeBookKey(ob.poolID, ob.baseToken, ob.quoteToken)
+}
+
+// attach indexes the book's orders in index and keeps them indexed as the
+// book changes, logging its contents and every change to wal
+func (ob *OrderBook) attach(index *orderIndex, wal *bookWAL) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
 
-	// Remove from the appropriate queue
+	ob.index = index
+	key := ob.key()
+	for id := range ob.ordersByID {
+		index.add(id, key)
+	}
+	ob.wal = wal
+	ob.wal.load(ob)
+}
+
+// detach drops the book's orders from its index and stops maintaining it or
+// logging changes
+func (ob *OrderBook) detach() {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	key := ob.key()
+	for id := range ob.ordersByID {
+		ob.index.remove(id, key)
+	}
+	ob.index = nil
+	ob.wal.drop(ob)
+	ob.wal = nil
+}
+
+// removeFromHeap takes an indexed order out of the queue for its side. If it
+// isn't there the book has desynced: the mismatch is counted and logged, and
+// the other side is searched in case the order's type changed while it
+// rested. Reports whether the order was found in either heap. Callers hold
+// ob.mu.
+func (ob *OrderBook) removeFromHeap(order *Order) bool {
+	own, other := ob.asks, ob.bids
 	if order.OrderType == OrderTypeBuy {
-		ob.bids.Remove(order)
-	} else {
-		ob.asks.Remove(order)
+		own, other = ob.bids, ob.asks
+	}
+	if own.Remove(order) {
+		return true
 	}
 
-	return order
+	repaired := other.Remove(order)
+	ob.desyncs++
+	log.Warn().
+		Str("order_id", order.ID).
+		Str("order_type", string(order.OrderType)).
+		Str("base_token", ob.baseToken).
+		Str("quote_token", ob.quoteToken).
+		Bool("found_on_other_side", repaired).
+		Msg("Order book desync: order missing from its heap")
+	return repaired
+}
+
+// Version returns a counter that increases whenever an order is added to,
+// removed from or filled in the book
+func (ob *OrderBook) Version() uint64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.version
+}
+
+// Desyncs returns how many removals found an order missing from its heap
+func (ob *OrderBook) Desyncs() int64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.desyncs
 }
 
 // GetOrder retrieves an order by ID
@@ -117,6 +328,62 @@ func (ob *OrderBook) GetOrder(orderID string) *Order {
 	return ob.ordersByID[orderID]
 }
 
+// QueuePosition locates a resting order within its price level
+type QueuePosition struct {
+	Order          Order
+	AheadQuantity  decimal.Decimal // At the same price, matched first
+	AheadOrders    int
+	LevelQuantity  decimal.Decimal // At the same price, including the order
+	LevelOrders    int
+	BetterQuantity decimal.Decimal  // At better prices on the same side
+	OppositeBest   *decimal.Decimal // Best price on the other side; nil if it is empty
+}
+
+// QueuePosition walks the order's side in priority order and sums the
+// quantity matched before it. False if the book doesn't hold the order.
+func (ob *OrderBook) QueuePosition(orderID string) (QueuePosition, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	order, exists := ob.ordersByID[orderID]
+	if !exists {
+		return QueuePosition{}, false
+	}
+
+	side, opposite := ob.asks, ob.bids
+	if order.OrderType == OrderTypeBuy {
+		side, opposite = ob.bids, ob.asks
+	}
+
+	pos := QueuePosition{Order: *order}
+	if best := opposite.Peek(); best != nil {
+		price := best.Price
+		pos.OppositeBest = &price
+	}
+	reached := false
+	for _, o := range side.GetAll() {
+		if o.ID == order.ID {
+			reached = true
+		}
+		if !o.Price.Equal(order.Price) {
+			if !reached {
+				pos.BetterQuantity = pos.BetterQuantity.Add(o.RemainingQuantity)
+			}
+			continue
+		}
+		pos.LevelQuantity = pos.LevelQuantity.Add(o.RemainingQuantity)
+		pos.LevelOrders++
+		if !reached {
+			pos.AheadQuantity = pos.AheadQuantity.Add(o.RemainingQuantity)
+			pos.AheadOrders++
+		}
+	}
+
+	pos.Order.trace = nil
+	pos.Order.done = nil
+	return pos, true
+}
+
 // PeekBestBid returns the highest bid without removing it
 func (ob *OrderBook) PeekBestBid() *Order {
 	ob.mu.RLock()
@@ -153,6 +420,56 @@ func (ob *OrderBook) GetAsks() []*Order {
 	return ob.asks.GetAll()
 }
 
+// Snapshot copies both sides of the book, in priority order, with the
+// version they reflect, all under one read lock. Unlike GetBids and GetAsks
+// the copies are safe to read while fills are applied, and the two sides
+// always agree with each other.
+func (ob *OrderBook) Snapshot() (bids, asks []*Order, version uint64) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return copyOrders(ob.bids.GetAll()), copyOrders(ob.asks.GetAll()), ob.version
+}
+
+// copyOrders copies orders without their matching state
+func copyOrders(orders []*Order) []*Order {
+	copies := make([]*Order, len(orders))
+	for i, o := range orders {
+		c := *o
+		c.trace = nil
+		c.done = nil
+		copies[i] = &c
+	}
+	return copies
+}
+
+// AnyResting reports whether any order resting on the side holds for match,
+// checked under the book's read lock. Orders are visited in no particular
+// order.
+func (ob *OrderBook) AnyResting(side OrderType, match func(*Order) bool) bool {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	queue := ob.asks
+	if side == OrderTypeBuy {
+		queue = ob.bids
+	}
+	for _, o := range queue.orders {
+		if match(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reprioritize restores heap order after a time-dependent priority has moved
+// orders relative to each other
+func (ob *OrderBook) Reprioritize() {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	heap.Init(ob.bids)
+	heap.Init(ob.asks)
+}
+
 // Size returns the total number of orders in the book
 func (ob *OrderBook) Size() int {
 	ob.mu.RLock()
@@ -160,10 +477,50 @@ func (ob *OrderBook) Size() int {
 	return len(ob.ordersByID)
 }
 
-// PriorityQueue implements a heap-based priority queue for orders
+// SideCounts returns the number of bids and asks in the book
+func (ob *OrderBook) SideCounts() (int, int) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.bids.Len(), ob.asks.Len()
+}
+
+// Compact releases memory the book kept from busier times: heap slices only
+// ever grow and Go maps never shrink, so a book that once held many orders
+// keeps their footprint. Books touched within idleFor, and books unchanged
+// since their last compaction, are left alone. Reports whether it compacted.
+func (ob *OrderBook) Compact(idleFor time.Duration) bool {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	if time.Since(ob.lastActive) < idleFor || ob.compacted == ob.version {
+		return false
+	}
+	ob.compacted = ob.version
+
+	ob.bids.compact()
+	ob.asks.compact()
+	ordersByID := make(map[string]*Order, len(ob.ordersByID))
+	for id, order := range ob.ordersByID {
+		ordersByID[id] = order
+	}
+	ob.ordersByID = ordersByID
+	return true
+}
+
+// IdleSince reports whether the book is empty and, if so, when it last changed
+func (ob *OrderBook) IdleSince() (time.Time, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.lastActive, len(ob.ordersByID) == 0
+}
+
+// PriorityQueue implements a heap-based priority queue for orders.
+// positions tracks each order's slot in the heap so Remove is O(log n).
 type PriorityQueue struct {
 	orders     []*Order
-	descending bool // true for bids (highest first), false for asks (lowest first)
+	positions  map[string]int // order ID -> index in orders
+	descending bool           // true for bids (highest first), false for asks (lowest first)
+	earlier    timePriority   // Orders the queue at equal price
 	mu         sync.RWMutex
 }
 
@@ -171,7 +528,9 @@ type PriorityQueue struct {
 func NewPriorityQueue(descending bool) *PriorityQueue {
 	pq := &PriorityQueue{
 		orders:     make([]*Order, 0),
+		positions:  make(map[string]int),
 		descending: descending,
+		earlier:    createdBefore,
 	}
 	heap.Init(pq)
 	return pq
@@ -186,9 +545,11 @@ func (pq *PriorityQueue) Len() int {
 // For bids: higher price comes first, then earlier time
 // For asks: lower price comes first, then earlier time
 func (pq *PriorityQueue) Less(i, j int) bool {
-	orderI := pq.orders[i]
-	orderJ := pq.orders[j]
+	return pq.before(pq.orders[i], pq.orders[j])
+}
 
+// before reports whether orderI has priority over orderJ
+func (pq *PriorityQueue) before(orderI, orderJ *Order) bool {
 	// Price comparison
 	priceI := orderI.Price
 	priceJ := orderJ.Price
@@ -201,17 +562,20 @@ func (pq *PriorityQueue) Less(i, j int) bool {
 	}
 
 	// Time priority: earlier orders come first
-	return orderI.CreatedAt.Before(orderJ.CreatedAt)
+	return pq.earlier(orderI, orderJ)
 }
 
 // Swap implements heap.Interface
 func (pq *PriorityQueue) Swap(i, j int) {
 	pq.orders[i], pq.orders[j] = pq.orders[j], pq.orders[i]
+	pq.positions[pq.orders[i].ID] = i
+	pq.positions[pq.orders[j].ID] = j
 }
 
 // Push implements heap.Interface
 func (pq *PriorityQueue) Push(x interface{}) {
 	order := x.(*Order)
+	pq.positions[order.ID] = len(pq.orders)
 	pq.orders = append(pq.orders, order)
 }
 
@@ -222,9 +586,24 @@ func (pq *PriorityQueue) Pop() interface{} {
 	order := old[n-1]
 	old[n-1] = nil // avoid memory leak
 	pq.orders = old[0 : n-1]
+	delete(pq.positions, order.ID)
 	return order
 }
 
+// compact reallocates the heap slice and position index at their current
+// size, keeping heap order
+func (pq *PriorityQueue) compact() {
+	orders := make([]*Order, len(pq.orders))
+	copy(orders, pq.orders)
+	pq.orders = orders
+
+	positions := make(map[string]int, len(orders))
+	for i, order := range orders {
+		positions[order.ID] = i
+	}
+	pq.positions = positions
+}
+
 // Peek returns the top order without removing it
 func (pq *PriorityQueue) Peek() *Order {
 	if len(pq.orders) == 0 {
@@ -233,39 +612,84 @@ func (pq *PriorityQueue) Peek() *Order {
 	return pq.orders[0]
 }
 
-// Remove removes a specific order from the queue
-func (pq *PriorityQueue) Remove(order *Order) {
-	for i, o := range pq.orders {
-		if o.ID == order.ID {
-			heap.Remove(pq, i)
-			return
-		}
+// Remove removes a specific order from the queue and reports whether it was present
+func (pq *PriorityQueue) Remove(order *Order) bool {
+	i, exists := pq.positions[order.ID]
+	if !exists {
+		return false
 	}
+	heap.Remove(pq, i)
+	return true
 }
 
 // GetAll returns all orders in the queue (sorted)
 func (pq *PriorityQueue) GetAll() []*Order {
 	result := make([]*Order, len(pq.orders))
 	copy(result, pq.orders)
+	// The heap only keeps the best order in front; callers walk the book by priority
+	sort.Slice(result, func(i, j int) bool {
+		return pq.before(result[i], result[j])
+	})
 	return result
 }
 
+// orderIndex maps each resting order's ID to the key of the book holding it.
+// Installed books maintain it as orders are added, filled and removed. Its
+// methods are no-ops on a nil index, so uninstalled books skip it.
+type orderIndex struct {
+	keys map[string]string
+	mu   sync.RWMutex
+}
+
+func (idx *orderIndex) add(orderID, key string) {
+	if idx == nil {
+		return
+	}
+	idx.mu.Lock()
+	idx.keys[orderID] = key
+	idx.mu.Unlock()
+}
+
+// remove drops an order's entry if it still points at key, so a book
+// forgetting an order never unindexes another book's copy
+func (idx *orderIndex) remove(orderID, key string) {
+	if idx == nil {
+		return
+	}
+	idx.mu.Lock()
+	if idx.keys[orderID] == key {
+		delete(idx.keys, orderID)
+	}
+	idx.mu.Unlock()
+}
+
+func (idx *orderIndex) lookup(orderID string) (string, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	key, ok := idx.keys[orderID]
+	return key, ok
+}
+
 // OrderBookManager manages multiple order books (one per token pair)
 type OrderBookManager struct {
-	books map[string]*OrderBook // key: "baseToken-quoteToken"
-	mu    sync.RWMutex
+	books   map[string]*OrderBook // key: "baseToken-quoteToken"
+	index   *orderIndex           // Order ID -> key of the book holding it
+	wal     *bookWAL              // Log of installed books' changes; nil when disabled
+	earlier timePriority          // Time priority for new books; nil keeps arrival order
+	mu      sync.RWMutex
 }
 
 // NewOrderBookManager creates a new order book manager
 func NewOrderBookManager() *OrderBookManager {
 	return &OrderBookManager{
 		books: make(map[string]*OrderBook),
+		index: &orderIndex{keys: make(map[string]string)},
 	}
 }
 
-// GetOrCreateBook gets or creates an order book for a token pair
-func (obm *OrderBookManager) GetOrCreateBook(baseToken, quoteToken string) *OrderBook {
-	key := makeBookKey(baseToken, quoteToken)
+// GetOrCreateBook gets or creates an order book for a token pair in a pool
+func (obm *OrderBookManager) GetOrCreateBook(poolID, baseToken, quoteToken string) *OrderBook {
+	key := makeBookKey(poolID, baseToken, quoteToken)
 
 	obm.mu.RLock()
 	book, exists := obm.books[key]
@@ -284,14 +708,25 @@ func (obm *OrderBookManager) GetOrCreateBook(baseToken, quoteToken string) *Orde
 		return book
 	}
 
-	book = NewOrderBook(baseToken, quoteToken)
+	book = obm.NewBook(poolID, baseToken, quoteToken)
+	book.attach(obm.index, obm.wal)
 	obm.books[key] = book
 	return book
 }
 
-// GetBook retrieves an order book for a token pair
-func (obm *OrderBookManager) GetBook(baseToken, quoteToken string) *OrderBook {
-	key := makeBookKey(baseToken, quoteToken)
+// NewBook creates a book using the manager's time priority without installing it
+func (obm *OrderBookManager) NewBook(poolID, baseToken, quoteToken string) *OrderBook {
+	book := NewOrderBook(poolID, baseToken, quoteToken)
+	if obm.earlier != nil {
+		book.bids.earlier = obm.earlier
+		book.asks.earlier = obm.earlier
+	}
+	return book
+}
+
+// GetBook retrieves an order book for a token pair in a pool
+func (obm *OrderBookManager) GetBook(poolID, baseToken, quoteToken string) *OrderBook {
+	key := makeBookKey(poolID, baseToken, quoteToken)
 
 	obm.mu.RLock()
 	defer obm.mu.RUnlock()
@@ -299,7 +734,106 @@ func (obm *OrderBookManager) GetBook(baseToken, quoteToken string) *OrderBook {
 	return obm.books[key]
 }
 
-// makeBookKey creates a unique key for a token pair
-func makeBookKey(baseToken, quoteToken string) string {
+// FindOrder returns the book holding an order and the book's copy of it, or
+// nils. The order index names the book, so only that book is read.
+func (obm *OrderBookManager) FindOrder(orderID string) (*OrderBook, *Order) {
+	obm.mu.RLock()
+	defer obm.mu.RUnlock()
+
+	key, ok := obm.index.lookup(orderID)
+	if !ok {
+		return nil, nil
+	}
+	book := obm.books[key]
+	if book == nil {
+		return nil, nil
+	}
+	if order := book.GetOrder(orderID); order != nil {
+		return book, order
+	}
+	return nil, nil
+}
+
+// Books returns every book across all pairs and pools
+func (obm *OrderBookManager) Books() []*OrderBook {
+	obm.mu.RLock()
+	defer obm.mu.RUnlock()
+
+	books := make([]*OrderBook, 0, len(obm.books))
+	for _, book := range obm.books {
+		books = append(books, book)
+	}
+	return books
+}
+
+// PairBooks returns the books for a token pair across all pools
+func (obm *OrderBookManager) PairBooks(baseToken, quoteToken string) []*OrderBook {
+	obm.mu.RLock()
+	defer obm.mu.RUnlock()
+
+	books := make([]*OrderBook, 0, 1)
+	for _, book := range obm.books {
+		if book.baseToken == baseToken && book.quoteToken == quoteToken {
+			books = append(books, book)
+		}
+	}
+	return books
+}
+
+// PairCount returns the number of distinct token pairs with a book in any pool
+func (obm *OrderBookManager) PairCount() int {
+	obm.mu.RLock()
+	defer obm.mu.RUnlock()
+
+	pairs := make(map[string]bool, len(obm.books))
+	for _, book := range obm.books {
+		pairs[makePairKey(book.baseToken, book.quoteToken)] = true
+	}
+	return len(pairs)
+}
+
+// RemoveBook drops a pool's book for a token pair if it is still the given book
+func (obm *OrderBookManager) RemoveBook(book *OrderBook) bool {
+	key := book.key()
+
+	obm.mu.Lock()
+	defer obm.mu.Unlock()
+
+	if obm.books[key] != book {
+		return false
+	}
+	delete(obm.books, key)
+	book.detach()
+	return true
+}
+
+// ReplacePairBooks installs books for a token pair, discarding every existing
+// book for the pair in any pool
+func (obm *OrderBookManager) ReplacePairBooks(baseToken, quoteToken string, books []*OrderBook) {
+	obm.mu.Lock()
+	defer obm.mu.Unlock()
+
+	for key, book := range obm.books {
+		if book.baseToken == baseToken && book.quoteToken == quoteToken {
+			delete(obm.books, key)
+			book.detach()
+		}
+	}
+	for _, book := range books {
+		obm.books[book.key()] = book
+		book.attach(obm.index, obm.wal)
+	}
+}
+
+// makePairKey creates a unique key for a token pair
+func makePairKey(baseToken, quoteToken string) string {
 	return baseToken + "-" + quoteToken
 }
+
+// makeBookKey creates a unique key for a pool's book; the shared pool uses the plain pair key
+func makeBookKey(poolID, baseToken, quoteToken string) string {
+	if poolID == "" {
+		return makePairKey(baseToken, quoteToken)
+	}
+	return poolID + "/" + makePairKey(baseToken, quoteToken)
+}
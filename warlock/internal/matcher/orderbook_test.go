@@ -0,0 +1,173 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestFindOrderReturnsTheBooksCopy(t *testing.T) {
+	obm := NewOrderBookManager()
+	book := obm.GetOrCreateBook("", "WETH", "USDC")
+
+	resting := testOrder("0xalice", OrderTypeSell, "5", "100", 100)
+	book.AddOrder(resting)
+
+	found, order := obm.FindOrder(resting.ID)
+	if found != book || order != resting {
+		t.Fatalf("FindOrder = %p, %p, want the book %p and its entry %p", found, order, book, resting)
+	}
+
+	// A maker loaded from the store is a different copy; filling it updates
+	// the book's entry, which is what FindOrder keeps returning
+	loaded := *resting
+	book.ApplyFill(decimal.NewFromInt(2), &loaded)
+	if _, order := obm.FindOrder(resting.ID); order != resting || !order.RemainingQuantity.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("after a partial fill FindOrder = %v, want the book's entry with 3 remaining", order)
+	}
+
+	book.ApplyFill(decimal.NewFromInt(3), &loaded)
+	if found, order := obm.FindOrder(resting.ID); found != nil || order != nil {
+		t.Errorf("filled order still found in %p", found)
+	}
+
+	other := testOrder("0xbob", OrderTypeBuy, "1", "99", 100)
+	book.AddOrder(other)
+	book.RemoveOrder(other.ID)
+	if _, order := obm.FindOrder(other.ID); order != nil {
+		t.Error("removed order still found")
+	}
+}
+
+func TestFindOrderFollowsBookReplacement(t *testing.T) {
+	obm := NewOrderBookManager()
+	old := obm.GetOrCreateBook("", "WETH", "USDC")
+	dropped := testOrder("0xalice", OrderTypeSell, "5", "100", 100)
+	kept := testOrder("0xbob", OrderTypeBuy, "1", "99", 100)
+	old.AddOrder(dropped)
+	old.AddOrder(kept)
+
+	rebuilt := obm.NewBook("", "WETH", "USDC")
+	reloaded := *kept
+	rebuilt.AddOrder(&reloaded)
+	pooled := obm.NewBook("pool-1", "WETH", "USDC")
+	inPool := testOrder("0xcarol", OrderTypeSell, "2", "101", 100)
+	pooled.AddOrder(inPool)
+
+	// A book isn't indexed until it is installed
+	if _, order := obm.FindOrder(inPool.ID); order != nil {
+		t.Fatal("order in an uninstalled book was found")
+	}
+
+	obm.ReplacePairBooks("WETH", "USDC", []*OrderBook{rebuilt, pooled})
+
+	if found, order := obm.FindOrder(kept.ID); found != rebuilt || order != &reloaded {
+		t.Errorf("FindOrder(kept) = %p, %p, want the rebuilt book's copy %p", found, order, &reloaded)
+	}
+	if found, order := obm.FindOrder(inPool.ID); found != pooled || order != inPool {
+		t.Errorf("FindOrder(inPool) = %p, %p, want the pool's book", found, order)
+	}
+	if _, order := obm.FindOrder(dropped.ID); order != nil {
+		t.Error("order only in the discarded book was found")
+	}
+
+	// The discarded book no longer maintains the index
+	late := testOrder("0xdave", OrderTypeSell, "1", "100", 100)
+	old.AddOrder(late)
+	if _, order := obm.FindOrder(late.ID); order != nil {
+		t.Error("order added to the discarded book was found")
+	}
+	old.RemoveOrder(kept.ID)
+	if _, order := obm.FindOrder(kept.ID); order != &reloaded {
+		t.Error("removal from the discarded book unindexed the rebuilt book's copy")
+	}
+
+	pooled.RemoveOrder(inPool.ID)
+	if !obm.RemoveBook(pooled) {
+		t.Fatal("RemoveBook of an installed book failed")
+	}
+	pooled.AddOrder(inPool)
+	if _, order := obm.FindOrder(inPool.ID); order != nil {
+		t.Error("order in a removed book was found")
+	}
+}
+
+func TestQueuePositionReadsTheBooksCopy(t *testing.T) {
+	e, _ := newTestEngine(t, testConfig(t))
+
+	sell := testOrder("0xalice", OrderTypeSell, "5", "100", 100)
+	submit(t, e, sell)
+	submit(t, e, testOrder("0xbob", OrderTypeBuy, "2", "100", 100))
+
+	pos, ok := e.QueuePosition(sell.ID)
+	if !ok {
+		t.Fatal("resting order has no queue position")
+	}
+	if !pos.Order.RemainingQuantity.Equal(decimal.NewFromInt(3)) || !pos.LevelQuantity.Equal(decimal.NewFromInt(3)) {
+		t.Errorf("position reports %s remaining of a %s level, want 3 and 3", pos.Order.RemainingQuantity, pos.LevelQuantity)
+	}
+	if _, ok := e.QueuePosition("unknown"); ok {
+		t.Error("unknown order has a queue position")
+	}
+}
+
+func TestSnapshotNeverSeesAHalfAppliedFill(t *testing.T) {
+	obm := NewOrderBookManager()
+	book := obm.GetOrCreateBook("", "WETH", "USDC")
+
+	// Fills land on the pair together, so a consistent snapshot always shows
+	// them filled alike
+	bid := testOrder("0xalice", OrderTypeBuy, "200", "100", 100)
+	ask := testOrder("0xbob", OrderTypeSell, "200", "101", 100)
+	book.AddOrder(bid)
+	book.AddOrder(ask)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		// Makers loaded from the store are copies of the book's entries
+		buy, sell := *bid, *ask
+		for i := 0; i < 200; i++ {
+			book.ApplyFill(decimal.NewFromInt(1), &buy, &sell)
+		}
+	}()
+
+	var lastVersion uint64
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			finished = true
+		default:
+		}
+
+		bids, asks, version := book.Snapshot()
+		if version < lastVersion {
+			t.Fatalf("snapshot version went back from %d to %d", lastVersion, version)
+		}
+		lastVersion = version
+		if len(bids) != len(asks) {
+			t.Fatalf("version %d has %d bids and %d asks, want both or neither", version, len(bids), len(asks))
+		}
+		for _, o := range append(bids, asks...) {
+			if !o.FilledQuantity.Add(o.RemainingQuantity).Equal(o.Quantity) || !o.RemainingQuantity.IsPositive() {
+				t.Fatalf("version %d shows %s with %s filled and %s remaining of %s",
+					version, o.ID, o.FilledQuantity, o.RemainingQuantity, o.Quantity)
+			}
+			wantStatus := OrderStatusPartiallyFilled
+			if o.FilledQuantity.IsZero() {
+				wantStatus = OrderStatusRevealed
+			}
+			if o.Status != wantStatus {
+				t.Fatalf("version %d shows %s as %s with %s filled", version, o.ID, o.Status, o.FilledQuantity)
+			}
+		}
+		if len(bids) == 1 && !bids[0].FilledQuantity.Equal(asks[0].FilledQuantity) {
+			t.Fatalf("version %d shows the bid with %s filled and the ask with %s",
+				version, bids[0].FilledQuantity, asks[0].FilledQuantity)
+		}
+	}
+
+	if bids, asks, _ := book.Snapshot(); len(bids) != 0 || len(asks) != 0 {
+		t.Errorf("filled orders still in the book: %d bids, %d asks", len(bids), len(asks))
+	}
+}
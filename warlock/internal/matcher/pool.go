@@ -0,0 +1,36 @@
+package matcher
+
+import "sync"
+
+// orderPool recycles the scratch orders scanned during candidate lookup.
+// Orders that rest in a book are never drawn from or returned to the pool.
+var orderPool = sync.Pool{
+	New: func() interface{} {
+		return new(Order)
+	},
+}
+
+// acquireOrder returns a zeroed order from the pool
+func acquireOrder() *Order {
+	o := orderPool.Get().(*Order)
+	*o = Order{}
+	return o
+}
+
+// releaseOrder clears an order and returns it to the pool.
+// The caller must not touch the order afterwards.
+func releaseOrder(o *Order) {
+	if o == nil {
+		return
+	}
+	*o = Order{}
+	orderPool.Put(o)
+}
+
+// releaseOrders returns every order in the slice to the pool and clears the slots
+func releaseOrders(orders []*Order) {
+	for i, o := range orders {
+		releaseOrder(o)
+		orders[i] = nil
+	}
+}
@@ -0,0 +1,109 @@
+package matcher
+
+import (
+	"sort"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/shopspring/decimal"
+)
+
+// timePriority reports whether a should be matched before b when both rest at
+// the same price
+type timePriority func(a, b *Order) bool
+
+// createdBefore is plain FIFO: the earlier order goes first
+func createdBefore(a, b *Order) bool {
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// sequencedBefore is FIFO by insertion sequence, so ties never depend on
+// clock resolution or skew; see DeterministicMatching
+func sequencedBefore(a, b *Order) bool {
+	return a.Seq < b.Seq
+}
+
+// decayedPriority demotes orders that have rested longer than after: at equal
+// price they rank behind every younger order, and FIFO applies within each
+// group. An order's rank changes as it ages, so books using this must be
+// reprioritized periodically.
+func decayedPriority(after time.Duration) timePriority {
+	return func(a, b *Order) bool {
+		cutoff := time.Now().Add(-after)
+		aStale, bStale := a.CreatedAt.Before(cutoff), b.CreatedAt.Before(cutoff)
+		if aStale != bStale {
+			return bStale
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+}
+
+// sortCandidates reorders candidates by price, as findMatchingCandidates
+// does, then by the given time priority
+func sortCandidates(candidates []*Order, earlier timePriority) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.OrderType == OrderTypeSell && !a.MinPrice.Equal(b.MinPrice) {
+			return a.MinPrice.LessThan(b.MinPrice)
+		}
+		if a.OrderType == OrderTypeBuy && !a.MaxPrice.Equal(b.MaxPrice) {
+			return a.MaxPrice.GreaterThan(b.MaxPrice)
+		}
+		return earlier(a, b)
+	})
+}
+
+// rankByTimeWithinTolerance stably moves the candidates whose limit price is
+// within toleranceBps of the best candidate's to the front, in time priority,
+// so patient makers near the top of the book fill before newer ones that only
+// marginally improve on them. Candidates must already be in limit price order.
+func rankByTimeWithinTolerance(candidates []*Order, toleranceBps int, earlier timePriority) {
+	if len(candidates) < 2 {
+		return
+	}
+
+	band := decimal.NewFromInt(int64(toleranceBps)).Div(decimal.NewFromInt(10000))
+	best := candidates[0]
+	inBand := make(map[string]bool, len(candidates))
+	if best.OrderType == OrderTypeSell {
+		// Asks: the best is the lowest minimum, the band reaches up from it
+		limit := best.MinPrice.Mul(decimal.NewFromInt(1).Add(band))
+		for _, c := range candidates {
+			inBand[c.ID] = c.MinPrice.LessThanOrEqual(limit)
+		}
+	} else {
+		// Bids: the best is the highest maximum, the band reaches down from it
+		limit := best.MaxPrice.Mul(decimal.NewFromInt(1).Sub(band))
+		for _, c := range candidates {
+			inBand[c.ID] = c.MaxPrice.GreaterThanOrEqual(limit)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if inBand[a.ID] != inBand[b.ID] {
+			return inBand[a.ID]
+		}
+		return inBand[a.ID] && earlier(a, b)
+	})
+}
+
+// rankByPriceImprovement stably reorders candidates so those giving the taker
+// the most improvement on its limit, at the price each would execute at, come
+// first. With midpoint pricing this can differ from limit-price order.
+func rankByPriceImprovement(cfg *config.Config, incomingOrder *Order, candidates []*Order) {
+	improvement := make(map[string]decimal.Decimal, len(candidates))
+	for _, candidate := range candidates {
+		taker, maker := liquidityRoles(cfg, incomingOrder, candidate)
+		price := calculateExecutionPrice(taker, maker, cfg.PriceTieBreak, cfg.PriceSignificantDigits)
+		if incomingOrder.OrderType == OrderTypeBuy {
+			improvement[candidate.ID] = incomingOrder.MaxPrice.Sub(price)
+		} else {
+			improvement[candidate.ID] = price.Sub(incomingOrder.MinPrice)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return improvement[candidates[i].ID].GreaterThan(improvement[candidates[j].ID])
+	})
+}
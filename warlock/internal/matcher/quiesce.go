@@ -0,0 +1,22 @@
+package matcher
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Quiesce pauses the engine for work that must not race matching, such as a
+// schema migration. It waits for in-flight orders, cancels and maintenance to
+// finish, then holds new ones off until resume is called. Submissions keep
+// queueing meanwhile and are processed once the engine resumes.
+func (e *Engine) Quiesce() (resume func()) {
+	start := time.Now()
+	e.quiesceMu.Lock()
+	log.Warn().Dur("drained_in", time.Since(start)).Msg("Engine quiesced")
+
+	return func() {
+		e.quiesceMu.Unlock()
+		log.Info().Dur("paused_for", time.Since(start)).Msg("Engine resumed")
+	}
+}
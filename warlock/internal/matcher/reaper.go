@@ -0,0 +1,154 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// reaper runs periodic maintenance tasks until the engine stops
+func (e *Engine) reaper(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.cfg.ReaperInterval)
+	defer ticker.Stop()
+
+	log.Debug().Dur("interval", e.cfg.ReaperInterval).Msg("Reaper started")
+
+	for {
+		select {
+		case <-e.stopChan:
+			log.Debug().Msg("Reaper stopped")
+			return
+
+		case <-ticker.C:
+			e.runMaintenance(ctx)
+		}
+	}
+}
+
+// runMaintenance runs one reaper pass
+func (e *Engine) runMaintenance(ctx context.Context) {
+	e.quiesceMu.RLock()
+	defer e.quiesceMu.RUnlock()
+
+	if e.cfg.SettlementTimeout > 0 {
+		if err := e.reapStaleSettlements(ctx); err != nil {
+			log.Error().Err(err).Msg("Failed to reap stale settlements")
+		}
+	}
+	if err := e.cancelUnfilledOrders(ctx); err != nil {
+		log.Error().Err(err).Msg("Failed to cancel orders past their no-fill deadline")
+	}
+	if e.cfg.CheckApprovals {
+		e.cancelUnapprovedOrders(ctx)
+	}
+	if e.cfg.MaxMarketDistanceBps > 0 {
+		e.cancelFarFromMarket(ctx)
+	}
+	if e.cfg.BookCompactAfter > 0 {
+		e.compactIdleBooks()
+	}
+	if e.cfg.BookIdleTTL > 0 {
+		e.evictIdleBooks()
+	}
+	if e.cfg.PriorityDecayAfter > 0 {
+		for _, book := range e.bookMgr.Books() {
+			book.Reprioritize()
+		}
+	}
+}
+
+// reapStaleSettlements fails matches whose settlement deadline has passed
+// and restores the matched quantity to both orders
+func (e *Engine) reapStaleSettlements(ctx context.Context) error {
+	matchIDs, err := e.store.StaleMatches(ctx, e.Now(), 100)
+	if err != nil {
+		return err
+	}
+
+	for _, matchID := range matchIDs {
+		if err := e.failMatch(ctx, matchID, "settlement deadline exceeded"); err != nil {
+			log.Error().Err(err).Str("match_id", matchID).Msg("Failed to expire match")
+		}
+	}
+
+	return nil
+}
+
+// cancelUnfilledOrders cancels orders past their no-fill deadline that have
+// not had a single fill. Partially filled orders are left alone.
+func (e *Engine) cancelUnfilledOrders(ctx context.Context) error {
+	rows, err := e.db.Query(ctx, `
+		UPDATE orders
+		SET status = 'CANCELLED'
+		WHERE status = 'REVEALED'
+		  AND filled_quantity = 0
+		  AND no_fill_deadline IS NOT NULL
+		  AND no_fill_deadline <= $1
+		RETURNING id
+	`, e.Now())
+	if err != nil {
+		return fmt.Errorf("failed to cancel unfilled orders: %w", err)
+	}
+
+	orderIDs := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan cancelled order: %w", err)
+		}
+		orderIDs = append(orderIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to cancel unfilled orders: %w", err)
+	}
+
+	for _, orderID := range orderIDs {
+		e.EvictOrder(orderID)
+		log.Info().Str("order_id", orderID).Msg("Cancelled order with no fills before its no-fill deadline")
+	}
+
+	return nil
+}
+
+// restoreToBook replaces an order's book entry with its restored state,
+// waiting out any rebuild of the pair so the update lands in the current book
+func (e *Engine) restoreToBook(o *Order) {
+	pairLock := e.pairLock(o.BaseToken, o.QuoteToken)
+	pairLock.RLock()
+	defer pairLock.RUnlock()
+
+	book := e.bookMgr.GetOrCreateBook(o.PoolID, o.BaseToken, o.QuoteToken)
+	book.RemoveOrder(o.ID)
+	if o.IsActive() && !o.Expired(e.Now()) {
+		book.AddOrder(o)
+	}
+}
+
+// failMatch marks a match FAILED and gives its quantity back to both orders.
+// Cancelled orders get their quantities corrected but stay cancelled.
+func (e *Engine) failMatch(ctx context.Context, matchID, reason string) error {
+	restored, err := e.store.FailMatch(ctx, matchID, reason)
+	if err != nil {
+		return err
+	}
+
+	// Put the restored quantity back in the books
+	for _, o := range restored {
+		e.restoreToBook(o)
+	}
+
+	log.Warn().
+		Str("match_id", matchID).
+		Str("buy_order_id", restored[0].ID).
+		Str("sell_order_id", restored[1].ID).
+		Str("reason", reason).
+		Msg("Match failed and quantity restored")
+
+	return nil
+}
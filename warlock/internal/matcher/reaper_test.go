@@ -0,0 +1,121 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestStaleSettlementRestoresQuantityToTheBook(t *testing.T) {
+	useConfigFile(t, `
+settlement_timeout: 1m
+`)
+	start := time.Now()
+	clock := &mockClock{now: start}
+	e, store := newClockedEngine(t, clock)
+	ctx := context.Background()
+
+	maker := testOrder("0xalice", OrderTypeSell, "5", "100", 100)
+	submit(t, e, maker)
+	taker := testOrder("0xbob", OrderTypeBuy, "2", "100", 100)
+	if matches := submit(t, e, taker); len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+
+	// Inside the deadline the match is left pending
+	clock.Set(start.Add(30 * time.Second))
+	if err := e.reapStaleSettlements(ctx); err != nil {
+		t.Fatalf("reapStaleSettlements: %v", err)
+	}
+	if status := store.Matches()[0].SettlementStatus; status != "PENDING" {
+		t.Fatalf("match %s before its deadline, want PENDING", status)
+	}
+
+	clock.Set(start.Add(2 * time.Minute))
+	if err := e.reapStaleSettlements(ctx); err != nil {
+		t.Fatalf("reapStaleSettlements: %v", err)
+	}
+	if status := store.Matches()[0].SettlementStatus; status != "FAILED" {
+		t.Fatalf("match %s past its deadline, want FAILED", status)
+	}
+
+	// Both orders get the quantity back, in the store and in the book
+	for _, o := range []*Order{maker, taker} {
+		stored := loadOrder(t, store, o.ID)
+		if stored.Status != OrderStatusRevealed || !stored.FilledQuantity.IsZero() || !stored.RemainingQuantity.Equal(o.Quantity) {
+			t.Errorf("stored %s: %s with %s filled, %s remaining; want REVEALED with nothing filled",
+				o.UserAddress, stored.Status, stored.FilledQuantity, stored.RemainingQuantity)
+		}
+		resting, inBook := e.GetInMemoryOrder(o.ID)
+		if !inBook || !resting.RemainingQuantity.Equal(o.Quantity) {
+			t.Errorf("%s in book: %t with %s remaining, want %s", o.UserAddress, inBook, resting.RemainingQuantity, o.Quantity)
+		}
+	}
+
+	// A failed match is not reaped again
+	if err := e.reapStaleSettlements(ctx); err != nil {
+		t.Fatalf("reapStaleSettlements: %v", err)
+	}
+	if stored := loadOrder(t, store, maker.ID); !stored.RemainingQuantity.Equal(maker.Quantity) {
+		t.Errorf("maker has %s remaining after a second pass, want %s", stored.RemainingQuantity, maker.Quantity)
+	}
+}
+
+func TestFailMatchWaitsOutAFillOnTheSameOrder(t *testing.T) {
+	useConfigFile(t, `
+settlement_timeout: 10ms
+`)
+	e, store := newTestEngine(t, testConfig(t))
+	ctx := context.Background()
+
+	maker := testOrder("0xalice", OrderTypeSell, "5", "100", 100)
+	submit(t, e, maker)
+	submit(t, e, testOrder("0xbob", OrderTypeBuy, "2", "100", 100))
+	time.Sleep(20 * time.Millisecond)
+
+	// A second fill holds the maker while the first match times out
+	second := testOrder("0xcarol", OrderTypeBuy, "1", "100", 100)
+	if err := store.CreateOrders(ctx, []NewOrder{{Order: second}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+	tx, err := store.BeginFill(ctx)
+	if err != nil {
+		t.Fatalf("BeginFill: %v", err)
+	}
+	makerNow := loadOrder(t, store, maker.ID)
+	if err := tx.LockOrders(ctx, second, makerNow); err != nil {
+		t.Fatalf("LockOrders: %v", err)
+	}
+
+	reaped := make(chan error, 1)
+	go func() { reaped <- e.reapStaleSettlements(ctx) }()
+	select {
+	case err := <-reaped:
+		t.Fatalf("reap returned %v while a fill held the maker", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := tx.UpdateOrderFill(ctx, second, decimal.NewFromInt(1)); err != nil {
+		t.Fatalf("UpdateOrderFill: %v", err)
+	}
+	if err := tx.UpdateOrderFill(ctx, makerNow, decimal.NewFromInt(1)); err != nil {
+		t.Fatalf("UpdateOrderFill: %v", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if err := <-reaped; err != nil {
+		t.Fatalf("reapStaleSettlements: %v", err)
+	}
+
+	// The restore lands on top of the fill rather than undoing it
+	stored := loadOrder(t, store, maker.ID)
+	if !stored.FilledQuantity.Equal(decimal.NewFromInt(1)) || !stored.RemainingQuantity.Equal(decimal.NewFromInt(4)) {
+		t.Errorf("maker has %s filled and %s remaining, want 1 and 4", stored.FilledQuantity, stored.RemainingQuantity)
+	}
+	if resting, inBook := e.GetInMemoryOrder(maker.ID); !inBook || !resting.RemainingQuantity.Equal(decimal.NewFromInt(4)) {
+		t.Errorf("maker in book: %t with %s remaining, want 4", inBook, resting.RemainingQuantity)
+	}
+}
@@ -0,0 +1,142 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// reconciler checks every BookReconcileInterval that the in-memory books
+// still agree with the database
+func (e *Engine) reconciler(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.cfg.BookReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+
+		case <-ticker.C:
+			if err := e.reconcileBooks(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to reconcile order books")
+			}
+		}
+	}
+}
+
+// reconcileBooks compares every resting order with its stored copy, pair by
+// pair with matching on the pair held off. Orders no longer active in the
+// database are removed, and those whose status or remaining quantity differ
+// are replaced by the stored copy. Stored orders missing from memory are left
+// alone, since they may still be queued for matching; RebuildBook loads them.
+// The reconcile time is recorded only when every pair was checked.
+func (e *Engine) reconcileBooks(ctx context.Context) error {
+	e.quiesceMu.RLock()
+	defer e.quiesceMu.RUnlock()
+
+	started := time.Now()
+	pairs := make(map[string][2]string)
+	for _, book := range e.bookMgr.Books() {
+		pairs[makePairKey(book.baseToken, book.quoteToken)] = [2]string{book.baseToken, book.quoteToken}
+	}
+
+	drifted := 0
+	for _, pair := range pairs {
+		n, err := e.reconcilePair(ctx, pair[0], pair[1])
+		if err != nil {
+			return fmt.Errorf("failed to reconcile %s/%s: %w", pair[0], pair[1], err)
+		}
+		drifted += n
+	}
+
+	e.reconcileMu.Lock()
+	e.lastReconcile = started
+	e.reconcileMu.Unlock()
+
+	if drifted > 0 {
+		log.Warn().Int("orders", drifted).Msg("Corrected in-memory orders that drifted from the database")
+	}
+	return nil
+}
+
+// reconcilePair reconciles one pair's books, reporting how many orders drifted
+func (e *Engine) reconcilePair(ctx context.Context, baseToken, quoteToken string) (int, error) {
+	pairLock := e.pairLock(baseToken, quoteToken)
+	pairLock.Lock()
+	defer pairLock.Unlock()
+
+	books := e.bookMgr.PairBooks(baseToken, quoteToken)
+	resting := make(map[string]*OrderBook)
+	ids := make([]string, 0)
+	for _, book := range books {
+		for _, o := range append(book.GetBids(), book.GetAsks()...) {
+			resting[o.ID] = book
+			ids = append(ids, o.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	stored, err := e.LoadOrders(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	byID := make(map[string]*Order, len(stored))
+	for _, o := range stored {
+		byID[o.ID] = o
+	}
+
+	drifted := 0
+	now := e.Now()
+	for id, book := range resting {
+		current := book.GetOrder(id)
+		if current == nil {
+			continue
+		}
+		s := byID[id]
+		active := s != nil && s.IsActive() && !s.Expired(now)
+		if active && s.Status == current.Status && s.RemainingQuantity.Equal(current.RemainingQuantity) {
+			continue
+		}
+
+		drifted++
+		book.RemoveOrder(id)
+		if active {
+			book.AddOrder(s)
+		}
+		log.Warn().
+			Str("order_id", id).
+			Str("memory_status", string(current.Status)).
+			Str("memory_remaining", current.RemainingQuantity.String()).
+			Bool("stored_active", active).
+			Msg("In-memory order drifted from the database")
+	}
+	return drifted, nil
+}
+
+// markReconciled records that the books match the database as of now, e.g.
+// right after they were loaded
+func (e *Engine) markReconciled() {
+	e.reconcileMu.Lock()
+	e.lastReconcile = time.Now()
+	e.reconcileMu.Unlock()
+}
+
+// LastReconcile returns when the books were last known to match the database
+func (e *Engine) LastReconcile() time.Time {
+	e.reconcileMu.RLock()
+	defer e.reconcileMu.RUnlock()
+	return e.lastReconcile
+}
+
+// BookStale reports whether the books have gone longer than MaxBookStaleness
+// without a successful reconcile; always false when the limit is unset
+func (e *Engine) BookStale() bool {
+	return e.cfg.MaxBookStaleness > 0 && time.Since(e.LastReconcile()) > e.cfg.MaxBookStaleness
+}
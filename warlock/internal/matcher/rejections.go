@@ -0,0 +1,93 @@
+package matcher
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// rejectionChannelSize bounds the rejections waiting for RejectionChan's
+// reader; past it they are only stored
+const rejectionChannelSize = 1000
+
+// Where in the order's life a rejection happened
+const (
+	RejectionStageValidation = "VALIDATION" // Turned away at submission, never stored as an order
+	RejectionStageMatching   = "MATCHING"   // Stored, then cancelled by the engine
+)
+
+// RejectReasonMinRestingSpread is the reason recorded when an order's
+// remainder is cancelled for resting inside MinRestingSpreadBps
+const RejectReasonMinRestingSpread = "MIN_RESTING_SPREAD"
+
+// Rejection is one order submission the engine turned away
+type Rejection struct {
+	ID          int64
+	OrderID     string // Only for matching-stage rejections
+	UserAddress string
+	BaseToken   string
+	QuoteToken  string
+	OrderType   OrderType // Empty when the submission didn't give a valid side
+	Stage       string
+	Reason      string
+	Message     string
+	RejectedAt  time.Time
+}
+
+// RecordRejection stores a rejection in rejected_orders for later analysis and
+// passes it on to RejectionChan. Failures are only logged: the submitter gets
+// its rejection either way.
+func (e *Engine) RecordRejection(ctx context.Context, r *Rejection) {
+	if e.db == nil {
+		r.RejectedAt = e.Now()
+		e.streamRejection(r)
+		return
+	}
+
+	err := e.db.QueryRow(ctx, `
+		INSERT INTO rejected_orders (
+			order_id, user_address, base_token, quote_token, order_type, stage, reason, message
+		) VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, rejected_at
+	`, r.OrderID, r.UserAddress, r.BaseToken, r.QuoteToken, string(r.OrderType), r.Stage, r.Reason, r.Message).
+		Scan(&r.ID, &r.RejectedAt)
+	if err != nil {
+		log.Error().Err(err).
+			Str("reason", r.Reason).
+			Str("user_address", r.UserAddress).
+			Msg("Failed to record order rejection")
+		return
+	}
+	e.streamRejection(r)
+}
+
+// streamRejection passes a rejection on to RejectionChan unless it is full
+func (e *Engine) streamRejection(r *Rejection) {
+	select {
+	case e.rejectionChan <- r:
+	default:
+		log.Warn().Int64("rejection_id", r.ID).Msg("Rejection channel full; rejection stored but not streamed")
+	}
+}
+
+// RejectionChan returns the channel every recorded rejection is sent on. It
+// is never closed, since rejections are recorded outside the engine's life.
+func (e *Engine) RejectionChan() <-chan *Rejection {
+	return e.rejectionChan
+}
+
+// recordMatchingRejection records an order the engine cancelled after it was
+// stored
+func (e *Engine) recordMatchingRejection(ctx context.Context, order *Order, reason, message string) {
+	e.RecordRejection(ctx, &Rejection{
+		OrderID:     order.ID,
+		UserAddress: order.UserAddress,
+		BaseToken:   order.BaseToken,
+		QuoteToken:  order.QuoteToken,
+		OrderType:   order.OrderType,
+		Stage:       RejectionStageMatching,
+		Reason:      reason,
+		Message:     message,
+	})
+}
@@ -0,0 +1,74 @@
+package matcher
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// applyRemainderPolicy handles what is left of a freshly submitted order after
+// its first matching pass partially filled it. REST leaves it as is.
+func (e *Engine) applyRemainderPolicy(ctx context.Context, orderBook *OrderBook, order *Order, matches []*Match) {
+	if order.Status != OrderStatusPartiallyFilled {
+		return
+	}
+
+	switch order.RemainderPolicy {
+	case RemainderCancel:
+		e.cancelRemainder(ctx, orderBook, order)
+	case RemainderRepeg:
+		e.repegRemainder(ctx, orderBook, order, matches)
+	}
+}
+
+// cancelRemainder cancels the unfilled part; the fills stand
+func (e *Engine) cancelRemainder(ctx context.Context, orderBook *OrderBook, order *Order) {
+	if err := e.store.CancelOrder(ctx, order.ID, ""); err != nil {
+		log.Error().Err(err).Str("order_id", order.ID).Msg("Failed to cancel order remainder")
+		return
+	}
+
+	order.Status = OrderStatusCancelled
+	orderBook.RemoveOrder(order.ID)
+
+	log.Info().
+		Str("order_id", order.ID).
+		Str("remaining_quantity", order.RemainingQuantity.String()).
+		Msg("Cancelled remainder after partial fill")
+}
+
+// repegRemainder moves the order's reference price to the volume-weighted
+// price of its fills and recomputes its range from the original variance.
+// The remainder rests at the new price until the next incoming order.
+func (e *Engine) repegRemainder(ctx context.Context, orderBook *OrderBook, order *Order, matches []*Match) {
+	notional := decimal.Zero
+	quantity := decimal.Zero
+	for _, m := range matches {
+		notional = notional.Add(m.Quantity.Mul(m.Price))
+		quantity = quantity.Add(m.Quantity)
+	}
+	price := notional.Div(quantity)
+
+	varianceFactor := decimal.NewFromInt(int64(order.VarianceBPS)).Div(decimal.NewFromInt(10000))
+	minPrice := price.Mul(decimal.NewFromInt(1).Sub(varianceFactor))
+	maxPrice := price.Mul(decimal.NewFromInt(1).Add(varianceFactor))
+
+	if err := e.store.RepegOrder(ctx, order.ID, price, minPrice, maxPrice); err != nil {
+		log.Error().Err(err).Str("order_id", order.ID).Msg("Failed to re-peg order remainder")
+		return
+	}
+
+	// Take it out before changing the price so the heap stays ordered
+	orderBook.RemoveOrder(order.ID)
+	order.Price = price
+	order.MinPrice = minPrice
+	order.MaxPrice = maxPrice
+	orderBook.AddOrder(order)
+
+	log.Info().
+		Str("order_id", order.ID).
+		Str("price", price.String()).
+		Str("remaining_quantity", order.RemainingQuantity.String()).
+		Msg("Re-pegged remainder after partial fill")
+}
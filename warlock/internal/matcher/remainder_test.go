@@ -0,0 +1,93 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRemainderPolicies(t *testing.T) {
+	tests := []struct {
+		policy     RemainderPolicy
+		wantStatus OrderStatus
+		wantBook   bool
+		wantPrice  string // Stored and in-book price of the remainder; empty for the fill price
+	}{
+		{policy: RemainderRest, wantStatus: OrderStatusPartiallyFilled, wantBook: true, wantPrice: "101"},
+		{policy: RemainderCancel, wantStatus: OrderStatusCancelled, wantBook: false},
+		{policy: RemainderRepeg, wantStatus: OrderStatusPartiallyFilled, wantBook: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			e, store := newTestEngine(t, testConfig(t))
+
+			submit(t, e, testOrder("0xalice", OrderTypeSell, "1", "100", 0))
+			buy := testOrder("0xbob", OrderTypeBuy, "3", "101", 100)
+			buy.RemainderPolicy = tt.policy
+			matches := submit(t, e, buy)
+			if len(matches) != 1 {
+				t.Fatalf("got %d matches, want 1", len(matches))
+			}
+
+			stored := loadOrder(t, store, buy.ID)
+			if stored.Status != tt.wantStatus || !stored.RemainingQuantity.Equal(decimal.NewFromInt(2)) {
+				t.Fatalf("stored %s with %s remaining, want %s with 2", stored.Status, stored.RemainingQuantity, tt.wantStatus)
+			}
+
+			resting, inBook := e.GetInMemoryOrder(buy.ID)
+			if inBook != tt.wantBook {
+				t.Fatalf("in book: %t, want %t", inBook, tt.wantBook)
+			}
+			if !tt.wantBook {
+				return
+			}
+
+			price := matches[0].Price
+			if tt.wantPrice != "" {
+				price = decimal.RequireFromString(tt.wantPrice)
+			} else if price.Equal(decimal.NewFromInt(101)) {
+				t.Fatal("filled at the order's own price, so a re-peg can't be seen")
+			}
+			minPrice := price.Mul(decimal.RequireFromString("0.99"))
+			maxPrice := price.Mul(decimal.RequireFromString("1.01"))
+			for _, o := range []Order{*stored, resting} {
+				if !o.Price.Equal(price) || !o.MinPrice.Equal(minPrice) || !o.MaxPrice.Equal(maxPrice) {
+					t.Errorf("remainder at %s [%s, %s], want %s [%s, %s]",
+						o.Price, o.MinPrice, o.MaxPrice, price, minPrice, maxPrice)
+				}
+			}
+		})
+	}
+}
+
+func TestCancelOrdersWhereInMemory(t *testing.T) {
+	e, store := newTestEngine(t, testConfig(t))
+	ctx := context.Background()
+
+	low := testOrder("0xalice", OrderTypeSell, "1", "105", 100)
+	high := testOrder("0xalice", OrderTypeSell, "1", "120", 100)
+	other := testOrder("0xcarol", OrderTypeSell, "1", "120", 100)
+	for _, o := range []*Order{low, high, other} {
+		submit(t, e, o)
+	}
+
+	ids, err := e.CancelOrdersWhere(ctx, CancelFilter{UserAddress: "0xALICE", PriceAbove: decimal.NewFromInt(110)})
+	if err != nil {
+		t.Fatalf("CancelOrdersWhere: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != high.ID {
+		t.Fatalf("cancelled %v, want only %s", ids, high.ID)
+	}
+
+	for _, o := range []*Order{low, high, other} {
+		cancelled := o == high
+		if got := loadOrder(t, store, o.ID).Status == OrderStatusCancelled; got != cancelled {
+			t.Errorf("order at %s by %s cancelled: %t, want %t", o.Price, o.UserAddress, got, cancelled)
+		}
+		if _, inBook := e.GetInMemoryOrder(o.ID); inBook == cancelled {
+			t.Errorf("order at %s by %s in book: %t, want %t", o.Price, o.UserAddress, inBook, !cancelled)
+		}
+	}
+}
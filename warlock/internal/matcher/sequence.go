@@ -0,0 +1,150 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// matchSequencer numbers matches and publishes them in that order. A match's
+// sequence is reserved as it is created, before its transaction commits, and
+// resolved once the worker knows whether it did; matches only go out once
+// every lower sequence is resolved. Workers commit in any order, but whoever
+// has seen match N has seen every committed match up to N, so a stream can
+// resume with the matches after N.
+type matchSequencer struct {
+	mu       sync.Mutex
+	floor    int64            // Highest sequence recorded before matching started
+	next     int64            // Last sequence reserved
+	released int64            // Every sequence up to this is resolved and published
+	resolved map[int64]*Match // Resolved beyond released; nil if rolled back
+}
+
+func newMatchSequencer() *matchSequencer {
+	return &matchSequencer{resolved: make(map[int64]*Match)}
+}
+
+// start numbers matches from after last, the highest sequence recorded
+func (s *matchSequencer) start(last int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.floor, s.next, s.released = last, last, last
+	clear(s.resolved)
+}
+
+// reserve hands out the next sequence
+func (s *matchSequencer) reserve() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.next++
+	return s.next
+}
+
+// resolve records which reserved sequences committed, as matches, and which
+// were rolled back, then publishes every match no longer waiting on a lower
+// sequence. It reports false if publish did, i.e. the engine stopped.
+func (s *matchSequencer) resolve(committed []*Match, rolledBack []int64, publish func(*Match) bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, m := range committed {
+		s.resolved[m.Seq] = m
+	}
+	for _, seq := range rolledBack {
+		s.resolved[seq] = nil
+	}
+
+	// Published under the lock, so they leave in sequence order
+	for {
+		m, ok := s.resolved[s.released+1]
+		if !ok {
+			return true
+		}
+		delete(s.resolved, s.released+1)
+		s.released++
+		if m != nil && !publish(m) {
+			return false
+		}
+	}
+}
+
+// MatchSeqFloor is the highest match sequence recorded before the engine
+// started matching: every match after it is published on MatchChan, in
+// sequence order
+func (e *Engine) MatchSeqFloor() int64 {
+	e.sequencer.mu.Lock()
+	defer e.sequencer.mu.Unlock()
+	return e.sequencer.floor
+}
+
+// startSequencer numbers matches from after the last one stored
+func (e *Engine) startSequencer(ctx context.Context) error {
+	last, err := e.store.LastMatchSeq(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the last match sequence: %w", err)
+	}
+	e.sequencer.start(last)
+	return nil
+}
+
+// sequencedStore is the engine's Store for one matching pass: it reserves the
+// sequence of each match created through it, remembering them so the pass can
+// resolve every one; see publishMatches
+type sequencedStore struct {
+	Store
+	sequencer *matchSequencer
+	reserved  []int64
+}
+
+// sequenced returns the store for a matching pass
+func (e *Engine) sequenced() *sequencedStore {
+	return &sequencedStore{Store: e.store, sequencer: e.sequencer}
+}
+
+// BeginFill implements MatchScope
+func (s *sequencedStore) BeginFill(ctx context.Context) (FillTx, error) {
+	tx, err := s.Store.BeginFill(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sequencedFill{FillTx: tx, store: s}, nil
+}
+
+// BeginClaim implements Store
+func (s *sequencedStore) BeginClaim(ctx context.Context) (Claim, error) {
+	claim, err := s.Store.BeginClaim(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sequencedClaim{Claim: claim, store: s}, nil
+}
+
+// sequencedClaim is a Claim whose fills reserve their match sequences
+type sequencedClaim struct {
+	Claim
+	store *sequencedStore
+}
+
+// BeginFill implements MatchScope
+func (c sequencedClaim) BeginFill(ctx context.Context) (FillTx, error) {
+	tx, err := c.Claim.BeginFill(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sequencedFill{FillTx: tx, store: c.store}, nil
+}
+
+// sequencedFill is a FillTx that reserves its match's sequence
+type sequencedFill struct {
+	FillTx
+	store *sequencedStore
+}
+
+// CreateMatch implements FillTx
+func (f sequencedFill) CreateMatch(ctx context.Context, m *Match) error {
+	m.Seq = f.store.sequencer.reserve()
+	f.store.reserved = append(f.store.reserved, m.Seq)
+	return f.FillTx.CreateMatch(ctx, m)
+}
@@ -0,0 +1,197 @@
+package matcher
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+)
+
+// gatedStore is a MemoryStore whose fills on one base token hold their commit
+// until released
+type gatedStore struct {
+	*MemoryStore
+	baseToken string
+	blocked   chan struct{} // Receives once a gated commit is waiting
+	release   chan struct{} // Closed to let gated commits through
+}
+
+func (s *gatedStore) BeginFill(ctx context.Context) (FillTx, error) {
+	tx, err := s.MemoryStore.BeginFill(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &gatedFill{FillTx: tx, store: s}, nil
+}
+
+// gatedFill is a fill of a gatedStore
+type gatedFill struct {
+	FillTx
+	store *gatedStore
+	gated bool
+}
+
+func (f *gatedFill) CreateMatch(ctx context.Context, m *Match) error {
+	f.gated = m.BaseToken == f.store.baseToken
+	return f.FillTx.CreateMatch(ctx, m)
+}
+
+func (f *gatedFill) Commit(ctx context.Context) error {
+	if f.gated {
+		f.store.blocked <- struct{}{}
+		<-f.store.release
+	}
+	return f.FillTx.Commit(ctx)
+}
+
+// startGatedEngine starts an engine over a gatedStore holding fills on
+// baseToken. The returned release lets them through; it also runs on cleanup
+// so a failed test never leaves a worker stalled.
+func startGatedEngine(t *testing.T, cfg *config.Config, baseToken string) (*Engine, *gatedStore, func()) {
+	t.Helper()
+	store := &gatedStore{
+		MemoryStore: NewMemoryStore(),
+		baseToken:   baseToken,
+		blocked:     make(chan struct{}, 16),
+		release:     make(chan struct{}),
+	}
+	e := NewEngine(nil, cfg)
+	e.SetStore(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := e.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		e.Stop()
+		cancel()
+	})
+	release := sync.OnceFunc(func() { close(store.release) })
+	t.Cleanup(release)
+	return e, store, release
+}
+
+// waitGated waits for a gated fill to reach its commit
+func waitGated(t *testing.T, store *gatedStore) {
+	t.Helper()
+	select {
+	case <-store.blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fill never reached its commit")
+	}
+}
+
+// pairOrder is testOrder on another pair
+func pairOrder(user string, side OrderType, baseToken, quantity, price string) *Order {
+	o := testOrder(user, side, quantity, price, 100)
+	o.BaseToken = baseToken
+	return o
+}
+
+func TestMatchesPublishInSequenceOrder(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.MatchSkipLocked = false
+	store := &gatedStore{
+		MemoryStore: NewMemoryStore(),
+		baseToken:   "WBTC",
+		blocked:     make(chan struct{}, 1),
+		release:     make(chan struct{}),
+	}
+	e := NewEngine(nil, cfg)
+	e.SetStore(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := e.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		e.Stop()
+		cancel()
+	})
+	// A failed test must not leave the stalled worker holding Stop up
+	var releaseOnce sync.Once
+	release := func() { releaseOnce.Do(func() { close(store.release) }) }
+	t.Cleanup(release)
+
+	submit(t, e, pairOrder("0xalice", OrderTypeSell, "WBTC", "1", "100"))
+	submit(t, e, pairOrder("0xcarol", OrderTypeSell, "WETH", "1", "100"))
+
+	// One worker reserves the first sequence and stalls before committing
+	buy := pairOrder("0xbob", OrderTypeBuy, "WBTC", "1", "100")
+	if err := store.CreateOrders(ctx, []NewOrder{{Order: buy}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+	var wg sync.WaitGroup
+	var first []*Match
+	var firstErr error
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		first, firstErr = e.SubmitOrderSync(ctx, buy)
+	}()
+	select {
+	case <-store.blocked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("WBTC fill never reached its commit")
+	}
+
+	// Another commits the next sequence meanwhile
+	second := submit(t, e, pairOrder("0xdave", OrderTypeBuy, "WETH", "1", "100"))
+	if len(second) != 1 {
+		t.Fatalf("WETH buy got %d matches, want 1", len(second))
+	}
+	if committed := store.Matches(); len(committed) != 1 || committed[0].ID != second[0].ID {
+		t.Fatalf("store holds %v, want only the WETH match", committed)
+	}
+
+	// It waits for the lower sequence, or a stream resuming after it would
+	// skip the WBTC match for good
+	select {
+	case m := <-e.MatchChan():
+		t.Fatalf("match %d published while match %d was still uncommitted", m.Seq, second[0].Seq-1)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release()
+	wg.Wait()
+	if firstErr != nil {
+		t.Fatalf("SubmitOrderSync: %v", firstErr)
+	}
+	if len(first) != 1 || first[0].Seq >= second[0].Seq {
+		t.Fatalf("WBTC buy got %v, want one match sequenced before %d", first, second[0].Seq)
+	}
+
+	for _, want := range []*Match{first[0], second[0]} {
+		select {
+		case m := <-e.MatchChan():
+			if m.ID != want.ID || m.Seq != want.Seq {
+				t.Errorf("published match %d, want %d", m.Seq, want.Seq)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("match %d was never published", want.Seq)
+		}
+	}
+}
+
+func TestRolledBackSequenceReleasesLaterMatches(t *testing.T) {
+	s := newMatchSequencer()
+	s.start(10)
+
+	var published []int64
+	publish := func(m *Match) bool {
+		published = append(published, m.Seq)
+		return true
+	}
+
+	rolledBack, committed := s.reserve(), s.reserve()
+	s.resolve([]*Match{{Seq: committed}}, nil, publish)
+	if len(published) != 0 {
+		t.Fatalf("published %v while %d was pending", published, rolledBack)
+	}
+	s.resolve(nil, []int64{rolledBack}, publish)
+	if len(published) != 1 || published[0] != committed {
+		t.Errorf("published %v, want only %d", published, committed)
+	}
+}
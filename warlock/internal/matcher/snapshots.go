@@ -0,0 +1,71 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// bookSampler records every book's best bid and ask into book_snapshots each
+// BookSnapshotInterval, giving price history for pairs that rarely trade
+func (e *Engine) bookSampler(ctx context.Context) {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.cfg.BookSnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+
+		case <-ticker.C:
+			if err := e.sampleBooks(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to record book snapshots")
+			}
+		}
+	}
+}
+
+// sampleBooks writes one snapshot per book that has at least one side
+func (e *Engine) sampleBooks(ctx context.Context) error {
+	sampledAt := time.Now()
+	batch := &pgx.Batch{}
+
+	for _, book := range e.bookMgr.Books() {
+		var bestBid, bestAsk, mid *string
+		bid, ask := book.PeekBestBid(), book.PeekBestAsk()
+		if bid == nil && ask == nil {
+			continue
+		}
+		if bid != nil {
+			s := bid.Price.String()
+			bestBid = &s
+		}
+		if ask != nil {
+			s := ask.Price.String()
+			bestAsk = &s
+		}
+		if bid != nil && ask != nil {
+			s := bid.Price.Add(ask.Price).Div(decimal.NewFromInt(2)).String()
+			mid = &s
+		}
+
+		batch.Queue(`
+			INSERT INTO book_snapshots (pool_id, base_token, quote_token, best_bid, best_ask, mid_price, sampled_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, book.poolID, book.baseToken, book.quoteToken, bestBid, bestAsk, mid, sampledAt)
+	}
+
+	if batch.Len() == 0 {
+		return nil
+	}
+	if err := e.db.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to insert book snapshots: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package matcher
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// HoldBehindSpeedBump sets the order's VisibleAt a random delay between
+// SpeedBumpMin and SpeedBumpMax from now, a deliberate fairness measure
+// against latency arbitrage. Call before storing the order: candidate queries
+// pass it over until then, so it can't be matched as a maker early.
+func (e *Engine) HoldBehindSpeedBump(order *Order) {
+	if delay := speedBumpDelay(e.cfg.SpeedBumpMin, e.cfg.SpeedBumpMax); delay > 0 {
+		order.VisibleAt = e.Now().Add(delay)
+	}
+}
+
+// speedBump holds the calling worker until the order's VisibleAt before it
+// processes the order, so it can't match as a taker early either, nor be seen
+// in the book. Reports false if the engine stopped during the delay; the
+// order is stored and loads again at the next start.
+func (e *Engine) speedBump(order *Order) bool {
+	delay := order.VisibleAt.Sub(e.Now())
+	if delay <= 0 {
+		return true
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-e.stopChan:
+		return false
+	}
+}
+
+// speedBumpDelay picks a delay uniformly from [low, high]
+func speedBumpDelay(low, high time.Duration) time.Duration {
+	if high <= low {
+		return low
+	}
+	return low + rand.N(high-low+1)
+}
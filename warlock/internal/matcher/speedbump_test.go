@@ -0,0 +1,110 @@
+package matcher
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSpeedBumpDelayStaysInRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		low      time.Duration
+		high     time.Duration
+		wantLow  time.Duration
+		wantHigh time.Duration
+	}{
+		{name: "disabled", low: 0, high: 0, wantLow: 0, wantHigh: 0},
+		{name: "fixed", low: 5 * time.Millisecond, high: 5 * time.Millisecond, wantLow: 5 * time.Millisecond, wantHigh: 5 * time.Millisecond},
+		{name: "range from zero", low: 0, high: 3 * time.Millisecond, wantLow: 0, wantHigh: 3 * time.Millisecond},
+		{name: "range", low: time.Millisecond, high: 2 * time.Millisecond, wantLow: time.Millisecond, wantHigh: 2 * time.Millisecond},
+		{name: "one nanosecond wide", low: 10, high: 11, wantLow: 10, wantHigh: 11},
+		{name: "inverted keeps the minimum", low: 2 * time.Millisecond, high: time.Millisecond, wantLow: 2 * time.Millisecond, wantHigh: 2 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 1000; i++ {
+				if d := speedBumpDelay(tt.low, tt.high); d < tt.wantLow || d > tt.wantHigh {
+					t.Fatalf("speedBumpDelay(%s, %s) = %s, outside [%s, %s]", tt.low, tt.high, d, tt.wantLow, tt.wantHigh)
+				}
+			}
+		})
+	}
+}
+
+func TestStopDuringSpeedBumpSignalsSubmitter(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.SpeedBumpMin = time.Hour
+	cfg.SpeedBumpMax = time.Hour
+	store := NewMemoryStore()
+	e := NewEngine(nil, cfg)
+	e.SetStore(store)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := e.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	order := testOrder("0xalice", OrderTypeSell, "1", "100", 100)
+	e.HoldBehindSpeedBump(order)
+	done := make(chan orderOutcome, 1)
+	order.done = done
+	if err := e.SubmitOrder(order); err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+	// Let a worker pick the order up and start its bump
+	time.Sleep(50 * time.Millisecond)
+	e.Stop()
+
+	select {
+	case outcome := <-done:
+		if outcome.err == nil {
+			t.Error("order abandoned mid-bump reported success")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("submitter never heard about the order abandoned mid-bump")
+	}
+}
+
+func TestBumpedOrderIsNoMakerMidBump(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Workers = 2
+	cfg.SpeedBumpMin = 300 * time.Millisecond
+	cfg.SpeedBumpMax = 300 * time.Millisecond
+	e, store := newTestEngine(t, cfg)
+	ctx := context.Background()
+
+	sell := testOrder("0xalice", OrderTypeSell, "1", "100", 100)
+	e.HoldBehindSpeedBump(sell)
+	if err := store.CreateOrders(ctx, []NewOrder{{Order: sell}}); err != nil {
+		t.Fatalf("CreateOrders: %v", err)
+	}
+	done := make(chan orderOutcome, 1)
+	sell.done = done
+	if err := e.SubmitOrder(sell); err != nil {
+		t.Fatalf("SubmitOrder: %v", err)
+	}
+
+	// A crossing buy, itself unbumped, finds nothing while the sell is held
+	buy := testOrder("0xbob", OrderTypeBuy, "1", "100", 100)
+	if matches := submit(t, e, buy); len(matches) != 0 {
+		t.Fatalf("buy matched the bumped sell mid-bump: %v", matches)
+	}
+	if time.Now().After(sell.VisibleAt) {
+		t.Fatal("the bump ended before the buy was matched")
+	}
+	if filled := loadOrder(t, store, sell.ID).FilledQuantity; !filled.IsZero() {
+		t.Fatalf("bumped sell filled %s mid-bump", filled)
+	}
+
+	// Once the bump ends the sell matches the resting buy
+	select {
+	case outcome := <-done:
+		if outcome.err != nil || len(outcome.matches) != 1 {
+			t.Fatalf("after its bump the sell got %v, %v, want one match", outcome.matches, outcome.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("bumped sell never processed")
+	}
+}
@@ -0,0 +1,70 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// enforceMinRestingSpread cancels whatever remains of the incoming order after
+// matching if resting it would leave less than MinRestingSpreadBps against the
+// opposite best. The part that traded is unaffected. Returns why the
+// remainder was cancelled, or "" if it rests.
+func enforceMinRestingSpread(ctx context.Context, store Store, cfg *config.Config, orderBook *OrderBook, order *Order) string {
+	if cfg.MinRestingSpreadBps <= 0 || !order.IsActive() {
+		return ""
+	}
+
+	var bid, ask decimal.Decimal
+	if order.OrderType == OrderTypeBuy {
+		best := orderBook.PeekBestAsk()
+		if best == nil {
+			return ""
+		}
+		bid, ask = order.Price, best.Price
+	} else {
+		best := orderBook.PeekBestBid()
+		if best == nil {
+			return ""
+		}
+		bid, ask = best.Price, order.Price
+	}
+
+	spreadBps := spreadBasisPoints(bid, ask)
+	if spreadBps.GreaterThanOrEqual(decimal.NewFromInt(int64(cfg.MinRestingSpreadBps))) {
+		return ""
+	}
+
+	if err := store.CancelOrder(ctx, order.ID, ""); err != nil {
+		log.Error().Err(err).
+			Str("order_id", order.ID).
+			Msg("Failed to cancel order below minimum resting spread")
+		return ""
+	}
+
+	order.Status = OrderStatusCancelled
+	orderBook.RemoveOrder(order.ID)
+
+	log.Warn().
+		Str("order_id", order.ID).
+		Str("remaining_quantity", order.RemainingQuantity.String()).
+		Str("spread_bps", spreadBps.StringFixed(2)).
+		Int("min_spread_bps", cfg.MinRestingSpreadBps).
+		Msg("Cancelled remainder that would rest inside the minimum spread")
+
+	return fmt.Sprintf("remainder %s would rest %s bps from the opposite best, inside the %d bps minimum",
+		order.RemainingQuantity, spreadBps.StringFixed(2), cfg.MinRestingSpreadBps)
+}
+
+// spreadBasisPoints returns (ask - bid) relative to the mid price, in basis
+// points. A crossed or locked book gives zero or less.
+func spreadBasisPoints(bid, ask decimal.Decimal) decimal.Decimal {
+	mid := bid.Add(ask).Div(decimal.NewFromInt(2))
+	if !mid.IsPositive() {
+		return decimal.Zero
+	}
+	return ask.Sub(bid).Div(mid).Mul(decimal.NewFromInt(10000))
+}
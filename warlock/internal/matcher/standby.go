@@ -0,0 +1,279 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// replicationBatchSize bounds the changed orders read per query
+const replicationBatchSize = 1000
+
+// ErrNotStandby is returned by Promote on an engine that already matches
+var ErrNotStandby = errors.New("engine is not a standby")
+
+// initReplication starts the replication cursor at the database's horizon,
+// before the books are loaded, so no change made meanwhile is missed
+func (e *Engine) initReplication(ctx context.Context) error {
+	horizon, err := e.replicationHorizon(ctx)
+	if err != nil {
+		return err
+	}
+	e.replicaCursor = horizon
+	return nil
+}
+
+// replicationHorizon returns the xmin of a fresh snapshot: the oldest
+// transaction still running. Every transaction below it has committed or
+// aborted, so a read started after this sees all it will ever write.
+func (e *Engine) replicationHorizon(ctx context.Context) (uint64, error) {
+	var xmin string
+	if err := e.db.QueryRow(ctx, `SELECT pg_snapshot_xmin(pg_current_snapshot())::text`).Scan(&xmin); err != nil {
+		return 0, fmt.Errorf("failed to read replication horizon: %w", err)
+	}
+	horizon, err := strconv.ParseUint(xmin, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse replication horizon %q: %w", xmin, err)
+	}
+	return horizon, nil
+}
+
+// startStandby runs a standby: no workers or maintenance, only replication
+// of the primary's order changes into the books until Promote
+func (e *Engine) startStandby(ctx context.Context) {
+	e.roleMu.Lock()
+	e.standby = true
+	e.lastReplicated = time.Now()
+	e.roleMu.Unlock()
+
+	e.startReplication(ctx)
+}
+
+// startReplication starts the replicator goroutine
+func (e *Engine) startReplication(ctx context.Context) {
+	e.replicaStop = make(chan struct{})
+	e.replicaDone = make(chan struct{})
+	e.wg.Add(1)
+	go e.replicate(ctx)
+}
+
+// replicate applies order changes every StandbySyncInterval until the engine
+// stops or is promoted
+func (e *Engine) replicate(ctx context.Context) {
+	defer e.wg.Done()
+	defer close(e.replicaDone)
+
+	ticker := time.NewTicker(e.cfg.StandbySyncInterval)
+	defer ticker.Stop()
+
+	log.Info().Dur("interval", e.cfg.StandbySyncInterval).Msg("Standby replication started")
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+
+		case <-e.replicaStop:
+			return
+
+		case <-ticker.C:
+			if err := e.replicateChanges(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to replicate order changes")
+			}
+		}
+	}
+}
+
+// replicateChanges applies every order changed since the cursor to the books.
+// The orders table is the replication log: the primary commits every order
+// change there before its own book changes, so replaying the changed rows
+// gives the same books. Orders still open replace their book entry and the
+// rest leave the book.
+//
+// Changes are tracked by transaction, not by time. updated_at is when the
+// writing transaction started, so one running longer than any window re-read
+// behind a time cursor commits rows stamped before changes already read
+// past, and they would never replicate. Instead each row carries the id of
+// the transaction that last wrote it, and the cursor is the horizon read
+// before the previous pass: every transaction below it had ended, so that
+// pass saw its changes. A pass reads the rows written at or above the cursor
+// and moves it to its own horizon. A long transaction holds the horizon
+// back, so its rows and those after it are re-read until it ends; applying
+// a change twice is harmless.
+func (e *Engine) replicateChanges(ctx context.Context) error {
+	horizon, err := e.replicationHorizon(ctx)
+	if err != nil {
+		return err
+	}
+
+	afterXact := strconv.FormatUint(e.replicaCursor, 10)
+	afterID := "00000000-0000-0000-0000-000000000000"
+	applied := 0
+
+	for {
+		rows, err := e.db.Query(ctx, `
+			SELECT id, xact_id::text
+			FROM orders
+			WHERE (xact_id, id) > ($1::text::xid8, $2::uuid)
+			ORDER BY xact_id, id
+			LIMIT $3
+		`, afterXact, afterID, replicationBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to query changed orders: %w", err)
+		}
+
+		ids := make([]string, 0, replicationBatchSize)
+		for rows.Next() {
+			if err := rows.Scan(&afterID, &afterXact); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan changed order: %w", err)
+			}
+			ids = append(ids, afterID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read changed orders: %w", err)
+		}
+
+		if len(ids) > 0 {
+			orders, err := e.store.LoadOrders(ctx, ids)
+			if err != nil {
+				return err
+			}
+			applied += e.applyReplicated(orders)
+		}
+		if len(ids) < replicationBatchSize {
+			break
+		}
+	}
+
+	evicted := e.evictExpired()
+
+	e.replicaCursor = horizon
+	e.roleMu.Lock()
+	e.lastReplicated = time.Now()
+	e.roleMu.Unlock()
+
+	if applied > 0 || evicted > 0 {
+		log.Debug().Int("applied", applied).Int("expired", evicted).Msg("Replicated order changes")
+	}
+	return nil
+}
+
+// applyReplicated brings each order's book entry in line with its stored
+// copy, pair by pair with the pair locked. Entries that already agree are
+// left alone, so re-read changes don't disturb the book. Reports how many
+// entries changed.
+func (e *Engine) applyReplicated(orders []*Order) int {
+	byPair := make(map[string][]*Order)
+	for _, o := range orders {
+		key := makePairKey(o.BaseToken, o.QuoteToken)
+		byPair[key] = append(byPair[key], o)
+	}
+
+	now := e.Now()
+	changed := 0
+	for _, pairOrders := range byPair {
+		pairLock := e.pairLock(pairOrders[0].BaseToken, pairOrders[0].QuoteToken)
+		pairLock.Lock()
+		for _, o := range pairOrders {
+			open := o.IsActive() && !o.Expired(now)
+			book := e.bookMgr.GetBook(o.PoolID, o.BaseToken, o.QuoteToken)
+			if book == nil {
+				if !open {
+					continue
+				}
+				book = e.bookMgr.GetOrCreateBook(o.PoolID, o.BaseToken, o.QuoteToken)
+			}
+
+			current := book.GetOrder(o.ID)
+			if current != nil {
+				if open && current.Status == o.Status && current.RemainingQuantity.Equal(o.RemainingQuantity) {
+					continue
+				}
+				book.RemoveOrder(o.ID)
+			}
+			if open {
+				book.AddOrder(o)
+			}
+			if current != nil || open {
+				changed++
+			}
+		}
+		pairLock.Unlock()
+	}
+	return changed
+}
+
+// evictExpired removes expired orders from every book. Expiry changes no
+// row, so it never reaches the standby as a change.
+func (e *Engine) evictExpired() int {
+	now := e.Now()
+	evicted := 0
+	for _, book := range e.bookMgr.Books() {
+		pairLock := e.pairLock(book.baseToken, book.quoteToken)
+		pairLock.Lock()
+		for _, o := range append(book.GetBids(), book.GetAsks()...) {
+			if o.Expired(now) {
+				book.RemoveOrder(o.ID)
+				evicted++
+			}
+		}
+		pairLock.Unlock()
+	}
+	return evicted
+}
+
+// Standby reports whether the engine is a standby that doesn't match
+func (e *Engine) Standby() bool {
+	e.roleMu.RLock()
+	defer e.roleMu.RUnlock()
+	return e.standby
+}
+
+// LastReplicated returns when a standby last applied the primary's changes
+func (e *Engine) LastReplicated() time.Time {
+	e.roleMu.RLock()
+	defer e.roleMu.RUnlock()
+	return e.lastReplicated
+}
+
+// Promote turns a standby into the primary: replication stops after one
+// last pass, then the engine starts matching. Promote only once the old
+// primary is down; two engines on one database can't fill an order twice,
+// but they would race each other for every fill. If the last pass fails,
+// replication resumes and the engine stays a standby.
+func (e *Engine) Promote(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.Standby() {
+		return ErrNotStandby
+	}
+
+	close(e.replicaStop)
+	<-e.replicaDone
+
+	if err := e.replicateChanges(ctx); err != nil {
+		e.startReplication(e.runCtx)
+		return fmt.Errorf("failed to catch up before promotion: %w", err)
+	}
+	if err := e.startSequencer(ctx); err != nil {
+		e.startReplication(e.runCtx)
+		return err
+	}
+
+	e.roleMu.Lock()
+	e.standby = false
+	e.roleMu.Unlock()
+
+	e.markReconciled()
+	e.startMatching(e.runCtx, nil)
+
+	log.Warn().Msg("Standby promoted; matching engine is now the primary")
+	return nil
+}
@@ -0,0 +1,841 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+)
+
+// Store persists orders and the fills matching records: the books are
+// loaded from it, candidates read from it, and submissions, cancels and fills
+// written to it. PostgresStore is the default and MemoryStore keeps
+// everything in process; SetStore swaps in another backend. The background
+// sweeps (reaper, snapshots, reconciliation) still query Postgres directly.
+type Store interface {
+	MatchScope
+
+	// CreateOrders stores new orders, every one or none, setting each Seq
+	CreateOrders(ctx context.Context, orders []NewOrder) error
+
+	// CancelOrder cancels an open order, returning ErrOrderNotFound,
+	// ErrCancelTooLate or ErrOrderNotCancelable if it can't. An empty
+	// userAddress cancels the order whoever owns it.
+	CancelOrder(ctx context.Context, orderID, userAddress string) error
+
+	// ReplaceOrder cancels an open order of the user and stores its
+	// replacement, both or neither. It fails like CancelOrder.
+	ReplaceOrder(ctx context.Context, orderID, userAddress string, replacement NewOrder) error
+
+	// BeginClaim starts a Claim, for MatchSkipLocked
+	BeginClaim(ctx context.Context) (Claim, error)
+
+	// LoadOrders reads orders by id, in any status; ids with no order are
+	// simply absent from the result
+	LoadOrders(ctx context.Context, ids []string) ([]*Order, error)
+
+	// LoadActiveOrders reads the orders still open to matching at now, oldest
+	// first: those of one pair, or of every pair when baseToken and quoteToken
+	// are empty
+	LoadActiveOrders(ctx context.Context, now time.Time, baseToken, quoteToken string) ([]*Order, error)
+
+	// ActivePairs lists the pairs with orders open to matching at now
+	ActivePairs(ctx context.Context, now time.Time) ([]TokenPair, error)
+
+	// RepegOrder moves an open order's price and range; an order no longer
+	// open is left alone
+	RepegOrder(ctx context.Context, orderID string, price, minPrice, maxPrice decimal.Decimal) error
+
+	// CancelOrdersWhere cancels every open order the filter selects, waiting
+	// for fills in flight on them, and returns their ids
+	CancelOrdersWhere(ctx context.Context, f CancelFilter) ([]string, error)
+
+	// StaleMatches lists up to limit matches still awaiting settlement whose
+	// deadline passed before now, oldest deadline first
+	StaleMatches(ctx context.Context, now time.Time, limit int) ([]string, error)
+
+	// FailMatch marks a match awaiting settlement FAILED and gives its
+	// quantity back to both orders, returning the buy and sell order as
+	// restored. A match that settled meanwhile is left alone and reported as
+	// an error.
+	FailMatch(ctx context.Context, matchID, reason string) ([]*Order, error)
+
+	// LastMatchSeq returns the highest match sequence recorded, or 0
+	LastMatchSeq(ctx context.Context) (int64, error)
+
+	// LoadMatches reads up to limit matches passing filter with a sequence
+	// above afterSeq and at most throughSeq, in sequence order
+	LoadMatches(ctx context.Context, filter MatchFilter, afterSeq, throughSeq int64, limit int) ([]*Match, error)
+
+	// Ping checks the backend answers, for the probe that ends an outage
+	Ping(ctx context.Context) error
+}
+
+// TokenPair is a trading pair
+type TokenPair struct {
+	BaseToken  string
+	QuoteToken string
+}
+
+// MatchFilter narrows LoadMatches to a pair and to the matches of one
+// address; empty fields match anything
+type MatchFilter struct {
+	BaseToken   string
+	QuoteToken  string
+	UserAddress string
+}
+
+// MatchScope is what one MatchOrder reads candidates from and records fills
+// in: the Store, or with MatchSkipLocked a Claim on it
+type MatchScope interface {
+	// FindCandidates reads up to 100 open orders that can cross the order at
+	// now: the opposite side of its pair and pool, not on a paused chain, with
+	// a price range reaching its own after rounding. Best priced first, then
+	// earliest. The orders are pooled scratch copies; see releaseOrders.
+	FindCandidates(ctx context.Context, cfg *config.Config, order *Order, pausedChains []int32, now time.Time) ([]*Order, error)
+
+	// BeginFill starts recording one fill; nothing is visible until Commit
+	BeginFill(ctx context.Context) (FillTx, error)
+}
+
+// Claim holds the candidates found through it until it ends, and other claims
+// pass them over instead of waiting, so concurrent workers never match the
+// same resting order. Fills begun from it are part of it: nothing they record
+// is visible until Commit, and Rollback undoes them. Rollback after Commit is
+// a no-op.
+type Claim interface {
+	MatchScope
+
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// NewOrder is an order to store along with the on-chain commitment it was
+// submitted with
+type NewOrder struct {
+	Order      *Order
+	Commitment OrderCommitment
+}
+
+// OrderCommitment is the on-chain side of an order; matching never reads it
+type OrderCommitment struct {
+	Hash         string
+	OrderID      string
+	SellAmount   string
+	MinBuyAmount string
+}
+
+// FillTx records one fill: the match and both orders' new quantities, all or
+// nothing. Rollback after Commit is a no-op, so it can always be deferred.
+type FillTx interface {
+	// LockOrders holds both orders until the fill ends and returns
+	// ErrDuplicateMatch if either no longer has the remaining quantity and
+	// status we matched it with
+	LockOrders(ctx context.Context, buyOrder, sellOrder *Order) error
+
+	// CreateMatch stores the match, setting its ID and Seq
+	CreateMatch(ctx context.Context, match *Match) error
+
+	// UpdateOrderFill fills quantity more of the order
+	UpdateOrderFill(ctx context.Context, order *Order, quantity decimal.Decimal) error
+
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+// ErrDuplicateMatch means one of the orders changed in the store after we
+// read it, typically because another worker already recorded this fill
+var ErrDuplicateMatch = errors.New("duplicate match: order was filled or cancelled concurrently")
+
+// SetStore replaces the backend orders are loaded from and fills recorded in.
+// Call before Start.
+func (e *Engine) SetStore(store Store) {
+	e.store = store
+}
+
+// Store returns the backend set with SetStore, for submissions that write
+// orders before queueing them
+func (e *Engine) Store() Store {
+	return e.store
+}
+
+// PostgresStore is the Store backed by the orders and matches tables
+type PostgresStore struct {
+	db pgxDB
+}
+
+// pgxDB is a PostgresStore's handle: the pool, or the transaction of a claim,
+// in which case every Begin opens a savepoint
+type pgxDB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// NewPostgresStore returns a Store over the connection pool
+func NewPostgresStore(pool *pgxpool.Pool) *PostgresStore {
+	return &PostgresStore{db: pool}
+}
+
+// LoadOrders implements Store
+func (s *PostgresStore) LoadOrders(ctx context.Context, ids []string) ([]*Order, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT `+orderColumns+`
+		FROM orders
+		WHERE id = ANY($1::uuid[])
+	`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query orders: %w", err)
+	}
+	return scanOrders(rows, len(ids))
+}
+
+// LoadActiveOrders implements Store
+func (s *PostgresStore) LoadActiveOrders(ctx context.Context, now time.Time, baseToken, quoteToken string) ([]*Order, error) {
+	rows, err := s.db.Query(ctx, activeOrdersQuery+`
+		  AND ($2 = '' OR (base_token = $2 AND quote_token = $3))
+		ORDER BY created_at ASC
+	`, now, baseToken, quoteToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active orders: %w", err)
+	}
+	return scanOrders(rows, 0)
+}
+
+// ActivePairs implements Store
+func (s *PostgresStore) ActivePairs(ctx context.Context, now time.Time) ([]TokenPair, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT DISTINCT base_token, quote_token
+		FROM orders
+		WHERE status IN ('REVEALED', 'PARTIALLY_FILLED')
+		  AND (expires_at IS NULL OR expires_at > $1)
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query active pairs: %w", err)
+	}
+	defer rows.Close()
+
+	pairs := make([]TokenPair, 0)
+	for rows.Next() {
+		var p TokenPair
+		if err := rows.Scan(&p.BaseToken, &p.QuoteToken); err != nil {
+			return nil, fmt.Errorf("failed to scan pair: %w", err)
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// RepegOrder implements Store
+func (s *PostgresStore) RepegOrder(ctx context.Context, orderID string, price, minPrice, maxPrice decimal.Decimal) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE orders
+		SET price = $2, min_price = $3, max_price = $4
+		WHERE id = $1
+		  AND status IN ('REVEALED', 'PARTIALLY_FILLED')
+	`, orderID, price.String(), minPrice.String(), maxPrice.String())
+	if err != nil {
+		return fmt.Errorf("failed to re-peg order: %w", err)
+	}
+	return nil
+}
+
+// CancelOrdersWhere implements Store. The UPDATE waits on row locks held by
+// fills in flight, so it sees their results.
+func (s *PostgresStore) CancelOrdersWhere(ctx context.Context, f CancelFilter) ([]string, error) {
+	conds := []string{
+		"status IN ('REVEALED', 'PARTIALLY_FILLED')",
+		"LOWER(user_address) = LOWER($1)",
+	}
+	args := []any{f.UserAddress}
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.BaseToken != "" {
+		where("base_token = $%d", f.BaseToken)
+		where("quote_token = $%d", f.QuoteToken)
+	}
+	if f.Side != "" {
+		where("order_type = $%d", string(f.Side))
+	}
+	if !f.PriceBelow.IsZero() {
+		where("price < $%d", f.PriceBelow.String())
+	}
+	if !f.PriceAbove.IsZero() {
+		where("price > $%d", f.PriceAbove.String())
+	}
+	if !f.OlderThan.IsZero() {
+		where("created_at < $%d", f.OlderThan)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		UPDATE orders
+		SET status = 'CANCELLED'
+		WHERE `+strings.Join(conds, " AND ")+`
+		RETURNING id
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cancel orders: %w", err)
+	}
+	defer rows.Close()
+
+	orderIDs := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan cancelled order: %w", err)
+		}
+		orderIDs = append(orderIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to cancel orders: %w", err)
+	}
+	return orderIDs, nil
+}
+
+// StaleMatches implements Store
+func (s *PostgresStore) StaleMatches(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT id
+		FROM matches
+		WHERE settlement_status IN ('PENDING', 'SETTLING')
+		  AND settlement_deadline IS NOT NULL
+		  AND settlement_deadline < $1
+		ORDER BY settlement_deadline ASC
+		LIMIT $2
+	`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale matches: %w", err)
+	}
+	defer rows.Close()
+
+	matchIDs := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan stale match: %w", err)
+		}
+		matchIDs = append(matchIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query stale matches: %w", err)
+	}
+	return matchIDs, nil
+}
+
+// FailMatch implements Store
+func (s *PostgresStore) FailMatch(ctx context.Context, matchID, reason string) ([]*Order, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Re-check the status so a match that settled in the meantime is left alone
+	var buyOrderID, sellOrderID, quantityStr string
+	err = tx.QueryRow(ctx, `
+		UPDATE matches
+		SET settlement_status = 'FAILED',
+		    settlement_error = $2
+		WHERE id = $1
+		  AND settlement_status IN ('PENDING', 'SETTLING')
+		RETURNING buy_order_id, sell_order_id, quantity
+	`, matchID, reason).Scan(&buyOrderID, &sellOrderID, &quantityStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark match failed: %w", err)
+	}
+
+	// Lock both orders in id order, as LockOrders does, before touching
+	// either; updating buy then sell could deadlock against a fill
+	if _, err := tx.Exec(ctx, `
+		SELECT id
+		FROM orders
+		WHERE id = ANY($1::uuid[])
+		ORDER BY id
+		FOR UPDATE
+	`, []string{buyOrderID, sellOrderID}); err != nil {
+		return nil, fmt.Errorf("failed to lock orders: %w", err)
+	}
+
+	restored := make([]*Order, 0, 2)
+	for _, orderID := range []string{buyOrderID, sellOrderID} {
+		var o Order
+		row := tx.QueryRow(ctx, `
+			UPDATE orders
+			SET filled_quantity = filled_quantity - $2,
+			    remaining_quantity = remaining_quantity + $2,
+			    status = CASE
+			        WHEN status = 'CANCELLED' THEN status
+			        WHEN filled_quantity - $2 > 0 THEN 'PARTIALLY_FILLED'
+			        ELSE 'REVEALED'
+			    END
+			WHERE id = $1
+			RETURNING `+orderColumns, orderID, quantityStr)
+		if err := scanOrder(row, &o); err != nil {
+			return nil, fmt.Errorf("failed to restore order %s: %w", orderID, err)
+		}
+		restored = append(restored, &o)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return restored, nil
+}
+
+// LastMatchSeq implements Store
+func (s *PostgresStore) LastMatchSeq(ctx context.Context) (int64, error) {
+	var seq int64
+	if err := s.db.QueryRow(ctx, `SELECT COALESCE(MAX(seq), 0) FROM matches`).Scan(&seq); err != nil {
+		return 0, fmt.Errorf("failed to query last match sequence: %w", err)
+	}
+	return seq, nil
+}
+
+// Ping implements Store
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	if _, err := s.db.Exec(ctx, `SELECT 1`); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+	return nil
+}
+
+// LoadMatches implements Store
+func (s *PostgresStore) LoadMatches(ctx context.Context, filter MatchFilter, afterSeq, throughSeq int64, limit int) ([]*Match, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT m.id, m.seq, m.buy_order_id, m.sell_order_id, m.base_token, m.quote_token,
+		       m.quantity::text, m.price::text, m.settlement_status, m.matched_at,
+		       b.user_address, sl.user_address, b.metadata, sl.metadata,
+		       COALESCE(m.taker_side, ''), m.taker_fee::text, m.maker_rebate::text, m.rounded_price
+		FROM matches m
+		JOIN orders b ON b.id = m.buy_order_id
+		JOIN orders sl ON sl.id = m.sell_order_id
+		WHERE m.seq > $1 AND m.seq <= $2
+		  AND ($3 = '' OR m.base_token = $3)
+		  AND ($4 = '' OR m.quote_token = $4)
+		  AND ($5 = '' OR LOWER(b.user_address) = LOWER($5) OR LOWER(sl.user_address) = LOWER($5))
+		ORDER BY m.seq ASC
+		LIMIT $6
+	`, afterSeq, throughSeq, filter.BaseToken, filter.QuoteToken, filter.UserAddress, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query matches: %w", err)
+	}
+	defer rows.Close()
+
+	matches := make([]*Match, 0)
+	for rows.Next() {
+		var m Match
+		var quantityStr, priceStr, takerSide, takerFeeStr, makerRebateStr string
+
+		err := rows.Scan(
+			&m.ID, &m.Seq, &m.BuyOrderID, &m.SellOrderID, &m.BaseToken, &m.QuoteToken,
+			&quantityStr, &priceStr, &m.SettlementStatus, &m.MatchedAt,
+			&m.BuyerAddress, &m.SellerAddress, &m.BuyMetadata, &m.SellMetadata,
+			&takerSide, &takerFeeStr, &makerRebateStr, &m.RoundedPrice,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan match: %w", err)
+		}
+
+		m.Quantity, _ = decimal.NewFromString(quantityStr)
+		m.Price, _ = decimal.NewFromString(priceStr)
+		m.TakerSide = OrderType(takerSide)
+		m.TakerFee, _ = decimal.NewFromString(takerFeeStr)
+		m.MakerRebate, _ = decimal.NewFromString(makerRebateStr)
+		matches = append(matches, &m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read matches: %w", err)
+	}
+	return matches, nil
+}
+
+// FindCandidates implements MatchScope. Expiry is judged at now, the engine
+// clock's time, rather than by the database's clock.
+func (s *PostgresStore) FindCandidates(ctx context.Context, cfg *config.Config, order *Order, pausedChains []int32, now time.Time) ([]*Order, error) {
+	return s.findCandidates(ctx, cfg, order, pausedChains, now, false)
+}
+
+// findCandidates queries the database for potential matching orders. With
+// skipLocked the rows are locked for the caller's transaction and rows
+// another worker has locked are passed over.
+func (s *PostgresStore) findCandidates(ctx context.Context, cfg *config.Config, order *Order, pausedChains []int32, now time.Time, skipLocked bool) ([]*Order, error) {
+	var query string
+	var args []interface{}
+
+	// Time priority comes from the wall clock unless matching must be reproducible
+	timeOrder := "created_at"
+	if cfg.DeterministicMatching {
+		timeOrder = "seq"
+	}
+
+	// A nil slice binds as NULL, which would exclude every candidate
+	if pausedChains == nil {
+		pausedChains = []int32{}
+	}
+
+	if order.OrderType == OrderTypeBuy {
+		// Find SELL orders where sell.min_price <= buy.max_price
+		query = `
+			SELECT ` + orderColumns + `
+			FROM orders
+			WHERE base_token = $1
+			  AND quote_token = $2
+			  AND pool_id = $4
+			  AND order_type = 'SELL'
+			  AND status IN ('REVEALED', 'PARTIALLY_FILLED')
+			  AND min_price <= $3
+			  AND chain_id <> ALL($5)
+			  AND (expires_at IS NULL OR expires_at > $6)
+			  AND (visible_at IS NULL OR visible_at <= $6)
+			ORDER BY min_price ASC, ` + timeOrder + ` ASC
+			LIMIT 100
+		`
+		args = []interface{}{order.BaseToken, order.QuoteToken, candidatePriceLimit(cfg, order).String(), order.PoolID, pausedChains, now}
+	} else {
+		// Find BUY orders where buy.max_price >= sell.min_price
+		query = `
+			SELECT ` + orderColumns + `
+			FROM orders
+			WHERE base_token = $1
+			  AND quote_token = $2
+			  AND pool_id = $4
+			  AND order_type = 'BUY'
+			  AND status IN ('REVEALED', 'PARTIALLY_FILLED')
+			  AND max_price >= $3
+			  AND chain_id <> ALL($5)
+			  AND (expires_at IS NULL OR expires_at > $6)
+			  AND (visible_at IS NULL OR visible_at <= $6)
+			ORDER BY max_price DESC, ` + timeOrder + ` ASC
+			LIMIT 100
+		`
+		args = []interface{}{order.BaseToken, order.QuoteToken, candidatePriceLimit(cfg, order).String(), order.PoolID, pausedChains, now}
+	}
+
+	if skipLocked {
+		query += " FOR UPDATE SKIP LOCKED"
+	}
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query candidates: %w", err)
+	}
+	defer rows.Close()
+
+	// Candidates are scratch copies of DB rows that never enter the book,
+	// so they come from the pool and are handed back by MatchOrder
+	candidates := make([]*Order, 0)
+	for rows.Next() {
+		o := acquireOrder()
+		if err := scanOrder(rows, o); err != nil {
+			releaseOrder(o)
+			releaseOrders(candidates)
+			return nil, fmt.Errorf("failed to scan candidate: %w", err)
+		}
+
+		candidates = append(candidates, o)
+	}
+
+	return candidates, nil
+}
+
+// candidatePriceLimit is the furthest a candidate's range may start from the
+// order's and still cross it once both are rounded: the highest sell min
+// price a buy reaches, or the lowest buy max price a sell reaches
+func candidatePriceLimit(cfg *config.Config, order *Order) decimal.Decimal {
+	if order.OrderType == OrderTypeBuy {
+		return significantPriceBound(roundSignificant(order.MaxPrice, cfg.PriceSignificantDigits), cfg.PriceSignificantDigits, true)
+	}
+	return significantPriceBound(roundSignificant(order.MinPrice, cfg.PriceSignificantDigits), cfg.PriceSignificantDigits, false)
+}
+
+// BeginClaim implements Store with a transaction: candidates are locked FOR
+// UPDATE SKIP LOCKED and each fill is a savepoint in it
+func (s *PostgresStore) BeginClaim(ctx context.Context) (Claim, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return postgresClaim{store: &PostgresStore{db: tx}, tx: tx}, nil
+}
+
+// postgresClaim is a Claim in one database transaction
+type postgresClaim struct {
+	store *PostgresStore
+	tx    pgx.Tx
+}
+
+// FindCandidates implements MatchScope
+func (c postgresClaim) FindCandidates(ctx context.Context, cfg *config.Config, order *Order, pausedChains []int32, now time.Time) ([]*Order, error) {
+	return c.store.findCandidates(ctx, cfg, order, pausedChains, now, true)
+}
+
+// BeginFill implements MatchScope
+func (c postgresClaim) BeginFill(ctx context.Context) (FillTx, error) {
+	return c.store.BeginFill(ctx)
+}
+
+// Commit implements Claim
+func (c postgresClaim) Commit(ctx context.Context) error {
+	return c.tx.Commit(ctx)
+}
+
+// Rollback implements Claim
+func (c postgresClaim) Rollback(ctx context.Context) error {
+	return c.tx.Rollback(ctx)
+}
+
+// CreateOrders implements Store
+func (s *PostgresStore) CreateOrders(ctx context.Context, orders []NewOrder) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	for _, o := range orders {
+		if err := insertOrder(ctx, tx, o); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// CancelOrder implements Store. The order row is locked first, so the cancel
+// serializes with any fill in flight on the same order.
+func (s *PostgresStore) CancelOrder(ctx context.Context, orderID, userAddress string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := cancelOrder(ctx, tx, orderID, userAddress); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit cancel: %w", err)
+	}
+	return nil
+}
+
+// ReplaceOrder implements Store
+func (s *PostgresStore) ReplaceOrder(ctx context.Context, orderID, userAddress string, replacement NewOrder) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := cancelOrder(ctx, tx, orderID, userAddress); err != nil {
+		return err
+	}
+	if err := insertOrder(ctx, tx, replacement); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit replace: %w", err)
+	}
+	return nil
+}
+
+// cancelOrder marks the order cancelled within tx if it is still matchable
+func cancelOrder(ctx context.Context, tx pgx.Tx, orderID, userAddress string) error {
+	// Blocks until any fill holding this row commits, then sees its result
+	var status string
+	err := tx.QueryRow(ctx, `
+		SELECT status
+		FROM orders
+		WHERE id = $1
+		  AND ($2 = '' OR LOWER(user_address) = LOWER($2))
+		FOR UPDATE
+	`, orderID, userAddress).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrOrderNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock order: %w", err)
+	}
+
+	switch OrderStatus(status) {
+	case OrderStatusRevealed, OrderStatusPartiallyFilled:
+	case OrderStatusFilled:
+		return ErrCancelTooLate
+	default:
+		return ErrOrderNotCancelable
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE orders SET status = 'CANCELLED' WHERE id = $1`, orderID); err != nil {
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	return nil
+}
+
+// insertOrder stores a freshly built order along with its on-chain commitment
+// fields, and records the sequence the database assigned it
+func insertOrder(ctx context.Context, tx pgx.Tx, o NewOrder) error {
+	order := o.Order
+	err := tx.QueryRow(ctx, `
+		INSERT INTO orders (
+			id, user_address, chain_id, order_type, base_token, quote_token,
+			quantity, price, variance_bps, min_price, max_price,
+			filled_quantity, remaining_quantity, status,
+			commitment_hash, order_id, sell_amount, min_buy_amount, expires_at,
+			counterparty_allowlist, pool_id, remainder_policy, no_fill_deadline, metadata, visible_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, COALESCE($24, '{}'::jsonb), $25)
+		RETURNING seq
+	`,
+		order.ID, order.UserAddress, order.ChainID, string(order.OrderType),
+		order.BaseToken, order.QuoteToken,
+		order.Quantity.String(), order.Price.String(), order.VarianceBPS, order.MinPrice.String(), order.MaxPrice.String(),
+		"0", order.Quantity.String(), "REVEALED",
+		o.Commitment.Hash, o.Commitment.OrderID, o.Commitment.SellAmount, o.Commitment.MinBuyAmount, nullTimeOrValue(order.ExpiresAt),
+		order.CounterpartyAllowlist, order.PoolID, string(order.RemainderPolicy),
+		nullTimeOrValue(order.NoFillDeadline), metadataOrNull(order.Metadata), nullTimeOrValue(order.VisibleAt),
+	).Scan(&order.Seq)
+	if err != nil {
+		return fmt.Errorf("failed to insert order %s: %w", order.ID, err)
+	}
+	return nil
+}
+
+// metadataOrNull passes empty metadata as NULL so the column default applies
+func metadataOrNull(metadata map[string]string) interface{} {
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+// scanOrders reads every row selected with orderColumns and closes rows. Not
+// pooled: loaded orders go into the books.
+func scanOrders(rows pgx.Rows, sizeHint int) ([]*Order, error) {
+	defer rows.Close()
+
+	orders := make([]*Order, 0, sizeHint)
+	for rows.Next() {
+		var o Order
+		if err := scanOrder(rows, &o); err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, &o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read orders: %w", err)
+	}
+	return orders, nil
+}
+
+// BeginFill implements Store. Over a transaction, e.g. the one holding
+// MatchSkipLocked's claimed candidates, the fill is a savepoint in it.
+func (s *PostgresStore) BeginFill(ctx context.Context) (FillTx, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return postgresFill{tx}, nil
+}
+
+// postgresFill is a FillTx in one database transaction
+type postgresFill struct {
+	pgx.Tx
+}
+
+// LockOrders implements FillTx by row-locking both orders. A worker racing for
+// the same fill blocks here until the first commits, then sees the new
+// quantities and gets ErrDuplicateMatch instead of recording the fill twice.
+func (f postgresFill) LockOrders(ctx context.Context, buyOrder, sellOrder *Order) error {
+	expected := map[string]*Order{
+		buyOrder.ID:  buyOrder,
+		sellOrder.ID: sellOrder,
+	}
+
+	// Lock in id order so two workers never wait on each other's rows
+	rows, err := f.Query(ctx, `
+		SELECT id, remaining_quantity, status
+		FROM orders
+		WHERE id = ANY($1::uuid[])
+		ORDER BY id
+		FOR UPDATE
+	`, []string{buyOrder.ID, sellOrder.ID})
+	if err != nil {
+		return fmt.Errorf("failed to lock orders: %w", err)
+	}
+	defer rows.Close()
+
+	locked := 0
+	for rows.Next() {
+		var id, remainingStr, status string
+		if err := rows.Scan(&id, &remainingStr, &status); err != nil {
+			return fmt.Errorf("failed to scan locked order: %w", err)
+		}
+
+		remaining, _ := decimal.NewFromString(remainingStr)
+		order := expected[id]
+		if order == nil || !remaining.Equal(order.RemainingQuantity) ||
+			(status != string(OrderStatusRevealed) && status != string(OrderStatusPartiallyFilled)) {
+			return ErrDuplicateMatch
+		}
+		locked++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to lock orders: %w", err)
+	}
+
+	if locked != len(expected) {
+		return ErrDuplicateMatch
+	}
+	return nil
+}
+
+// CreateMatch implements FillTx. The match keeps the sequence the engine
+// reserved for it; only one created outside an engine takes the column's.
+func (f postgresFill) CreateMatch(ctx context.Context, m *Match) error {
+	var seq any
+	if m.Seq != 0 {
+		seq = m.Seq
+	}
+	return f.QueryRow(ctx, `
+		INSERT INTO matches (buy_order_id, sell_order_id, base_token, quote_token, quantity, price, settlement_status, settlement_deadline,
+		                     taker_side, taker_fee, maker_rebate, rounded_price, seq)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12,
+		        COALESCE($13::bigint, nextval(pg_get_serial_sequence('matches', 'seq'))))
+		RETURNING id, seq
+	`, m.BuyOrderID, m.SellOrderID, m.BaseToken, m.QuoteToken, m.Quantity.String(), m.Price.String(), m.SettlementStatus,
+		nullTimeOrValue(m.SettlementDeadline), string(m.TakerSide), m.TakerFee.String(), m.MakerRebate.String(), m.RoundedPrice, seq).Scan(&m.ID, &m.Seq)
+}
+
+// UpdateOrderFill implements FillTx
+func (f postgresFill) UpdateOrderFill(ctx context.Context, order *Order, quantity decimal.Decimal) error {
+	newFilled := order.FilledQuantity.Add(quantity)
+	newRemaining := order.RemainingQuantity.Sub(quantity)
+
+	var newStatus OrderStatus
+	if newRemaining.IsZero() {
+		newStatus = OrderStatusFilled
+	} else {
+		newStatus = OrderStatusPartiallyFilled
+	}
+
+	_, err := f.Exec(ctx, `
+		UPDATE orders
+		SET filled_quantity = $1,
+		    remaining_quantity = $2,
+		    status = $3
+		WHERE id = $4
+	`, newFilled.String(), newRemaining.String(), newStatus, order.ID)
+
+	return err
+}
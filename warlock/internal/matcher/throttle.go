@@ -0,0 +1,61 @@
+package matcher
+
+import (
+	"sync"
+	"time"
+)
+
+// matchThrottle caps how many matches each pair produces per second, so one
+// hot pair can't keep every worker busy. Each pair has a token bucket that
+// refills at the pair's rate and holds at most one second's worth; matching
+// needs a whole token and then spends one per match, so a burst may leave
+// the bucket in debt that later orders wait out.
+type matchThrottle struct {
+	mu      sync.Mutex
+	buckets map[string]*matchBucket // pair key -> bucket
+}
+
+type matchBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newMatchThrottle() *matchThrottle {
+	return &matchThrottle{buckets: make(map[string]*matchBucket)}
+}
+
+// refill returns the pair's bucket topped up to now
+func (t *matchThrottle) refill(pair string, rate int, now time.Time) *matchBucket {
+	bucket, ok := t.buckets[pair]
+	if !ok {
+		bucket = &matchBucket{tokens: float64(rate), updated: now}
+		t.buckets[pair] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.updated).Seconds() * float64(rate)
+	if bucket.tokens > float64(rate) {
+		bucket.tokens = float64(rate)
+	}
+	bucket.updated = now
+	return bucket
+}
+
+// wait reports how long the pair must wait before it may match again; zero
+// means now
+func (t *matchThrottle) wait(pair string, rate int, now time.Time) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	bucket := t.refill(pair, rate, now)
+	if bucket.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - bucket.tokens) / float64(rate) * float64(time.Second))
+}
+
+// spend takes one token per match produced
+func (t *matchThrottle) spend(pair string, rate, matches int, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.refill(pair, rate, now).tokens -= float64(matches)
+}
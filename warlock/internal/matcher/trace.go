@@ -0,0 +1,126 @@
+package matcher
+
+import (
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TraceOutcome says what happened to one candidate in a match trace
+type TraceOutcome string
+
+const (
+	TraceMatched                TraceOutcome = "MATCHED"
+	TraceCounterpartyNotAllowed TraceOutcome = "COUNTERPARTY_NOT_ALLOWED"
+	TraceSelfTrade              TraceOutcome = "SELF_TRADE"
+	TracePriceIncompatible      TraceOutcome = "PRICE_INCOMPATIBLE"
+	TraceOutsideVWAP            TraceOutcome = "OUTSIDE_VWAP"
+	TraceBelowMinMatchSize      TraceOutcome = "BELOW_MIN_MATCH_SIZE"
+	TraceBelowMinSettlement     TraceOutcome = "BELOW_MIN_SETTLEMENT_NOTIONAL"
+	TraceBelowMinMakerFill      TraceOutcome = "BELOW_MIN_MAKER_FILL"
+	TraceDuplicateMatch         TraceOutcome = "DUPLICATE_MATCH"
+	TraceExecutionFailed        TraceOutcome = "EXECUTION_FAILED"
+	TraceNotReached             TraceOutcome = "NOT_REACHED" // Incoming order filled first
+)
+
+// maxStoredTraces bounds the traces kept for GetMatchTrace; the oldest are dropped first
+const maxStoredTraces = 1000
+
+// MatchTrace records every candidate considered for one incoming order and why
+// it was or wasn't matched
+type MatchTrace struct {
+	OrderID    string
+	TracedAt   time.Time
+	Candidates []CandidateTrace
+}
+
+// CandidateTrace is one candidate's entry in a match trace
+type CandidateTrace struct {
+	OrderID           string
+	UserAddress       string
+	MinPrice          decimal.Decimal
+	MaxPrice          decimal.Decimal
+	RemainingQuantity decimal.Decimal
+	Outcome           TraceOutcome
+	Detail            string
+	MatchID           string // Set when Outcome is TraceMatched
+}
+
+// record adds a candidate's outcome. Safe on a nil trace, which is how
+// untraced orders skip recording.
+func (t *MatchTrace) record(candidate *Order, outcome TraceOutcome, detail string) {
+	if t == nil {
+		return
+	}
+	t.Candidates = append(t.Candidates, CandidateTrace{
+		OrderID:           candidate.ID,
+		UserAddress:       candidate.UserAddress,
+		MinPrice:          candidate.MinPrice,
+		MaxPrice:          candidate.MaxPrice,
+		RemainingQuantity: candidate.RemainingQuantity,
+		Outcome:           outcome,
+		Detail:            detail,
+	})
+}
+
+// recordMatch adds a candidate that was matched
+func (t *MatchTrace) recordMatch(candidate *Order, match *Match) {
+	if t == nil {
+		return
+	}
+	detail := "quantity " + match.Quantity.String() + " @ " + match.Price.String()
+	if match.RoundedPrice {
+		detail += " (rounded common price)"
+	}
+	t.record(candidate, TraceMatched, detail)
+	t.Candidates[len(t.Candidates)-1].MatchID = match.ID
+}
+
+// recordUnreached marks candidates with no outcome yet as never reached
+func (t *MatchTrace) recordUnreached(candidates []*Order) {
+	if t == nil {
+		return
+	}
+	seen := make(map[string]bool, len(t.Candidates))
+	for _, c := range t.Candidates {
+		seen[c.OrderID] = true
+	}
+	for _, candidate := range candidates {
+		if !seen[candidate.ID] {
+			t.record(candidate, TraceNotReached, "")
+		}
+	}
+}
+
+// traceStore keeps recent match traces by incoming order id
+type traceStore struct {
+	mu     sync.Mutex
+	traces map[string]*MatchTrace
+	order  []string
+}
+
+func newTraceStore() *traceStore {
+	return &traceStore{traces: make(map[string]*MatchTrace)}
+}
+
+func (s *traceStore) put(trace *MatchTrace) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.traces[trace.OrderID]; !exists {
+		s.order = append(s.order, trace.OrderID)
+	}
+	s.traces[trace.OrderID] = trace
+
+	for len(s.order) > maxStoredTraces {
+		delete(s.traces, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func (s *traceStore) get(orderID string) *MatchTrace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.traces[orderID]
+}
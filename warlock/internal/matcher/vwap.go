@@ -0,0 +1,237 @@
+package matcher
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// vwapLeg is one maker's share of a VWAP execution
+type vwapLeg struct {
+	candidate *Order
+	quantity  decimal.Decimal
+	price     decimal.Decimal // What this leg would execute at on its own
+}
+
+// matchAtVWAP fills the incoming order against every eligible candidate at a
+// single price: the average of each leg's own execution price, weighted by the
+// quantity that maker contributes. Each leg is still executed and settled as
+// its own match. The error is the last leg that failed to execute, if any.
+func matchAtVWAP(ctx context.Context, store MatchScope, cfg *config.Config, book *OrderBook, stats *EngineStats, now time.Time, incomingOrder *Order, candidates []*Order) ([]*Match, error) {
+	legs, price := planBlendedVWAP(cfg, incomingOrder, candidates)
+	legs = dropUnsettleableLegs(cfg, incomingOrder, legs, price)
+	if len(legs) == 0 {
+		return nil, nil
+	}
+
+	log.Info().
+		Str("order_id", incomingOrder.ID).
+		Int("legs", len(legs)).
+		Str("vwap", price.String()).
+		Msg("Executing at VWAP")
+
+	matches := make([]*Match, 0, len(legs))
+	var execErr error
+	for _, leg := range legs {
+		match, err := executeMatch(ctx, store, cfg, book, stats, now, incomingOrder, leg.candidate, leg.quantity, price)
+		if errors.Is(err, ErrDuplicateMatch) {
+			log.Warn().
+				Str("incoming_order_id", incomingOrder.ID).
+				Str("candidate_order_id", leg.candidate.ID).
+				Msg("Skipping duplicate match")
+			incomingOrder.trace.record(leg.candidate, TraceDuplicateMatch, "")
+			continue
+		}
+		if err != nil {
+			log.Error().Err(err).
+				Str("incoming_order_id", incomingOrder.ID).
+				Str("candidate_order_id", leg.candidate.ID).
+				Msg("Failed to execute match")
+			incomingOrder.trace.record(leg.candidate, TraceExecutionFailed, err.Error())
+			execErr = err
+			continue
+		}
+
+		matches = append(matches, match)
+		incomingOrder.trace.recordMatch(leg.candidate, match)
+
+		log.Info().
+			Str("match_id", match.ID).
+			Str("buy_order_id", match.BuyOrderID).
+			Str("sell_order_id", match.SellOrderID).
+			Str("quantity", leg.quantity.String()).
+			Str("price", price.String()).
+			Msg("Match executed")
+	}
+
+	return matches, execErr
+}
+
+// planVWAPLegs walks candidates in priority order, allotting quantity to each
+// eligible one until the incoming order would be fully filled. A maker's share
+// above the pair's MaxMatchSize becomes several legs.
+func planVWAPLegs(cfg *config.Config, incomingOrder *Order, candidates []*Order) []vwapLeg {
+	bounds := cfg.BoundsFor(incomingOrder.BaseToken, incomingOrder.QuoteToken)
+	remaining := incomingOrder.RemainingQuantity
+	legs := make([]vwapLeg, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		if remaining.IsZero() {
+			break
+		}
+
+		if !isCandidateEligible(cfg, incomingOrder, candidate) {
+			continue
+		}
+
+		crossQty := decimal.Min(remaining, candidate.RemainingQuantity)
+		fills := splitFill(crossQty, bounds)
+		if len(fills) == 0 {
+			incomingOrder.trace.record(candidate, TraceBelowMinMatchSize, "quantity "+crossQty.String())
+			continue
+		}
+
+		taker, maker := liquidityRoles(cfg, incomingOrder, candidate)
+		price := calculateExecutionPrice(taker, maker, cfg.PriceTieBreak, cfg.PriceSignificantDigits)
+		fills = settleableFills(fills, price, bounds)
+		if len(fills) == 0 {
+			incomingOrder.trace.record(candidate, TraceBelowMinSettlement, "notional "+crossQty.Mul(price).String())
+			continue
+		}
+		if belowMakerFill(fills, maker, bounds) {
+			incomingOrder.trace.record(candidate, TraceBelowMinMakerFill, "quantity "+sumFills(fills).String())
+			continue
+		}
+		for _, qty := range fills {
+			legs = append(legs, vwapLeg{
+				candidate: candidate,
+				quantity:  qty,
+				price:     price,
+			})
+			remaining = remaining.Sub(qty)
+		}
+	}
+
+	return legs
+}
+
+// blendVWAP computes the quantity-weighted price of the legs. The blend always
+// sits inside the taker's range, but can fall outside an individual maker's,
+// so such legs are dropped and the price recomputed until every leg accepts it.
+func blendVWAP(legs []vwapLeg) ([]vwapLeg, decimal.Decimal) {
+	for len(legs) > 0 {
+		price := vwapPrice(legs)
+
+		kept := make([]vwapLeg, 0, len(legs))
+		for _, leg := range legs {
+			if acceptsPrice(leg.candidate, price) {
+				kept = append(kept, leg)
+			}
+		}
+
+		if len(kept) == len(legs) {
+			return legs, price
+		}
+		legs = kept
+	}
+
+	return nil, decimal.Zero
+}
+
+// planBlendedVWAP plans legs and blends their price. Makers the blend falls
+// outside are left out and the order is planned again without them, so the
+// quantity they were allotted goes to candidates further down instead of
+// going unfilled. Each pass leaves out at least one more maker, so this ends;
+// a maker left out stays out even if a later blend would suit it. Only the
+// final plan's skipped candidates are traced, after the makers left out.
+func planBlendedVWAP(cfg *config.Config, incomingOrder *Order, candidates []*Order) ([]vwapLeg, decimal.Decimal) {
+	trace := incomingOrder.trace
+	defer func() { incomingOrder.trace = trace }()
+
+	excluded := make(map[string]bool)
+	for {
+		eligible := make([]*Order, 0, len(candidates))
+		for _, candidate := range candidates {
+			if !excluded[candidate.ID] {
+				eligible = append(eligible, candidate)
+			}
+		}
+
+		incomingOrder.trace = nil
+		planned := planVWAPLegs(cfg, incomingOrder, eligible)
+		legs, price := blendVWAP(planned)
+		incomingOrder.trace = trace
+
+		if len(legs) == len(planned) {
+			if trace != nil {
+				// Planning again is deterministic; this time it is recorded
+				planVWAPLegs(cfg, incomingOrder, eligible)
+			}
+			return legs, price
+		}
+
+		if len(legs) == 0 {
+			// Every maker was left out; report the blend they started from
+			price = vwapPrice(planned)
+		}
+		kept := make(map[string]bool, len(legs))
+		for _, leg := range legs {
+			kept[leg.candidate.ID] = true
+		}
+		for _, leg := range planned {
+			// A split maker has several legs but is only left out once
+			if !kept[leg.candidate.ID] && !excluded[leg.candidate.ID] {
+				excluded[leg.candidate.ID] = true
+				incomingOrder.trace.record(leg.candidate, TraceOutsideVWAP, "vwap "+price.String())
+			}
+		}
+	}
+}
+
+// dropUnsettleableLegs removes legs whose notional at the blended price falls
+// below the pair's MinSettlementNotional. Planning checked each leg at its own
+// price, so only legs the blend moved against are affected; the rest still
+// execute at the blended price. Dropped legs aren't re-planned, since that
+// would move the price again: their quantity is left unfilled, to rest or be
+// handled by the order's remainder policy.
+func dropUnsettleableLegs(cfg *config.Config, incomingOrder *Order, legs []vwapLeg, price decimal.Decimal) []vwapLeg {
+	minNotional := cfg.BoundsFor(incomingOrder.BaseToken, incomingOrder.QuoteToken).MinSettlementNotional
+	if !minNotional.IsPositive() {
+		return legs
+	}
+	kept := make([]vwapLeg, 0, len(legs))
+	for _, leg := range legs {
+		notional := leg.quantity.Mul(price)
+		if notional.LessThan(minNotional) {
+			incomingOrder.trace.record(leg.candidate, TraceBelowMinSettlement, "notional "+notional.String())
+			continue
+		}
+		kept = append(kept, leg)
+	}
+	return kept
+}
+
+// vwapPrice returns sum(quantity * price) / sum(quantity) over the legs
+func vwapPrice(legs []vwapLeg) decimal.Decimal {
+	notional := decimal.Zero
+	quantity := decimal.Zero
+	for _, leg := range legs {
+		notional = notional.Add(leg.quantity.Mul(leg.price))
+		quantity = quantity.Add(leg.quantity)
+	}
+
+	return notional.Div(quantity)
+}
+
+// acceptsPrice reports whether an order would trade at price: buyers up to
+// their max price, sellers down to their min price
+func acceptsPrice(order *Order, price decimal.Decimal) bool {
+	if order.OrderType == OrderTypeBuy {
+		return price.LessThanOrEqual(order.MaxPrice)
+	}
+	return price.GreaterThanOrEqual(order.MinPrice)
+}
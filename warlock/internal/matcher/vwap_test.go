@@ -0,0 +1,105 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestVWAPPrice(t *testing.T) {
+	type leg struct{ quantity, price string }
+	tests := []struct {
+		name string
+		legs []leg
+		want string
+	}{
+		{name: "single leg", legs: []leg{{"3", "101"}}, want: "101"},
+		{name: "equal weights", legs: []leg{{"1", "100"}, {"1", "104"}}, want: "102"},
+		{name: "weighted by quantity", legs: []leg{{"2", "100"}, {"3", "105"}}, want: "103"},
+		{name: "fractional", legs: []leg{{"0.5", "2000"}, {"1.5", "2004"}}, want: "2003"},
+	}
+	for _, tt := range tests {
+		legs := make([]vwapLeg, 0, len(tt.legs))
+		for _, l := range tt.legs {
+			legs = append(legs, vwapLeg{quantity: decimal.RequireFromString(l.quantity), price: decimal.RequireFromString(l.price)})
+		}
+		if got := vwapPrice(legs); !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("%s: vwapPrice = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBlendVWAPDropsMakersOutsideTheBlend(t *testing.T) {
+	cheap := testOrder("0xcheap", OrderTypeSell, "2", "100", 0)
+	dear := testOrder("0xdear", OrderTypeSell, "2", "118", 0)
+	legs := []vwapLeg{
+		{candidate: cheap, quantity: decimal.NewFromInt(2), price: decimal.NewFromInt(105)},
+		{candidate: dear, quantity: decimal.NewFromInt(2), price: decimal.NewFromInt(118)},
+	}
+
+	// The blend of 111.5 is below the dear maker's 118; without it, 105
+	kept, price := blendVWAP(legs)
+	if len(kept) != 1 || kept[0].candidate != cheap || !price.Equal(decimal.NewFromInt(105)) {
+		t.Errorf("blendVWAP kept %d legs at %s, want only the cheap maker at 105", len(kept), price)
+	}
+}
+
+func TestVWAPReplansAfterDroppingMakers(t *testing.T) {
+	cfg := testConfig(t)
+
+	// The taker accepts up to 121. At the default SPLIT tie-break each leg's
+	// own price is the midpoint of the limits, raised to the maker's minimum:
+	// 105, 118 and 106.
+	buy := testOrder("0xbuyer", OrderTypeBuy, "4", "110", 1000)
+	buy.trace = &MatchTrace{OrderID: buy.ID}
+	cheap := testOrder("0xcheap", OrderTypeSell, "2", "100", 0)
+	dear := testOrder("0xdear", OrderTypeSell, "2", "118", 0)
+	next := testOrder("0xnext", OrderTypeSell, "5", "102", 0)
+
+	// The first plan fills the taker from cheap and dear, blending to 111.5,
+	// below dear's 118. Dear's 2 go to next rather than going unfilled.
+	legs, price := planBlendedVWAP(cfg, buy, []*Order{cheap, dear, next})
+
+	filled := decimal.Zero
+	byMaker := make(map[string]decimal.Decimal)
+	for _, leg := range legs {
+		filled = filled.Add(leg.quantity)
+		byMaker[leg.candidate.UserAddress] = byMaker[leg.candidate.UserAddress].Add(leg.quantity)
+	}
+	if !filled.Equal(decimal.NewFromInt(4)) {
+		t.Errorf("planned %s, want the taker's full 4", filled)
+	}
+	if !byMaker["0xcheap"].Equal(decimal.NewFromInt(2)) || !byMaker["0xnext"].Equal(decimal.NewFromInt(2)) || byMaker["0xdear"].IsPositive() {
+		t.Errorf("planned %v, want 2 from cheap and 2 from next", byMaker)
+	}
+	// (2 × 105 + 2 × 106) / 4
+	if !price.Equal(decimal.RequireFromString("105.5")) {
+		t.Errorf("blended price %s, want 105.5", price)
+	}
+	for _, leg := range legs {
+		if !acceptsPrice(leg.candidate, price) {
+			t.Errorf("maker %s doesn't accept the blend %s", leg.candidate.UserAddress, price)
+		}
+	}
+
+	if n := len(buy.trace.Candidates); n != 1 {
+		t.Fatalf("trace has %d entries, want only dear's", n)
+	}
+	if got := buy.trace.Candidates[0]; got.OrderID != dear.ID || got.Outcome != TraceOutsideVWAP {
+		t.Errorf("trace entry %s %s, want dear OUTSIDE_VWAP", got.UserAddress, got.Outcome)
+	}
+}
+
+func TestVWAPUnderfillsWithoutFurtherCandidates(t *testing.T) {
+	cfg := testConfig(t)
+	buy := testOrder("0xbuyer", OrderTypeBuy, "4", "110", 1000)
+	// Legs at 109.5 and 118 blend to 113.75, below dear's 118. With nothing
+	// left to re-plan against, only cheap's 2 fill, at its own 109.5.
+	cheap := testOrder("0xcheap", OrderTypeSell, "2", "109", 0)
+	dear := testOrder("0xdear", OrderTypeSell, "2", "118", 0)
+
+	legs, price := planBlendedVWAP(cfg, buy, []*Order{cheap, dear})
+	if len(legs) != 1 || legs[0].candidate != cheap || !price.Equal(decimal.RequireFromString("109.5")) {
+		t.Errorf("planned %d legs at %s, want cheap alone at 109.5", len(legs), price)
+	}
+}
@@ -0,0 +1,684 @@
+package matcher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+)
+
+// walSyncInterval is how often appended WAL records are flushed to disk.
+// Every record is written as it is appended, so a crash of the process loses
+// none; a crash of the machine loses at most this much.
+const walSyncInterval = 100 * time.Millisecond
+
+// Files in BookWALDir: the checkpoint, and the log segments, each named after
+// the sequence of its first record
+const (
+	walCheckpointName = "books.checkpoint"
+	walSegmentPrefix  = "books-"
+	walSegmentSuffix  = ".wal"
+)
+
+// Book mutations recorded in the WAL
+const (
+	walAdd    = "add"    // An order rests, replacing any entry with its ID
+	walRemove = "remove" // An order leaves the book
+	walFill   = "fill"   // Orders take new fill state; those no longer active leave
+	walLoad   = "load"   // The book is installed holding exactly these orders
+	walDrop   = "drop"   // The book is uninstalled
+)
+
+// walRecord is one mutation of one book
+type walRecord struct {
+	Seq        uint64         `json:"seq"`
+	At         time.Time      `json:"at"`
+	Op         string         `json:"op"`
+	PoolID     string         `json:"pool_id,omitempty"`
+	BaseToken  string         `json:"base_token"`
+	QuoteToken string         `json:"quote_token"`
+	Order      *Order         `json:"order,omitempty"`    // add
+	OrderID    string         `json:"order_id,omitempty"` // remove
+	Fills      []walFillState `json:"fills,omitempty"`    // fill
+	Orders     []*Order       `json:"orders,omitempty"`   // load
+}
+
+// walFillState is an order's fill state after a fill
+type walFillState struct {
+	OrderID   string          `json:"order_id"`
+	Filled    decimal.Decimal `json:"filled"`
+	Remaining decimal.Decimal `json:"remaining"`
+	Status    OrderStatus     `json:"status"`
+}
+
+// walCheckpoint is every installed book as of a point in the WAL
+type walCheckpoint struct {
+	Seq           uint64         `json:"seq"` // Last record before the books were read
+	At            time.Time      `json:"at"`
+	ReplicaCursor uint64         `json:"replica_cursor,omitempty"` // Replication horizon before the books were read; zero without a database
+	Books         []walBookState `json:"books"`
+}
+
+// walBookState is one book in a checkpoint: its resting orders once every
+// record up to Seq is applied
+type walBookState struct {
+	PoolID     string   `json:"pool_id,omitempty"`
+	BaseToken  string   `json:"base_token"`
+	QuoteToken string   `json:"quote_token"`
+	Seq        uint64   `json:"seq"`
+	Orders     []*Order `json:"orders"`
+}
+
+// bookWAL is a write-ahead log of book mutations in BookWALDir. Installed
+// books append a record for every order added, removed or filled while they
+// hold their lock, so each book's records are in the order its changes were
+// made. A checkpoint writes every book out and starts a new segment, then
+// deletes the segments before it. Appends are dropped while no segment is
+// open, i.e. before the first checkpoint and after close. Methods are no-ops
+// on a nil WAL.
+type bookWAL struct {
+	dir string
+
+	mu     sync.Mutex
+	file   *os.File // Segment being appended to; nil while closed
+	seq    uint64   // Sequence of the last record
+	dirty  bool     // Appended to since the last fsync
+	failed bool     // An append failed since the segment was opened
+
+	// Serializes checkpoints
+	checkpointMu sync.Mutex
+}
+
+func newBookWAL(dir string) *bookWAL {
+	return &bookWAL{dir: dir}
+}
+
+// add records order resting in ob. Callers hold ob.mu, as for every record.
+func (w *bookWAL) add(ob *OrderBook, order *Order) {
+	w.record(ob, walRecord{Op: walAdd, Order: order})
+}
+
+// remove records the order leaving ob
+func (w *bookWAL) remove(ob *OrderBook, orderID string) {
+	w.record(ob, walRecord{Op: walRemove, OrderID: orderID})
+}
+
+// fill records the fill state of ob's entries after a fill
+func (w *bookWAL) fill(ob *OrderBook, entries []*Order) {
+	if w == nil || len(entries) == 0 {
+		return
+	}
+	fills := make([]walFillState, len(entries))
+	for i, o := range entries {
+		fills[i] = walFillState{
+			OrderID:   o.ID,
+			Filled:    o.FilledQuantity,
+			Remaining: o.RemainingQuantity,
+			Status:    o.Status,
+		}
+	}
+	w.record(ob, walRecord{Op: walFill, Fills: fills})
+}
+
+// load records ob being installed with its current orders. An empty book
+// needs no record: a book absent from the log is empty.
+func (w *bookWAL) load(ob *OrderBook) {
+	if w == nil || len(ob.ordersByID) == 0 {
+		return
+	}
+	orders := make([]*Order, 0, len(ob.ordersByID))
+	for _, o := range ob.ordersByID {
+		orders = append(orders, o)
+	}
+	w.record(ob, walRecord{Op: walLoad, Orders: orders})
+}
+
+// drop records ob being uninstalled
+func (w *bookWAL) drop(ob *OrderBook) {
+	w.record(ob, walRecord{Op: walDrop})
+}
+
+// record appends rec as a mutation of ob
+func (w *bookWAL) record(ob *OrderBook, rec walRecord) {
+	if w == nil {
+		return
+	}
+	rec.PoolID, rec.BaseToken, rec.QuoteToken = ob.poolID, ob.baseToken, ob.quoteToken
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil || w.failed {
+		return
+	}
+	w.seq++
+	rec.Seq = w.seq
+	rec.At = time.Now()
+	line, err := json.Marshal(rec)
+	if err == nil {
+		_, err = w.file.Write(append(line, '\n'))
+	}
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	w.dirty = true
+}
+
+// fail stops appending until the next checkpoint once a record is lost, and
+// deletes the checkpoint, so a crash meanwhile loads the books from the
+// database rather than recovering them from a log with a gap. Callers hold
+// w.mu.
+func (w *bookWAL) fail(err error) {
+	w.failed = true
+	log.Error().Err(err).Msg("Failed to write the book WAL; books will load from the database until the next checkpoint")
+	if err := os.Remove(filepath.Join(w.dir, walCheckpointName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Error().Err(err).Msg("Failed to delete the book checkpoint")
+	}
+}
+
+// sync flushes the records appended since the last sync to disk
+func (w *bookWAL) sync() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil || !w.dirty || w.failed {
+		return
+	}
+	if err := w.file.Sync(); err != nil {
+		w.fail(err)
+		return
+	}
+	w.dirty = false
+}
+
+// close syncs and closes the segment; later appends are dropped
+func (w *bookWAL) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Sync()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file = nil
+	return err
+}
+
+// checkpoint writes every book installed in bookMgr to the checkpoint and
+// starts a new segment, then deletes the segments the checkpoint supersedes.
+// Each book is read under its lock together with the sequence of the last
+// record, so replay applies exactly the book's records that came after.
+// cursor is the replication horizon, read before the call.
+func (w *bookWAL) checkpoint(bookMgr *OrderBookManager, cursor uint64) error {
+	w.checkpointMu.Lock()
+	defer w.checkpointMu.Unlock()
+
+	started := time.Now()
+	seq, err := w.rotate()
+	if err != nil {
+		return err
+	}
+
+	cp := walCheckpoint{Seq: seq, At: started, ReplicaCursor: cursor, Books: []walBookState{}}
+	for _, book := range bookMgr.Books() {
+		if state, ok := w.bookState(book); ok {
+			cp.Books = append(cp.Books, state)
+		}
+	}
+	if err := w.writeCheckpoint(&cp); err != nil {
+		return err
+	}
+
+	// Every record in the older segments is at or before seq
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if segment.first <= seq {
+			if err := os.Remove(segment.path); err != nil {
+				return fmt.Errorf("failed to delete WAL segment: %w", err)
+			}
+		}
+	}
+
+	log.Debug().Uint64("seq", seq).Int("books", len(cp.Books)).Dur("took", time.Since(started)).Msg("Checkpointed order books")
+	return nil
+}
+
+// rotate closes the segment and opens one for the records after the last,
+// returning the last record's sequence
+func (w *bookWAL) rotate() (uint64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file != nil {
+		if err := w.file.Sync(); err != nil {
+			log.Warn().Err(err).Msg("Failed to sync WAL segment; the checkpoint supersedes it")
+		}
+		w.file.Close()
+		w.file = nil
+	}
+
+	file, err := os.OpenFile(w.segmentPath(w.seq+1), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open WAL segment: %w", err)
+	}
+	w.file, w.dirty, w.failed = file, false, false
+	return w.seq, nil
+}
+
+// bookState copies a book's orders for a checkpoint. False if the book was
+// uninstalled meanwhile; its replacement, if any, is installed after the
+// checkpoint started and replayed from the log.
+func (w *bookWAL) bookState(ob *OrderBook) (walBookState, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	if ob.wal != w {
+		return walBookState{}, false
+	}
+	state := walBookState{
+		PoolID:     ob.poolID,
+		BaseToken:  ob.baseToken,
+		QuoteToken: ob.quoteToken,
+		Orders:     make([]*Order, 0, len(ob.ordersByID)),
+	}
+	for _, o := range ob.ordersByID {
+		c := *o
+		c.trace, c.done = nil, nil
+		state.Orders = append(state.Orders, &c)
+	}
+
+	w.mu.Lock()
+	state.Seq = w.seq
+	w.mu.Unlock()
+	return state, true
+}
+
+// writeCheckpoint atomically replaces the checkpoint file with cp, unless an
+// append failed since the checkpoint started
+func (w *bookWAL) writeCheckpoint(cp *walCheckpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	path := filepath.Join(w.dir, walCheckpointName)
+	tmp := path + ".tmp"
+	if err := writeFileSynced(tmp, data); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+
+	w.mu.Lock()
+	if w.failed {
+		w.mu.Unlock()
+		os.Remove(tmp)
+		return fmt.Errorf("a WAL append failed during the checkpoint")
+	}
+	err = os.Rename(tmp, path)
+	w.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to install checkpoint: %w", err)
+	}
+	return syncDir(w.dir)
+}
+
+// reset deletes the checkpoint and every segment, for a log that can't be
+// recovered from; the next checkpoint starts it afresh
+func (w *bookWAL) reset() error {
+	segments, err := w.segments()
+	if err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if err := os.Remove(segment.path); err != nil {
+			return fmt.Errorf("failed to delete WAL segment: %w", err)
+		}
+	}
+	if err := os.Remove(filepath.Join(w.dir, walCheckpointName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete checkpoint: %w", err)
+	}
+	w.seq = 0
+	return nil
+}
+
+// recover replays the log on top of the checkpoint and returns the resting
+// orders of every book, the checkpoint's replication cursor and the time of
+// the last record. ok is false without a checkpoint to start from. A record torn by a crash at the end of the log
+// is cut off; the WAL continues after the last whole record.
+func (w *bookWAL) recover() (orders []*Order, cursor uint64, lastAt time.Time, ok bool, err error) {
+	if err := os.MkdirAll(w.dir, 0o700); err != nil {
+		return nil, 0, time.Time{}, false, fmt.Errorf("failed to create WAL directory: %w", err)
+	}
+	data, err := os.ReadFile(filepath.Join(w.dir, walCheckpointName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, 0, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, 0, time.Time{}, false, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var cp walCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, 0, time.Time{}, false, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+
+	replay := newWALReplay(&cp)
+	segments, err := w.segments()
+	if err != nil {
+		return nil, 0, time.Time{}, false, err
+	}
+	for i, segment := range segments {
+		if err := replay.segment(segment.path, i == len(segments)-1); err != nil {
+			return nil, 0, time.Time{}, false, err
+		}
+	}
+
+	w.seq = replay.seq
+	return replay.orders(), cp.ReplicaCursor, replay.lastAt, true, nil
+}
+
+// walSegment is one segment file
+type walSegment struct {
+	first uint64 // Sequence of its first record
+	path  string
+}
+
+// segments lists the log's segments, oldest first
+func (w *bookWAL) segments() ([]walSegment, error) {
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list WAL directory: %w", err)
+	}
+	segments := make([]walSegment, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, walSegmentPrefix) || !strings.HasSuffix(name, walSegmentSuffix) {
+			continue
+		}
+		first, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, walSegmentPrefix), walSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, walSegment{first: first, path: filepath.Join(w.dir, name)})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].first < segments[j].first })
+	return segments, nil
+}
+
+func (w *bookWAL) segmentPath(first uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%s%020d%s", walSegmentPrefix, first, walSegmentSuffix))
+}
+
+// walReplay rebuilds books from a checkpoint and the records after it
+type walReplay struct {
+	since  map[string]uint64 // Per checkpointed book, the last record its state reflects
+	from   uint64            // Books missing from the checkpoint take the records after this
+	books  map[string]map[string]*Order
+	seq    uint64
+	lastAt time.Time
+}
+
+func newWALReplay(cp *walCheckpoint) *walReplay {
+	r := &walReplay{
+		since:  make(map[string]uint64, len(cp.Books)),
+		from:   cp.Seq,
+		books:  make(map[string]map[string]*Order, len(cp.Books)),
+		seq:    cp.Seq,
+		lastAt: cp.At,
+	}
+	for _, b := range cp.Books {
+		key := makeBookKey(b.PoolID, b.BaseToken, b.QuoteToken)
+		r.since[key] = b.Seq
+		r.books[key] = ordersByID(b.Orders)
+		if b.Seq > r.seq {
+			r.seq = b.Seq
+		}
+	}
+	return r
+}
+
+// segment applies every record in a segment file. A record that doesn't
+// parse ends the log if it is the last of the last segment, which is then
+// truncated before it; anywhere else the log is corrupt.
+func (r *walReplay) segment(path string, last bool) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL segment: %w", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr == io.EOF && len(line) == 0 {
+			return nil
+		}
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("failed to read WAL segment: %w", readErr)
+		}
+
+		var rec walRecord
+		err := readErr
+		if err == nil {
+			err = json.Unmarshal(line, &rec)
+		}
+		if err == nil {
+			err = r.apply(&rec)
+		}
+		if err != nil {
+			if _, peekErr := reader.Peek(1); !last || peekErr != io.EOF {
+				return fmt.Errorf("corrupt WAL segment %s at offset %d: %w", filepath.Base(path), offset, err)
+			}
+			log.Warn().Str("segment", filepath.Base(path)).Int64("offset", offset).Msg("Discarding torn record at the end of the book WAL")
+			return os.Truncate(path, offset)
+		}
+		offset += int64(len(line))
+	}
+}
+
+// apply replays one record, unless the checkpoint already reflects it
+func (r *walReplay) apply(rec *walRecord) error {
+	key := makeBookKey(rec.PoolID, rec.BaseToken, rec.QuoteToken)
+	from, ok := r.since[key]
+	if !ok {
+		from = r.from
+	}
+	if rec.Seq > r.seq {
+		r.seq = rec.Seq
+	}
+	if rec.At.After(r.lastAt) {
+		r.lastAt = rec.At
+	}
+	if rec.Seq <= from {
+		return nil
+	}
+
+	book := r.books[key]
+	if book == nil {
+		book = make(map[string]*Order)
+		r.books[key] = book
+	}
+	switch rec.Op {
+	case walAdd:
+		if rec.Order == nil {
+			return fmt.Errorf("add record %d has no order", rec.Seq)
+		}
+		book[rec.Order.ID] = rec.Order
+	case walRemove:
+		delete(book, rec.OrderID)
+	case walFill:
+		for _, f := range rec.Fills {
+			o := book[f.OrderID]
+			if o == nil {
+				continue
+			}
+			o.FilledQuantity, o.RemainingQuantity, o.Status = f.Filled, f.Remaining, f.Status
+			if !o.IsActive() {
+				delete(book, f.OrderID)
+			}
+		}
+	case walLoad:
+		r.books[key] = ordersByID(rec.Orders)
+	case walDrop:
+		delete(r.books, key)
+	default:
+		return fmt.Errorf("record %d has unknown operation %q", rec.Seq, rec.Op)
+	}
+	return nil
+}
+
+// orders returns every book's resting orders
+func (r *walReplay) orders() []*Order {
+	orders := make([]*Order, 0)
+	for _, book := range r.books {
+		for _, o := range book {
+			orders = append(orders, o)
+		}
+	}
+	return orders
+}
+
+func ordersByID(orders []*Order) map[string]*Order {
+	byID := make(map[string]*Order, len(orders))
+	for _, o := range orders {
+		byID[o.ID] = o
+	}
+	return byID
+}
+
+// writeFileSynced writes data to a new file at path and flushes it to disk
+func writeFileSynced(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// syncDir flushes a directory's entries to disk, making renames and new
+// files in it durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// recoverBooks installs the books recovered from the WAL and brings them up
+// to date with the orders table, for changes committed there whose records
+// didn't reach the log before the crash; with a nil pool expired orders are
+// only evicted. Replication resumes from the horizon read before the
+// checkpoint, so every change the checkpoint may have missed is re-read.
+// False if there
+// was nothing to recover from, or the log is unusable; the WAL is then
+// cleared and the books must be loaded from the database.
+func (e *Engine) recoverBooks(ctx context.Context) (bool, error) {
+	orders, cursor, lastAt, ok, err := e.wal.recover()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to recover order books from the WAL; loading them from the database")
+	}
+	if err == nil && ok && e.db != nil && cursor == 0 {
+		log.Warn().Msg("WAL checkpoint has no replication cursor to catch up from; loading order books from the database")
+		ok = false
+	}
+	if err != nil || !ok {
+		return false, e.wal.reset()
+	}
+
+	byPair := make(map[string][]*OrderBook)
+	pools := make(map[string]*OrderBook)
+	for _, o := range orders {
+		key := makeBookKey(o.PoolID, o.BaseToken, o.QuoteToken)
+		book, exists := pools[key]
+		if !exists {
+			book = e.bookMgr.NewBook(o.PoolID, o.BaseToken, o.QuoteToken)
+			pools[key] = book
+			pair := makePairKey(o.BaseToken, o.QuoteToken)
+			byPair[pair] = append(byPair[pair], book)
+		}
+		book.AddOrder(o)
+	}
+	for _, books := range byPair {
+		e.bookMgr.ReplacePairBooks(books[0].baseToken, books[0].quoteToken, books)
+	}
+
+	if e.db != nil {
+		e.replicaCursor = cursor
+		if err := e.replicateChanges(ctx); err != nil {
+			return true, fmt.Errorf("failed to catch up recovered books with the database: %w", err)
+		}
+	} else {
+		e.evictExpired()
+	}
+
+	log.Info().Int("orders", len(orders)).Time("last_record", lastAt).Msg("Recovered order books from the WAL")
+	return true, nil
+}
+
+// checkpointBooks checkpoints the WAL with the replication horizon, read
+// before any book so no change committed while they are read is skipped
+func (e *Engine) checkpointBooks(ctx context.Context) error {
+	var cursor uint64
+	if e.db != nil {
+		horizon, err := e.replicationHorizon(ctx)
+		if err != nil {
+			return err
+		}
+		cursor = horizon
+	}
+	return e.wal.checkpoint(e.bookMgr, cursor)
+}
+
+// walWriter syncs the WAL every walSyncInterval and checkpoints the books
+// every BookWALCheckpoint until the engine stops, which closes the WAL
+func (e *Engine) walWriter(ctx context.Context) {
+	defer e.wg.Done()
+
+	syncTicker := time.NewTicker(walSyncInterval)
+	defer syncTicker.Stop()
+	checkpointTicker := time.NewTicker(e.cfg.BookWALCheckpoint)
+	defer checkpointTicker.Stop()
+
+	for {
+		select {
+		case <-e.stopChan:
+			return
+
+		case <-syncTicker.C:
+			e.wal.sync()
+
+		case <-checkpointTicker.C:
+			if err := e.checkpointBooks(ctx); err != nil {
+				log.Error().Err(err).Msg("Failed to checkpoint order books")
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/darkpool/warlock/internal/config"
+)
+
+// startEngine starts an engine over store, with no database
+func startEngine(t *testing.T, cfg *config.Config, store *MemoryStore) *Engine {
+	t.Helper()
+	e := NewEngine(nil, cfg)
+	e.SetStore(store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := e.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() {
+		e.Stop()
+		cancel()
+	})
+	return e
+}
+
+// crash copies the WAL directory as a crash of its engine would leave it:
+// every record appended so far, nothing flushed on the way down
+func crash(t *testing.T, dir string) string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	copied := t.TempDir()
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(copied, entry.Name()), data, 0o600); err != nil {
+			t.Fatalf("WriteFile: %v", err)
+		}
+	}
+	return copied
+}
+
+// recoverEngine starts an engine on the WAL in dir over an empty store, so
+// its books can only come from the WAL
+func recoverEngine(t *testing.T, cfg *config.Config, dir string) *Engine {
+	t.Helper()
+	recovered := *cfg
+	recovered.BookWALDir = dir
+	return startEngine(t, &recovered, NewMemoryStore())
+}
+
+// bookOrders describes every resting order by book and ID
+func bookOrders(e *Engine) map[string]string {
+	orders := make(map[string]string)
+	for _, book := range e.bookMgr.Books() {
+		bids, asks, _ := book.Snapshot()
+		for _, o := range append(bids, asks...) {
+			orders[book.key()+"/"+o.ID] = fmt.Sprintf("%s %s@%s filled %s remaining %s %s",
+				o.OrderType, o.Quantity, o.Price, o.FilledQuantity, o.RemainingQuantity, o.Status)
+		}
+	}
+	return orders
+}
+
+func TestWALReplaysBooksAfterCrash(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BookWALDir = filepath.Join(t.TempDir(), "wal")
+	e := startEngine(t, cfg, NewMemoryStore())
+	ctx := context.Background()
+
+	submit(t, e, testOrder("0xalice", OrderTypeSell, "5", "100", 100))
+	cancelled := testOrder("0xcarol", OrderTypeSell, "3", "102", 100)
+	submit(t, e, cancelled)
+	submit(t, e, testOrder("0xdave", OrderTypeBuy, "2", "90", 100))
+	pooled := testOrder("0xerin", OrderTypeSell, "1", "100", 100)
+	pooled.PoolID = "institutional"
+	submit(t, e, pooled)
+
+	// A partial fill, a full fill and a cancel, then a rebuild from the store
+	if matches := submit(t, e, testOrder("0xbob", OrderTypeBuy, "2", "100", 100)); len(matches) != 1 {
+		t.Fatalf("got %d matches, want 1", len(matches))
+	}
+	if err := e.CancelOrder(ctx, cancelled.ID, "0xcarol"); err != nil {
+		t.Fatalf("CancelOrder: %v", err)
+	}
+	if _, _, err := e.RebuildBook(ctx, "WETH", "USDC"); err != nil {
+		t.Fatalf("RebuildBook: %v", err)
+	}
+	submit(t, e, testOrder("0xfrank", OrderTypeBuy, "1", "95", 100))
+
+	want := bookOrders(e)
+	if len(want) != 4 {
+		t.Fatalf("%d orders resting before the crash, want 4: %v", len(want), want)
+	}
+
+	recovered := recoverEngine(t, cfg, crash(t, cfg.BookWALDir))
+	if got := bookOrders(recovered); !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered books\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestWALCheckpointTruncatesLog(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BookWALDir = t.TempDir()
+	e := startEngine(t, cfg, NewMemoryStore())
+
+	submit(t, e, testOrder("0xalice", OrderTypeSell, "5", "100", 100))
+	submit(t, e, testOrder("0xbob", OrderTypeBuy, "2", "100", 100))
+	if err := e.checkpointBooks(context.Background()); err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+
+	segments, err := e.wal.segments()
+	if err != nil {
+		t.Fatalf("segments: %v", err)
+	}
+	if len(segments) != 1 {
+		t.Fatalf("%d segments after a checkpoint, want only the new one", len(segments))
+	}
+	info, err := os.Stat(segments[0].path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if info.Size() != 0 {
+		t.Fatalf("new segment holds %d bytes, want none", info.Size())
+	}
+
+	// Records after the checkpoint replay on top of it
+	submit(t, e, testOrder("0xcarol", OrderTypeSell, "1", "101", 100))
+	submit(t, e, testOrder("0xdave", OrderTypeBuy, "1", "100", 100))
+
+	want := bookOrders(e)
+	recovered := recoverEngine(t, cfg, crash(t, cfg.BookWALDir))
+	if got := bookOrders(recovered); !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered books\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestWALCheckpointKeepsReplicationCursor(t *testing.T) {
+	dir := t.TempDir()
+	w := newBookWAL(dir)
+	if _, _, _, _, err := w.recover(); err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+
+	if err := w.checkpoint(NewOrderBookManager(), 12345); err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	_, cursor, _, ok, err := newBookWAL(dir).recover()
+	if err != nil || !ok {
+		t.Fatalf("recover = %t, %v", ok, err)
+	}
+	if cursor != 12345 {
+		t.Errorf("recovered replication cursor %d, want 12345", cursor)
+	}
+}
+
+func TestWALDiscardsTornRecord(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BookWALDir = t.TempDir()
+	e := startEngine(t, cfg, NewMemoryStore())
+
+	submit(t, e, testOrder("0xalice", OrderTypeSell, "5", "100", 100))
+	submit(t, e, testOrder("0xbob", OrderTypeBuy, "1", "99", 100))
+	want := bookOrders(e)
+
+	// The crash cut the next record short
+	dir := crash(t, cfg.BookWALDir)
+	segments, err := (&bookWAL{dir: dir}).segments()
+	if err != nil || len(segments) == 0 {
+		t.Fatalf("segments = %v, %v", segments, err)
+	}
+	last := segments[len(segments)-1].path
+	intact, err := os.ReadFile(last)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	torn := append(append([]byte(nil), intact...), `{"seq":99,"op":"add","order":{"ID":`...)
+	if err := os.WriteFile(last, torn, 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	recovered := recoverEngine(t, cfg, dir)
+	if got := bookOrders(recovered); !reflect.DeepEqual(got, want) {
+		t.Errorf("recovered books\n%v\nwant\n%v", got, want)
+	}
+	if got, err := os.ReadFile(last); err == nil && len(got) > len(intact) {
+		t.Errorf("torn record left in the segment: %q", got[len(intact):])
+	}
+}
+
+func TestWALWithoutCheckpointLoadsFromStore(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.BookWALDir = t.TempDir()
+	store := NewMemoryStore()
+	e := startEngine(t, cfg, store)
+
+	submit(t, e, testOrder("0xalice", OrderTypeSell, "5", "100", 100))
+	submit(t, e, testOrder("0xbob", OrderTypeBuy, "2", "100", 100))
+	want := bookOrders(e)
+
+	// A failed append deletes the checkpoint; the log alone can't be trusted
+	dir := crash(t, cfg.BookWALDir)
+	if err := os.Remove(filepath.Join(dir, walCheckpointName)); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+
+	recovered := *cfg
+	recovered.BookWALDir = dir
+	reloaded := startEngine(t, &recovered, store)
+	if got := bookOrders(reloaded); !reflect.DeepEqual(got, want) {
+		t.Errorf("reloaded books\n%v\nwant\n%v", got, want)
+	}
+
+	// The stale log went, and the reload was checkpointed
+	segments, err := reloaded.wal.segments()
+	if err != nil || len(segments) != 1 {
+		t.Errorf("%d segments (%v), want only the new one", len(segments), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, walCheckpointName)); err != nil {
+		t.Errorf("no checkpoint after reloading: %v", err)
+	}
+}
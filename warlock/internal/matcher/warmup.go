@@ -0,0 +1,112 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darkpool/warlock/internal/config"
+	"github.com/rs/zerolog/log"
+)
+
+// tokenPair identifies one order book
+type tokenPair struct {
+	base  string
+	quote string
+}
+
+// hotPairs returns the configured hot pairs; entries were checked by Validate
+func (e *Engine) hotPairs() []tokenPair {
+	pairs := make([]tokenPair, 0, len(e.cfg.HotPairs))
+	for _, entry := range e.cfg.HotPairs {
+		base, quote, _ := config.ParsePair(entry)
+		pairs = append(pairs, tokenPair{base: base, quote: quote})
+	}
+	return pairs
+}
+
+// loadPairs loads each pair's book from the database and marks it ready
+func (e *Engine) loadPairs(ctx context.Context, pairs []tokenPair) error {
+	for _, p := range pairs {
+		if _, _, err := e.RebuildBook(ctx, p.base, p.quote); err != nil {
+			return fmt.Errorf("failed to load %s/%s: %w", p.base, p.quote, err)
+		}
+		e.markPairReady(p.base, p.quote)
+	}
+	return nil
+}
+
+// warmRemainingPairs loads every pair that isn't hot in the background, then
+// marks the engine fully warmed up
+func (e *Engine) warmRemainingPairs(ctx context.Context, hot []tokenPair) {
+	defer e.wg.Done()
+
+	// Even on failure stop reporting pairs as warming; a failed book can be
+	// reloaded with RebuildBook
+	defer e.markWarmedUp()
+
+	pairs, err := e.activePairs(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list pairs for warm-up")
+		return
+	}
+
+	isHot := make(map[string]bool, len(hot))
+	for _, p := range hot {
+		isHot[makePairKey(p.base, p.quote)] = true
+	}
+
+	loaded := 0
+	for _, p := range pairs {
+		select {
+		case <-e.stopChan:
+			return
+		default:
+		}
+
+		if isHot[makePairKey(p.base, p.quote)] {
+			continue
+		}
+
+		if err := e.loadPairs(ctx, []tokenPair{p}); err != nil {
+			log.Error().Err(err).Msg("Failed to warm up order book")
+			continue
+		}
+		loaded++
+	}
+
+	log.Info().Int("pairs", loaded).Msg("Order book warm-up complete")
+}
+
+// activePairs lists the pairs that currently have matchable orders
+func (e *Engine) activePairs(ctx context.Context) ([]tokenPair, error) {
+	active, err := e.store.ActivePairs(ctx, e.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]tokenPair, 0, len(active))
+	for _, p := range active {
+		pairs = append(pairs, tokenPair{base: p.BaseToken, quote: p.QuoteToken})
+	}
+	return pairs, nil
+}
+
+// IsPairReady reports whether a pair's book has finished loading.
+// Every pair is ready once warm-up completes.
+func (e *Engine) IsPairReady(baseToken, quoteToken string) bool {
+	e.readyMu.RLock()
+	defer e.readyMu.RUnlock()
+	return e.warmedUp || e.readyPairs[makePairKey(baseToken, quoteToken)]
+}
+
+func (e *Engine) markPairReady(baseToken, quoteToken string) {
+	e.readyMu.Lock()
+	defer e.readyMu.Unlock()
+	e.readyPairs[makePairKey(baseToken, quoteToken)] = true
+}
+
+func (e *Engine) markWarmedUp() {
+	e.readyMu.Lock()
+	defer e.readyMu.Unlock()
+	e.warmedUp = true
+}
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_matches_settlement_deadline;
+ALTER TABLE matches DROP COLUMN IF EXISTS settlement_deadline;
@@ -0,0 +1,6 @@
+-- Settlement deadline: matches not settled by this time are failed by the
+-- engine's reaper and their quantity is restored to the orders
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS settlement_deadline TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_matches_settlement_deadline ON matches (settlement_deadline)
+    WHERE settlement_status IN ('PENDING', 'SETTLING');
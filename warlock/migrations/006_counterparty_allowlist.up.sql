@@ -0,0 +1,2 @@
+-- Optional per-order counterparty allowlist; NULL/empty means any counterparty
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS counterparty_allowlist TEXT[];
@@ -0,0 +1,2 @@
+-- Lowercasing token addresses is not reversible; only the index is dropped
+DROP INDEX IF EXISTS idx_orders_user_lower;
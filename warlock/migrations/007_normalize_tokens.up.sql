@@ -0,0 +1,9 @@
+-- Canonicalize EVM token addresses to lowercase so mixed-case submissions of
+-- the same token share one order book
+UPDATE orders SET base_token = LOWER(base_token) WHERE base_token LIKE '0x%' AND base_token <> LOWER(base_token);
+UPDATE orders SET quote_token = LOWER(quote_token) WHERE quote_token LIKE '0x%' AND quote_token <> LOWER(quote_token);
+UPDATE matches SET base_token = LOWER(base_token) WHERE base_token LIKE '0x%' AND base_token <> LOWER(base_token);
+UPDATE matches SET quote_token = LOWER(quote_token) WHERE quote_token LIKE '0x%' AND quote_token <> LOWER(quote_token);
+
+-- Case-insensitive user lookups (cancel authorization)
+CREATE INDEX IF NOT EXISTS idx_orders_user_lower ON orders (LOWER(user_address));
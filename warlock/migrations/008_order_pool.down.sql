@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_orders_matching_buy;
+DROP INDEX IF EXISTS idx_orders_matching_sell;
+
+CREATE INDEX idx_orders_matching_buy ON orders (
+    base_token, quote_token, status, max_price DESC, created_at ASC
+) WHERE order_type = 'BUY' AND status IN ('REVEALED', 'PARTIALLY_FILLED');
+
+CREATE INDEX idx_orders_matching_sell ON orders (
+    base_token, quote_token, status, min_price ASC, created_at ASC
+) WHERE order_type = 'SELL' AND status IN ('REVEALED', 'PARTIALLY_FILLED');
+
+ALTER TABLE orders DROP COLUMN IF EXISTS pool_id;
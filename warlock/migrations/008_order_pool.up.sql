@@ -0,0 +1,14 @@
+-- Liquidity pools: orders only match within their pool. The empty string is
+-- the shared pool every existing order belongs to.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS pool_id VARCHAR(64) NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS idx_orders_matching_buy;
+DROP INDEX IF EXISTS idx_orders_matching_sell;
+
+CREATE INDEX idx_orders_matching_buy ON orders (
+    base_token, quote_token, pool_id, status, max_price DESC, created_at ASC
+) WHERE order_type = 'BUY' AND status IN ('REVEALED', 'PARTIALLY_FILLED');
+
+CREATE INDEX idx_orders_matching_sell ON orders (
+    base_token, quote_token, pool_id, status, min_price ASC, created_at ASC
+) WHERE order_type = 'SELL' AND status IN ('REVEALED', 'PARTIALLY_FILLED');
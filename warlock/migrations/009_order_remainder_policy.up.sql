@@ -0,0 +1,3 @@
+-- What happens to the unfilled part of an order partially filled on submission
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS remainder_policy VARCHAR(10) NOT NULL DEFAULT 'REST'
+    CHECK (remainder_policy IN ('REST', 'CANCEL', 'REPEG'));
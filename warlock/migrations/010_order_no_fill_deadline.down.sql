@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_orders_no_fill_deadline;
+ALTER TABLE orders DROP COLUMN IF EXISTS no_fill_deadline;
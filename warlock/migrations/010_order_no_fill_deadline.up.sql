@@ -0,0 +1,5 @@
+-- Orders still without any fill at this time are cancelled by the reaper
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS no_fill_deadline TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_orders_no_fill_deadline ON orders (no_fill_deadline)
+    WHERE no_fill_deadline IS NOT NULL AND status = 'REVEALED';
@@ -0,0 +1,2 @@
+-- Opaque client metadata echoed back with the order; never used for matching
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}'::jsonb;
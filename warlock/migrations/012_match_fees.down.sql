@@ -0,0 +1,4 @@
+ALTER TABLE matches DROP CONSTRAINT IF EXISTS matches_rebate_within_fee;
+ALTER TABLE matches DROP COLUMN IF EXISTS maker_rebate;
+ALTER TABLE matches DROP COLUMN IF EXISTS taker_fee;
+ALTER TABLE matches DROP COLUMN IF EXISTS taker_side;
@@ -0,0 +1,8 @@
+-- Fees per match, in the quote token: the taker pays taker_fee and the maker
+-- is rebated maker_rebate out of it. taker_side is NULL for older matches.
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS taker_side VARCHAR(4) CHECK (taker_side IN ('BUY', 'SELL'));
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS taker_fee NUMERIC(36, 18) NOT NULL DEFAULT 0 CHECK (taker_fee >= 0);
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS maker_rebate NUMERIC(36, 18) NOT NULL DEFAULT 0 CHECK (maker_rebate >= 0);
+
+-- The venue never pays out more than it collects
+ALTER TABLE matches ADD CONSTRAINT matches_rebate_within_fee CHECK (maker_rebate <= taker_fee);
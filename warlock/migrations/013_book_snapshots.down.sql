@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_book_snapshots_pair;
+DROP TABLE IF EXISTS book_snapshots;
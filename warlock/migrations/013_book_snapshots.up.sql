@@ -0,0 +1,15 @@
+-- Best bid/ask per book, sampled every BOOK_SNAPSHOT_INTERVAL.
+-- A side is NULL while it has no resting orders.
+
+CREATE TABLE book_snapshots (
+    id BIGSERIAL PRIMARY KEY,
+    pool_id VARCHAR(64) NOT NULL DEFAULT '',
+    base_token VARCHAR(42) NOT NULL,
+    quote_token VARCHAR(42) NOT NULL,
+    best_bid NUMERIC(36, 18),
+    best_ask NUMERIC(36, 18),
+    mid_price NUMERIC(36, 18),               -- Only when both sides are present
+    sampled_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_book_snapshots_pair ON book_snapshots (base_token, quote_token, pool_id, sampled_at);
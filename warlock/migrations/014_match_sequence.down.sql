@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_matches_seq;
+ALTER TABLE matches DROP COLUMN IF EXISTS seq;
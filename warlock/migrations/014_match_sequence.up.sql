@@ -0,0 +1,4 @@
+-- Monotonic match sequence backing StreamMatches resume tokens
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_matches_seq ON matches (seq);
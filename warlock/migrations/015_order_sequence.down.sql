@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_orders_seq;
+ALTER TABLE orders DROP COLUMN IF EXISTS seq;
@@ -0,0 +1,4 @@
+-- Monotonic order sequence; breaks time-priority ties under DETERMINISTIC_MATCHING
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS seq BIGSERIAL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_seq ON orders (seq);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS record_order_event ON orders;
+DROP TRIGGER IF EXISTS record_match_event ON matches;
+DROP FUNCTION IF EXISTS record_outbox_event();
+DROP TABLE IF EXISTS event_outbox;
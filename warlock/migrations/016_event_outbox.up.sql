@@ -0,0 +1,39 @@
+-- Transactional outbox for the event publisher. Triggers record every match
+-- and order status or fill change in the same transaction as the change
+-- itself; the publisher relays the rows to the broker and deletes them once
+-- the broker has them. Sessions only record events when they set
+-- warlock.event_outbox = 'on', which the engine does when EVENT_BROKER is set.
+
+CREATE TABLE event_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(32) NOT NULL,        -- match, or order.<status in lower case>
+    aggregate_id UUID NOT NULL,             -- Match or order id
+    payload JSONB NOT NULL,                 -- The row as it was written
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE OR REPLACE FUNCTION record_outbox_event()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF COALESCE(current_setting('warlock.event_outbox', true), '') <> 'on' THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_TABLE_NAME = 'matches' THEN
+        INSERT INTO event_outbox (event_type, aggregate_id, payload)
+        VALUES ('match', NEW.id, to_jsonb(NEW));
+    ELSIF TG_OP = 'INSERT'
+       OR NEW.status IS DISTINCT FROM OLD.status
+       OR NEW.filled_quantity IS DISTINCT FROM OLD.filled_quantity THEN
+        INSERT INTO event_outbox (event_type, aggregate_id, payload)
+        VALUES ('order.' || lower(NEW.status), NEW.id, to_jsonb(NEW));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER record_match_event AFTER INSERT ON matches
+    FOR EACH ROW EXECUTE FUNCTION record_outbox_event();
+
+CREATE TRIGGER record_order_event AFTER INSERT OR UPDATE ON orders
+    FOR EACH ROW EXECUTE FUNCTION record_outbox_event();
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_rejected_orders_reason;
+DROP INDEX IF EXISTS idx_rejected_orders_rejected_at;
+DROP TABLE IF EXISTS rejected_orders;
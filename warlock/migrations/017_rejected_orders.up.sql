@@ -0,0 +1,19 @@
+-- Order submissions the engine turned away, kept for tuning limits and bands.
+-- order_id is only set for orders rejected after they were stored, i.e. by matching.
+-- Identifiers are TEXT since rejected input needn't be well-formed.
+
+CREATE TABLE rejected_orders (
+    id BIGSERIAL PRIMARY KEY,
+    order_id UUID,
+    user_address TEXT NOT NULL DEFAULT '',
+    base_token TEXT NOT NULL DEFAULT '',
+    quote_token TEXT NOT NULL DEFAULT '',
+    order_type VARCHAR(4) NOT NULL DEFAULT '',  -- BUY, SELL or empty when unspecified
+    stage VARCHAR(16) NOT NULL,                 -- VALIDATION or MATCHING
+    reason VARCHAR(64) NOT NULL,
+    message TEXT NOT NULL DEFAULT '',
+    rejected_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_rejected_orders_rejected_at ON rejected_orders (rejected_at);
+CREATE INDEX idx_rejected_orders_reason ON rejected_orders (reason, rejected_at);
@@ -0,0 +1,4 @@
+-- Matches between orders whose bands only met once their limits were rounded
+-- to PRICE_SIGNIFICANT_DIGITS; these executed at the price both limits round
+-- to, outside one side's unrounded band.
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS rounded_price BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+-- When a speed bump lets an order be matched; until then candidate queries
+-- pass it over. NULL for orders submitted without a speed bump.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS visible_at TIMESTAMPTZ;
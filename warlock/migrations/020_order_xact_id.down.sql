@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_orders_xact_id;
+DROP TRIGGER IF EXISTS update_orders_xact_id ON orders;
+DROP FUNCTION IF EXISTS update_xact_id_column();
+ALTER TABLE orders DROP COLUMN IF EXISTS xact_id;
@@ -0,0 +1,18 @@
+-- The transaction that last wrote each order, so a standby can replicate
+-- every change however late it commits. updated_at is when the writing transaction
+-- started, and a long one commits rows stamped before changes already read.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS xact_id xid8 NOT NULL DEFAULT pg_current_xact_id();
+
+CREATE OR REPLACE FUNCTION update_xact_id_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.xact_id = pg_current_xact_id();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS update_orders_xact_id ON orders;
+CREATE TRIGGER update_orders_xact_id BEFORE INSERT OR UPDATE ON orders
+    FOR EACH ROW EXECUTE FUNCTION update_xact_id_column();
+
+CREATE INDEX IF NOT EXISTS idx_orders_xact_id ON orders (xact_id, id);
@@ -19,11 +19,47 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	MatcherService_SubmitOrder_FullMethodName   = "/warlock.v1.MatcherService/SubmitOrder"
-	MatcherService_CancelOrder_FullMethodName   = "/warlock.v1.MatcherService/CancelOrder"
-	MatcherService_GetOrderBook_FullMethodName  = "/warlock.v1.MatcherService/GetOrderBook"
-	MatcherService_StreamMatches_FullMethodName = "/warlock.v1.MatcherService/StreamMatches"
-	MatcherService_HealthCheck_FullMethodName   = "/warlock.v1.MatcherService/HealthCheck"
+	MatcherService_SubmitOrder_FullMethodName             = "/warlock.v1.MatcherService/SubmitOrder"
+	MatcherService_CancelOrder_FullMethodName             = "/warlock.v1.MatcherService/CancelOrder"
+	MatcherService_CancelReplace_FullMethodName           = "/warlock.v1.MatcherService/CancelReplace"
+	MatcherService_CancelOrdersWhere_FullMethodName       = "/warlock.v1.MatcherService/CancelOrdersWhere"
+	MatcherService_GetOrderBook_FullMethodName            = "/warlock.v1.MatcherService/GetOrderBook"
+	MatcherService_GetDepthChart_FullMethodName           = "/warlock.v1.MatcherService/GetDepthChart"
+	MatcherService_GetBookChecksum_FullMethodName         = "/warlock.v1.MatcherService/GetBookChecksum"
+	MatcherService_EstimateFill_FullMethodName            = "/warlock.v1.MatcherService/EstimateFill"
+	MatcherService_GetQueuePosition_FullMethodName        = "/warlock.v1.MatcherService/GetQueuePosition"
+	MatcherService_EstimateFillProbability_FullMethodName = "/warlock.v1.MatcherService/EstimateFillProbability"
+	MatcherService_GetBookHistory_FullMethodName          = "/warlock.v1.MatcherService/GetBookHistory"
+	MatcherService_GetOrders_FullMethodName               = "/warlock.v1.MatcherService/GetOrders"
+	MatcherService_GetOrderFills_FullMethodName           = "/warlock.v1.MatcherService/GetOrderFills"
+	MatcherService_GetUserPnL_FullMethodName              = "/warlock.v1.MatcherService/GetUserPnL"
+	MatcherService_GetNettedSettlements_FullMethodName    = "/warlock.v1.MatcherService/GetNettedSettlements"
+	MatcherService_StreamMatches_FullMethodName           = "/warlock.v1.MatcherService/StreamMatches"
+	MatcherService_StreamTicker_FullMethodName            = "/warlock.v1.MatcherService/StreamTicker"
+	MatcherService_HealthCheck_FullMethodName             = "/warlock.v1.MatcherService/HealthCheck"
+	MatcherService_GetServerTime_FullMethodName           = "/warlock.v1.MatcherService/GetServerTime"
+	MatcherService_GetCapabilities_FullMethodName         = "/warlock.v1.MatcherService/GetCapabilities"
+	MatcherService_StreamStats_FullMethodName             = "/warlock.v1.MatcherService/StreamStats"
+	MatcherService_RebuildBook_FullMethodName             = "/warlock.v1.MatcherService/RebuildBook"
+	MatcherService_PausePair_FullMethodName               = "/warlock.v1.MatcherService/PausePair"
+	MatcherService_ResumePair_FullMethodName              = "/warlock.v1.MatcherService/ResumePair"
+	MatcherService_SetChainStatus_FullMethodName          = "/warlock.v1.MatcherService/SetChainStatus"
+	MatcherService_GetChainStatus_FullMethodName          = "/warlock.v1.MatcherService/GetChainStatus"
+	MatcherService_GetMarketSession_FullMethodName        = "/warlock.v1.MatcherService/GetMarketSession"
+	MatcherService_GetMatchTrace_FullMethodName           = "/warlock.v1.MatcherService/GetMatchTrace"
+	MatcherService_GetInMemoryOrder_FullMethodName        = "/warlock.v1.MatcherService/GetInMemoryOrder"
+	MatcherService_DrainWorker_FullMethodName             = "/warlock.v1.MatcherService/DrainWorker"
+	MatcherService_GetChannelStatus_FullMethodName        = "/warlock.v1.MatcherService/GetChannelStatus"
+	MatcherService_DrainChannel_FullMethodName            = "/warlock.v1.MatcherService/DrainChannel"
+	MatcherService_ApplyMigrations_FullMethodName         = "/warlock.v1.MatcherService/ApplyMigrations"
+	MatcherService_ImportOrders_FullMethodName            = "/warlock.v1.MatcherService/ImportOrders"
+	MatcherService_GetEntityForAddress_FullMethodName     = "/warlock.v1.MatcherService/GetEntityForAddress"
+	MatcherService_GetCounterpartyMatrix_FullMethodName   = "/warlock.v1.MatcherService/GetCounterpartyMatrix"
+	MatcherService_GetRejections_FullMethodName           = "/warlock.v1.MatcherService/GetRejections"
+	MatcherService_StreamRejections_FullMethodName        = "/warlock.v1.MatcherService/StreamRejections"
+	MatcherService_GetStandbyStatus_FullMethodName        = "/warlock.v1.MatcherService/GetStandbyStatus"
+	MatcherService_PromoteStandby_FullMethodName          = "/warlock.v1.MatcherService/PromoteStandby"
+	MatcherService_GetUserActivityStats_FullMethodName    = "/warlock.v1.MatcherService/GetUserActivityStats"
 )
 
 // MatcherServiceClient is the client API for MatcherService service.
@@ -34,12 +70,84 @@ type MatcherServiceClient interface {
 	SubmitOrder(ctx context.Context, in *SubmitOrderRequest, opts ...grpc.CallOption) (*SubmitOrderResponse, error)
 	// CancelOrder cancels an existing order
 	CancelOrder(ctx context.Context, in *CancelOrderRequest, opts ...grpc.CallOption) (*CancelOrderResponse, error)
+	// CancelReplace atomically cancels an order and submits its replacement
+	CancelReplace(ctx context.Context, in *CancelReplaceRequest, opts ...grpc.CallOption) (*CancelReplaceResponse, error)
+	// CancelOrdersWhere cancels all of a user's open orders matching a filter
+	CancelOrdersWhere(ctx context.Context, in *CancelOrdersWhereRequest, opts ...grpc.CallOption) (*CancelOrdersWhereResponse, error)
 	// GetOrderBook retrieves the current order book for a token pair
 	GetOrderBook(ctx context.Context, in *GetOrderBookRequest, opts ...grpc.CallOption) (*GetOrderBookResponse, error)
+	// GetDepthChart returns cumulative bid/ask depth ready to plot
+	GetDepthChart(ctx context.Context, in *GetDepthChartRequest, opts ...grpc.CallOption) (*GetDepthChartResponse, error)
+	// GetBookChecksum returns a CRC32 of the book's top levels so clients can verify their copy
+	GetBookChecksum(ctx context.Context, in *GetBookChecksumRequest, opts ...grpc.CallOption) (*GetBookChecksumResponse, error)
+	// EstimateFill prices a hypothetical order against the current book without submitting it
+	EstimateFill(ctx context.Context, in *EstimateFillRequest, opts ...grpc.CallOption) (*EstimateFillResponse, error)
+	// GetQueuePosition reports how much resting quantity is ahead of an order at its price level
+	GetQueuePosition(ctx context.Context, in *GetQueuePositionRequest, opts ...grpc.CallOption) (*GetQueuePositionResponse, error)
+	// EstimateFillProbability estimates the chance a resting order fills within a horizon
+	EstimateFillProbability(ctx context.Context, in *EstimateFillProbabilityRequest, opts ...grpc.CallOption) (*EstimateFillProbabilityResponse, error)
+	// GetBookHistory returns sampled best bid/ask history for a pair
+	GetBookHistory(ctx context.Context, in *GetBookHistoryRequest, opts ...grpc.CallOption) (*GetBookHistoryResponse, error)
+	// GetOrders fetches several orders by id in one call
+	GetOrders(ctx context.Context, in *GetOrdersRequest, opts ...grpc.CallOption) (*GetOrdersResponse, error)
+	// GetOrderFills lists every match that filled part of an order
+	GetOrderFills(ctx context.Context, in *GetOrderFillsRequest, opts ...grpc.CallOption) (*GetOrderFillsResponse, error)
+	// GetUserPnL computes a user's realized PnL on one pair from their fills
+	GetUserPnL(ctx context.Context, in *GetUserPnLRequest, opts ...grpc.CallOption) (*GetUserPnLResponse, error)
+	// GetNettedSettlements nets the pending matches between two counterparties on one pair
+	GetNettedSettlements(ctx context.Context, in *GetNettedSettlementsRequest, opts ...grpc.CallOption) (*GetNettedSettlementsResponse, error)
 	// StreamMatches streams match events in real-time
 	StreamMatches(ctx context.Context, in *StreamMatchesRequest, opts ...grpc.CallOption) (MatcherService_StreamMatchesClient, error)
+	// StreamTicker streams a pair's last trade and top of book whenever either changes
+	StreamTicker(ctx context.Context, in *StreamTickerRequest, opts ...grpc.CallOption) (MatcherService_StreamTickerClient, error)
 	// HealthCheck verifies the service is running
 	HealthCheck(ctx context.Context, in *HealthCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error)
+	// GetServerTime returns the server clock that order expiries are judged by
+	GetServerTime(ctx context.Context, in *GetServerTimeRequest, opts ...grpc.CallOption) (*GetServerTimeResponse, error)
+	// GetCapabilities reports the order types and optional features this deployment supports
+	GetCapabilities(ctx context.Context, in *GetCapabilitiesRequest, opts ...grpc.CallOption) (*GetCapabilitiesResponse, error)
+	// StreamStats pushes engine statistics at a fixed interval
+	StreamStats(ctx context.Context, in *StreamStatsRequest, opts ...grpc.CallOption) (MatcherService_StreamStatsClient, error)
+	// Admin: RebuildBook drops a pair's in-memory book and reloads it from the database
+	RebuildBook(ctx context.Context, in *RebuildBookRequest, opts ...grpc.CallOption) (*RebuildBookResponse, error)
+	// Admin: PausePair stops a pair from accepting new orders
+	PausePair(ctx context.Context, in *PausePairRequest, opts ...grpc.CallOption) (*PausePairResponse, error)
+	// Admin: ResumePair lets a paused pair accept orders again
+	ResumePair(ctx context.Context, in *ResumePairRequest, opts ...grpc.CallOption) (*ResumePairResponse, error)
+	// Admin: SetChainStatus pauses or resumes a chain for settlement maintenance
+	SetChainStatus(ctx context.Context, in *SetChainStatusRequest, opts ...grpc.CallOption) (*SetChainStatusResponse, error)
+	// Admin: GetChainStatus reports whether a chain is paused and lists the paused chains
+	GetChainStatus(ctx context.Context, in *GetChainStatusRequest, opts ...grpc.CallOption) (*GetChainStatusResponse, error)
+	// Admin: GetMarketSession reports whether a pair's market session is open and when that changes
+	GetMarketSession(ctx context.Context, in *GetMarketSessionRequest, opts ...grpc.CallOption) (*GetMarketSessionResponse, error)
+	// Admin: GetMatchTrace returns the candidate-by-candidate matching decisions for a traced order
+	GetMatchTrace(ctx context.Context, in *GetMatchTraceRequest, opts ...grpc.CallOption) (*GetMatchTraceResponse, error)
+	// Admin: GetInMemoryOrder returns the engine's in-memory copy of an order, to compare against GetOrders
+	GetInMemoryOrder(ctx context.Context, in *GetInMemoryOrderRequest, opts ...grpc.CallOption) (*GetInMemoryOrderResponse, error)
+	// Admin: DrainWorker retires one worker, leaving its load to the others
+	DrainWorker(ctx context.Context, in *DrainWorkerRequest, opts ...grpc.CallOption) (*DrainWorkerResponse, error)
+	// Admin: GetChannelStatus reports the depth of the engine's order, cancel and match channels
+	GetChannelStatus(ctx context.Context, in *GetChannelStatusRequest, opts ...grpc.CallOption) (*GetChannelStatusResponse, error)
+	// Admin: DrainChannel discards everything queued on one engine channel
+	DrainChannel(ctx context.Context, in *DrainChannelRequest, opts ...grpc.CallOption) (*DrainChannelResponse, error)
+	// Admin: ApplyMigrations pauses matching, applies pending schema migrations and resumes
+	ApplyMigrations(ctx context.Context, in *ApplyMigrationsRequest, opts ...grpc.CallOption) (*ApplyMigrationsResponse, error)
+	// Admin: ImportOrders bulk-loads orders from a CSV or JSON document
+	ImportOrders(ctx context.Context, in *ImportOrdersRequest, opts ...grpc.CallOption) (*ImportOrdersResponse, error)
+	// Admin: GetEntityForAddress returns the entity an address belongs to for self-trade prevention
+	GetEntityForAddress(ctx context.Context, in *GetEntityForAddressRequest, opts ...grpc.CallOption) (*GetEntityForAddressResponse, error)
+	// Admin: GetCounterpartyMatrix lists address pairs that match each other unusually often
+	GetCounterpartyMatrix(ctx context.Context, in *GetCounterpartyMatrixRequest, opts ...grpc.CallOption) (*GetCounterpartyMatrixResponse, error)
+	// Admin: GetRejections lists recorded order rejections, newest first
+	GetRejections(ctx context.Context, in *GetRejectionsRequest, opts ...grpc.CallOption) (*GetRejectionsResponse, error)
+	// Admin: StreamRejections streams order rejections as they are recorded
+	StreamRejections(ctx context.Context, in *StreamRejectionsRequest, opts ...grpc.CallOption) (MatcherService_StreamRejectionsClient, error)
+	// Admin: GetStandbyStatus reports a standby's replication and checksum comparison state
+	GetStandbyStatus(ctx context.Context, in *GetStandbyStatusRequest, opts ...grpc.CallOption) (*GetStandbyStatusResponse, error)
+	// Admin: PromoteStandby makes a standby the primary once the old primary is down
+	PromoteStandby(ctx context.Context, in *PromoteStandbyRequest, opts ...grpc.CallOption) (*PromoteStandbyResponse, error)
+	// Admin: GetUserActivityStats reports a user's cancel and fill rates and flags likely spoofing
+	GetUserActivityStats(ctx context.Context, in *GetUserActivityStatsRequest, opts ...grpc.CallOption) (*GetUserActivityStatsResponse, error)
 }
 
 type matcherServiceClient struct {
@@ -68,6 +176,24 @@ func (c *matcherServiceClient) CancelOrder(ctx context.Context, in *CancelOrderR
 	return out, nil
 }
 
+func (c *matcherServiceClient) CancelReplace(ctx context.Context, in *CancelReplaceRequest, opts ...grpc.CallOption) (*CancelReplaceResponse, error) {
+	out := new(CancelReplaceResponse)
+	err := c.cc.Invoke(ctx, MatcherService_CancelReplace_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) CancelOrdersWhere(ctx context.Context, in *CancelOrdersWhereRequest, opts ...grpc.CallOption) (*CancelOrdersWhereResponse, error) {
+	out := new(CancelOrdersWhereResponse)
+	err := c.cc.Invoke(ctx, MatcherService_CancelOrdersWhere_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *matcherServiceClient) GetOrderBook(ctx context.Context, in *GetOrderBookRequest, opts ...grpc.CallOption) (*GetOrderBookResponse, error) {
 	out := new(GetOrderBookResponse)
 	err := c.cc.Invoke(ctx, MatcherService_GetOrderBook_FullMethodName, in, out, opts...)
@@ -77,185 +203,1397 @@ func (c *matcherServiceClient) GetOrderBook(ctx context.Context, in *GetOrderBoo
 	return out, nil
 }
 
-func (c *matcherServiceClient) StreamMatches(ctx context.Context, in *StreamMatchesRequest, opts ...grpc.CallOption) (MatcherService_StreamMatchesClient, error) {
-	stream, err := c.cc.NewStream(ctx, &MatcherService_ServiceDesc.Streams[0], MatcherService_StreamMatches_FullMethodName, opts...)
-	if err != nil {
+func (c *matcherServiceClient) GetDepthChart(ctx context.Context, in *GetDepthChartRequest, opts ...grpc.CallOption) (*GetDepthChartResponse, error) {
+	out := new(GetDepthChartResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetDepthChart_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetBookChecksum(ctx context.Context, in *GetBookChecksumRequest, opts ...grpc.CallOption) (*GetBookChecksumResponse, error) {
+	out := new(GetBookChecksumResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetBookChecksum_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) EstimateFill(ctx context.Context, in *EstimateFillRequest, opts ...grpc.CallOption) (*EstimateFillResponse, error) {
+	out := new(EstimateFillResponse)
+	err := c.cc.Invoke(ctx, MatcherService_EstimateFill_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetQueuePosition(ctx context.Context, in *GetQueuePositionRequest, opts ...grpc.CallOption) (*GetQueuePositionResponse, error) {
+	out := new(GetQueuePositionResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetQueuePosition_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) EstimateFillProbability(ctx context.Context, in *EstimateFillProbabilityRequest, opts ...grpc.CallOption) (*EstimateFillProbabilityResponse, error) {
+	out := new(EstimateFillProbabilityResponse)
+	err := c.cc.Invoke(ctx, MatcherService_EstimateFillProbability_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetBookHistory(ctx context.Context, in *GetBookHistoryRequest, opts ...grpc.CallOption) (*GetBookHistoryResponse, error) {
+	out := new(GetBookHistoryResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetBookHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetOrders(ctx context.Context, in *GetOrdersRequest, opts ...grpc.CallOption) (*GetOrdersResponse, error) {
+	out := new(GetOrdersResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetOrders_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetOrderFills(ctx context.Context, in *GetOrderFillsRequest, opts ...grpc.CallOption) (*GetOrderFillsResponse, error) {
+	out := new(GetOrderFillsResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetOrderFills_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetUserPnL(ctx context.Context, in *GetUserPnLRequest, opts ...grpc.CallOption) (*GetUserPnLResponse, error) {
+	out := new(GetUserPnLResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetUserPnL_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetNettedSettlements(ctx context.Context, in *GetNettedSettlementsRequest, opts ...grpc.CallOption) (*GetNettedSettlementsResponse, error) {
+	out := new(GetNettedSettlementsResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetNettedSettlements_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) StreamMatches(ctx context.Context, in *StreamMatchesRequest, opts ...grpc.CallOption) (MatcherService_StreamMatchesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MatcherService_ServiceDesc.Streams[0], MatcherService_StreamMatches_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &matcherServiceStreamMatchesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MatcherService_StreamMatchesClient interface {
+	Recv() (*MatchEvent, error)
+	grpc.ClientStream
+}
+
+type matcherServiceStreamMatchesClient struct {
+	grpc.ClientStream
+}
+
+func (x *matcherServiceStreamMatchesClient) Recv() (*MatchEvent, error) {
+	m := new(MatchEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *matcherServiceClient) StreamTicker(ctx context.Context, in *StreamTickerRequest, opts ...grpc.CallOption) (MatcherService_StreamTickerClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MatcherService_ServiceDesc.Streams[1], MatcherService_StreamTicker_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &matcherServiceStreamTickerClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MatcherService_StreamTickerClient interface {
+	Recv() (*Ticker, error)
+	grpc.ClientStream
+}
+
+type matcherServiceStreamTickerClient struct {
+	grpc.ClientStream
+}
+
+func (x *matcherServiceStreamTickerClient) Recv() (*Ticker, error) {
+	m := new(Ticker)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *matcherServiceClient) HealthCheck(ctx context.Context, in *HealthCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error) {
+	out := new(HealthCheckResponse)
+	err := c.cc.Invoke(ctx, MatcherService_HealthCheck_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetServerTime(ctx context.Context, in *GetServerTimeRequest, opts ...grpc.CallOption) (*GetServerTimeResponse, error) {
+	out := new(GetServerTimeResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetServerTime_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetCapabilities(ctx context.Context, in *GetCapabilitiesRequest, opts ...grpc.CallOption) (*GetCapabilitiesResponse, error) {
+	out := new(GetCapabilitiesResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetCapabilities_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) StreamStats(ctx context.Context, in *StreamStatsRequest, opts ...grpc.CallOption) (MatcherService_StreamStatsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MatcherService_ServiceDesc.Streams[2], MatcherService_StreamStats_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &matcherServiceStreamStatsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MatcherService_StreamStatsClient interface {
+	Recv() (*EngineStatsSnapshot, error)
+	grpc.ClientStream
+}
+
+type matcherServiceStreamStatsClient struct {
+	grpc.ClientStream
+}
+
+func (x *matcherServiceStreamStatsClient) Recv() (*EngineStatsSnapshot, error) {
+	m := new(EngineStatsSnapshot)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *matcherServiceClient) RebuildBook(ctx context.Context, in *RebuildBookRequest, opts ...grpc.CallOption) (*RebuildBookResponse, error) {
+	out := new(RebuildBookResponse)
+	err := c.cc.Invoke(ctx, MatcherService_RebuildBook_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) PausePair(ctx context.Context, in *PausePairRequest, opts ...grpc.CallOption) (*PausePairResponse, error) {
+	out := new(PausePairResponse)
+	err := c.cc.Invoke(ctx, MatcherService_PausePair_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) ResumePair(ctx context.Context, in *ResumePairRequest, opts ...grpc.CallOption) (*ResumePairResponse, error) {
+	out := new(ResumePairResponse)
+	err := c.cc.Invoke(ctx, MatcherService_ResumePair_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) SetChainStatus(ctx context.Context, in *SetChainStatusRequest, opts ...grpc.CallOption) (*SetChainStatusResponse, error) {
+	out := new(SetChainStatusResponse)
+	err := c.cc.Invoke(ctx, MatcherService_SetChainStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetChainStatus(ctx context.Context, in *GetChainStatusRequest, opts ...grpc.CallOption) (*GetChainStatusResponse, error) {
+	out := new(GetChainStatusResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetChainStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetMarketSession(ctx context.Context, in *GetMarketSessionRequest, opts ...grpc.CallOption) (*GetMarketSessionResponse, error) {
+	out := new(GetMarketSessionResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetMarketSession_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetMatchTrace(ctx context.Context, in *GetMatchTraceRequest, opts ...grpc.CallOption) (*GetMatchTraceResponse, error) {
+	out := new(GetMatchTraceResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetMatchTrace_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetInMemoryOrder(ctx context.Context, in *GetInMemoryOrderRequest, opts ...grpc.CallOption) (*GetInMemoryOrderResponse, error) {
+	out := new(GetInMemoryOrderResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetInMemoryOrder_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) DrainWorker(ctx context.Context, in *DrainWorkerRequest, opts ...grpc.CallOption) (*DrainWorkerResponse, error) {
+	out := new(DrainWorkerResponse)
+	err := c.cc.Invoke(ctx, MatcherService_DrainWorker_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetChannelStatus(ctx context.Context, in *GetChannelStatusRequest, opts ...grpc.CallOption) (*GetChannelStatusResponse, error) {
+	out := new(GetChannelStatusResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetChannelStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) DrainChannel(ctx context.Context, in *DrainChannelRequest, opts ...grpc.CallOption) (*DrainChannelResponse, error) {
+	out := new(DrainChannelResponse)
+	err := c.cc.Invoke(ctx, MatcherService_DrainChannel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) ApplyMigrations(ctx context.Context, in *ApplyMigrationsRequest, opts ...grpc.CallOption) (*ApplyMigrationsResponse, error) {
+	out := new(ApplyMigrationsResponse)
+	err := c.cc.Invoke(ctx, MatcherService_ApplyMigrations_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) ImportOrders(ctx context.Context, in *ImportOrdersRequest, opts ...grpc.CallOption) (*ImportOrdersResponse, error) {
+	out := new(ImportOrdersResponse)
+	err := c.cc.Invoke(ctx, MatcherService_ImportOrders_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetEntityForAddress(ctx context.Context, in *GetEntityForAddressRequest, opts ...grpc.CallOption) (*GetEntityForAddressResponse, error) {
+	out := new(GetEntityForAddressResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetEntityForAddress_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetCounterpartyMatrix(ctx context.Context, in *GetCounterpartyMatrixRequest, opts ...grpc.CallOption) (*GetCounterpartyMatrixResponse, error) {
+	out := new(GetCounterpartyMatrixResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetCounterpartyMatrix_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetRejections(ctx context.Context, in *GetRejectionsRequest, opts ...grpc.CallOption) (*GetRejectionsResponse, error) {
+	out := new(GetRejectionsResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetRejections_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) StreamRejections(ctx context.Context, in *StreamRejectionsRequest, opts ...grpc.CallOption) (MatcherService_StreamRejectionsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MatcherService_ServiceDesc.Streams[3], MatcherService_StreamRejections_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &matcherServiceStreamRejectionsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MatcherService_StreamRejectionsClient interface {
+	Recv() (*Rejection, error)
+	grpc.ClientStream
+}
+
+type matcherServiceStreamRejectionsClient struct {
+	grpc.ClientStream
+}
+
+func (x *matcherServiceStreamRejectionsClient) Recv() (*Rejection, error) {
+	m := new(Rejection)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *matcherServiceClient) GetStandbyStatus(ctx context.Context, in *GetStandbyStatusRequest, opts ...grpc.CallOption) (*GetStandbyStatusResponse, error) {
+	out := new(GetStandbyStatusResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetStandbyStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) PromoteStandby(ctx context.Context, in *PromoteStandbyRequest, opts ...grpc.CallOption) (*PromoteStandbyResponse, error) {
+	out := new(PromoteStandbyResponse)
+	err := c.cc.Invoke(ctx, MatcherService_PromoteStandby_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *matcherServiceClient) GetUserActivityStats(ctx context.Context, in *GetUserActivityStatsRequest, opts ...grpc.CallOption) (*GetUserActivityStatsResponse, error) {
+	out := new(GetUserActivityStatsResponse)
+	err := c.cc.Invoke(ctx, MatcherService_GetUserActivityStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MatcherServiceServer is the server API for MatcherService service.
+// All implementations must embed UnimplementedMatcherServiceServer
+// for forward compatibility
+type MatcherServiceServer interface {
+	// SubmitOrder submits a new order to the matching engine
+	SubmitOrder(context.Context, *SubmitOrderRequest) (*SubmitOrderResponse, error)
+	// CancelOrder cancels an existing order
+	CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error)
+	// CancelReplace atomically cancels an order and submits its replacement
+	CancelReplace(context.Context, *CancelReplaceRequest) (*CancelReplaceResponse, error)
+	// CancelOrdersWhere cancels all of a user's open orders matching a filter
+	CancelOrdersWhere(context.Context, *CancelOrdersWhereRequest) (*CancelOrdersWhereResponse, error)
+	// GetOrderBook retrieves the current order book for a token pair
+	GetOrderBook(context.Context, *GetOrderBookRequest) (*GetOrderBookResponse, error)
+	// GetDepthChart returns cumulative bid/ask depth ready to plot
+	GetDepthChart(context.Context, *GetDepthChartRequest) (*GetDepthChartResponse, error)
+	// GetBookChecksum returns a CRC32 of the book's top levels so clients can verify their copy
+	GetBookChecksum(context.Context, *GetBookChecksumRequest) (*GetBookChecksumResponse, error)
+	// EstimateFill prices a hypothetical order against the current book without submitting it
+	EstimateFill(context.Context, *EstimateFillRequest) (*EstimateFillResponse, error)
+	// GetQueuePosition reports how much resting quantity is ahead of an order at its price level
+	GetQueuePosition(context.Context, *GetQueuePositionRequest) (*GetQueuePositionResponse, error)
+	// EstimateFillProbability estimates the chance a resting order fills within a horizon
+	EstimateFillProbability(context.Context, *EstimateFillProbabilityRequest) (*EstimateFillProbabilityResponse, error)
+	// GetBookHistory returns sampled best bid/ask history for a pair
+	GetBookHistory(context.Context, *GetBookHistoryRequest) (*GetBookHistoryResponse, error)
+	// GetOrders fetches several orders by id in one call
+	GetOrders(context.Context, *GetOrdersRequest) (*GetOrdersResponse, error)
+	// GetOrderFills lists every match that filled part of an order
+	GetOrderFills(context.Context, *GetOrderFillsRequest) (*GetOrderFillsResponse, error)
+	// GetUserPnL computes a user's realized PnL on one pair from their fills
+	GetUserPnL(context.Context, *GetUserPnLRequest) (*GetUserPnLResponse, error)
+	// GetNettedSettlements nets the pending matches between two counterparties on one pair
+	GetNettedSettlements(context.Context, *GetNettedSettlementsRequest) (*GetNettedSettlementsResponse, error)
+	// StreamMatches streams match events in real-time
+	StreamMatches(*StreamMatchesRequest, MatcherService_StreamMatchesServer) error
+	// StreamTicker streams a pair's last trade and top of book whenever either changes
+	StreamTicker(*StreamTickerRequest, MatcherService_StreamTickerServer) error
+	// HealthCheck verifies the service is running
+	HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error)
+	// GetServerTime returns the server clock that order expiries are judged by
+	GetServerTime(context.Context, *GetServerTimeRequest) (*GetServerTimeResponse, error)
+	// GetCapabilities reports the order types and optional features this deployment supports
+	GetCapabilities(context.Context, *GetCapabilitiesRequest) (*GetCapabilitiesResponse, error)
+	// StreamStats pushes engine statistics at a fixed interval
+	StreamStats(*StreamStatsRequest, MatcherService_StreamStatsServer) error
+	// Admin: RebuildBook drops a pair's in-memory book and reloads it from the database
+	RebuildBook(context.Context, *RebuildBookRequest) (*RebuildBookResponse, error)
+	// Admin: PausePair stops a pair from accepting new orders
+	PausePair(context.Context, *PausePairRequest) (*PausePairResponse, error)
+	// Admin: ResumePair lets a paused pair accept orders again
+	ResumePair(context.Context, *ResumePairRequest) (*ResumePairResponse, error)
+	// Admin: SetChainStatus pauses or resumes a chain for settlement maintenance
+	SetChainStatus(context.Context, *SetChainStatusRequest) (*SetChainStatusResponse, error)
+	// Admin: GetChainStatus reports whether a chain is paused and lists the paused chains
+	GetChainStatus(context.Context, *GetChainStatusRequest) (*GetChainStatusResponse, error)
+	// Admin: GetMarketSession reports whether a pair's market session is open and when that changes
+	GetMarketSession(context.Context, *GetMarketSessionRequest) (*GetMarketSessionResponse, error)
+	// Admin: GetMatchTrace returns the candidate-by-candidate matching decisions for a traced order
+	GetMatchTrace(context.Context, *GetMatchTraceRequest) (*GetMatchTraceResponse, error)
+	// Admin: GetInMemoryOrder returns the engine's in-memory copy of an order, to compare against GetOrders
+	GetInMemoryOrder(context.Context, *GetInMemoryOrderRequest) (*GetInMemoryOrderResponse, error)
+	// Admin: DrainWorker retires one worker, leaving its load to the others
+	DrainWorker(context.Context, *DrainWorkerRequest) (*DrainWorkerResponse, error)
+	// Admin: GetChannelStatus reports the depth of the engine's order, cancel and match channels
+	GetChannelStatus(context.Context, *GetChannelStatusRequest) (*GetChannelStatusResponse, error)
+	// Admin: DrainChannel discards everything queued on one engine channel
+	DrainChannel(context.Context, *DrainChannelRequest) (*DrainChannelResponse, error)
+	// Admin: ApplyMigrations pauses matching, applies pending schema migrations and resumes
+	ApplyMigrations(context.Context, *ApplyMigrationsRequest) (*ApplyMigrationsResponse, error)
+	// Admin: ImportOrders bulk-loads orders from a CSV or JSON document
+	ImportOrders(context.Context, *ImportOrdersRequest) (*ImportOrdersResponse, error)
+	// Admin: GetEntityForAddress returns the entity an address belongs to for self-trade prevention
+	GetEntityForAddress(context.Context, *GetEntityForAddressRequest) (*GetEntityForAddressResponse, error)
+	// Admin: GetCounterpartyMatrix lists address pairs that match each other unusually often
+	GetCounterpartyMatrix(context.Context, *GetCounterpartyMatrixRequest) (*GetCounterpartyMatrixResponse, error)
+	// Admin: GetRejections lists recorded order rejections, newest first
+	GetRejections(context.Context, *GetRejectionsRequest) (*GetRejectionsResponse, error)
+	// Admin: StreamRejections streams order rejections as they are recorded
+	StreamRejections(*StreamRejectionsRequest, MatcherService_StreamRejectionsServer) error
+	// Admin: GetStandbyStatus reports a standby's replication and checksum comparison state
+	GetStandbyStatus(context.Context, *GetStandbyStatusRequest) (*GetStandbyStatusResponse, error)
+	// Admin: PromoteStandby makes a standby the primary once the old primary is down
+	PromoteStandby(context.Context, *PromoteStandbyRequest) (*PromoteStandbyResponse, error)
+	// Admin: GetUserActivityStats reports a user's cancel and fill rates and flags likely spoofing
+	GetUserActivityStats(context.Context, *GetUserActivityStatsRequest) (*GetUserActivityStatsResponse, error)
+	mustEmbedUnimplementedMatcherServiceServer()
+}
+
+// UnimplementedMatcherServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedMatcherServiceServer struct {
+}
+
+func (UnimplementedMatcherServiceServer) SubmitOrder(context.Context, *SubmitOrderRequest) (*SubmitOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitOrder not implemented")
+}
+func (UnimplementedMatcherServiceServer) CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
+}
+func (UnimplementedMatcherServiceServer) CancelReplace(context.Context, *CancelReplaceRequest) (*CancelReplaceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelReplace not implemented")
+}
+func (UnimplementedMatcherServiceServer) CancelOrdersWhere(context.Context, *CancelOrdersWhereRequest) (*CancelOrdersWhereResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelOrdersWhere not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetOrderBook(context.Context, *GetOrderBookRequest) (*GetOrderBookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderBook not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetDepthChart(context.Context, *GetDepthChartRequest) (*GetDepthChartResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDepthChart not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetBookChecksum(context.Context, *GetBookChecksumRequest) (*GetBookChecksumResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBookChecksum not implemented")
+}
+func (UnimplementedMatcherServiceServer) EstimateFill(context.Context, *EstimateFillRequest) (*EstimateFillResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EstimateFill not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetQueuePosition(context.Context, *GetQueuePositionRequest) (*GetQueuePositionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQueuePosition not implemented")
+}
+func (UnimplementedMatcherServiceServer) EstimateFillProbability(context.Context, *EstimateFillProbabilityRequest) (*EstimateFillProbabilityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EstimateFillProbability not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetBookHistory(context.Context, *GetBookHistoryRequest) (*GetBookHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBookHistory not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetOrders(context.Context, *GetOrdersRequest) (*GetOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrders not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetOrderFills(context.Context, *GetOrderFillsRequest) (*GetOrderFillsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderFills not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetUserPnL(context.Context, *GetUserPnLRequest) (*GetUserPnLResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserPnL not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetNettedSettlements(context.Context, *GetNettedSettlementsRequest) (*GetNettedSettlementsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNettedSettlements not implemented")
+}
+func (UnimplementedMatcherServiceServer) StreamMatches(*StreamMatchesRequest, MatcherService_StreamMatchesServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamMatches not implemented")
+}
+func (UnimplementedMatcherServiceServer) StreamTicker(*StreamTickerRequest, MatcherService_StreamTickerServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamTicker not implemented")
+}
+func (UnimplementedMatcherServiceServer) HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HealthCheck not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetServerTime(context.Context, *GetServerTimeRequest) (*GetServerTimeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetServerTime not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetCapabilities(context.Context, *GetCapabilitiesRequest) (*GetCapabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCapabilities not implemented")
+}
+func (UnimplementedMatcherServiceServer) StreamStats(*StreamStatsRequest, MatcherService_StreamStatsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamStats not implemented")
+}
+func (UnimplementedMatcherServiceServer) RebuildBook(context.Context, *RebuildBookRequest) (*RebuildBookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RebuildBook not implemented")
+}
+func (UnimplementedMatcherServiceServer) PausePair(context.Context, *PausePairRequest) (*PausePairResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PausePair not implemented")
+}
+func (UnimplementedMatcherServiceServer) ResumePair(context.Context, *ResumePairRequest) (*ResumePairResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResumePair not implemented")
+}
+func (UnimplementedMatcherServiceServer) SetChainStatus(context.Context, *SetChainStatusRequest) (*SetChainStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetChainStatus not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetChainStatus(context.Context, *GetChainStatusRequest) (*GetChainStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChainStatus not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetMarketSession(context.Context, *GetMarketSessionRequest) (*GetMarketSessionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMarketSession not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetMatchTrace(context.Context, *GetMatchTraceRequest) (*GetMatchTraceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMatchTrace not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetInMemoryOrder(context.Context, *GetInMemoryOrderRequest) (*GetInMemoryOrderResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetInMemoryOrder not implemented")
+}
+func (UnimplementedMatcherServiceServer) DrainWorker(context.Context, *DrainWorkerRequest) (*DrainWorkerResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainWorker not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetChannelStatus(context.Context, *GetChannelStatusRequest) (*GetChannelStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetChannelStatus not implemented")
+}
+func (UnimplementedMatcherServiceServer) DrainChannel(context.Context, *DrainChannelRequest) (*DrainChannelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DrainChannel not implemented")
+}
+func (UnimplementedMatcherServiceServer) ApplyMigrations(context.Context, *ApplyMigrationsRequest) (*ApplyMigrationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyMigrations not implemented")
+}
+func (UnimplementedMatcherServiceServer) ImportOrders(context.Context, *ImportOrdersRequest) (*ImportOrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportOrders not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetEntityForAddress(context.Context, *GetEntityForAddressRequest) (*GetEntityForAddressResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetEntityForAddress not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetCounterpartyMatrix(context.Context, *GetCounterpartyMatrixRequest) (*GetCounterpartyMatrixResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCounterpartyMatrix not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetRejections(context.Context, *GetRejectionsRequest) (*GetRejectionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRejections not implemented")
+}
+func (UnimplementedMatcherServiceServer) StreamRejections(*StreamRejectionsRequest, MatcherService_StreamRejectionsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamRejections not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetStandbyStatus(context.Context, *GetStandbyStatusRequest) (*GetStandbyStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStandbyStatus not implemented")
+}
+func (UnimplementedMatcherServiceServer) PromoteStandby(context.Context, *PromoteStandbyRequest) (*PromoteStandbyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PromoteStandby not implemented")
+}
+func (UnimplementedMatcherServiceServer) GetUserActivityStats(context.Context, *GetUserActivityStatsRequest) (*GetUserActivityStatsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUserActivityStats not implemented")
+}
+func (UnimplementedMatcherServiceServer) mustEmbedUnimplementedMatcherServiceServer() {}
+
+// UnsafeMatcherServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MatcherServiceServer will
+// result in compilation errors.
+type UnsafeMatcherServiceServer interface {
+	mustEmbedUnimplementedMatcherServiceServer()
+}
+
+func RegisterMatcherServiceServer(s grpc.ServiceRegistrar, srv MatcherServiceServer) {
+	s.RegisterService(&MatcherService_ServiceDesc, srv)
+}
+
+func _MatcherService_SubmitOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).SubmitOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_SubmitOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).SubmitOrder(ctx, req.(*SubmitOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).CancelOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_CancelOrder_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).CancelOrder(ctx, req.(*CancelOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_CancelReplace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelReplaceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).CancelReplace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_CancelReplace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).CancelReplace(ctx, req.(*CancelReplaceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_CancelOrdersWhere_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelOrdersWhereRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).CancelOrdersWhere(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_CancelOrdersWhere_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).CancelOrdersWhere(ctx, req.(*CancelOrdersWhereRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetOrderBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetOrderBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetOrderBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetOrderBook(ctx, req.(*GetOrderBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetDepthChart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDepthChartRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetDepthChart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetDepthChart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetDepthChart(ctx, req.(*GetDepthChartRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetBookChecksum_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBookChecksumRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetBookChecksum(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetBookChecksum_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetBookChecksum(ctx, req.(*GetBookChecksumRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_EstimateFill_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EstimateFillRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).EstimateFill(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_EstimateFill_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).EstimateFill(ctx, req.(*EstimateFillRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetQueuePosition_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetQueuePositionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetQueuePosition(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetQueuePosition_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetQueuePosition(ctx, req.(*GetQueuePositionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_EstimateFillProbability_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EstimateFillProbabilityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).EstimateFillProbability(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_EstimateFillProbability_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).EstimateFillProbability(ctx, req.(*EstimateFillProbabilityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetBookHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBookHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetBookHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetBookHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetBookHistory(ctx, req.(*GetBookHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetOrders(ctx, req.(*GetOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetOrderFills_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderFillsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetOrderFills(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetOrderFills_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetOrderFills(ctx, req.(*GetOrderFillsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetUserPnL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserPnLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetUserPnL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetUserPnL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetUserPnL(ctx, req.(*GetUserPnLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetNettedSettlements_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNettedSettlementsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetNettedSettlements(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetNettedSettlements_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetNettedSettlements(ctx, req.(*GetNettedSettlementsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_StreamMatches_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamMatchesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MatcherServiceServer).StreamMatches(m, &matcherServiceStreamMatchesServer{stream})
+}
+
+type MatcherService_StreamMatchesServer interface {
+	Send(*MatchEvent) error
+	grpc.ServerStream
+}
+
+type matcherServiceStreamMatchesServer struct {
+	grpc.ServerStream
+}
+
+func (x *matcherServiceStreamMatchesServer) Send(m *MatchEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MatcherService_StreamTicker_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamTickerRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MatcherServiceServer).StreamTicker(m, &matcherServiceStreamTickerServer{stream})
+}
+
+type MatcherService_StreamTickerServer interface {
+	Send(*Ticker) error
+	grpc.ServerStream
+}
+
+type matcherServiceStreamTickerServer struct {
+	grpc.ServerStream
+}
+
+func (x *matcherServiceStreamTickerServer) Send(m *Ticker) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MatcherService_HealthCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).HealthCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_HealthCheck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).HealthCheck(ctx, req.(*HealthCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetServerTime_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetServerTimeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetServerTime(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetServerTime_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetServerTime(ctx, req.(*GetServerTimeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCapabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetCapabilities(ctx, req.(*GetCapabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_StreamStats_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamStatsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MatcherServiceServer).StreamStats(m, &matcherServiceStreamStatsServer{stream})
+}
+
+type MatcherService_StreamStatsServer interface {
+	Send(*EngineStatsSnapshot) error
+	grpc.ServerStream
+}
+
+type matcherServiceStreamStatsServer struct {
+	grpc.ServerStream
+}
+
+func (x *matcherServiceStreamStatsServer) Send(m *EngineStatsSnapshot) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MatcherService_RebuildBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RebuildBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).RebuildBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_RebuildBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).RebuildBook(ctx, req.(*RebuildBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_PausePair_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PausePairRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).PausePair(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_PausePair_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).PausePair(ctx, req.(*PausePairRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_ResumePair_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResumePairRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).ResumePair(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_ResumePair_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).ResumePair(ctx, req.(*ResumePairRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_SetChainStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetChainStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).SetChainStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_SetChainStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).SetChainStatus(ctx, req.(*SetChainStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetChainStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChainStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetChainStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetChainStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetChainStatus(ctx, req.(*GetChainStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetMarketSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMarketSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetMarketSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetMarketSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetMarketSession(ctx, req.(*GetMarketSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetMatchTrace_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMatchTraceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetMatchTrace(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetMatchTrace_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetMatchTrace(ctx, req.(*GetMatchTraceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetInMemoryOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetInMemoryOrderRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	x := &matcherServiceStreamMatchesClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetInMemoryOrder(ctx, in)
 	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetInMemoryOrder_FullMethodName,
 	}
-	return x, nil
-}
-
-type MatcherService_StreamMatchesClient interface {
-	Recv() (*MatchEvent, error)
-	grpc.ClientStream
-}
-
-type matcherServiceStreamMatchesClient struct {
-	grpc.ClientStream
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetInMemoryOrder(ctx, req.(*GetInMemoryOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (x *matcherServiceStreamMatchesClient) Recv() (*MatchEvent, error) {
-	m := new(MatchEvent)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
+func _MatcherService_DrainWorker_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainWorkerRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return m, nil
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).DrainWorker(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_DrainWorker_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).DrainWorker(ctx, req.(*DrainWorkerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *matcherServiceClient) HealthCheck(ctx context.Context, in *HealthCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error) {
-	out := new(HealthCheckResponse)
-	err := c.cc.Invoke(ctx, MatcherService_HealthCheck_FullMethodName, in, out, opts...)
-	if err != nil {
+func _MatcherService_GetChannelStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetChannelStatusRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
-}
-
-// MatcherServiceServer is the server API for MatcherService service.
-// All implementations must embed UnimplementedMatcherServiceServer
-// for forward compatibility
-type MatcherServiceServer interface {
-	// SubmitOrder submits a new order to the matching engine
-	SubmitOrder(context.Context, *SubmitOrderRequest) (*SubmitOrderResponse, error)
-	// CancelOrder cancels an existing order
-	CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error)
-	// GetOrderBook retrieves the current order book for a token pair
-	GetOrderBook(context.Context, *GetOrderBookRequest) (*GetOrderBookResponse, error)
-	// StreamMatches streams match events in real-time
-	StreamMatches(*StreamMatchesRequest, MatcherService_StreamMatchesServer) error
-	// HealthCheck verifies the service is running
-	HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error)
-	mustEmbedUnimplementedMatcherServiceServer()
-}
-
-// UnimplementedMatcherServiceServer must be embedded to have forward compatible implementations.
-type UnimplementedMatcherServiceServer struct {
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetChannelStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetChannelStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetChannelStatus(ctx, req.(*GetChannelStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (UnimplementedMatcherServiceServer) SubmitOrder(context.Context, *SubmitOrderRequest) (*SubmitOrderResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SubmitOrder not implemented")
-}
-func (UnimplementedMatcherServiceServer) CancelOrder(context.Context, *CancelOrderRequest) (*CancelOrderResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CancelOrder not implemented")
-}
-func (UnimplementedMatcherServiceServer) GetOrderBook(context.Context, *GetOrderBookRequest) (*GetOrderBookResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetOrderBook not implemented")
-}
-func (UnimplementedMatcherServiceServer) StreamMatches(*StreamMatchesRequest, MatcherService_StreamMatchesServer) error {
-	return status.Errorf(codes.Unimplemented, "method StreamMatches not implemented")
-}
-func (UnimplementedMatcherServiceServer) HealthCheck(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method HealthCheck not implemented")
+func _MatcherService_DrainChannel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainChannelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).DrainChannel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_DrainChannel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).DrainChannel(ctx, req.(*DrainChannelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedMatcherServiceServer) mustEmbedUnimplementedMatcherServiceServer() {}
 
-// UnsafeMatcherServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to MatcherServiceServer will
-// result in compilation errors.
-type UnsafeMatcherServiceServer interface {
-	mustEmbedUnimplementedMatcherServiceServer()
+func _MatcherService_ApplyMigrations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyMigrationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).ApplyMigrations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_ApplyMigrations_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).ApplyMigrations(ctx, req.(*ApplyMigrationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterMatcherServiceServer(s grpc.ServiceRegistrar, srv MatcherServiceServer) {
-	s.RegisterService(&MatcherService_ServiceDesc, srv)
+func _MatcherService_ImportOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).ImportOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_ImportOrders_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).ImportOrders(ctx, req.(*ImportOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _MatcherService_SubmitOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SubmitOrderRequest)
+func _MatcherService_GetEntityForAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEntityForAddressRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MatcherServiceServer).SubmitOrder(ctx, in)
+		return srv.(MatcherServiceServer).GetEntityForAddress(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MatcherService_SubmitOrder_FullMethodName,
+		FullMethod: MatcherService_GetEntityForAddress_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MatcherServiceServer).SubmitOrder(ctx, req.(*SubmitOrderRequest))
+		return srv.(MatcherServiceServer).GetEntityForAddress(ctx, req.(*GetEntityForAddressRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MatcherService_CancelOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CancelOrderRequest)
+func _MatcherService_GetCounterpartyMatrix_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCounterpartyMatrixRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MatcherServiceServer).CancelOrder(ctx, in)
+		return srv.(MatcherServiceServer).GetCounterpartyMatrix(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MatcherService_CancelOrder_FullMethodName,
+		FullMethod: MatcherService_GetCounterpartyMatrix_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MatcherServiceServer).CancelOrder(ctx, req.(*CancelOrderRequest))
+		return srv.(MatcherServiceServer).GetCounterpartyMatrix(ctx, req.(*GetCounterpartyMatrixRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MatcherService_GetOrderBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetOrderBookRequest)
+func _MatcherService_GetRejections_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRejectionsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MatcherServiceServer).GetOrderBook(ctx, in)
+		return srv.(MatcherServiceServer).GetRejections(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MatcherService_GetOrderBook_FullMethodName,
+		FullMethod: MatcherService_GetRejections_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MatcherServiceServer).GetOrderBook(ctx, req.(*GetOrderBookRequest))
+		return srv.(MatcherServiceServer).GetRejections(ctx, req.(*GetRejectionsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _MatcherService_StreamMatches_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(StreamMatchesRequest)
+func _MatcherService_StreamRejections_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRejectionsRequest)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
-	return srv.(MatcherServiceServer).StreamMatches(m, &matcherServiceStreamMatchesServer{stream})
+	return srv.(MatcherServiceServer).StreamRejections(m, &matcherServiceStreamRejectionsServer{stream})
 }
 
-type MatcherService_StreamMatchesServer interface {
-	Send(*MatchEvent) error
+type MatcherService_StreamRejectionsServer interface {
+	Send(*Rejection) error
 	grpc.ServerStream
 }
 
-type matcherServiceStreamMatchesServer struct {
+type matcherServiceStreamRejectionsServer struct {
 	grpc.ServerStream
 }
 
-func (x *matcherServiceStreamMatchesServer) Send(m *MatchEvent) error {
+func (x *matcherServiceStreamRejectionsServer) Send(m *Rejection) error {
 	return x.ServerStream.SendMsg(m)
 }
 
-func _MatcherService_HealthCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(HealthCheckRequest)
+func _MatcherService_GetStandbyStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStandbyStatusRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(MatcherServiceServer).HealthCheck(ctx, in)
+		return srv.(MatcherServiceServer).GetStandbyStatus(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: MatcherService_HealthCheck_FullMethodName,
+		FullMethod: MatcherService_GetStandbyStatus_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(MatcherServiceServer).HealthCheck(ctx, req.(*HealthCheckRequest))
+		return srv.(MatcherServiceServer).GetStandbyStatus(ctx, req.(*GetStandbyStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_PromoteStandby_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromoteStandbyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).PromoteStandby(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_PromoteStandby_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).PromoteStandby(ctx, req.(*PromoteStandbyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MatcherService_GetUserActivityStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetUserActivityStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MatcherServiceServer).GetUserActivityStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MatcherService_GetUserActivityStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MatcherServiceServer).GetUserActivityStats(ctx, req.(*GetUserActivityStatsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -275,14 +1613,146 @@ var MatcherService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CancelOrder",
 			Handler:    _MatcherService_CancelOrder_Handler,
 		},
+		{
+			MethodName: "CancelReplace",
+			Handler:    _MatcherService_CancelReplace_Handler,
+		},
+		{
+			MethodName: "CancelOrdersWhere",
+			Handler:    _MatcherService_CancelOrdersWhere_Handler,
+		},
 		{
 			MethodName: "GetOrderBook",
 			Handler:    _MatcherService_GetOrderBook_Handler,
 		},
+		{
+			MethodName: "GetDepthChart",
+			Handler:    _MatcherService_GetDepthChart_Handler,
+		},
+		{
+			MethodName: "GetBookChecksum",
+			Handler:    _MatcherService_GetBookChecksum_Handler,
+		},
+		{
+			MethodName: "EstimateFill",
+			Handler:    _MatcherService_EstimateFill_Handler,
+		},
+		{
+			MethodName: "GetQueuePosition",
+			Handler:    _MatcherService_GetQueuePosition_Handler,
+		},
+		{
+			MethodName: "EstimateFillProbability",
+			Handler:    _MatcherService_EstimateFillProbability_Handler,
+		},
+		{
+			MethodName: "GetBookHistory",
+			Handler:    _MatcherService_GetBookHistory_Handler,
+		},
+		{
+			MethodName: "GetOrders",
+			Handler:    _MatcherService_GetOrders_Handler,
+		},
+		{
+			MethodName: "GetOrderFills",
+			Handler:    _MatcherService_GetOrderFills_Handler,
+		},
+		{
+			MethodName: "GetUserPnL",
+			Handler:    _MatcherService_GetUserPnL_Handler,
+		},
+		{
+			MethodName: "GetNettedSettlements",
+			Handler:    _MatcherService_GetNettedSettlements_Handler,
+		},
 		{
 			MethodName: "HealthCheck",
 			Handler:    _MatcherService_HealthCheck_Handler,
 		},
+		{
+			MethodName: "GetServerTime",
+			Handler:    _MatcherService_GetServerTime_Handler,
+		},
+		{
+			MethodName: "GetCapabilities",
+			Handler:    _MatcherService_GetCapabilities_Handler,
+		},
+		{
+			MethodName: "RebuildBook",
+			Handler:    _MatcherService_RebuildBook_Handler,
+		},
+		{
+			MethodName: "PausePair",
+			Handler:    _MatcherService_PausePair_Handler,
+		},
+		{
+			MethodName: "ResumePair",
+			Handler:    _MatcherService_ResumePair_Handler,
+		},
+		{
+			MethodName: "SetChainStatus",
+			Handler:    _MatcherService_SetChainStatus_Handler,
+		},
+		{
+			MethodName: "GetChainStatus",
+			Handler:    _MatcherService_GetChainStatus_Handler,
+		},
+		{
+			MethodName: "GetMarketSession",
+			Handler:    _MatcherService_GetMarketSession_Handler,
+		},
+		{
+			MethodName: "GetMatchTrace",
+			Handler:    _MatcherService_GetMatchTrace_Handler,
+		},
+		{
+			MethodName: "GetInMemoryOrder",
+			Handler:    _MatcherService_GetInMemoryOrder_Handler,
+		},
+		{
+			MethodName: "DrainWorker",
+			Handler:    _MatcherService_DrainWorker_Handler,
+		},
+		{
+			MethodName: "GetChannelStatus",
+			Handler:    _MatcherService_GetChannelStatus_Handler,
+		},
+		{
+			MethodName: "DrainChannel",
+			Handler:    _MatcherService_DrainChannel_Handler,
+		},
+		{
+			MethodName: "ApplyMigrations",
+			Handler:    _MatcherService_ApplyMigrations_Handler,
+		},
+		{
+			MethodName: "ImportOrders",
+			Handler:    _MatcherService_ImportOrders_Handler,
+		},
+		{
+			MethodName: "GetEntityForAddress",
+			Handler:    _MatcherService_GetEntityForAddress_Handler,
+		},
+		{
+			MethodName: "GetCounterpartyMatrix",
+			Handler:    _MatcherService_GetCounterpartyMatrix_Handler,
+		},
+		{
+			MethodName: "GetRejections",
+			Handler:    _MatcherService_GetRejections_Handler,
+		},
+		{
+			MethodName: "GetStandbyStatus",
+			Handler:    _MatcherService_GetStandbyStatus_Handler,
+		},
+		{
+			MethodName: "PromoteStandby",
+			Handler:    _MatcherService_PromoteStandby_Handler,
+		},
+		{
+			MethodName: "GetUserActivityStats",
+			Handler:    _MatcherService_GetUserActivityStats_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -290,6 +1760,21 @@ var MatcherService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _MatcherService_StreamMatches_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "StreamTicker",
+			Handler:       _MatcherService_StreamTicker_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamStats",
+			Handler:       _MatcherService_StreamStats_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "StreamRejections",
+			Handler:       _MatcherService_StreamRejections_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "warlock.proto",
 }